@@ -0,0 +1,6 @@
+create table caravan_templates (
+    caravan_template_id serial primary key
+    , corporation_id integer references corporations on delete cascade
+    , name varchar(100)
+    , data json
+);
@@ -0,0 +1,11 @@
+create table caravan_events (
+    caravan_event_id serial primary key
+    , caravan_id integer references caravans(caravan_id) on delete cascade
+    , from_state integer
+    , to_state integer
+    , cycle timestamp without time zone
+    , credits integer default 0
+    , reason varchar(200)
+    , data json
+    , inserted timestamp without time zone default (now() at time zone 'utc')
+);
@@ -0,0 +1,12 @@
+create table fame_ledger (
+    fame_ledger_id serial primary key
+    , city_id integer references cities(city_id) on delete cascade
+    , corporation_id integer
+    , delta integer
+    , fame integer
+    , reason varchar(200)
+    , cycle timestamp without time zone
+    , inserted timestamp without time zone default (now() at time zone 'utc')
+);
+
+create index fame_ledger_city_corp on fame_ledger(city_id, corporation_id);
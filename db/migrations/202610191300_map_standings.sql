@@ -0,0 +1,16 @@
+alter table maps add column ended_at timestamp without time zone default NULL;
+
+create table map_standings (
+    map_standing_id serial primary key
+    , map_id integer references maps on delete cascade
+    , rank integer
+    , corporation_id integer
+    , corporation_name varchar(50)
+    , user_id integer references users(user_id) on delete set NULL default NULL
+    , credits integer
+    , cities integer
+    , reputation integer
+    , eliminated boolean
+    , winner boolean
+    , archived_at timestamp without time zone
+);
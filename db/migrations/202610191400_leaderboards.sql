@@ -0,0 +1,8 @@
+create table leaderboards (
+    leaderboard_id serial primary key
+    , map_id integer references maps on delete cascade
+    , taken_at timestamp without time zone
+    , data json
+);
+
+create index leaderboards_map on leaderboards(map_id, taken_at);
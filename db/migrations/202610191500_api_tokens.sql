@@ -0,0 +1,12 @@
+create table api_tokens (
+    api_token_id serial primary key
+    , user_id integer references users(user_id) on delete cascade
+    , name varchar(50)
+    , scope varchar(10)
+    , hint varchar(16)
+    , token_hash varchar(64) unique
+    , created_at timestamp without time zone
+    , last_used_at timestamp without time zone
+);
+
+create index api_tokens_user on api_tokens(user_id);
@@ -0,0 +1,2 @@
+alter table users add column failed_logins integer not null default 0;
+alter table users add column locked_until timestamp without time zone default NULL;
@@ -0,0 +1,10 @@
+create table mail_tokens (
+    mail_token_id serial primary key
+    , user_id integer references users(user_id) on delete cascade
+    , purpose varchar(10)
+    , token_hash varchar(64) unique
+    , expires_at timestamp without time zone
+    , used_at timestamp without time zone default NULL
+);
+
+create index mail_tokens_user on mail_tokens(user_id, purpose);
@@ -0,0 +1,2 @@
+alter table caravan_events drop constraint if exists caravan_events_caravan_id_fkey;
+create index caravan_events_caravan_id on caravan_events(caravan_id);
@@ -0,0 +1,5 @@
+create table user_sessions (
+    user_id integer references users(user_id) on delete cascade
+    , key bytea
+    , primary key (user_id, key)
+);
@@ -12,6 +12,7 @@ create table maps (
     , created_at timestamp without time zone default (now() at time zone 'utc')
     , updated_at timestamp without time zone default (now() at time zone 'utc')
     , data json
+    , ended_at timestamp without time zone default NULL
 );
 
 create table users (
@@ -24,6 +25,8 @@ create table users (
     , admin boolean
     , last_login timestamp without time zone default (now() at time zone 'utc')
     , data json -- dont know maybe will have user preferences and stuff like that ;)
+    , failed_logins integer not null default 0
+    , locked_until timestamp without time zone default NULL
 );
 
 create table corporations (
@@ -61,6 +64,94 @@ create table caravans (
     , data json
 );
 
+create table caravan_templates (
+    caravan_template_id serial primary key
+    , corporation_id integer references corporations on delete cascade
+    , name varchar(100)
+    , data json
+);
+
+create table caravan_events (
+    caravan_event_id serial primary key
+    , caravan_id integer
+    , from_state integer
+    , to_state integer
+    , cycle timestamp without time zone
+    , credits integer default 0
+    , reason varchar(200)
+    , data json
+    , inserted timestamp without time zone default (now() at time zone 'utc')
+);
+
+create index caravan_events_caravan_id on caravan_events(caravan_id);
+
+create table fame_ledger (
+    fame_ledger_id serial primary key
+    , city_id integer references cities(city_id) on delete cascade
+    , corporation_id integer
+    , delta integer
+    , fame integer
+    , reason varchar(200)
+    , cycle timestamp without time zone
+    , inserted timestamp without time zone default (now() at time zone 'utc')
+);
+
+create index fame_ledger_city_corp on fame_ledger(city_id, corporation_id);
+
+create table map_standings (
+    map_standing_id serial primary key
+    , map_id integer references maps on delete cascade
+    , rank integer
+    , corporation_id integer
+    , corporation_name varchar(50)
+    , user_id integer references users(user_id) on delete set NULL default NULL
+    , credits integer
+    , cities integer
+    , reputation integer
+    , eliminated boolean
+    , winner boolean
+    , archived_at timestamp without time zone
+);
+
+create table leaderboards (
+    leaderboard_id serial primary key
+    , map_id integer references maps on delete cascade
+    , taken_at timestamp without time zone
+    , data json
+);
+
+create index leaderboards_map on leaderboards(map_id, taken_at);
+
+create table api_tokens (
+    api_token_id serial primary key
+    , user_id integer references users(user_id) on delete cascade
+    , name varchar(50)
+    , scope varchar(10)
+    , hint varchar(16)
+    , token_hash varchar(64) unique
+    , created_at timestamp without time zone
+    , last_used_at timestamp without time zone
+);
+
+create index api_tokens_user on api_tokens(user_id);
+
+create table mail_tokens (
+    mail_token_id serial primary key
+    , user_id integer references users(user_id) on delete cascade
+    , purpose varchar(10)
+    , token_hash varchar(64) unique
+    , expires_at timestamp without time zone
+    , used_at timestamp without time zone default NULL
+);
+
+create index mail_tokens_user on mail_tokens(user_id, purpose);
+
+create table user_sessions (
+    user_id integer references users(user_id) on delete cascade
+    , key bytea
+    , primary key (user_id, key)
+);
+
 create table user_logs (
     user_log_id  serial primary key
     , user_id integer references users(user_id) on delete cascade
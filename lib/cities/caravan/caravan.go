@@ -3,7 +3,6 @@ package caravan
 import (
 	"errors"
 	"fmt"
-	"log"
 	"strings"
 	"time"
 	"upsilon_cities_go/lib/cities/city"
@@ -17,6 +16,7 @@ import (
 	"upsilon_cities_go/lib/cities/user_log"
 	"upsilon_cities_go/lib/db"
 	"upsilon_cities_go/lib/misc/config/gameplay"
+	"upsilon_cities_go/lib/misc/logger"
 )
 
 //Object describe what will be transited in a Caravan
@@ -137,6 +137,24 @@ type Caravan struct {
 	LastChange time.Time
 	NextChange time.Time
 	EndOfTerm  time.Time
+
+	// renewal, both sides must opt in for automatic renewal.
+	OriginAutoRenew bool
+	TargetAutoRenew bool
+	RenewedID       int // caravan created on renewal.
+
+	// penalty clauses, each side escrows Penalty on accept, breaching side forfeits it.
+	Penalty       int
+	OriginInsured bool
+	TargetInsured bool
+	OriginEscrow  int
+	TargetEscrow  int
+	BreachedBy    int // corporation that broke the contract.
+	Settled       bool
+
+	// compensation locked for next leg, refunded when contract ends.
+	OriginCompEscrow int
+	TargetCompEscrow int
 }
 
 //New instantiate new caravan.
@@ -244,9 +262,11 @@ func (caravan *Caravan) Counter(dbh *db.Handler, corporationID int) error {
 			return errors.New("invalid counter")
 		}
 
+		from := caravan.State
 		caravan.State = CRVCounterProposal
 		caravan.LastChange = tools.RoundTime(time.Now().UTC())
 		caravan.NextChange = tools.AddCycles(caravan.LastChange, StateToDelay[caravan.State])
+		caravan.record(dbh, from, caravan.LastChange, fmt.Sprintf("countered by corporation %d", corporationID), nil, nil, 0)
 		return caravan.Update(dbh)
 	}
 	return errors.New("invalid state, can't counter")
@@ -263,12 +283,29 @@ func (caravan *Caravan) Refuse(dbh *db.Handler, corporationID int) error {
 			return errors.New("invalid refusal")
 		}
 
+		from := caravan.State
 		caravan.State = CRVRefused
+		caravan.record(dbh, from, time.Now().UTC(), fmt.Sprintf("refused by corporation %d", corporationID), nil, nil, 0)
 		return caravan.Update(dbh)
 	}
 	return errors.New("invalid state, can't refuse")
 }
 
+//Withdraw a pending proposal, by either party.
+func (caravan *Caravan) Withdraw(dbh *db.Handler, corporationID int) error {
+	if caravan.State != CRVProposal && caravan.State != CRVCounterProposal {
+		return errors.New("invalid state, can't withdraw")
+	}
+	if caravan.CorpTargetID != corporationID && caravan.CorpOriginID != corporationID {
+		return errors.New("invalid withdrawal")
+	}
+
+	from := caravan.State
+	caravan.State = CRVRefused
+	caravan.record(dbh, from, time.Now().UTC(), fmt.Sprintf("withdrawn by corporation %d", corporationID), nil, nil, 0)
+	return caravan.Update(dbh)
+}
+
 //Accept caravan contract.
 func (caravan *Caravan) Accept(dbh *db.Handler, corporationID int) error {
 	if caravan.State == CRVProposal || caravan.State == CRVCounterProposal {
@@ -280,17 +317,64 @@ func (caravan *Caravan) Accept(dbh *db.Handler, corporationID int) error {
 			return errors.New("invalid accept")
 		}
 
+		if err := caravan.escrowFunds(dbh); err != nil {
+			return err
+		}
+
 		user_log.NewFromCorp(caravan.CorpOriginID, user_log.UL_Good, fmt.Sprintf("%s Contract has been accepted", caravan.String()))
 		user_log.NewFromCorp(caravan.CorpTargetID, user_log.UL_Good, fmt.Sprintf("%s Contract has been accepted", caravan.String()))
 
+		from := caravan.State
 		caravan.State = CRVWaitingOriginLoad
 		caravan.LastChange = tools.RoundTime(time.Now().UTC())
 		caravan.NextChange = tools.AddCycles(caravan.LastChange, StateToDelay[caravan.State])
+		caravan.record(dbh, from, caravan.LastChange, fmt.Sprintf("accepted by corporation %d", corporationID), nil, nil, 0)
 		return caravan.Update(dbh)
 	}
 	return errors.New("invalid state, can't accept")
 }
 
+//ProposalFor facts of proposal as seen by targeted corporation owning target city.
+func (caravan *Caravan) ProposalFor(target *city.City) (res corporation.Proposal) {
+	res.FromCorpID = caravan.CorpOriginID
+	res.ItemName = caravan.Exported.ItemName
+	res.Quality = caravan.Exported.Quality.Min
+	res.ExchangeRate = float64(caravan.ExchangeRateLHS) / float64(tools.Max(caravan.ExchangeRateRHS, 1))
+	if caravan.Exported.ItemName != "" {
+		res.Producible = target.CanProduce(item.Item{Name: caravan.Exported.ItemName})
+	} else {
+		res.Producible = target.CanProduceMatching(storage.ByTypesNQuality(caravan.Exported.ItemType, caravan.Exported.Quality))
+	}
+	return
+}
+
+//ApplyProposalRules let targeted corporation standing rules answer a fresh proposal. Tells whether proposal got answered.
+func (caravan *Caravan) ApplyProposalRules(dbh *db.Handler, corp *corporation.Corporation, target *city.City) bool {
+	if caravan.State != CRVProposal || caravan.CorpTargetID != corp.ID {
+		return false
+	}
+
+	rule, found := corp.EvaluateProposal(caravan.ProposalFor(target))
+	if !found {
+		return false
+	}
+
+	var err error
+	if rule.Accept {
+		err = caravan.Accept(dbh, corp.ID)
+	} else {
+		err = caravan.Refuse(dbh, corp.ID)
+	}
+
+	if err != nil {
+		caravan.log().Errorf("Failed to apply %s on %s: %s", rule.String(), caravan.String(), err)
+		return false
+	}
+
+	user_log.NewFromCorp(corp.ID, user_log.UL_Info, fmt.Sprintf("%s answered automatically by %s", caravan.String(), rule.String()))
+	return true
+}
+
 //Abort caravan contract. Premature end of contract
 func (caravan *Caravan) Abort(dbh *db.Handler, corporationID int) error {
 	if caravan.IsActive() {
@@ -312,13 +396,18 @@ func (caravan *Caravan) Abort(dbh *db.Handler, corporationID int) error {
 			})
 		}
 
+		from := caravan.State
 		caravan.Aborted = true
+		if caravan.BreachedBy == 0 {
+			caravan.BreachedBy = corporationID
+		}
 		if caravan.State == CRVWaitingOriginLoad {
 			// no need to pursue...
 			caravan.State = CRVAborted
 			caravan.LastChange = tools.RoundTime(time.Now().UTC())
 			caravan.NextChange = tools.AddCycles(caravan.LastChange, StateToDelay[caravan.State])
 		}
+		caravan.record(dbh, from, time.Now().UTC(), fmt.Sprintf("aborted by corporation %d", corporationID), nil, nil, 0)
 		return caravan.Update(dbh)
 	}
 	return errors.New("invalid state, can't refuse")
@@ -408,6 +497,17 @@ func (caravan *Caravan) CurrentCorp() int {
 	return 0
 }
 
+//LoadingCorp returns corporation owning the city caravan is loading in, 0 when not loading.
+func (caravan *Caravan) LoadingCorp() int {
+	switch caravan.State {
+	case CRVWaitingOriginLoad:
+		return caravan.CorpOriginID
+	case CRVWaitingTargetLoad:
+		return caravan.CorpTargetID
+	}
+	return 0
+}
+
 //OtherCorpStr returns the other corp (!= to current corp)
 func (caravan *Caravan) OtherCorpStr() string {
 
@@ -476,6 +576,7 @@ func (caravan *Caravan) CorpStr(id int) string {
 
 //SetNextState caravan contract.
 func (caravan *Caravan) SetNextState(dbh *db.Handler, now time.Time) error {
+	from := caravan.State
 
 	if caravan.State == CRVTravelingToOrigin {
 		// termination check !
@@ -487,6 +588,7 @@ func (caravan *Caravan) SetNextState(dbh *db.Handler, now time.Time) error {
 
 			user_log.NewFromCorp(caravan.CorpOriginID, user_log.UL_Bad, fmt.Sprintf("%s has been aborted", caravan.String()))
 			user_log.NewFromCorp(caravan.CorpTargetID, user_log.UL_Bad, fmt.Sprintf("%s has been aborted", caravan.String()))
+			caravan.record(dbh, from, now, "back to origin after abort", nil, nil, 0)
 			return caravan.Update(dbh)
 		}
 
@@ -496,13 +598,15 @@ func (caravan *Caravan) SetNextState(dbh *db.Handler, now time.Time) error {
 			caravan.NextChange = tools.AddCycles(caravan.LastChange, StateToDelay[caravan.State])
 			user_log.NewFromCorp(caravan.CorpOriginID, user_log.UL_Good, fmt.Sprintf("%s has completed its contract", caravan.String()))
 			user_log.NewFromCorp(caravan.CorpTargetID, user_log.UL_Good, fmt.Sprintf("%s has completed its contract", caravan.String()))
+			caravan.record(dbh, from, now, "reached end of term", nil, nil, 0)
 			return caravan.Update(dbh)
 		}
 
 	}
 
-	log.Printf("################ Caravan: %d from state: %s to state %s", caravan.ID, StateToString[caravan.State], StateToString[StateToNext[caravan.State]])
+	caravan.log().Infof("%d from state: %s to state %s", caravan.ID, StateToString[caravan.State], StateToString[StateToNext[caravan.State]])
 	caravan.State = StateToNext[caravan.State]
+	caravan.record(dbh, from, now, "next step", nil, nil, 0)
 	caravan.LastChange = tools.RoundTime(now)
 	if caravan.IsMoving() {
 		user_log.NewFromCorp(caravan.CorpOriginID, user_log.UL_Info, fmt.Sprintf("%s moves toward %s", caravan.String(), caravan.DestinationStr()))
@@ -531,15 +635,17 @@ func (caravan *Caravan) TimeToMove(dbh *db.Handler, city *city.City, now time.Ti
 	}
 
 	if now.Equal(caravan.NextChange) || now.After(caravan.NextChange) {
-		caravan.Fill(dbh, city)
+		caravan.Fill(dbh, city, now)
 		if !caravan.IsFilledAtAcceptableLevel() {
 			caravan.Aborted = true // this will be last travel ;)
 
 			user_log.NewFromCorp(caravan.CorpOriginID, user_log.UL_Warn, fmt.Sprintf("%s %s Failed to meet caravan contract", caravan.String(), caravan.CurrentCorpStr()))
 			user_log.NewFromCorp(caravan.CorpTargetID, user_log.UL_Warn, fmt.Sprintf("%s %s Failed to meet caravan contract", caravan.String(), caravan.CurrentCorpStr()))
 
-			// caravan is going to move but isn't filled.
-			caravan.Abort(dbh, caravan.CurrentCorp())
+			// caravan is going to move but isn't filled: loading corporation breached the contract.
+			if err := caravan.Abort(dbh, caravan.LoadingCorp()); err != nil {
+				caravan.log().Errorf("Failed to abort under filled %s: %s", caravan.String(), err)
+			}
 		} else {
 
 		}
@@ -570,7 +676,15 @@ func (caravan *Caravan) TimeToUnload(dbh *db.Handler, city *city.City, now time.
 		user_log.NewFromCorp(caravan.CorpOriginID, user_log.UL_Info, fmt.Sprintf("%s reached %s, has unloaded.", caravan.String(), caravan.DestinationStr()))
 		user_log.NewFromCorp(caravan.CorpTargetID, user_log.UL_Info, fmt.Sprintf("%s reached %s, has unloaded.", caravan.String(), caravan.DestinationStr()))
 
-		caravan.Unload(dbh, city)
+		// perishable goods decay while traveling.
+		before := caravan.Store.All(func(item.Item) bool { return true })
+		if spoiled := caravan.Store.Decay(tools.CyclesBetween(caravan.LastChange, caravan.NextChange)); spoiled > 0 {
+			caravan.record(dbh, caravan.State, now, fmt.Sprintf("%d goods spoiled on the road", spoiled), caravan.Store.All(func(item.Item) bool { return true }), before, 0)
+			user_log.NewFromCorp(caravan.CorpOriginID, user_log.UL_Warn, fmt.Sprintf("%s %d goods spoiled on the road", caravan.String(), spoiled))
+			user_log.NewFromCorp(caravan.CorpTargetID, user_log.UL_Warn, fmt.Sprintf("%s %d goods spoiled on the road", caravan.String(), spoiled))
+		}
+
+		caravan.Unload(dbh, city, now)
 
 		caravan.SetNextState(dbh, now)
 		return true, nil
@@ -604,7 +718,7 @@ func (caravan *Caravan) IsFilled() bool {
 
 		return count == (caravan.SendQty*caravan.ExchangeRateRHS)/caravan.ExchangeRateLHS
 	}
-	log.Printf("Caravan: Invalid state")
+	caravan.log().Warnf("Invalid state")
 	return false
 }
 
@@ -619,7 +733,7 @@ func (caravan *Caravan) IsFilledAtAcceptableLevel() bool {
 			count += v.Quantity
 		}
 
-		log.Printf("Caravan: Filled caravan %d expected min: %d", count, caravan.Exported.Quantity.Min)
+		caravan.log().Infof("Filled caravan %d expected min: %d", count, caravan.Exported.Quantity.Min)
 
 		return count >= caravan.Exported.Quantity.Min
 	}
@@ -633,15 +747,15 @@ func (caravan *Caravan) IsFilledAtAcceptableLevel() bool {
 			count += v.Quantity
 		}
 
-		log.Printf("Caravan: Filled caravan %d expected %d", count, (caravan.SendQty*caravan.ExchangeRateRHS)/caravan.ExchangeRateLHS)
+		caravan.log().Infof("Filled caravan %d expected %d", count, (caravan.SendQty*caravan.ExchangeRateRHS)/caravan.ExchangeRateLHS)
 		return count == (caravan.SendQty*caravan.ExchangeRateRHS)/caravan.ExchangeRateLHS
 	}
-	log.Printf("Caravan: Invalid state")
+	caravan.log().Warnf("Invalid state")
 	return false
 }
 
 //Fill caravan with provided city store.
-func (caravan *Caravan) Fill(dbh *db.Handler, city *city.City) error {
+func (caravan *Caravan) Fill(dbh *db.Handler, city *city.City, now time.Time) error {
 	// check first if this is appropriate city to fill from ;)
 	var items []item.Item
 	var instore []item.Item
@@ -672,6 +786,7 @@ func (caravan *Caravan) Fill(dbh *db.Handler, city *city.City) error {
 		max -= v.Quantity
 	}
 
+	loaded := make([]item.Item, 0)
 	if max != 0 {
 		for _, v := range items {
 			count := tools.Min(max, v.Quantity)
@@ -682,6 +797,7 @@ func (caravan *Caravan) Fill(dbh *db.Handler, city *city.City) error {
 			city.Storage.Remove(v.ID, count)
 			v.Quantity = count
 			caravan.Store.Add(v)
+			loaded = append(loaded, v)
 
 			if max == 0 {
 				break
@@ -693,6 +809,10 @@ func (caravan *Caravan) Fill(dbh *db.Handler, city *city.City) error {
 		caravan.SendQty = caravan.Exported.Quantity.Max - max
 	}
 
+	if len(loaded) > 0 {
+		caravan.record(dbh, caravan.State, now, fmt.Sprintf("loaded at %s", city.Name), loaded, nil, 0)
+	}
+
 	city.Update(dbh)
 	caravan.Update(dbh)
 
@@ -700,7 +820,7 @@ func (caravan *Caravan) Fill(dbh *db.Handler, city *city.City) error {
 }
 
 //Unload caravan with provided city store.
-func (caravan *Caravan) Unload(dbh *db.Handler, city *city.City) error {
+func (caravan *Caravan) Unload(dbh *db.Handler, city *city.City, now time.Time) error {
 	// check first if this is appropriate city to fill from ;)
 
 	if caravan.State == CRVTravelingToTarget {
@@ -715,9 +835,15 @@ func (caravan *Caravan) Unload(dbh *db.Handler, city *city.City) error {
 		}
 	}
 
+	unloaded := make([]item.Item, 0, len(caravan.Store.Content))
 	for _, v := range caravan.Store.Content {
 
 		city.Storage.Add(v)
+		unloaded = append(unloaded, v)
+	}
+
+	if len(unloaded) > 0 {
+		caravan.record(dbh, caravan.State, now, fmt.Sprintf("unloaded at %s", city.Name), nil, unloaded, 0)
 	}
 
 	caravan.Store.Clear()
@@ -739,8 +865,14 @@ func (caravan *Caravan) IsValid() bool {
 }
 
 //PerformNextStep seek next which step should complete, and complete it.
-func (caravan *Caravan) PerformNextStep(origin *city_manager.Handler, target *city_manager.Handler, originCorp *corporation_manager.Handler, targetCorp *corporation_manager.Handler, now time.Time) {
+// road is the list of nodes traversed between origin and target, hazards are rolled on it when caravan departs.
+func (caravan *Caravan) PerformNextStep(origin *city_manager.Handler, target *city_manager.Handler, originCorp *corporation_manager.Handler, targetCorp *corporation_manager.Handler, road []node.Node, now time.Time) {
 	if !caravan.IsProducing() {
+		if !caravan.IsActive() && !caravan.Settled {
+			dbh := db.New()
+			defer dbh.Close()
+			caravan.Settle(dbh)
+		}
 		return
 	}
 
@@ -748,26 +880,25 @@ func (caravan *Caravan) PerformNextStep(origin *city_manager.Handler, target *ci
 
 		switch caravan.State {
 		case CRVWaitingOriginLoad:
-			if originCorp.Get().Credits < caravan.ExportCompensation {
+			cdbh := db.New()
+			defer cdbh.Close()
+			if !caravan.releaseCompensation(cdbh, now, caravan.CorpOriginID, &caravan.OriginCompEscrow, caravan.ExportCompensation) {
 				// unable to provide appropriate compensation ... Aborting !
 				dbh := db.New()
 				defer dbh.Close()
-				log.Printf("Caravan: OriginCorp can't compensate export (got %d, need %d)", originCorp.Get().Credits, caravan.ExportCompensation)
+				caravan.log().Infof("OriginCorp can't compensate export (got %d, need %d)", originCorp.Get().Credits, caravan.ExportCompensation)
 
 				user_log.NewFromCorp(caravan.CorpOriginID, user_log.UL_Warn, fmt.Sprintf("%s %s can't compensate %s aborting caravan", caravan.String(), caravan.CurrentCorpStr(), caravan.OtherCorpStr()))
 				user_log.NewFromCorp(caravan.CorpTargetID, user_log.UL_Warn, fmt.Sprintf("%s %s can't compensate %s aborting caravan", caravan.String(), caravan.CurrentCorpStr(), caravan.OtherCorpStr()))
 
 				caravan.Abort(dbh, originCorp.ID())
+				// caravan never leaves, it won't be stepped again: penalties must be settled now.
+				caravan.Settle(dbh)
 				return
 			}
 
-			originCorp.Call(func(corp *corporation.Corporation) {
-				corp.Credits -= caravan.ExportCompensation
-				caravan.Credits += caravan.ExportCompensation
-				dbh := db.New()
-				defer dbh.Close()
-				corp.Update(dbh)
-			})
+			// lock next round trip compensation right away.
+			caravan.relockCompensation(cdbh, caravan.CorpOriginID, &caravan.OriginCompEscrow, caravan.ExportCompensation)
 
 			cb := make(chan bool)
 			defer close(cb)
@@ -776,7 +907,7 @@ func (caravan *Caravan) PerformNextStep(origin *city_manager.Handler, target *ci
 				defer dbh.Close()
 				done, err := caravan.TimeToMove(dbh, corigin, now)
 				if err != nil || !done {
-					log.Printf("Caravan: Can't perform fill %s %+vn", err, caravan)
+					caravan.log().Errorf("Can't perform fill %s %+v", err, caravan)
 					cb <- false
 					return
 				}
@@ -790,25 +921,29 @@ func (caravan *Caravan) PerformNextStep(origin *city_manager.Handler, target *ci
 			if !<-cb {
 
 				originCorp.Call(func(corp *corporation.Corporation) {
-					corp.Credits += caravan.Credits
-					caravan.Credits = 0
 					dbh := db.New()
 					defer dbh.Close()
+					caravan.RecordCredits(dbh, now, -caravan.Credits, fmt.Sprintf("compensation given back to corporation %d", corp.ID))
+					corp.Credits += caravan.Credits
+					caravan.Credits = 0
 					corp.Update(dbh)
 				})
 				dbh := db.New()
 				defer dbh.Close()
 				caravan.Abort(dbh, caravan.CorpOriginID)
+			} else if caravan.IsMoving() {
+				caravan.Travel(cdbh, road, now)
 			}
 
 			break
 		case CRVWaitingTargetLoad:
-
-			if targetCorp.Get().Credits < caravan.ImportCompensation {
+			cdbh := db.New()
+			defer cdbh.Close()
+			if !caravan.releaseCompensation(cdbh, now, caravan.CorpTargetID, &caravan.TargetCompEscrow, caravan.ImportCompensation) {
 				// unable to provide appropriate compensation ... Aborting !
 				dbh := db.New()
 				defer dbh.Close()
-				log.Printf("Caravan: targetCorp can't compensate export (got %d, need %d)", targetCorp.Get().Credits, caravan.ExportCompensation)
+				caravan.log().Infof("targetCorp can't compensate export (got %d, need %d)", targetCorp.Get().Credits, caravan.ExportCompensation)
 
 				user_log.NewFromCorp(caravan.CorpOriginID, user_log.UL_Warn, fmt.Sprintf("%s %s can't compensate %s aborting caravan", caravan.String(), caravan.CurrentCorpStr(), caravan.OtherCorpStr()))
 				user_log.NewFromCorp(caravan.CorpTargetID, user_log.UL_Warn, fmt.Sprintf("%s %s can't compensate %s aborting caravan", caravan.String(), caravan.CurrentCorpStr(), caravan.OtherCorpStr()))
@@ -817,14 +952,7 @@ func (caravan *Caravan) PerformNextStep(origin *city_manager.Handler, target *ci
 				// must still finish roundtrip
 			}
 
-			targetCorp.Call(func(corp *corporation.Corporation) {
-				amount := tools.Min(caravan.ImportCompensation, targetCorp.Get().Credits)
-				corp.Credits -= amount
-				caravan.Credits += amount
-				dbh := db.New()
-				defer dbh.Close()
-				corp.Update(dbh)
-			})
+			caravan.relockCompensation(cdbh, caravan.CorpTargetID, &caravan.TargetCompEscrow, caravan.ImportCompensation)
 
 			cb := make(chan bool)
 			defer close(cb)
@@ -833,7 +961,7 @@ func (caravan *Caravan) PerformNextStep(origin *city_manager.Handler, target *ci
 				defer dbh.Close()
 				done, err := caravan.TimeToMove(dbh, ctarget, now)
 				if err != nil || !done {
-					log.Printf("Caravan: Can't perform fill %s %+vn", err, caravan)
+					caravan.log().Errorf("Can't perform fill %s %+v", err, caravan)
 
 					cb <- false
 					return
@@ -847,15 +975,18 @@ func (caravan *Caravan) PerformNextStep(origin *city_manager.Handler, target *ci
 			if !<-cb {
 
 				targetCorp.Call(func(corp *corporation.Corporation) {
-					corp.Credits += caravan.Credits
-					caravan.Credits = 0
 					dbh := db.New()
 					defer dbh.Close()
+					caravan.RecordCredits(dbh, now, -caravan.Credits, fmt.Sprintf("compensation given back to corporation %d", corp.ID))
+					corp.Credits += caravan.Credits
+					caravan.Credits = 0
 					corp.Update(dbh)
 				})
 				dbh := db.New()
 				defer dbh.Close()
 				caravan.Abort(dbh, caravan.CorpTargetID)
+			} else if caravan.IsMoving() {
+				caravan.Travel(cdbh, road, now)
 			}
 
 			break
@@ -865,17 +996,18 @@ func (caravan *Caravan) PerformNextStep(origin *city_manager.Handler, target *ci
 				defer dbh.Close()
 				done, err := caravan.TimeToUnload(dbh, ctarget, now)
 				if err != nil || !done {
-					log.Printf("Caravan: Can't perform unload %s %+vn", err, caravan)
+					caravan.log().Errorf("Can't perform unload %s %+v", err, caravan)
 				} else {
 					ctarget.AddFame(originCorp.ID(), "successfull caravan delivery", gameplay.GetInt("fame_gain_by_caravan", 20))
 				}
 			})
 
 			targetCorp.Call(func(corp *corporation.Corporation) {
-				corp.Credits += caravan.Credits
-				caravan.Credits = 0
 				dbh := db.New()
 				defer dbh.Close()
+				caravan.RecordCredits(dbh, now, -caravan.Credits, fmt.Sprintf("compensation paid to corporation %d", corp.ID))
+				corp.Credits += caravan.Credits
+				caravan.Credits = 0
 				corp.Update(dbh)
 				caravan.Update(dbh)
 			})
@@ -887,29 +1019,33 @@ func (caravan *Caravan) PerformNextStep(origin *city_manager.Handler, target *ci
 				defer dbh.Close()
 				done, err := caravan.TimeToUnload(dbh, corigin, now)
 				if err != nil || !done {
-					log.Printf("Caravan: Can't perform unload %s %+vn", err, caravan)
+					caravan.log().Errorf("Can't perform unload %s %+v", err, caravan)
 				} else {
 					corigin.AddFame(targetCorp.ID(), "successfull caravan delivery", gameplay.GetInt("fame_gain_by_caravan", 20))
 				}
 			})
 
 			originCorp.Call(func(corp *corporation.Corporation) {
-				corp.Credits += caravan.Credits
-				caravan.Credits = 0
 				dbh := db.New()
 				defer dbh.Close()
+				caravan.RecordCredits(dbh, now, -caravan.Credits, fmt.Sprintf("compensation paid to corporation %d", corp.ID))
+				corp.Credits += caravan.Credits
+				caravan.Credits = 0
 				corp.Update(dbh)
 				caravan.Update(dbh)
 			})
 			break
 		default:
 			// unexpected !
-			log.Printf("Caravan: Unexpected call to PerformNextStep ... isn't processing ...")
+			caravan.log().Infof("Unexpected call to PerformNextStep ... isn't processing ...")
 		}
 
 		dbh := db.New()
 		defer dbh.Close()
 		caravan.Update(dbh)
+
+		// contract may have ended on this step.
+		caravan.Settle(dbh)
 	}
 }
 
@@ -917,3 +1053,8 @@ func (caravan *Caravan) PerformNextStep(origin *city_manager.Handler, target *ci
 func (caravan Caravan) FullStringState() string {
 	return StateToString[caravan.State]
 }
+
+//log logger bearing caravan and map ids.
+func (caravan *Caravan) log() *logger.Logger {
+	return logger.For("Caravan").WithFields(logger.Fields{"caravan_id": caravan.ID, "map_id": caravan.MapID})
+}
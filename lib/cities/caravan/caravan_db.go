@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"time"
 	"upsilon_cities_go/lib/cities/city_manager"
 	"upsilon_cities_go/lib/cities/corporation"
@@ -14,6 +13,7 @@ import (
 	"upsilon_cities_go/lib/cities/storage"
 	"upsilon_cities_go/lib/cities/tools"
 	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/logger"
 )
 
 type dbCaravan struct {
@@ -46,6 +46,21 @@ type dbCaravan struct {
 	// also need to add storage stuff ... that are forcibly removed.
 	CurrentMaxID int64
 	Reservations map[int64]int
+
+	OriginAutoRenew bool
+	TargetAutoRenew bool
+	RenewedID       int
+
+	Penalty       int
+	OriginInsured bool
+	TargetInsured bool
+	OriginEscrow  int
+	TargetEscrow  int
+	BreachedBy    int
+	Settled       bool
+
+	OriginCompEscrow int
+	TargetCompEscrow int
 }
 
 func (caravan *Caravan) dbjsonify() (res []byte, err error) {
@@ -75,6 +90,18 @@ func (caravan *Caravan) dbjsonify() (res []byte, err error) {
 	tmp.SendQty = caravan.SendQty
 	tmp.ExportCompensation = caravan.ExportCompensation
 	tmp.ImportCompensation = caravan.ImportCompensation
+	tmp.OriginAutoRenew = caravan.OriginAutoRenew
+	tmp.TargetAutoRenew = caravan.TargetAutoRenew
+	tmp.RenewedID = caravan.RenewedID
+	tmp.Penalty = caravan.Penalty
+	tmp.OriginInsured = caravan.OriginInsured
+	tmp.TargetInsured = caravan.TargetInsured
+	tmp.OriginEscrow = caravan.OriginEscrow
+	tmp.TargetEscrow = caravan.TargetEscrow
+	tmp.BreachedBy = caravan.BreachedBy
+	tmp.Settled = caravan.Settled
+	tmp.OriginCompEscrow = caravan.OriginCompEscrow
+	tmp.TargetCompEscrow = caravan.TargetCompEscrow
 
 	return json.Marshal(tmp)
 }
@@ -110,12 +137,28 @@ func (caravan *Caravan) dbunjsonify(fromJSON []byte) (err error) {
 	caravan.ImportCompensation = db.ImportCompensation
 	caravan.OriginDropped = db.OriginDropped
 	caravan.TargetDropped = db.TargetDropped
+	caravan.OriginAutoRenew = db.OriginAutoRenew
+	caravan.TargetAutoRenew = db.TargetAutoRenew
+	caravan.RenewedID = db.RenewedID
+	if caravan.RenewedID == RenewalPending {
+		// renewal got interrupted, it never got registered.
+		caravan.RenewedID = 0
+	}
+	caravan.Penalty = db.Penalty
+	caravan.OriginInsured = db.OriginInsured
+	caravan.TargetInsured = db.TargetInsured
+	caravan.OriginEscrow = db.OriginEscrow
+	caravan.TargetEscrow = db.TargetEscrow
+	caravan.BreachedBy = db.BreachedBy
+	caravan.Settled = db.Settled
+	caravan.OriginCompEscrow = db.OriginCompEscrow
+	caravan.TargetCompEscrow = db.TargetCompEscrow
 
 	return nil
 }
 
 //Reload a caravan from database
-func (caravan *Caravan) Reload(dbh *db.Handler) {
+func (caravan *Caravan) Reload(dbh *db.Handler) error {
 	id := caravan.ID
 	rows, err := dbh.Query(`select 
 							caravan_id, 
@@ -131,13 +174,14 @@ func (caravan *Caravan) Reload(dbh *db.Handler) {
 							left outer join cities as targetct on targetct.city_id = target_city_id
 							where caravan_id=$1`, id)
 	if err != nil {
-		log.Fatalf("Caravan DB: Failed to reload a caravan from database : %s ", err)
+		logger.Errorf("Caravan", "Failed to reload a caravan from database : %s", err)
+		return err
 	}
 	defer rows.Close()
 	for rows.Next() {
-		caravan.fill(rows)
-		return
+		return caravan.fill(rows)
 	}
+	return nil
 }
 
 //Insert a caravan in database
@@ -154,15 +198,18 @@ func (caravan *Caravan) Insert(dbh *db.Handler) error {
 	caravan.Store.Capacity = tools.Max(caravan.Exported.Quantity.Max, caravan.Imported.Quantity.Max)
 	caravan.EndOfTerm = tools.AboutNow(600)
 
-	cty, _ := city_manager.GetCityHandler(caravan.CityOriginID)
-	// expect city to exist ...
-
-	log.Printf("Caravan: Computing distance to target %d", caravan.CityTargetID)
-	for _, v := range cty.Get().Roads {
-		log.Printf("Caravan: City %d len %d", v.ToCityID, len(v.Road))
-		if v.ToCityID == caravan.CityTargetID {
-			caravan.TravelingDistance = len(v.Road)
-			break
+	// distance is usually computed over the road network beforehand, direct road is a fallback.
+	if caravan.TravelingDistance == 0 {
+		cty, _ := city_manager.GetCityHandler(caravan.CityOriginID)
+		// expect city to exist ...
+
+		logger.Debugf("Caravan", "Computing distance to target %d", caravan.CityTargetID)
+		for _, v := range cty.Get().Roads {
+			logger.Debugf("Caravan", "City %d len %d", v.ToCityID, len(v.Road))
+			if v.ToCityID == caravan.CityTargetID {
+				caravan.TravelingDistance = len(v.Road)
+				break
+			}
 		}
 	}
 
@@ -176,7 +223,9 @@ func (caravan *Caravan) Insert(dbh *db.Handler) error {
 	}
 	rows.Close()
 
-	log.Printf("Caravan: Inserted caravan into db.")
+	caravan.record(dbh, caravan.State, caravan.LastChange, fmt.Sprintf("proposed by corporation %d", caravan.CorpOriginID), nil, nil, 0)
+
+	logger.Infof("Caravan", "Inserted caravan into db.")
 	return caravan.Update(dbh)
 }
 
@@ -0,0 +1,214 @@
+package caravan
+
+import (
+	"fmt"
+	"math"
+	"time"
+	"upsilon_cities_go/lib/cities/corporation"
+	"upsilon_cities_go/lib/cities/corporation_manager"
+	"upsilon_cities_go/lib/cities/tools"
+	"upsilon_cities_go/lib/cities/user_log"
+	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/config/gameplay"
+)
+
+//InsurancePremium credits paid (and lost) by an insured corporation when contract is accepted.
+//There is no insurer: premiums leave the economy, and coverage paid on breach is created out of thin air.
+func (caravan *Caravan) InsurancePremium() int {
+	return int(math.Ceil(float64(caravan.Penalty) * gameplay.GetFloat("caravan_insurance_premium", 0.1)))
+}
+
+//EscrowCost credits corporation must have to accept contract: penalty, insurance premium and first leg compensation.
+func (caravan *Caravan) EscrowCost(corpID int) int {
+	cost := caravan.Penalty
+	if (corpID == caravan.CorpOriginID && caravan.OriginInsured) || (corpID == caravan.CorpTargetID && caravan.TargetInsured) {
+		cost += caravan.InsurancePremium()
+	}
+	if corpID == caravan.CorpOriginID {
+		cost += caravan.ExportCompensation
+	}
+	if corpID == caravan.CorpTargetID {
+		cost += caravan.ImportCompensation
+	}
+	return cost
+}
+
+//Committed credits of corporation currently locked in caravan escrow.
+func (caravan *Caravan) Committed(corpID int) int {
+	if corpID == caravan.CorpOriginID {
+		return caravan.OriginEscrow + caravan.OriginCompEscrow
+	}
+	if corpID == caravan.CorpTargetID {
+		return caravan.TargetEscrow + caravan.TargetCompEscrow
+	}
+	return 0
+}
+
+// debit take credits from corporation if it can afford it.
+func debit(dbh *db.Handler, corpID int, amount int) error {
+	if amount <= 0 {
+		return nil
+	}
+	cm, err := corporation_manager.GetCorporationHandler(corpID)
+	if err != nil {
+		return fmt.Errorf("unknown corporation %d", corpID)
+	}
+
+	cm.Call(func(corp *corporation.Corporation) {
+		if corp.Credits < amount {
+			err = fmt.Errorf("corporation %s can't escrow %d credits", corp.Name, amount)
+			return
+		}
+		corp.Credits -= amount
+		corp.Update(dbh)
+	})
+	return err
+}
+
+// credit give credits to corporation.
+func credit(dbh *db.Handler, corpID int, amount int) {
+	if amount <= 0 {
+		return
+	}
+	cm, err := corporation_manager.GetCorporationHandler(corpID)
+	if err != nil {
+		return
+	}
+	cm.Call(func(corp *corporation.Corporation) {
+		corp.Credits += amount
+		corp.Update(dbh)
+	})
+}
+
+// completed count a fulfilled contract for corporation.
+func completed(dbh *db.Handler, corpID int) {
+	cm, err := corporation_manager.GetCorporationHandler(corpID)
+	if err != nil {
+		return
+	}
+	cm.Call(func(corp *corporation.Corporation) {
+		corp.CaravansCompleted++
+		corp.Update(dbh)
+	})
+}
+
+// escrowFunds lock penalty and first leg compensation of both corporations, insured ones pay their premium as well.
+// Must not be called while holding either corporation.
+func (caravan *Caravan) escrowFunds(dbh *db.Handler) error {
+	originCost := caravan.EscrowCost(caravan.CorpOriginID)
+	targetCost := caravan.EscrowCost(caravan.CorpTargetID)
+
+	if err := debit(dbh, caravan.CorpOriginID, originCost); err != nil {
+		return err
+	}
+	if err := debit(dbh, caravan.CorpTargetID, targetCost); err != nil {
+		credit(dbh, caravan.CorpOriginID, originCost)
+		return err
+	}
+
+	caravan.OriginEscrow = caravan.Penalty
+	caravan.TargetEscrow = caravan.Penalty
+	caravan.OriginCompEscrow = caravan.ExportCompensation
+	caravan.TargetCompEscrow = caravan.ImportCompensation
+	return nil
+}
+
+// releaseCompensation move escrowed compensation into caravan for this leg, topping escrow up from corporation balance if needed.
+// Must not be called while holding corporation.
+func (caravan *Caravan) releaseCompensation(dbh *db.Handler, now time.Time, corpID int, escrow *int, amount int) bool {
+	if *escrow < amount {
+		if debit(dbh, corpID, amount-*escrow) != nil {
+			return false
+		}
+		*escrow = amount
+	}
+
+	*escrow -= amount
+	caravan.Credits += amount
+	caravan.RecordCredits(dbh, now, amount, fmt.Sprintf("compensation of corporation %d released from escrow", corpID))
+	return true
+}
+
+// relockCompensation lock compensation of next leg. Failing is fine, it'll be checked again on next leg.
+// Must not be called while holding corporation.
+func (caravan *Caravan) relockCompensation(dbh *db.Handler, corpID int, escrow *int, amount int) {
+	if caravan.Aborted || *escrow >= amount {
+		return
+	}
+	if debit(dbh, corpID, amount-*escrow) == nil {
+		*escrow = amount
+	}
+}
+
+//Settle release escrowed penalties once contract is over. Breaching corporation forfeits its own to the other party,
+//and insurance covers part of it, see InsurancePremium. Fame loss is applied on Abort already.
+//Must not be called while holding either corporation or city.
+func (caravan *Caravan) Settle(dbh *db.Handler) {
+	if caravan.Settled || caravan.IsActive() {
+		return
+	}
+	caravan.Settled = true
+
+	originGets, targetGets, covered := caravan.settlement()
+
+	credit(dbh, caravan.CorpOriginID, originGets)
+	credit(dbh, caravan.CorpTargetID, targetGets)
+	caravan.OriginEscrow = 0
+	caravan.TargetEscrow = 0
+	caravan.OriginCompEscrow = 0
+	caravan.TargetCompEscrow = 0
+
+	if caravan.State == CRVTerminated && !caravan.Aborted {
+		completed(dbh, caravan.CorpOriginID)
+		completed(dbh, caravan.CorpTargetID)
+	}
+
+	if caravan.BreachedBy != 0 && caravan.Penalty > 0 {
+		msg := fmt.Sprintf("%s breached by %s, penalty of %d paid to %s", caravan.String(), caravan.CorpStr(caravan.BreachedBy), caravan.Penalty, caravan.CorpStr(caravan.otherCorpOf(caravan.BreachedBy)))
+		if covered > 0 {
+			msg = fmt.Sprintf("%s, insurance covered %d", msg, covered)
+		}
+		user_log.NewFromCorp(caravan.CorpOriginID, user_log.UL_Warn, msg)
+		user_log.NewFromCorp(caravan.CorpTargetID, user_log.UL_Warn, msg)
+	}
+
+	caravan.record(dbh, caravan.State, tools.RoundNow(), fmt.Sprintf("settled: origin receives %d, target receives %d, insurance covered %d", originGets, targetGets, covered), nil, nil, 0)
+	caravan.Update(dbh)
+}
+
+// settlement credits each corporation gets back once contract is over, and part of them paid by insurance.
+func (caravan *Caravan) settlement() (originGets int, targetGets int, covered int) {
+	originGets = caravan.OriginEscrow
+	targetGets = caravan.TargetEscrow
+	coverage := gameplay.GetFloat("caravan_insurance_coverage", 0.5)
+
+	switch caravan.BreachedBy {
+	case caravan.CorpOriginID:
+		targetGets += caravan.OriginEscrow
+		originGets = 0
+		if caravan.OriginInsured {
+			covered = int(math.Floor(float64(caravan.OriginEscrow) * coverage))
+			originGets = covered
+		}
+	case caravan.CorpTargetID:
+		originGets += caravan.TargetEscrow
+		targetGets = 0
+		if caravan.TargetInsured {
+			covered = int(math.Floor(float64(caravan.TargetEscrow) * coverage))
+			targetGets = covered
+		}
+	}
+
+	// unused compensation goes back to its owner.
+	originGets += caravan.OriginCompEscrow
+	targetGets += caravan.TargetCompEscrow
+	return
+}
+
+// otherCorpOf tell which corporation is on the other side of contract.
+func (caravan *Caravan) otherCorpOf(corpID int) int {
+	if corpID == caravan.CorpOriginID {
+		return caravan.CorpTargetID
+	}
+	return caravan.CorpOriginID
+}
@@ -0,0 +1,79 @@
+package caravan
+
+import "testing"
+
+func TestEscrowCost(t *testing.T) {
+	crv := New()
+	crv.CorpOriginID = 1
+	crv.CorpTargetID = 2
+	crv.Penalty = 100
+	crv.OriginInsured = true
+
+	if crv.InsurancePremium() != 10 {
+		t.Errorf("Expected default premium to be 10%% of penalty, got %d", crv.InsurancePremium())
+		return
+	}
+
+	if crv.EscrowCost(1) != 110 {
+		t.Errorf("Insured origin should pay penalty and premium, got %d", crv.EscrowCost(1))
+		return
+	}
+
+	if crv.EscrowCost(2) != 100 {
+		t.Errorf("Uninsured target should only pay penalty, got %d", crv.EscrowCost(2))
+	}
+}
+
+func TestCompensationEscrow(t *testing.T) {
+	crv := New()
+	crv.CorpOriginID = 1
+	crv.CorpTargetID = 2
+	crv.ExportCompensation = 20
+	crv.ImportCompensation = 5
+
+	if crv.EscrowCost(1) != 20 || crv.EscrowCost(2) != 5 {
+		t.Errorf("Expected first leg compensation to be escrowed, got %d and %d", crv.EscrowCost(1), crv.EscrowCost(2))
+		return
+	}
+
+	crv.OriginEscrow = 100
+	crv.OriginCompEscrow = 20
+	crv.TargetCompEscrow = 5
+	if crv.Committed(1) != 120 {
+		t.Errorf("Origin should have penalty and compensation committed, got %d", crv.Committed(1))
+		return
+	}
+
+	if crv.Committed(3) != 0 {
+		t.Errorf("Unrelated corporation shouldn't have anything committed, got %d", crv.Committed(3))
+	}
+}
+
+func TestUnderFilledLoadingCorpForfeitsPenalty(t *testing.T) {
+	crv := New()
+	crv.CorpOriginID = 1
+	crv.CorpTargetID = 2
+	crv.Penalty = 100
+	crv.OriginEscrow = 100
+	crv.TargetEscrow = 100
+	crv.State = CRVWaitingTargetLoad
+
+	if crv.LoadingCorp() != 2 {
+		t.Errorf("Target corporation should be loading, got %d", crv.LoadingCorp())
+		return
+	}
+
+	crv.BreachedBy = crv.LoadingCorp()
+	originGets, targetGets, covered := crv.settlement()
+	if originGets != 200 || targetGets != 0 || covered != 0 {
+		t.Errorf("Breaching target should forfeit its penalty to origin, got origin %d target %d covered %d", originGets, targetGets, covered)
+		return
+	}
+
+	crv.State = CRVWaitingOriginLoad
+	crv.BreachedBy = crv.LoadingCorp()
+	originGets, targetGets, _ = crv.settlement()
+	if originGets != 0 || targetGets != 200 {
+		t.Errorf("Breaching origin should forfeit its penalty to target, got origin %d target %d", originGets, targetGets)
+	}
+}
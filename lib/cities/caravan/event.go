@@ -0,0 +1,106 @@
+package caravan
+
+import (
+	"fmt"
+	"time"
+	"upsilon_cities_go/lib/cities/item"
+	"upsilon_cities_go/lib/cities/tools"
+	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/logger"
+)
+
+//Event typed record of what happened to a caravan, every transition and every move of goods or credits.
+type Event struct {
+	ID        int
+	CaravanID int
+	FromState int
+	ToState   int
+	Cycle     time.Time
+	Loaded    []item.Item // goods put in caravan.
+	Unloaded  []item.Item // goods taken out of caravan.
+	Credits   int         // credits put in caravan, negative when taken out.
+	Reason    string
+}
+
+//Replayed caravan state rebuilt from its events.
+type Replayed struct {
+	State   int
+	Credits int
+	Goods   map[string]int // quantity carried by item name.
+	Events  int
+}
+
+//String version of an event
+func (ev Event) String() string {
+	return fmt.Sprintf("Caravan %d [%s -> %s] %s", ev.CaravanID, StateToString[ev.FromState], StateToString[ev.ToState], ev.Reason)
+}
+
+// record an event about caravan, failure to record is logged but doesn't prevent caravan to go on.
+func (caravan *Caravan) record(dbh *db.Handler, from int, now time.Time, reason string, loaded []item.Item, unloaded []item.Item, credits int) {
+	var ev Event
+	ev.CaravanID = caravan.ID
+	ev.FromState = from
+	ev.ToState = caravan.State
+	ev.Cycle = tools.RoundTime(now)
+	ev.Loaded = loaded
+	ev.Unloaded = unloaded
+	ev.Credits = credits
+	ev.Reason = reason
+
+	if err := ev.Insert(dbh); err != nil {
+		logger.Errorf("Caravan", "Failed to record %s: %s", ev.String(), err)
+	}
+}
+
+//RecordCredits register credits moved in (or out when negative) of caravan.
+func (caravan *Caravan) RecordCredits(dbh *db.Handler, now time.Time, credits int, reason string) {
+	if credits == 0 {
+		return
+	}
+	caravan.record(dbh, caravan.State, now, reason, nil, nil, credits)
+}
+
+//Replay rebuild caravan state from its events.
+func Replay(events []Event) (res Replayed) {
+	res.State = CRVProposal
+	res.Goods = make(map[string]int)
+	for _, v := range events {
+		res.State = v.ToState
+		res.Credits += v.Credits
+		for _, it := range v.Loaded {
+			res.Goods[it.Name] += it.Quantity
+		}
+		for _, it := range v.Unloaded {
+			res.Goods[it.Name] -= it.Quantity
+			if res.Goods[it.Name] <= 0 {
+				delete(res.Goods, it.Name)
+			}
+		}
+		res.Events++
+	}
+	return
+}
+
+//Matches tell whether replayed state is the one of provided caravan.
+func (r Replayed) Matches(caravan *Caravan) (bool, string) {
+	if r.State != caravan.State {
+		return false, fmt.Sprintf("state differs: replayed %s, current %s", StateToString[r.State], StateToString[caravan.State])
+	}
+	if r.Credits != caravan.Credits {
+		return false, fmt.Sprintf("credits differ: replayed %d, current %d", r.Credits, caravan.Credits)
+	}
+
+	goods := make(map[string]int)
+	for _, v := range caravan.Store.Content {
+		goods[v.Name] += v.Quantity
+	}
+	if len(goods) != len(r.Goods) {
+		return false, fmt.Sprintf("goods differ: replayed %v, current %v", r.Goods, goods)
+	}
+	for k, v := range goods {
+		if r.Goods[k] != v {
+			return false, fmt.Sprintf("goods differ: replayed %v, current %v", r.Goods, goods)
+		}
+	}
+	return true, ""
+}
@@ -0,0 +1,56 @@
+package caravan
+
+import (
+	"encoding/json"
+	"fmt"
+	"upsilon_cities_go/lib/cities/item"
+	"upsilon_cities_go/lib/db"
+)
+
+type dbEvent struct {
+	Loaded   []item.Item
+	Unloaded []item.Item
+}
+
+//Insert an event in database
+func (ev *Event) Insert(dbh *db.Handler) error {
+	data, err := json.Marshal(dbEvent{Loaded: ev.Loaded, Unloaded: ev.Unloaded})
+	if err != nil {
+		return err
+	}
+
+	rows, err := dbh.Query("insert into caravan_events(caravan_id, from_state, to_state, cycle, credits, reason, data) values($1,$2,$3,$4,$5,$6,$7) returning caravan_event_id",
+		ev.CaravanID, ev.FromState, ev.ToState, ev.Cycle, ev.Credits, ev.Reason, data)
+	if err != nil {
+		return fmt.Errorf("Caravan Event DB : Failed to insert event: %s", err)
+	}
+	for rows.Next() {
+		rows.Scan(&ev.ID)
+	}
+	rows.Close()
+	return nil
+}
+
+//EventsByCaravanID fetches all events of a caravan in order
+func EventsByCaravanID(dbh *db.Handler, id int) (res []Event, err error) {
+	rows, err := dbh.Query("select caravan_event_id, caravan_id, from_state, to_state, cycle, credits, reason, data from caravan_events where caravan_id=$1 order by caravan_event_id", id)
+	if err != nil {
+		return nil, fmt.Errorf("Caravan Event DB : Failed to select events (ByCaravanID): %s", err)
+	}
+	defer rows.Close()
+
+	res = make([]Event, 0)
+	for rows.Next() {
+		var ev Event
+		var data []byte
+		var tmp dbEvent
+		rows.Scan(&ev.ID, &ev.CaravanID, &ev.FromState, &ev.ToState, &ev.Cycle, &ev.Credits, &ev.Reason, &data)
+		if err := json.Unmarshal(data, &tmp); err != nil {
+			return res, fmt.Errorf("Caravan Event DB : Failed to parse event %d: %s", ev.ID, err)
+		}
+		ev.Loaded = tmp.Loaded
+		ev.Unloaded = tmp.Unloaded
+		res = append(res, ev)
+	}
+	return
+}
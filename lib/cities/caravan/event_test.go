@@ -0,0 +1,43 @@
+package caravan
+
+import (
+	"testing"
+	"upsilon_cities_go/lib/cities/item"
+)
+
+func TestReplayEvents(t *testing.T) {
+	Init()
+	apple := item.Item{Name: "Apple", Quantity: 10}
+	events := []Event{
+		{FromState: CRVProposal, ToState: CRVProposal, Reason: "proposed"},
+		{FromState: CRVProposal, ToState: CRVWaitingOriginLoad, Reason: "accepted"},
+		{FromState: CRVWaitingOriginLoad, ToState: CRVWaitingOriginLoad, Credits: 50, Reason: "export compensation"},
+		{FromState: CRVWaitingOriginLoad, ToState: CRVWaitingOriginLoad, Loaded: []item.Item{apple}, Reason: "loaded"},
+		{FromState: CRVWaitingOriginLoad, ToState: CRVTravelingToTarget, Reason: "next step"},
+	}
+
+	res := Replay(events)
+	if res.State != CRVTravelingToTarget || res.Credits != 50 || res.Goods["Apple"] != 10 || res.Events != 5 {
+		t.Errorf("Unexpected replayed state %+v", res)
+		return
+	}
+
+	crv := New()
+	crv.State = CRVTravelingToTarget
+	crv.Credits = 50
+	crv.Store.Add(apple)
+	if ok, mismatch := res.Matches(crv); !ok {
+		t.Errorf("Replayed state should match caravan: %s", mismatch)
+		return
+	}
+
+	events = append(events, Event{FromState: CRVTravelingToTarget, ToState: CRVTravelingToTarget, Unloaded: []item.Item{apple}, Credits: -50, Reason: "unloaded"})
+	res = Replay(events)
+	if len(res.Goods) != 0 || res.Credits != 0 {
+		t.Errorf("Expected caravan to be empty after unload %+v", res)
+		return
+	}
+	if ok, _ := res.Matches(crv); ok {
+		t.Errorf("Replayed state shouldn't match a loaded caravan")
+	}
+}
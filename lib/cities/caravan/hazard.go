@@ -0,0 +1,161 @@
+package caravan
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+	"upsilon_cities_go/lib/cities/item"
+	"upsilon_cities_go/lib/cities/node"
+	"upsilon_cities_go/lib/cities/nodetype"
+	"upsilon_cities_go/lib/cities/tools"
+	"upsilon_cities_go/lib/cities/user_log"
+	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/config/gameplay"
+)
+
+//Hazard kinds
+const (
+	HZDelay int = 0
+	HZLoss  int = 1
+	HZFind  int = 2
+)
+
+//HazardRates chances per traversed node of road hazards. May be overridden by "caravan_hazards" in gameplay.json
+type HazardRates struct {
+	Delay        float64            // chance to be delayed.
+	Loss         float64            // chance to lose part of cargo.
+	Find         float64            // chance to find extra goods.
+	DelayCycles  int                // cycles lost by a delay.
+	LossRatio    float64            // ratio of a carried stack lost.
+	FindQuantity int                // extra goods found.
+	Ground       map[string]float64 // chance factor by ground type.
+	Landscape    map[string]float64 // chance factor by landscape type.
+	Road         float64            // chance factor when node is a road, divided by road level.
+	ByDistance   float64            // chance factor added by node away from closest city.
+}
+
+//Hazard what happened to a caravan on a node of its road.
+type Hazard struct {
+	Kind     int
+	Location node.Point
+	Reason   string
+}
+
+//String version of a hazard
+func (hz Hazard) String() string {
+	switch hz.Kind {
+	case HZDelay:
+		return fmt.Sprintf("delayed by %s", hz.Reason)
+	case HZLoss:
+		return fmt.Sprintf("lost cargo to %s", hz.Reason)
+	}
+	return fmt.Sprintf("found goods near %s", hz.Reason)
+}
+
+//DefaultHazardRates rates used when gameplay doesn't provide any.
+func DefaultHazardRates() HazardRates {
+	return HazardRates{
+		Delay:        0.01,
+		Loss:         0.005,
+		Find:         0.005,
+		DelayCycles:  2,
+		LossRatio:    0.1,
+		FindQuantity: 2,
+		Ground:       map[string]float64{"Plain": 1, "Desert": 1.5},
+		Landscape:    map[string]float64{"Forest": 1.5, "Mountain": 2, "River": 2},
+		Road:         0.5,
+		ByDistance:   0.05,
+	}
+}
+
+//LoadHazardRates defaults overridden by gameplay configuration.
+func LoadHazardRates() HazardRates {
+	res := DefaultHazardRates()
+	gameplay.GetObject("caravan_hazards", &res)
+	return res
+}
+
+//Factor multiplier of hazard chances on node, distance being the number of nodes to closest city.
+func (rates HazardRates) Factor(nd node.Node, distance int) float64 {
+	res := 1.0
+	if f, found := rates.Ground[nd.Ground.String()]; found {
+		res *= f
+	}
+	if f, found := rates.Landscape[nd.Landscape.String()]; found {
+		res *= f
+	}
+	if nd.IsRoad {
+		res *= rates.Road / float64(1+nd.RoadLevel)
+	}
+	return res * (1 + float64(distance)*rates.ByDistance)
+}
+
+// hazardReason describe node for logs.
+func hazardReason(nd node.Node) string {
+	switch nd.Landscape {
+	case nodetype.River:
+		return fmt.Sprintf("river crossing at %d/%d", nd.Location.X, nd.Location.Y)
+	case nodetype.NoLandscape:
+		return fmt.Sprintf("%s at %d/%d", nd.Ground.String(), nd.Location.X, nd.Location.Y)
+	}
+	return fmt.Sprintf("%s at %d/%d", nd.Landscape.String(), nd.Location.X, nd.Location.Y)
+}
+
+//RollHazards roll hazards for every node of road, roll provides a number in [0,1).
+func RollHazards(road []node.Node, rates HazardRates, roll func() float64) (res []Hazard) {
+	for idx, nd := range road {
+		factor := rates.Factor(nd, tools.Min(idx, len(road)-1-idx))
+		for kind, chance := range []float64{rates.Delay, rates.Loss, rates.Find} {
+			if roll() < chance*factor {
+				res = append(res, Hazard{Kind: kind, Location: nd.Location, Reason: hazardReason(nd)})
+			}
+		}
+	}
+	return
+}
+
+//Travel roll and apply hazards of road to a departing caravan. Both corporations are told what happened.
+func (caravan *Caravan) Travel(dbh *db.Handler, road []node.Node, now time.Time) []Hazard {
+	rates := LoadHazardRates()
+	hazards := RollHazards(road, rates, rand.Float64)
+
+	for _, hz := range hazards {
+		var loaded, unloaded []item.Item
+		switch hz.Kind {
+		case HZDelay:
+			caravan.NextChange = tools.AddCycles(caravan.NextChange, rates.DelayCycles)
+		case HZLoss:
+			carried := caravan.Store.All(func(item.Item) bool { return true })
+			if len(carried) == 0 {
+				continue
+			}
+			it := carried[rand.Intn(len(carried))]
+			it.Quantity = tools.Max(1, int(float64(it.Quantity)*rates.LossRatio))
+			if caravan.Store.Remove(it.ID, it.Quantity) != nil {
+				continue
+			}
+			unloaded = append(unloaded, it)
+		case HZFind:
+			carried := caravan.Store.All(func(item.Item) bool { return true })
+			quantity := tools.Min(rates.FindQuantity, caravan.Store.Spaceleft())
+			if len(carried) == 0 || quantity <= 0 {
+				continue
+			}
+			it := carried[rand.Intn(len(carried))]
+			it.Quantity = quantity
+			if caravan.Store.Add(it) != nil {
+				continue
+			}
+			loaded = append(loaded, it)
+		}
+
+		caravan.record(dbh, caravan.State, now, hz.String(), loaded, unloaded, 0)
+		user_log.NewFromCorp(caravan.CorpOriginID, user_log.UL_Warn, fmt.Sprintf("%s %s", caravan.String(), hz.String()))
+		user_log.NewFromCorp(caravan.CorpTargetID, user_log.UL_Warn, fmt.Sprintf("%s %s", caravan.String(), hz.String()))
+	}
+
+	if len(hazards) > 0 {
+		caravan.Update(dbh)
+	}
+	return hazards
+}
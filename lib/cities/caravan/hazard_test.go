@@ -0,0 +1,34 @@
+package caravan
+
+import (
+	"testing"
+	"upsilon_cities_go/lib/cities/node"
+	"upsilon_cities_go/lib/cities/nodetype"
+)
+
+func TestRollHazards(t *testing.T) {
+	rates := DefaultHazardRates()
+	rates.Delay = 0.3
+	rates.Loss = 0
+	rates.Find = 0
+	rates.ByDistance = 0
+
+	road := make([]node.Node, 3)
+	for idx := range road {
+		road[idx].Location = node.NP(idx, 0)
+		road[idx].Ground = nodetype.Plain
+		road[idx].IsRoad = true
+	}
+	road[1].Landscape = nodetype.River
+
+	// plain road: 0.3 * 0.5 = 0.15, river road: 0.3 * 2 * 0.5 = 0.3
+	hazards := RollHazards(road, rates, func() float64 { return 0.2 })
+	if len(hazards) != 1 {
+		t.Errorf("Expected only river crossing to delay caravan, got %v", hazards)
+		return
+	}
+
+	if hazards[0].Kind != HZDelay || hazards[0].Location != node.NP(1, 0) {
+		t.Errorf("Expected a delay on river crossing, got %+v", hazards[0])
+	}
+}
@@ -0,0 +1,139 @@
+package caravan
+
+import "fmt"
+
+//Terms of a caravan contract, copied on renewal and saved in templates.
+type Terms struct {
+	Exported           Object
+	Imported           Object
+	ExportCompensation int
+	ImportCompensation int
+	ExchangeRateLHS    int
+	ExchangeRateRHS    int
+	LoadingDelay       int
+	Penalty            int
+	OriginInsured      bool
+	TargetInsured      bool
+}
+
+//Template saved contract terms a corporation may propose again.
+type Template struct {
+	ID            int
+	CorporationID int
+	Name          string
+	CityOriginID  int
+	CityTargetID  int
+	Terms         Terms
+}
+
+//String version of a template
+func (t Template) String() string {
+	return fmt.Sprintf("Template %d %s: %s <-> %s", t.ID, t.Name, t.Terms.Exported.String(), t.Terms.Imported.String())
+}
+
+//Terms of caravan contract.
+func (caravan *Caravan) Terms() (res Terms) {
+	res.Exported = caravan.Exported
+	res.Imported = caravan.Imported
+	res.ExportCompensation = caravan.ExportCompensation
+	res.ImportCompensation = caravan.ImportCompensation
+	res.ExchangeRateLHS = caravan.ExchangeRateLHS
+	res.ExchangeRateRHS = caravan.ExchangeRateRHS
+	res.LoadingDelay = caravan.LoadingDelay
+	res.Penalty = caravan.Penalty
+	res.OriginInsured = caravan.OriginInsured
+	res.TargetInsured = caravan.TargetInsured
+	return
+}
+
+//ApplyTerms replace caravan contract terms.
+func (caravan *Caravan) ApplyTerms(t Terms) {
+	caravan.Exported = t.Exported
+	caravan.Imported = t.Imported
+	caravan.ExportCompensation = t.ExportCompensation
+	caravan.ImportCompensation = t.ImportCompensation
+	caravan.ExchangeRateLHS = t.ExchangeRateLHS
+	caravan.ExchangeRateRHS = t.ExchangeRateRHS
+	caravan.LoadingDelay = t.LoadingDelay
+	caravan.Penalty = t.Penalty
+	caravan.OriginInsured = t.OriginInsured
+	caravan.TargetInsured = t.TargetInsured
+}
+
+//NewTemplate save caravan terms as a template for corporation.
+func NewTemplate(caravan *Caravan, corpID int, name string) (res Template) {
+	res.CorporationID = corpID
+	res.Name = name
+	if res.Name == "" {
+		res.Name = fmt.Sprintf("%s -> %s", caravan.CityOriginName, caravan.CityTargetName)
+	}
+	res.CityOriginID = caravan.CityOriginID
+	res.CityTargetID = caravan.CityTargetID
+	res.Terms = caravan.Terms()
+	return
+}
+
+//Proposal new caravan proposal based on template. Target corporation must still be set.
+func (t Template) Proposal(mapID int) *Caravan {
+	res := New()
+	res.MapID = mapID
+	res.CorpOriginID = t.CorporationID
+	res.CityOriginID = t.CityOriginID
+	res.CityTargetID = t.CityTargetID
+	res.ApplyTerms(t.Terms)
+	return res
+}
+
+//RenewalPending RenewedID of a contract which renewal is being registered, so that it isn't renewed twice.
+const RenewalPending = -1
+
+//CanRenew tell whether caravan contract may be renewed.
+func (caravan *Caravan) CanRenew() bool {
+	return caravan.State == CRVTerminated && caravan.RenewedID == 0
+}
+
+//ShouldAutoRenew tell whether both sides opted in for renewal of this terminated contract.
+func (caravan *Caravan) ShouldAutoRenew() bool {
+	return caravan.CanRenew() && caravan.OriginAutoRenew && caravan.TargetAutoRenew
+}
+
+//Renew new proposal with identical terms between same cities and corporations.
+func (caravan *Caravan) Renew() *Caravan {
+	res := New()
+	res.MapID = caravan.MapID
+	res.CorpOriginID = caravan.CorpOriginID
+	res.CityOriginID = caravan.CityOriginID
+	res.CorpTargetID = caravan.CorpTargetID
+	res.CityTargetID = caravan.CityTargetID
+	res.ApplyTerms(caravan.Terms())
+	res.OriginAutoRenew = caravan.OriginAutoRenew
+	res.TargetAutoRenew = caravan.TargetAutoRenew
+	return res
+}
+
+//StartRenewal reserve renewal of contract and build its proposal. Caller must EndRenewal once proposal got registered or failed.
+func (caravan *Caravan) StartRenewal() (*Caravan, error) {
+	if !caravan.CanRenew() {
+		return nil, fmt.Errorf("only terminated contracts may be renewed, and only once")
+	}
+	caravan.RenewedID = RenewalPending
+	return caravan.Renew(), nil
+}
+
+//EndRenewal record caravan created on renewal, 0 when renewal failed and contract may be renewed again.
+func (caravan *Caravan) EndRenewal(renewedID int) {
+	caravan.RenewedID = renewedID
+}
+
+//ToggleAutoRenew switch corporation opt-in for automatic renewal.
+func (caravan *Caravan) ToggleAutoRenew(corpID int) (bool, error) {
+	switch corpID {
+	case caravan.CorpOriginID:
+		caravan.OriginAutoRenew = !caravan.OriginAutoRenew
+		return caravan.OriginAutoRenew, nil
+	case caravan.CorpTargetID:
+		caravan.TargetAutoRenew = !caravan.TargetAutoRenew
+		return caravan.TargetAutoRenew, nil
+	}
+	return false, fmt.Errorf("corporation %d isn't part of %s", corpID, caravan.String())
+}
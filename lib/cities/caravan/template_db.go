@@ -0,0 +1,79 @@
+package caravan
+
+import (
+	"encoding/json"
+	"fmt"
+	"upsilon_cities_go/lib/db"
+)
+
+type dbTemplate struct {
+	CityOriginID int
+	CityTargetID int
+	Terms        Terms
+}
+
+//Insert a template in database
+func (t *Template) Insert(dbh *db.Handler) error {
+	data, err := json.Marshal(dbTemplate{CityOriginID: t.CityOriginID, CityTargetID: t.CityTargetID, Terms: t.Terms})
+	if err != nil {
+		return err
+	}
+
+	rows, err := dbh.Query("insert into caravan_templates(corporation_id, name, data) values($1,$2,$3) returning caravan_template_id", t.CorporationID, t.Name, data)
+	if err != nil {
+		return fmt.Errorf("Caravan Template DB : Failed to insert template: %s", err)
+	}
+	for rows.Next() {
+		rows.Scan(&t.ID)
+	}
+	rows.Close()
+	return nil
+}
+
+//DropTemplate a template of corporation from database
+func DropTemplate(dbh *db.Handler, id int, corpID int) error {
+	query, err := dbh.Query("delete from caravan_templates where caravan_template_id=$1 and corporation_id=$2", id, corpID)
+	if err != nil {
+		return fmt.Errorf("Caravan Template DB : Failed to drop template: %s", err)
+	}
+	query.Close()
+	return nil
+}
+
+//TemplatesByCorpID fetches all templates of corporation
+func TemplatesByCorpID(dbh *db.Handler, corpID int) (res []Template, err error) {
+	rows, err := dbh.Query("select caravan_template_id, corporation_id, name, data from caravan_templates where corporation_id=$1 order by caravan_template_id", corpID)
+	if err != nil {
+		return nil, fmt.Errorf("Caravan Template DB : Failed to select templates (ByCorpID): %s", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t Template
+		var data []byte
+		var tmp dbTemplate
+		rows.Scan(&t.ID, &t.CorporationID, &t.Name, &data)
+		if err := json.Unmarshal(data, &tmp); err != nil {
+			return res, fmt.Errorf("Caravan Template DB : Failed to parse template %d: %s", t.ID, err)
+		}
+		t.CityOriginID = tmp.CityOriginID
+		t.CityTargetID = tmp.CityTargetID
+		t.Terms = tmp.Terms
+		res = append(res, t)
+	}
+	return
+}
+
+//TemplateByID fetches template of corporation
+func TemplateByID(dbh *db.Handler, id int, corpID int) (res Template, err error) {
+	templates, err := TemplatesByCorpID(dbh, corpID)
+	if err != nil {
+		return res, err
+	}
+	for _, v := range templates {
+		if v.ID == id {
+			return v, nil
+		}
+	}
+	return res, fmt.Errorf("Caravan Template DB : Unknown template %d", id)
+}
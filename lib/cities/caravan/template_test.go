@@ -0,0 +1,59 @@
+package caravan
+
+import (
+	"testing"
+	"upsilon_cities_go/lib/cities/tools"
+)
+
+func TestRenewKeepsTerms(t *testing.T) {
+	Init()
+	tools.InitCycle()
+
+	crv := New()
+	crv.ID = 1
+	crv.CorpOriginID = 1
+	crv.CorpTargetID = 2
+	crv.Exported = Object{ItemName: "Some Item", Quality: tools.IntRange{Min: 10, Max: 50}, Quantity: tools.IntRange{Min: 5, Max: 10}}
+	crv.ExchangeRateLHS = 2
+	crv.State = CRVTerminated
+
+	if crv.ShouldAutoRenew() {
+		t.Errorf("Caravan shouldn't renew without both corporations opting in")
+		return
+	}
+
+	crv.ToggleAutoRenew(1)
+	crv.ToggleAutoRenew(2)
+	if !crv.ShouldAutoRenew() {
+		t.Errorf("Caravan should renew when both corporations opted in")
+		return
+	}
+
+	renewal := crv.Renew()
+	if renewal.State != CRVProposal || renewal.ID != 0 {
+		t.Errorf("Renewal should be a new proposal")
+		return
+	}
+	if renewal.Exported.ItemName != "Some Item" || renewal.ExchangeRateLHS != 2 || renewal.CorpTargetID != 2 {
+		t.Errorf("Renewal should keep contract terms")
+		return
+	}
+
+	if _, err := crv.StartRenewal(); err != nil {
+		t.Errorf("Terminated contract should be renewable: %s", err)
+		return
+	}
+	if _, err := crv.StartRenewal(); err == nil || crv.ShouldAutoRenew() {
+		t.Errorf("Contract shouldn't be renewed twice while renewal is pending")
+		return
+	}
+	crv.EndRenewal(0)
+	if !crv.CanRenew() {
+		t.Errorf("Failed renewal should let contract be renewed again")
+		return
+	}
+
+	if _, err := crv.ToggleAutoRenew(3); err == nil {
+		t.Errorf("Unrelated corporation shouldn't be able to opt in")
+	}
+}
@@ -2,8 +2,15 @@ package caravan_manager
 
 import (
 	"errors"
+	"time"
 	"upsilon_cities_go/lib/cities/caravan"
+	"upsilon_cities_go/lib/cities/city"
+	"upsilon_cities_go/lib/cities/city_manager"
+	"upsilon_cities_go/lib/cities/corporation"
+	"upsilon_cities_go/lib/cities/corporation_manager"
+	"upsilon_cities_go/lib/db"
 	"upsilon_cities_go/lib/misc/actor"
+	"upsilon_cities_go/lib/misc/logger"
 )
 
 //Handler own the carava, they're to be called upon to provide access to the carava
@@ -62,6 +69,33 @@ func GenerateHandler(caravan *caravan.Caravan) {
 
 }
 
+//Register insert a new caravan, generate its handler and let both cities and corporations know about it.
+func Register(dbh *db.Handler, crv *caravan.Caravan) (*Handler, error) {
+	err := crv.Insert(dbh)
+	if err != nil {
+		return nil, err
+	}
+	crv.Reload(dbh)
+	GenerateHandler(crv)
+
+	for _, v := range []int{crv.CityOriginID, crv.CityTargetID} {
+		if ctm, err := city_manager.GetCityHandler(v); err == nil {
+			ctm.Call(func(cty *city.City) {
+				cty.CaravanID = append(cty.CaravanID, crv.ID)
+			})
+		}
+	}
+	for _, v := range []int{crv.CorpOriginID, crv.CorpTargetID} {
+		if cm, err := corporation_manager.GetCorporationHandler(v); err == nil {
+			cm.Call(func(corp *corporation.Corporation) {
+				corp.CaravanID = append(corp.CaravanID, crv.ID)
+			})
+		}
+	}
+
+	return GetCaravanHandler(crv.ID)
+}
+
 //GetCaravanHandler Fetches grid from memory
 func GetCaravanHandler(id int) (*Handler, error) {
 	cm, found := manager.handlers[id]
@@ -128,6 +162,44 @@ func DropCaravanHandler(id int) error {
 	return nil
 }
 
+//Persist drain mailbox of every loaded caravan and save it, returns how many failed to be saved.
+func Persist(dbh *db.Handler) (failed int) {
+	var handlers []*Handler
+	manager.Call(func() {
+		for _, v := range manager.handlers {
+			handlers = append(handlers, v)
+		}
+	})
+
+	for _, v := range handlers {
+		v.Call(func(crv *caravan.Caravan) {
+			if err := crv.Update(dbh); err != nil {
+				logger.Errorf("CaravanMgr", "Failed to persist caravan %d: %s", crv.ID, err)
+				failed++
+			}
+		})
+	}
+	logger.Infof("CaravanMgr", "Persisted %d caravans, %d failed", len(handlers)-failed, failed)
+	return
+}
+
+//Stats how many caravans are loaded and how many functions wait in mailboxes, manager's included.
+func Stats() (loaded int, queued int) {
+	queued = manager.Queued()
+	manager.Call(func() {
+		loaded = len(manager.handlers)
+		for _, v := range manager.handlers {
+			queued += v.Queued()
+		}
+	})
+	return
+}
+
+//Ping tell whether manager is responsive.
+func Ping(timeout time.Duration) bool {
+	return manager.Ping(timeout)
+}
+
 //Cast send and forget. Will provide access to protected grid.
 // If you want a reply, dont forget to provide your function a chan
 // If you do so, DONT FORGET TO call defer close(<your chan>)
@@ -2,7 +2,6 @@ package city
 
 import (
 	"fmt"
-	"log"
 	"time"
 	"upsilon_cities_go/lib/cities/city/producer"
 	"upsilon_cities_go/lib/cities/corporation"
@@ -15,6 +14,7 @@ import (
 	"upsilon_cities_go/lib/cities/user_log"
 	"upsilon_cities_go/lib/db"
 	"upsilon_cities_go/lib/misc/config/gameplay"
+	"upsilon_cities_go/lib/misc/logger"
 )
 
 //StateHistory of city evolution
@@ -69,7 +69,16 @@ type City struct {
 	CurrentMaxID int
 
 	// Fame by CorporationID
-	Fame map[int]int
+	Fame          map[int]int
+	LastFameDecay time.Time
+	pendingFame   []FameEntry // fame changes not yet in fame ledger, written on Update.
+
+	// goods produced while owned, by CorporationID.
+	Produced map[int]int
+
+	// Claims pending on an uncorporated city, resolved once ContestEnd is reached.
+	Claims     []Claim
+	ContestEnd time.Time
 
 	State State
 }
@@ -88,7 +97,9 @@ func New() (city *City) {
 	city.ActiveProductFactories = make(map[int]*producer.Production, 0)
 	city.ActiveRessourceProducers = make(map[int]*producer.Production, 0)
 	city.Fame = make(map[int]int)
-	log.Printf("City: Creating a new city !")
+	city.Claims = make([]Claim, 0)
+	city.Produced = make(map[int]int)
+	city.log().Infof("Creating a new city !")
 
 	city.State.History = make([]StateHistory, 0)
 	city.State.Influence = pattern.Square
@@ -117,11 +128,20 @@ func (city *City) CheckActivity(origin time.Time) (changed bool) {
 	nextUpdate := tools.MinTime(origin, city.NextUpdate)
 
 	changed = false
+
+	// perishable goods decay while stored.
+	if spoiled := city.Storage.Decay(tools.CyclesBetween(city.LastUpdate, nextUpdate)); spoiled > 0 {
+		user_log.NewFromCorp(city.CorporationID, user_log.UL_Warn, fmt.Sprintf("City %s: %d goods spoiled in storage", city.Name, spoiled))
+		changed = true
+	}
+
 	nActFact := make(map[int]*producer.Production)
 
 	for _, v := range city.ActiveProductFactories {
 		if v.IsFinished(nextUpdate) {
-			producer.ProductionCompleted(city.Storage, v, nextUpdate)
+			if producer.ProductionCompleted(city.Storage, v, nextUpdate) == nil {
+				city.countProduction(v)
+			}
 
 			city.ProductFactories[v.ProducerID].Leveling(5)
 			changed = true
@@ -133,7 +153,9 @@ func (city *City) CheckActivity(origin time.Time) (changed bool) {
 	nActRc := make(map[int]*producer.Production)
 	for _, v := range city.ActiveRessourceProducers {
 		if v.IsFinished(nextUpdate) {
-			producer.ProductionCompleted(city.Storage, v, nextUpdate)
+			if producer.ProductionCompleted(city.Storage, v, nextUpdate) == nil {
+				city.countProduction(v)
+			}
 
 			city.RessourceProducers[v.ProducerID].Leveling(5)
 			changed = true
@@ -219,9 +241,11 @@ func (city *City) CheckActivity(origin time.Time) (changed bool) {
 	return
 }
 
-//AddFame update fame of city by provided margin.
+//AddFame update fame of city by provided margin. Change is kept in fame ledger once city gets updated.
 func (city *City) AddFame(corpID int, message string, fameDiff int) {
 	city.Fame[corpID] = city.Fame[corpID] + fameDiff
+	city.pendingFame = append(city.pendingFame, city.fameEntry(corpID, fameDiff, message, time.Now().UTC()))
+
 	if fameDiff >= 0 {
 		user_log.NewFromCorp(corpID, user_log.UL_Info, fmt.Sprintf("City %s gain %d Fame (New: %d) for %s", city.Name, fameDiff, city.Fame[corpID], message))
 	} else {
@@ -235,15 +259,16 @@ func (city *City) AddFame(corpID int, message string, fameDiff int) {
 //CheckCityOwnership Checks city's owner fame, if fame drops below threshold of 50, owner is kicked. A check is then made to see if the owner can still continue play.
 //returns true when everything is okay ;)
 func (city *City) CheckCityOwnership(dbh *db.Handler) bool {
-	if city.Fame[city.CorporationID] < 50 {
-		log.Printf("City: %d %s Kick %d %s out", city.ID, city.Name, city.CorporationID, city.CorporationName)
-		user_log.NewFromCorp(city.CorporationID, user_log.UL_Bad, fmt.Sprintf("City: %s Kick %s out", city.Name, city.CorporationName))
+	if city.CorporationID != 0 && city.Fame[city.CorporationID] < 50 {
+		corpID := city.CorporationID
+		city.log().Infof("%d %s Kick %d %s out", city.ID, city.Name, city.CorporationID, city.CorporationName)
+		user_log.NewFromCorp(corpID, user_log.UL_Bad, fmt.Sprintf("City: %s Kick %s out", city.Name, city.CorporationName))
 
 		city.CorporationID = 0
 		city.CorporationName = "Uncorporated"
 		city.Update(dbh)
 
-		corp, err := corporation_manager.GetCorporationHandler(city.CorporationID)
+		corp, err := corporation_manager.GetCorporationHandler(corpID)
 		if err != nil {
 			return false
 		}
@@ -259,29 +284,48 @@ func (city *City) CheckCityOwnership(dbh *db.Handler) bool {
 			}
 
 			corp.CitiesID = res
+			corp.Update(dbh)
 		})
 
 		if !corp.Get().IsViable() {
-			log.Printf("Corporation: Lost its last city ...")
+			logger.Infof("Corporation", "Lost its last city ...")
 			return false
 		}
-		user_log.NewFromCorp(city.CorporationID, user_log.UL_Warn, fmt.Sprintf("Corporation still has %d cities", len(corp.Get().CitiesID)))
+		user_log.NewFromCorp(corpID, user_log.UL_Warn, fmt.Sprintf("Corporation still has %d cities", len(corp.Get().CitiesID)))
 	}
 	return true
 }
 
+// countProduction credit owner with goods produced.
+func (city *City) countProduction(prtion *producer.Production) {
+	if city.CorporationID == 0 {
+		return
+	}
+	if city.Produced == nil {
+		city.Produced = make(map[int]int)
+	}
+	for _, v := range prtion.Production {
+		city.Produced[city.CorporationID] += v.Quantity
+	}
+}
+
 //CanProduce tell whether city can produce item based on name.
 func (city *City) CanProduce(itm item.Item) bool {
+	return city.CanProduceMatching(func(i item.Item) bool { return i.Name == itm.Name })
+}
+
+//CanProduceMatching tell whether city has a producer whose products match, at the best quality it may reach.
+func (city *City) CanProduceMatching(match func(item.Item) bool) bool {
 	for _, v := range city.RessourceProducers {
 		for _, w := range v.Products {
-			if w.ItemName == itm.Name {
+			if match(item.Item{Name: w.ItemName, Type: w.ItemTypes, Quality: w.Quality.Max}) {
 				return true
 			}
 		}
 	}
 	for _, v := range city.ProductFactories {
 		for _, w := range v.Products {
-			if w.ItemName == itm.Name {
+			if match(item.Item{Name: w.ItemName, Type: w.ItemTypes, Quality: w.Quality.Max}) {
 				return true
 			}
 		}
@@ -289,3 +333,18 @@ func (city *City) CanProduce(itm item.Item) bool {
 
 	return false
 }
+
+//SaleValue value of qty of item when sold to city, goods city produces itself are worth less.
+//Corporation bonus still has to be applied, see Corporation.SalePrice
+func (city *City) SaleValue(itm item.Item, qty int) float64 {
+	ratio := gameplay.GetFloat("unproducable_item_price", 1)
+	if city.CanProduce(itm) {
+		ratio = gameplay.GetFloat("producable_item_price", 0.5)
+	}
+	return float64(itm.Price()*qty) * ratio
+}
+
+//log logger bearing city and map ids.
+func (city *City) log() *logger.Logger {
+	return logger.For("City").WithFields(logger.Fields{"city_id": city.ID, "map_id": city.MapID})
+}
@@ -5,12 +5,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"time"
 	"upsilon_cities_go/lib/cities/city/producer"
 	"upsilon_cities_go/lib/cities/node"
 	"upsilon_cities_go/lib/cities/storage"
 	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/logger"
 
 	"github.com/lib/pq"
 )
@@ -32,9 +32,9 @@ func (city *City) Insert(dbh *db.Handler) error {
 		res.Close()
 
 		if city.ID <= 0 {
-			log.Fatalln("City: Failed to insert City in database.")
+			logger.Fatalf("City", "Failed to insert City in database.")
 		}
-		log.Printf("City: Added City %d: %s to database", city.ID, "")
+		logger.Infof("City", "Added City %d: %s to database", city.ID, "")
 	} else {
 		return errors.New("can't insert an already identified city")
 	}
@@ -80,12 +80,14 @@ func (city *City) Update(dbh *db.Handler) error {
 	}
 	res.Close()
 
+	city.flushFame(dbh)
+
 	err = city.dbCheckNeighbours(dbh)
 	if err != nil {
 		return err
 	}
 
-	log.Printf("City: Updated City %d: %s to database", city.ID, "")
+	logger.Infof("City", "Updated City %d: %s to database", city.ID, "")
 
 	return nil
 }
@@ -146,7 +148,7 @@ func (city *City) dbCheckNeighbours(dbh *db.Handler) error {
 	// add missing neighbours
 
 	if len(newNeighbours) > 0 {
-		log.Printf("City: About to insert %d / %d neighbours for city: %d", len(newNeighbours), len(city.NeighboursID), city.ID)
+		logger.Debugf("City", "About to insert %d / %d neighbours for city: %d", len(newNeighbours), len(city.NeighboursID), city.ID)
 		for _, v := range newNeighbours {
 			query, err := dbh.Query("insert into neighbouring_cities(to_city_id, from_city_id) values ($1,$2)", v, city.ID)
 			if err != nil {
@@ -182,7 +184,13 @@ type dbCity struct {
 	HasStorageFull   bool
 	StorageFullSince time.Time
 
-	Fame map[int]int
+	Fame          map[int]int
+	LastFameDecay time.Time
+
+	Claims     []Claim
+	ContestEnd time.Time
+
+	Produced map[int]int
 }
 
 // prepare the json version for database, may not be the appropriate one for API ;)
@@ -204,6 +212,10 @@ func (city *City) dbjsonify() (res []byte, err error) {
 	tmp.Fame = city.Fame
 	tmp.HasStorageFull = city.HasStorageFull
 	tmp.StorageFullSince = city.StorageFullSince
+	tmp.Claims = city.Claims
+	tmp.LastFameDecay = city.LastFameDecay
+	tmp.Produced = city.Produced
+	tmp.ContestEnd = city.ContestEnd
 
 	return json.Marshal(tmp)
 }
@@ -226,22 +238,36 @@ func (city *City) dbunjsonify(fromJSON []byte) (err error) {
 
 	city.Storage.CurrentMaxID = db.CurrentMaxID
 	city.Storage.Reservations = db.Reservations
+	if city.Storage.Reservations == nil {
+		city.Storage.Reservations = make(map[int64]int)
+	}
 	city.Roads = db.Roads
 	city.CurrentMaxID = db.FactoryCurrentMaxID
 	city.Fame = db.Fame
 	city.HasStorageFull = db.HasStorageFull
 	city.StorageFullSince = db.StorageFullSince
+	city.Claims = db.Claims
+	city.LastFameDecay = db.LastFameDecay
+	city.ContestEnd = db.ContestEnd
+	city.Produced = db.Produced
+	if city.Produced == nil {
+		city.Produced = make(map[int]int)
+	}
+	if city.Claims == nil {
+		city.Claims = make([]Claim, 0)
+	}
 
 	return nil
 }
 
 //Reload city ;)
-func (city *City) Reload(dbh *db.Handler) {
+func (city *City) Reload(dbh *db.Handler) error {
 	id := city.ID
 	rows, err := dbh.Query("select city_id, c.map_id, c.data, updated_at, city_name, corporation_id, corp.name from cities as c left outer join corporations as corp using(corporation_id) where c.city_id=$1", id)
 
 	if err != nil {
-		log.Fatalf("City DB : Failed to select City for reload : %s ", err)
+		logger.Errorf("City", "Failed to select City for reload : %s", err)
+		return err
 	}
 
 	for rows.Next() {
@@ -258,7 +284,8 @@ func (city *City) Reload(dbh *db.Handler) {
 	rows, err = dbh.Query("select to_city_id from neighbouring_cities where from_city_id=$1", id)
 
 	if err != nil {
-		log.Fatalf("City DB : Failed to select neighbouring_cities for reload : %s ", err)
+		logger.Errorf("City", "Failed to select neighbouring_cities for reload : %s", err)
+		return err
 	}
 
 	for rows.Next() {
@@ -272,7 +299,8 @@ func (city *City) Reload(dbh *db.Handler) {
 	rows, err = dbh.Query("select caravan_id from caravans where origin_city_id=$1 or target_city_id=$2", id, id)
 
 	if err != nil {
-		log.Fatalf("City DB : Failed to select caravans for reload : %s ", err)
+		logger.Errorf("City", "Failed to select caravans for reload : %s", err)
+		return err
 	}
 
 	for rows.Next() {
@@ -282,6 +310,7 @@ func (city *City) Reload(dbh *db.Handler) {
 	}
 
 	rows.Close()
+	return nil
 }
 
 //ByID Fetch a city by id; note, won't load neighbouring cities ... or maybe only their ids ? ...
@@ -361,7 +390,7 @@ func ByMap(dbh *db.Handler, id int) (cities map[int]*City, err error) {
 
 	rows.Close()
 
-	log.Printf("City: Found %d cities in map %d", len(cities), id)
+	logger.Infof("City", "Found %d cities in map %d", len(cities), id)
 
 	for k, v := range cities {
 		// seek its neighbours
@@ -413,7 +442,7 @@ func (city *City) Drop(dbh *db.Handler) (err error) {
 		return fmt.Errorf("City DB : Failed to Drop City neighbouring_cities : %s", err)
 	}
 
-	log.Printf("City: Dropped City %d: %s from database", city.ID, "")
+	logger.Infof("City", "Dropped City %d: %s from database", city.ID, "")
 	city.ID = 0
 	return nil
 }
@@ -2,7 +2,11 @@ package city
 
 import (
 	"testing"
+	"time"
+	"upsilon_cities_go/lib/cities/city/producer"
 	"upsilon_cities_go/lib/cities/city/producer_generator"
+	"upsilon_cities_go/lib/cities/storage"
+	"upsilon_cities_go/lib/cities/tools"
 	"upsilon_cities_go/lib/misc/generator"
 )
 
@@ -51,3 +55,98 @@ func TestGeneratedCityHasDistinctFactories(t *testing.T) {
 		}
 	}
 }
+
+func TestClaim(t *testing.T) {
+	city := &City{Name: "Claimed", CorporationID: 1, Fame: make(map[int]int)}
+	city.Fame[2] = ClaimFame()
+
+	if city.CanClaim(2) == nil {
+		t.Errorf("Shouldn't be able to claim an owned city")
+		return
+	}
+
+	city.CorporationID = 0
+	if city.CanClaim(3) == nil {
+		t.Errorf("Shouldn't be able to claim a city without fame")
+		return
+	}
+
+	contested, err := city.FileClaim(Claim{CorporationID: 2, Paid: 10}, time.Now().UTC())
+	if err != nil || contested {
+		t.Errorf("Single contender claim should be accepted uncontested: %v %s", contested, err)
+		return
+	}
+
+	if city.CanClaim(2) == nil {
+		t.Errorf("Shouldn't be able to claim twice")
+		return
+	}
+
+	city.Fame[3] = ClaimFame() + 10
+	contenders := city.Contenders()
+	if len(contenders) != 2 || contenders[0] != 3 {
+		t.Errorf("Expected most famous contender first, got %v", contenders)
+		return
+	}
+}
+
+func TestFameDecay(t *testing.T) {
+	rules := FameDecayRules{Ratio: 0.1, Floor: 100, Cycles: 10}
+
+	if FameDecay(200, rules, 1) != 190 {
+		t.Errorf("Expected 190 after one period, got %d", FameDecay(200, rules, 1))
+		return
+	}
+
+	if FameDecay(50, rules, 5) != 50 {
+		t.Errorf("Fame below floor shouldn't decay")
+		return
+	}
+
+	if FameDecay(105, rules, 100) != 100 {
+		t.Errorf("Fame shouldn't decay below floor, got %d", FameDecay(105, rules, 100))
+		return
+	}
+
+	cities := []*City{{Fame: map[int]int{1: 100, 2: 50}}, {Fame: map[int]int{1: 20}}}
+	if Reputation(cities, 1) != 120 {
+		t.Errorf("Expected reputation of 120, got %d", Reputation(cities, 1))
+		return
+	}
+}
+
+func TestAddFameIsKeptForLedger(t *testing.T) {
+	city := &City{ID: 4, Name: "Famous", Fame: make(map[int]int)}
+
+	city.AddFame(1, "good deed", 30)
+	city.AddFame(1, "bad deed", -10)
+
+	if city.Fame[1] != 20 || len(city.pendingFame) != 2 {
+		t.Errorf("Expected fame of 20 and 2 pending ledger entries, got %d and %d", city.Fame[1], len(city.pendingFame))
+		return
+	}
+
+	last := city.pendingFame[1]
+	if last.CityID != 4 || last.CorporationID != 1 || last.Delta != -10 || last.Fame != 20 || last.Reason != "bad deed" {
+		t.Errorf("Unexpected ledger entry %+v", last)
+	}
+}
+
+func TestCanProduceMatchingTypes(t *testing.T) {
+	city := &City{Name: "Producer", RessourceProducers: make(map[int]*producer.Producer), ProductFactories: make(map[int]*producer.Producer)}
+	city.RessourceProducers[1] = &producer.Producer{Products: map[int]producer.Product{1: {ItemName: "Iron Ore", ItemTypes: []string{"Iron"}, Quality: tools.MakeIntRange(10, 30)}}}
+
+	if !city.CanProduceMatching(storage.ByTypesNQuality([]string{"Iron"}, tools.MakeIntRange(20, 50))) {
+		t.Errorf("City should produce Iron within quality range")
+		return
+	}
+
+	if city.CanProduceMatching(storage.ByTypesNQuality([]string{"Iron"}, tools.MakeIntRange(40, 50))) {
+		t.Errorf("City shouldn't produce Iron of quality it can't reach")
+		return
+	}
+
+	if city.CanProduceMatching(storage.ByTypesNQuality([]string{"Wood"}, tools.MakeIntRange(0, 50))) {
+		t.Errorf("City shouldn't produce Wood")
+	}
+}
@@ -0,0 +1,139 @@
+package city
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+	"upsilon_cities_go/lib/cities/corporation"
+	"upsilon_cities_go/lib/cities/corporation_manager"
+	"upsilon_cities_go/lib/cities/tools"
+	"upsilon_cities_go/lib/cities/user_log"
+	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/config/gameplay"
+	"upsilon_cities_go/lib/misc/logger"
+)
+
+//Claim filed by a corporation to take ownership of an uncorporated city.
+type Claim struct {
+	CorporationID   int
+	CorporationName string
+	Paid            int // credits locked by the claim, refunded if it fails.
+	FiledAt         time.Time
+}
+
+//ClaimFame fame a corporation needs in a city to claim it.
+func ClaimFame() int {
+	return gameplay.GetInt("claim_fame_threshold", 100)
+}
+
+//ClaimCost credits a corporation pays to claim a city.
+func ClaimCost() int {
+	return gameplay.GetInt("claim_cost", 500)
+}
+
+//Contenders corporations famous enough to claim city, most famous first.
+func (city *City) Contenders() (res []int) {
+	for k, v := range city.Fame {
+		if k != 0 && v >= ClaimFame() {
+			res = append(res, k)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return city.Fame[res[i]] > city.Fame[res[j]] })
+	return
+}
+
+//HasClaimed tell whether corporation already filed a claim on city.
+func (city *City) HasClaimed(corpID int) bool {
+	for _, v := range city.Claims {
+		if v.CorporationID == corpID {
+			return true
+		}
+	}
+	return false
+}
+
+//CanClaim tell whether corporation may claim city.
+func (city *City) CanClaim(corpID int) error {
+	if city.CorporationID != 0 {
+		return errors.New("city already belongs to a corporation")
+	}
+	if city.Fame[corpID] < ClaimFame() {
+		return fmt.Errorf("not famous enough in %s, need %d fame", city.Name, ClaimFame())
+	}
+	if city.HasClaimed(corpID) {
+		return errors.New("corporation already claimed this city")
+	}
+	return nil
+}
+
+//FileClaim register a claim on city, claim is contested when other corporations qualify as well.
+func (city *City) FileClaim(claim Claim, now time.Time) (contested bool, err error) {
+	if err = city.CanClaim(claim.CorporationID); err != nil {
+		return false, err
+	}
+
+	claim.FiledAt = tools.RoundTime(now)
+	city.Claims = append(city.Claims, claim)
+
+	if len(city.Contenders()) > 1 || len(city.Claims) > 1 {
+		if city.ContestEnd.IsZero() {
+			city.ContestEnd = tools.AddCycles(claim.FiledAt, gameplay.GetInt("claim_contest_cycles", 10))
+			for _, v := range city.Contenders() {
+				user_log.NewFromCorp(v, user_log.UL_Warn, fmt.Sprintf("City %s is claimed by %s, contest ends %s", city.Name, claim.CorporationName, city.ContestEnd.Format(time.RFC3339)))
+			}
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+//ResolveClaims hand city to the most famous claimant once contest is over. Failed claims are refunded.
+//Must be called from within city, corporations are accessed.
+func (city *City) ResolveClaims(dbh *db.Handler, now time.Time) bool {
+	if len(city.Claims) == 0 || (!city.ContestEnd.IsZero() && city.ContestEnd.After(now)) {
+		return false
+	}
+
+	winner := -1
+	for idx, v := range city.Claims {
+		if city.CorporationID != 0 || city.Fame[v.CorporationID] < ClaimFame() {
+			continue
+		}
+		if winner == -1 || city.Fame[v.CorporationID] > city.Fame[city.Claims[winner].CorporationID] {
+			winner = idx
+		}
+	}
+
+	for idx, v := range city.Claims {
+		cm, err := corporation_manager.GetCorporationHandler(v.CorporationID)
+		if err != nil {
+			continue
+		}
+
+		if idx != winner {
+			cm.Call(func(corp *corporation.Corporation) {
+				corp.Credits += v.Paid
+				corp.Update(dbh)
+			})
+			user_log.NewFromCorp(v.CorporationID, user_log.UL_Bad, fmt.Sprintf("City %s: claim failed, %d credits refunded", city.Name, v.Paid))
+			continue
+		}
+
+		cm.Call(func(corp *corporation.Corporation) {
+			if !tools.InList(city.ID, corp.CitiesID) {
+				corp.CitiesID = append(corp.CitiesID, city.ID)
+			}
+			corp.Update(dbh)
+		})
+		city.CorporationID = v.CorporationID
+		city.CorporationName = v.CorporationName
+		logger.Infof("City", "%d %s claimed by %d %s", city.ID, city.Name, v.CorporationID, v.CorporationName)
+		user_log.NewFromCorp(v.CorporationID, user_log.UL_Good, fmt.Sprintf("City %s now belongs to %s", city.Name, v.CorporationName))
+	}
+
+	city.Claims = make([]Claim, 0)
+	city.ContestEnd = time.Time{}
+	city.Update(dbh)
+	return winner != -1
+}
@@ -0,0 +1,107 @@
+package city
+
+import (
+	"math"
+	"time"
+	"upsilon_cities_go/lib/cities/tools"
+	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/config/gameplay"
+	"upsilon_cities_go/lib/misc/logger"
+)
+
+//FameEntry a change of fame of a corporation in a city, kept in fame ledger.
+type FameEntry struct {
+	ID            int
+	CityID        int
+	CorporationID int
+	Delta         int
+	Fame          int // fame once change got applied.
+	Reason        string
+	Cycle         time.Time
+}
+
+//FameDecayRules how fame fades over time. May be overridden by "fame_decay" in gameplay.json
+type FameDecayRules struct {
+	Ratio  float64 // ratio of fame above floor lost by period.
+	Floor  int     // fame never decays below floor.
+	Cycles int     // cycles by period.
+}
+
+//LoadFameDecayRules defaults overridden by gameplay configuration.
+func LoadFameDecayRules() FameDecayRules {
+	res := FameDecayRules{Ratio: 0.02, Floor: 100, Cycles: 10}
+	gameplay.GetObject("fame_decay", &res)
+	res.Cycles = tools.Max(1, res.Cycles)
+	return res
+}
+
+//FameDecay fame left after periods of decay.
+func FameDecay(fame int, rules FameDecayRules, periods int) int {
+	for i := 0; i < periods && fame > rules.Floor; i++ {
+		fame -= int(math.Ceil(float64(fame-rules.Floor) * rules.Ratio))
+	}
+	return fame
+}
+
+// fameEntry ledger entry of a change of fame that just got applied.
+func (city *City) fameEntry(corpID int, delta int, reason string, now time.Time) FameEntry {
+	return FameEntry{CityID: city.ID, CorporationID: corpID, Delta: delta, Fame: city.Fame[corpID], Reason: reason, Cycle: tools.RoundTime(now)}
+}
+
+// recordFame add an entry to fame ledger, failure to record is logged but doesn't prevent fame to change.
+func (city *City) recordFame(dbh *db.Handler, corpID int, delta int, reason string, now time.Time) {
+	city.insertFame(dbh, city.fameEntry(corpID, delta, reason, now))
+}
+
+// insertFame write entry to fame ledger, failure is logged only.
+func (city *City) insertFame(dbh *db.Handler, entry FameEntry) {
+	if err := entry.Insert(dbh); err != nil {
+		logger.Errorf("City", "Failed to record fame of %d in %s: %s", entry.CorporationID, city.Name, err)
+	}
+}
+
+// flushFame write fame changes pending since last update to fame ledger.
+func (city *City) flushFame(dbh *db.Handler) {
+	for _, v := range city.pendingFame {
+		city.insertFame(dbh, v)
+	}
+	city.pendingFame = nil
+}
+
+//DecayFame fade fame of every corporation in city for each period elapsed since last decay.
+func (city *City) DecayFame(dbh *db.Handler, now time.Time) bool {
+	rules := LoadFameDecayRules()
+	if city.LastFameDecay.IsZero() {
+		city.LastFameDecay = tools.RoundTime(now)
+		return false
+	}
+
+	periods := 0
+	for next := tools.AddCycles(city.LastFameDecay, rules.Cycles); !next.After(now); next = tools.AddCycles(next, rules.Cycles) {
+		periods++
+		city.LastFameDecay = next
+	}
+	if periods == 0 {
+		return false
+	}
+
+	changed := false
+	for k, v := range city.Fame {
+		decayed := FameDecay(v, rules, periods)
+		if decayed == v {
+			continue
+		}
+		city.Fame[k] = decayed
+		city.recordFame(dbh, k, decayed-v, "fame decay", now)
+		changed = true
+	}
+	return changed
+}
+
+//Reputation fame of corporation summed over all cities.
+func Reputation(cities []*City, corpID int) (res int) {
+	for _, v := range cities {
+		res += v.Fame[corpID]
+	}
+	return
+}
@@ -0,0 +1,39 @@
+package city
+
+import (
+	"fmt"
+	"upsilon_cities_go/lib/db"
+)
+
+//Insert a fame entry in database
+func (entry *FameEntry) Insert(dbh *db.Handler) error {
+	rows, err := dbh.Query("insert into fame_ledger(city_id, corporation_id, delta, fame, reason, cycle) values($1,$2,$3,$4,$5,$6) returning fame_ledger_id",
+		entry.CityID, entry.CorporationID, entry.Delta, entry.Fame, entry.Reason, entry.Cycle)
+	if err != nil {
+		return fmt.Errorf("Fame Ledger DB : Failed to insert entry: %s", err)
+	}
+	for rows.Next() {
+		rows.Scan(&entry.ID)
+	}
+	rows.Close()
+	return nil
+}
+
+//FameHistory fetches last entries of fame ledger of a corporation in a city, oldest first.
+func FameHistory(dbh *db.Handler, cityID int, corpID int, limit int) (res []FameEntry, err error) {
+	rows, err := dbh.Query(`select fame_ledger_id, city_id, corporation_id, delta, fame, reason, cycle from (
+		select * from fame_ledger where city_id=$1 and corporation_id=$2 order by fame_ledger_id desc limit $3
+		) as last order by fame_ledger_id`, cityID, corpID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("Fame Ledger DB : Failed to select entries (FameHistory): %s", err)
+	}
+	defer rows.Close()
+
+	res = make([]FameEntry, 0)
+	for rows.Next() {
+		var entry FameEntry
+		rows.Scan(&entry.ID, &entry.CityID, &entry.CorporationID, &entry.Delta, &entry.Fame, &entry.Reason, &entry.Cycle)
+		res = append(res, entry)
+	}
+	return
+}
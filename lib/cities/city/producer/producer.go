@@ -3,15 +3,19 @@ package producer
 import (
 	"errors"
 	"fmt"
-	"log"
 	"math"
+	"math/rand"
 	"strings"
 	"time"
 	"upsilon_cities_go/lib/cities/item"
 	"upsilon_cities_go/lib/cities/storage"
 	"upsilon_cities_go/lib/cities/tools"
+	"upsilon_cities_go/lib/misc/logger"
+	"upsilon_cities_go/lib/misc/metrics"
 )
 
+var itemsProduced = metrics.NewCounter("upsilon_items_produced_total", "Quantity of items stored once production completed, by item.", "item")
+
 const (
 	quantityOne  int = 0
 	qualityOne   int = 1
@@ -37,6 +41,7 @@ type Product struct {
 	Quality     tools.IntRange
 	Quantity    tools.IntRange
 	BasePrice   int
+	Decay       item.Decay // optional, perishable products only.
 	UpgradeInfo upgrade
 }
 
@@ -98,6 +103,7 @@ func (prod *Producer) produce() (res []item.Item) {
 		rs.Quality = v.GetQuality().Roll()
 		rs.Quantity = v.GetQuantity().Roll()
 		rs.BasePrice = v.BasePrice
+		rs.Decay = v.Decay
 		res = append(res, rs)
 	}
 	prod.LastActivity = tools.RoundNow()
@@ -191,6 +197,28 @@ func (prod *Producer) Upgrade(action int, productID int) (result bool) {
 	return canUpgrade
 }
 
+//UpgradeAll spends every available upgrade point on random products. Used by AI corporations.
+func (prod *Producer) UpgradeAll() (changed bool) {
+	if len(prod.Products) == 0 {
+		return false
+	}
+
+	ids := make([]int, 0, len(prod.Products))
+	for k := range prod.Products {
+		ids = append(ids, k)
+	}
+
+	for prod.CanUpgrade() {
+		action := []int{quantityOne, qualityOne}[rand.Intn(2)]
+		changed = prod.Upgrade(action, ids[rand.Intn(len(ids))]) || changed
+	}
+	for prod.CanBigUpgrade() {
+		action := []int{delay, quantityFive, qualityFive}[rand.Intn(3)]
+		changed = prod.Upgrade(action, ids[rand.Intn(len(ids))]) || changed
+	}
+	return
+}
+
 //CanUpgrade Producer can make a simple Upgrade
 func (prod *Producer) CanUpgrade() bool {
 	return (prod.UpgradePoint.Total - prod.UpgradePoint.Used) > 0
@@ -412,14 +440,20 @@ func Produce(store *storage.Storage, prod *Producer, startDate time.Time) (*Prod
 //IsFinished tell whether production is finished or not ;)
 func (prtion *Production) IsFinished(now time.Time) (finished bool) {
 	finished = now.After(prtion.EndTime) || now.Equal(prtion.EndTime)
-	log.Printf("Producer: %d %s : End: %s, Now %s, Finished ? %v", prtion.ProducerID, prtion.ProducerName, prtion.EndTime.Format(time.RFC3339), now.Format(time.RFC3339), finished)
+	logger.Debugf("Producer", "%d %s : End: %s, Now %s, Finished ? %v", prtion.ProducerID, prtion.ProducerName, prtion.EndTime.Format(time.RFC3339), now.Format(time.RFC3339), finished)
 	return
 }
 
 //ProductionCompleted Update store
 func ProductionCompleted(store *storage.Storage, prtion *Production, nextUpdate time.Time) error {
 	if prtion.IsFinished(nextUpdate) {
-		return store.Claim(prtion.Reservation, prtion.Production)
+		err := store.Claim(prtion.Reservation, prtion.Production)
+		if err == nil {
+			for _, v := range prtion.Production {
+				itemsProduced.Add(float64(v.Quantity), v.Name)
+			}
+		}
+		return err
 	}
 	return errors.New("unable to complete production (not finished)")
 }
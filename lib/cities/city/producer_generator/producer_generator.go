@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"math/rand"
 	"os"
 	"path/filepath"
@@ -13,6 +12,7 @@ import (
 	"upsilon_cities_go/lib/cities/city/producer"
 	"upsilon_cities_go/lib/cities/tools"
 	"upsilon_cities_go/lib/misc/config/system"
+	"upsilon_cities_go/lib/misc/logger"
 )
 
 //Factory describe a producer at level 0
@@ -44,6 +44,9 @@ func CreateSampleFile() {
 	p.Quality.Max = 25
 	p.Quantity.Min = 1
 	p.Quantity.Max = 2
+	p.Decay.PerCycle = 1
+	p.Decay.MinQuality = 5
+	p.Decay.SpoilTo = "Spoiled TestItem"
 	f.Products = append(f.Products, p)
 
 	var r producer.Requirement
@@ -88,18 +91,18 @@ func Load() {
 
 	filepath.Walk(system.MakePath(system.Get("data_producers", "data/producers")), func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			log.Fatalf("Producer: prevent panic by handling failure accessing a path %q: %v\n", system.Get("data_producers", "data/producers"), err)
+			logger.Fatalf("Producer", "prevent panic by handling failure accessing a path %q: %v", system.Get("data_producers", "data/producers"), err)
 			return err
 		}
 		if strings.HasSuffix(info.Name(), ".json") {
 			f, ferr := os.Open(path)
 			if ferr != nil {
-				log.Fatalln("Producer: No Producer data file present")
+				logger.Fatalf("Producer", "No Producer data file present")
 			}
 
 			producerJSON, ferr := ioutil.ReadAll(f)
 			if ferr != nil {
-				log.Fatalln("Producer: Data file found but unable to read it all.")
+				logger.Fatalf("Producer", "Data file found but unable to read it all.")
 			}
 
 			f.Close()
@@ -111,7 +114,7 @@ func Load() {
 				baseID++
 
 				loadFactory(p, baseID, info.Name())
-				log.Printf("Producer loaded: %d %s", baseID, p.String())
+				logger.Infof("Producer", "Producer loaded: %d %s", baseID, p.String())
 			}
 		}
 
@@ -119,7 +122,12 @@ func Load() {
 	})
 
 	validate()
-	log.Printf("Producer: Loaded %d factories, %d ressource producers", len(factories), len(ressources))
+	logger.Infof("Producer", "Loaded %d factories, %d ressource producers", len(factories), len(ressources))
+}
+
+//Loaded number of factories and ressource producers known.
+func Loaded() int {
+	return len(factories) + len(ressources)
 }
 
 //loadFactory will store a factory in memory with appropriate links done.
@@ -238,14 +246,14 @@ func producerMatchingRequirement(rq producer.Requirement) (res []*Factory, found
 func validate() {
 	for _, v := range knownProducers {
 		for _, vv := range v {
-			log.Printf("Producer: Loaded: %s", vv.String())
+			logger.Debugf("Producer", "Loaded: %s", vv.String())
 			for _, req := range vv.Requirements {
 				ressourceFactories, found := ProducerMatchingTypes(req.ItemTypes)
 				if !found {
 					_, found = knownProducersNames[req.ItemName]
 					if !found {
-						log.Printf("Producer: Invalid Producer registered: %s", vv.String())
-						log.Fatalf("Producer: It misses required ressource: %s", req.String())
+						logger.Warnf("Producer", "Invalid Producer registered: %s", vv.String())
+						logger.Fatalf("Producer", "It misses required ressource: %s", req.String())
 					}
 				}
 
@@ -261,8 +269,8 @@ func validate() {
 					}
 
 					if !oneRessource {
-						log.Printf("Producer: Invalid Producer registered: %s", vv.String())
-						log.Fatalf("Producer: Marked as not advanced but requires non ressources type: %s", req.String())
+						logger.Warnf("Producer", "Invalid Producer registered: %s", vv.String())
+						logger.Fatalf("Producer", "Marked as not advanced but requires non ressources type: %s", req.String())
 					}
 				}
 			}
@@ -306,6 +314,7 @@ func (pf *Factory) Create() (prod *producer.Producer) {
 		p.Quality = v.Quality
 		p.Quantity = v.Quantity
 		p.BasePrice = v.BasePrice
+		p.Decay = v.Decay
 		prod.Products[(idx + 1)] = p
 	}
 	prod.Name = pf.ProducerName
@@ -389,8 +398,8 @@ func CreateProducerByName(item string) (*producer.Producer, error) {
 //CreateFactoryNotAdvanced find a factory whose requirement contains at least one of items.
 func CreateFactoryNotAdvanced(items map[string]bool, notin map[int]bool) (*producer.Producer, error) {
 
-	log.Printf("Producer: Attempting to find a factory using %v", items)
-	log.Printf("Producer: Attempting to find a factory not in %v", notin)
+	logger.Debugf("Producer", "Attempting to find a factory using %v", items)
+	logger.Debugf("Producer", "Attempting to find a factory not in %v", notin)
 
 	for _, v := range factories {
 		for _, vv := range knownProducersNames[v] {
@@ -404,7 +413,7 @@ func CreateFactoryNotAdvanced(items map[string]bool, notin map[int]bool) (*produ
 				continue
 			}
 
-			log.Printf("Producer: Checking %s", vv.String())
+			logger.Debugf("Producer", "Checking %s", vv.String())
 			for _, req := range vv.Requirements {
 				foundOne := false
 				for _, w := range req.ItemTypes {
@@ -3,7 +3,6 @@ package resource_generator
 import (
 	"encoding/json"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,6 +11,7 @@ import (
 	"upsilon_cities_go/lib/cities/map/pattern"
 	"upsilon_cities_go/lib/cities/node"
 	"upsilon_cities_go/lib/misc/config/system"
+	"upsilon_cities_go/lib/misc/logger"
 )
 
 //DB Store in memory all ressource definitions.
@@ -27,18 +27,18 @@ func Load() {
 
 	filepath.Walk(system.MakePath(system.Get("data_resources", "data/resources")), func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			log.Fatalf("Resource: prevent panic by handling failure accessing a path %q: %v\n", system.Get("data_producers", "data/producers"), err)
+			logger.Fatalf("Resource", "prevent panic by handling failure accessing a path %q: %v", system.Get("data_producers", "data/producers"), err)
 			return err
 		}
 		if strings.HasSuffix(info.Name(), ".json") {
 			f, ferr := os.Open(path)
 			if ferr != nil {
-				log.Fatalln("Resource: No Resource: data file present")
+				logger.Fatalf("Resource", "No Resource: data file present")
 			}
 
 			producerJSON, ferr := ioutil.ReadAll(f)
 			if ferr != nil {
-				log.Fatalln("Resource: Data file found but unable to read it all.")
+				logger.Fatalf("Resource", "Data file found but unable to read it all.")
 			}
 
 			f.Close()
@@ -55,7 +55,7 @@ func Load() {
 					}
 				}
 				DB[baseID] = p
-				log.Printf("Resource: Loaded Resource %v ", p.String())
+				logger.Infof("Resource", "Loaded Resource %v", p.String())
 			}
 		}
 
@@ -64,6 +64,11 @@ func Load() {
 
 }
 
+//Loaded number of resources known.
+func Loaded() int {
+	return len(DB)
+}
+
 func computeDepth(n node.Node, gd *grid.CompoundedGrid) (depth int) {
 	depth = 1
 	for true {
@@ -94,7 +99,7 @@ func matchConstraint(targets []node.Node, c resource.Constraint, depths *map[int
 	}
 
 	for _, t := range targets {
-		//log.Printf("RG: match Constraint: %v : %v (%d >= %d)", c.NodeType.String(), t.Type.String(), (*depths)[t.Location.ToInt(mapSize)], c.Depth)
+		//logger.Debugf("RG", "match Constraint: %v : %v (%d >= %d)", c.NodeType.String(), t.Type.String(), (*depths)[t.Location.ToInt(mapSize)], c.Depth)
 		if t.Type == c.NodeType {
 			if (*depths)[t.Location.ToInt(mapSize)] >= c.Depth {
 				return true
@@ -124,7 +129,7 @@ func GatherResourcesAvailable(loc node.Point, gd *grid.CompoundedGrid, depths *m
 
 		allOk := true
 		for _, c := range v.Constraints {
-			//log.Printf("RG: Check %v constraint %v D %d P %d, len %d", v.Type, c.NodeType.String(), c.Depth, c.Proximity, len(availableDists[c.Proximity]))
+			//logger.Debugf("RG", "Check %v constraint %v D %d P %d, len %d", v.Type, c.NodeType.String(), c.Depth, c.Proximity, len(availableDists[c.Proximity]))
 			if !matchConstraint(availableDists[c.Proximity], c, depths, gd.Base.Size) {
 				allOk = false
 				break
@@ -2,8 +2,11 @@ package city_manager
 
 import (
 	"errors"
+	"time"
 	"upsilon_cities_go/lib/cities/city"
+	"upsilon_cities_go/lib/db"
 	"upsilon_cities_go/lib/misc/actor"
+	"upsilon_cities_go/lib/misc/logger"
 )
 
 //Handler own the grid, they're to be called upon to provide access to the grid
@@ -88,6 +91,44 @@ func DropCityHandler(id int) error {
 	return nil
 }
 
+//Persist drain mailbox of every loaded city and save it, returns how many failed to be saved.
+func Persist(dbh *db.Handler) (failed int) {
+	var handlers []*Handler
+	manager.Call(func() {
+		for _, v := range manager.handlers {
+			handlers = append(handlers, v)
+		}
+	})
+
+	for _, v := range handlers {
+		v.Call(func(cty *city.City) {
+			if err := cty.Update(dbh); err != nil {
+				logger.Errorf("CityMgr", "Failed to persist city %d: %s", cty.ID, err)
+				failed++
+			}
+		})
+	}
+	logger.Infof("CityMgr", "Persisted %d citys, %d failed", len(handlers)-failed, failed)
+	return
+}
+
+//Stats how many cities are loaded and how many functions wait in mailboxes, manager's included.
+func Stats() (loaded int, queued int) {
+	queued = manager.Queued()
+	manager.Call(func() {
+		loaded = len(manager.handlers)
+		for _, v := range manager.handlers {
+			queued += v.Queued()
+		}
+	})
+	return
+}
+
+//Ping tell whether manager is responsive.
+func Ping(timeout time.Duration) bool {
+	return manager.Ping(timeout)
+}
+
 //Cast send and forget. Will provide access to protected grid.
 // If you want a reply, dont forget to provide your function a chan
 // If you do so, DONT FORGET TO call defer close(<your chan>)
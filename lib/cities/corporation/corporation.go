@@ -1,5 +1,10 @@
 package corporation
 
+import (
+	"time"
+	"upsilon_cities_go/lib/cities/storage"
+)
+
 type Corporation struct {
 	ID        int
 	Name      string
@@ -9,6 +14,32 @@ type Corporation struct {
 
 	Credits int
 
+	// corporation level storage, goods get there through transfers from owned cities.
+	Warehouse           *storage.Storage
+	WarehouseCityID     int // city from which transfer distances are computed.
+	WarehouseLastUpdate time.Time
+	Transfers           []Transfer
+	CurrentTransferID   int
+
+	// standing rules answering incoming proposals.
+	ProposalRules []ProposalRule
+	CurrentRuleID int
+
+	// roads being built by corporation.
+	RoadWorks         []RoadWork
+	CurrentRoadWorkID int
+
+	// fame summed over all cities of the map, unlocks perks.
+	Reputation int
+
+	// caravan contracts fulfilled up to their term, kept even once caravans are dropped.
+	CaravansCompleted int
+
+	// eliminated corporations are out of the game, they keep no city nor owner.
+	Eliminated    bool
+	EliminatedAt  time.Time
+	FormerOwnerID int
+
 	// user ;)
 	OwnerID int
 }
@@ -19,6 +50,10 @@ func New(MapID int, CorpName string) (corporation *Corporation) {
 	corporation.MapID = MapID
 	corporation.Name = CorpName
 	corporation.OwnerID = 0
+	corporation.Warehouse = NewWarehouse()
+	corporation.Transfers = make([]Transfer, 0)
+	corporation.ProposalRules = make([]ProposalRule, 0)
+	corporation.RoadWorks = make([]RoadWork, 0)
 	return corporation
 }
 
@@ -4,18 +4,22 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"time"
+	"upsilon_cities_go/lib/cities/item"
+	"upsilon_cities_go/lib/cities/storage"
 	"upsilon_cities_go/lib/cities/user"
 	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/logger"
 )
 
 //Reload corporation
-func (corp *Corporation) Reload(dbh *db.Handler) {
+func (corp *Corporation) Reload(dbh *db.Handler) error {
 	id := corp.ID
 	var data []byte
 	rows, err := dbh.Query("select map_id, name, data, (case when user_id is NULL then 0 else user_id end) from corporations where corporation_id=$1;", id)
 	if err != nil {
-		log.Fatalf("Corporation DB : Failed to select corporation for Reload : %s ", err)
+		logger.Errorf("Corporation", "Failed to select corporation for Reload : %s", err)
+		return err
 	}
 
 	for rows.Next() {
@@ -26,7 +30,8 @@ func (corp *Corporation) Reload(dbh *db.Handler) {
 
 	rows, err = dbh.Query("select city_id from cities where corporation_id=$1;", id)
 	if err != nil {
-		log.Fatalf("Corporation DB : Failed to select corporation city for Reload : %s ", err)
+		logger.Errorf("Corporation", "Failed to select corporation city for Reload : %s", err)
+		return err
 	}
 	for rows.Next() {
 		var cid int
@@ -37,7 +42,8 @@ func (corp *Corporation) Reload(dbh *db.Handler) {
 
 	rows, err = dbh.Query("select caravan_id from caravans where origin_corporation_id=$1 or target_corporation_id=$2;", id, id)
 	if err != nil {
-		log.Fatalf("Corporation DB : Failed to select corporation caravan for Reload : %s ", err)
+		logger.Errorf("Corporation", "Failed to select corporation caravan for Reload : %s", err)
+		return err
 	}
 	for rows.Next() {
 		var cid int
@@ -45,7 +51,7 @@ func (corp *Corporation) Reload(dbh *db.Handler) {
 		corp.CaravanID = append(corp.CaravanID, cid)
 	}
 	rows.Close()
-
+	return nil
 }
 
 //Insert corporation in database.
@@ -243,6 +249,9 @@ func ByMapIDClaimable(dbh *db.Handler, id int) (corps []*Corporation, err error)
 		var data []byte
 		rows.Scan(&corp.ID, &corp.MapID, &corp.Name, &data)
 		corp.dbunjsonify(data)
+		if corp.Eliminated {
+			continue
+		}
 
 		subrow, err := dbh.Query("select city_id from cities where corporation_id=$1;", corp.ID)
 		if err != nil {
@@ -274,20 +283,91 @@ func ByMapIDClaimable(dbh *db.Handler, id int) (corps []*Corporation, err error)
 }
 
 type dbCorporation struct {
+	Warehouse           *storage.Storage
+	WarehouseMaxID      int64
+	Reservations        map[int64]int
+	WarehouseCityID     int
+	WarehouseLastUpdate time.Time
+	Transfers           []Transfer
+	CurrentTransferID   int
+	ProposalRules       []ProposalRule
+	CurrentRuleID       int
+	RoadWorks           []RoadWork
+	CurrentRoadWorkID   int
+	Reputation          int
+	CaravansCompleted   int
+	Eliminated          bool
+	EliminatedAt        time.Time
+	FormerOwnerID       int
 }
 
 func (corp *Corporation) dbjsonify() (res []byte, err error) {
 	var tmp dbCorporation
+	tmp.Warehouse = corp.Warehouse
+	tmp.WarehouseMaxID = corp.Warehouse.CurrentMaxID
+	tmp.Reservations = corp.Warehouse.Reservations
+	tmp.WarehouseCityID = corp.WarehouseCityID
+	tmp.WarehouseLastUpdate = corp.WarehouseLastUpdate
+	tmp.Transfers = corp.Transfers
+	tmp.CurrentTransferID = corp.CurrentTransferID
+	tmp.ProposalRules = corp.ProposalRules
+	tmp.CurrentRuleID = corp.CurrentRuleID
+	tmp.RoadWorks = corp.RoadWorks
+	tmp.CurrentRoadWorkID = corp.CurrentRoadWorkID
+	tmp.Reputation = corp.Reputation
+	tmp.CaravansCompleted = corp.CaravansCompleted
+	tmp.Eliminated = corp.Eliminated
+	tmp.EliminatedAt = corp.EliminatedAt
+	tmp.FormerOwnerID = corp.FormerOwnerID
 	return json.Marshal(tmp)
 }
 
 func (corp *Corporation) dbunjsonify(fromJSON []byte) (err error) {
+	// older corporations don't have a warehouse yet.
+	corp.Warehouse = NewWarehouse()
+	corp.Transfers = make([]Transfer, 0)
+	corp.ProposalRules = make([]ProposalRule, 0)
+	corp.RoadWorks = make([]RoadWork, 0)
+
 	var db dbCorporation
 	err = json.Unmarshal(fromJSON, &db)
 	if err != nil {
 		return err
 	}
 
+	if db.Warehouse != nil {
+		corp.Warehouse = db.Warehouse
+		if corp.Warehouse.Content == nil {
+			corp.Warehouse.Content = make(map[int64]item.Item)
+		}
+		if db.WarehouseMaxID > 0 {
+			corp.Warehouse.CurrentMaxID = db.WarehouseMaxID
+		}
+		corp.Warehouse.Reservations = db.Reservations
+		if corp.Warehouse.Reservations == nil {
+			corp.Warehouse.Reservations = make(map[int64]int)
+		}
+	}
+	if db.Transfers != nil {
+		corp.Transfers = db.Transfers
+	}
+	corp.WarehouseCityID = db.WarehouseCityID
+	corp.WarehouseLastUpdate = db.WarehouseLastUpdate
+	corp.CurrentTransferID = db.CurrentTransferID
+	if db.ProposalRules != nil {
+		corp.ProposalRules = db.ProposalRules
+	}
+	corp.CurrentRuleID = db.CurrentRuleID
+	if db.RoadWorks != nil {
+		corp.RoadWorks = db.RoadWorks
+	}
+	corp.CurrentRoadWorkID = db.CurrentRoadWorkID
+	corp.Reputation = db.Reputation
+	corp.CaravansCompleted = db.CaravansCompleted
+	corp.Eliminated = db.Eliminated
+	corp.EliminatedAt = db.EliminatedAt
+	corp.FormerOwnerID = db.FormerOwnerID
+
 	return nil
 }
 
@@ -300,3 +380,14 @@ func Claim(dbh *db.Handler, usr *user.User, corp *Corporation) error {
 	corp.OwnerID = usr.ID
 	return corp.Update(dbh)
 }
+
+//Release corporation from its owner, reverse of Claim.
+func Release(dbh *db.Handler, corp *Corporation) error {
+	if corp.OwnerID == 0 {
+		return errors.New("unable to release corporation as it isn't owned")
+	}
+
+	corp.FormerOwnerID = corp.OwnerID
+	corp.OwnerID = 0
+	return corp.Update(dbh)
+}
@@ -1 +1,260 @@
 package corporation
+
+import (
+	"testing"
+	"upsilon_cities_go/lib/cities/item"
+	"upsilon_cities_go/lib/cities/node"
+	"upsilon_cities_go/lib/cities/tools"
+)
+
+func generateItem() (res item.Item) {
+	res.Name = "Some Item"
+	res.Type = []string{"Some Item type"}
+	res.Quality = 10
+	res.Quantity = 5
+	res.BasePrice = 10
+	return
+}
+
+func TestTransferToWarehouse(t *testing.T) {
+	tools.InitCycle()
+	corp := New(1, "Test")
+	corp.CitiesID = []int{1, 2}
+
+	itm := generateItem()
+	res, err := corp.Warehouse.Reserve(itm.Quantity)
+	if err != nil {
+		t.Errorf("Unable to reserve warehouse space: %s", err)
+		return
+	}
+
+	now := tools.RoundNow()
+	_, err = corp.StartTransfer(1, "Some City", true, []item.Item{itm}, res, 3, now)
+	if err != nil {
+		t.Errorf("Unable to start transfer: %s", err)
+		return
+	}
+
+	corp.CompleteTransfers(tools.AddCycles(now, 2))
+	if len(corp.Transfers) != 1 || len(corp.Warehouse.Content) != 0 {
+		t.Errorf("Transfer shouldn't be completed yet")
+		return
+	}
+
+	corp.CompleteTransfers(tools.AddCycles(now, 3))
+	if len(corp.Transfers) != 0 || corp.Warehouse.CountAll(func(item.Item) bool { return true }) != itm.Quantity {
+		t.Errorf("Expected goods to be in warehouse after transfer")
+		return
+	}
+}
+
+func TestTransferToFullWarehouseWaits(t *testing.T) {
+	tools.InitCycle()
+	corp := New(1, "Test")
+	corp.CitiesID = []int{1, 2}
+
+	itm := generateItem()
+	res, _ := corp.Warehouse.Reserve(itm.Quantity)
+	now := tools.RoundNow()
+	corp.StartTransfer(1, "Some City", true, []item.Item{itm}, res, 1, now)
+
+	// reservation got lost, and warehouse filled up meanwhile.
+	corp.Warehouse.GiveBack(res)
+	filler := generateItem()
+	filler.Name = "Filler"
+	filler.Quantity = corp.Warehouse.Capacity
+	corp.Warehouse.Add(filler)
+
+	corp.CompleteTransfers(tools.AddCycles(now, 1))
+	if len(corp.Transfers) != 1 || corp.Transfers[0].Reservation != 0 {
+		t.Errorf("Expected goods to wait for space instead of vanishing")
+		return
+	}
+
+	for id, v := range corp.Warehouse.Content {
+		corp.Warehouse.Remove(id, v.Quantity)
+	}
+	corp.CompleteTransfers(tools.AddCycles(now, 2))
+	if len(corp.Transfers) != 0 || corp.Warehouse.Count() != itm.Quantity {
+		t.Errorf("Expected goods to be in warehouse once space is available")
+		return
+	}
+}
+
+func TestWarehouseReservationsSurviveReload(t *testing.T) {
+	corp := New(1, "Test")
+	res, _ := corp.Warehouse.Reserve(5)
+
+	data, err := corp.dbjsonify()
+	if err != nil {
+		t.Errorf("Unable to jsonify corporation: %s", err)
+		return
+	}
+
+	var loaded Corporation
+	loaded.dbunjsonify(data)
+	if loaded.Warehouse.Reservations[res] != 5 || loaded.Warehouse.CurrentMaxID != corp.Warehouse.CurrentMaxID {
+		t.Errorf("Expected reservation %d to survive reload, got %v", res, loaded.Warehouse.Reservations)
+		return
+	}
+}
+
+func TestCaravansCompletedSurviveReload(t *testing.T) {
+	corp := New(1, "Test")
+	corp.CaravansCompleted = 3
+
+	data, err := corp.dbjsonify()
+	if err != nil {
+		t.Errorf("Unable to jsonify corporation: %s", err)
+		return
+	}
+
+	var loaded Corporation
+	loaded.dbunjsonify(data)
+	if loaded.CaravansCompleted != 3 {
+		t.Errorf("Expected completed caravans to survive reload, got %d", loaded.CaravansCompleted)
+	}
+}
+
+func TestTransferFromNotOwnedCity(t *testing.T) {
+	tools.InitCycle()
+	corp := New(1, "Test")
+	corp.CitiesID = []int{1, 2}
+
+	_, err := corp.StartTransfer(3, "Some City", true, []item.Item{generateItem()}, 0, 1, tools.RoundNow())
+	if err == nil {
+		t.Errorf("Shouldn't be able to transfer from a city not owned")
+	}
+}
+
+func TestUpgradeWarehouse(t *testing.T) {
+	corp := New(1, "Test")
+	capacity := corp.Warehouse.Capacity
+
+	if corp.UpgradeWarehouse() == nil {
+		t.Errorf("Shouldn't be able to upgrade without credits")
+		return
+	}
+
+	corp.Credits = corp.WarehouseUpgradeCost()
+	if err := corp.UpgradeWarehouse(); err != nil {
+		t.Errorf("Unable to upgrade warehouse: %s", err)
+		return
+	}
+
+	if corp.Credits != 0 || corp.Warehouse.Capacity <= capacity {
+		t.Errorf("Expected credits to be spent and capacity to increase")
+	}
+}
+
+func TestProposalRules(t *testing.T) {
+	corp := New(1, "Test")
+	corp.AddRule(ProposalRule{Accept: false, BelowQuality: 30})
+	corp.AddRule(ProposalRule{Accept: true, FromCorpID: 2, MinRate: 1, NotProducible: true})
+
+	p := Proposal{FromCorpID: 2, ItemName: "Some Item", Quality: 20, ExchangeRate: 1}
+	rule, found := corp.EvaluateProposal(p)
+	if !found || rule.Accept {
+		t.Errorf("Expected low quality proposal to be rejected")
+		return
+	}
+
+	p.Quality = 50
+	rule, found = corp.EvaluateProposal(p)
+	if !found || !rule.Accept {
+		t.Errorf("Expected proposal to be accepted")
+		return
+	}
+
+	p.Producible = true
+	_, found = corp.EvaluateProposal(p)
+	if found {
+		t.Errorf("Producible item shouldn't match any rule")
+		return
+	}
+
+	p.Producible = false
+	p.ExchangeRate = 0.5
+	_, found = corp.EvaluateProposal(p)
+	if found {
+		t.Errorf("Low exchange rate shouldn't match any rule")
+		return
+	}
+
+	corp.DropRule(rule.ID)
+	if len(corp.ProposalRules) != 1 {
+		t.Errorf("Expected rule to be dropped")
+	}
+}
+
+func TestCommissionRoad(t *testing.T) {
+	tools.InitCycle()
+	corp := New(1, "Test")
+	corp.Credits = 100
+
+	road := node.Path{node.NP(0, 0), node.NP(1, 0), node.NP(2, 0)}
+	work := NewRoadWork(1, 2, false, road, 5, 2)
+	work.Items = map[string]int{"Some Item type": 2}
+
+	now := tools.RoundNow()
+	if _, err := corp.CommissionRoad(work, now); err == nil {
+		t.Errorf("Road shouldn't be built without goods in warehouse")
+		return
+	}
+
+	corp.Warehouse.Add(generateItem())
+	work, err := corp.CommissionRoad(work, now)
+	if err != nil {
+		t.Errorf("Unable to commission road: %s", err)
+		return
+	}
+
+	if corp.Credits != 100-work.Credits || corp.Warehouse.Count() != 3 {
+		t.Errorf("Road should have been paid, got %d credits and %d goods left", corp.Credits, corp.Warehouse.Count())
+		return
+	}
+
+	if len(corp.CompleteRoadWorks(now)) != 0 {
+		t.Errorf("Road work shouldn't be completed yet")
+		return
+	}
+
+	if len(corp.CompleteRoadWorks(work.EndTime)) != 1 || len(corp.RoadWorks) != 0 {
+		t.Errorf("Road work should be completed")
+	}
+}
+
+func TestReputationTier(t *testing.T) {
+	corp := New(0, "Renowned")
+
+	if corp.Tier().Name != "Unknown" || corp.SalePrice(100) != 100 {
+		t.Errorf("Expected no perk without reputation, got %s", corp.Tier().Name)
+		return
+	}
+
+	corp.Reputation = 5000
+	if !corp.Tier().ProposalPriority || corp.SalePrice(100) != 110 {
+		t.Errorf("Expected renowned perks, got %s", corp.Tier().Name)
+		return
+	}
+
+	if _, found := corp.NextTier(); found {
+		t.Errorf("Shouldn't have any tier left to reach")
+		return
+	}
+}
+
+func TestSellCountsMintedCredits(t *testing.T) {
+	corp := New(0, "Seller")
+	corp.Reputation = 5000
+	before := creditsMinted.Value("sell")
+
+	if corp.Sell(100) != 110 || corp.Credits != 110 {
+		t.Errorf("Expected sale to earn 110 credits, got %d", corp.Credits)
+		return
+	}
+
+	if creditsMinted.Value("sell")-before != 110 {
+		t.Errorf("Expected minted credits to be counted, got %f", creditsMinted.Value("sell")-before)
+	}
+}
@@ -0,0 +1,64 @@
+package corporation
+
+import (
+	"upsilon_cities_go/lib/misc/config/gameplay"
+	"upsilon_cities_go/lib/misc/metrics"
+)
+
+var creditsMinted = metrics.NewCounter("upsilon_credits_minted_total", "Credits created out of thin air, by source.", "source")
+
+//ReputationTier perks unlocked once corporation reaches enough reputation. May be overridden by "reputation_tiers" in gameplay.json
+type ReputationTier struct {
+	Name             string
+	Reputation       int     // reputation required.
+	PriceBonus       float64 // ratio added to credits earned selling goods to cities.
+	ProposalPriority bool    // proposals are shown first and favoured by AI corporations.
+}
+
+//ReputationTiers tiers ordered by required reputation.
+func ReputationTiers() []ReputationTier {
+	res := []ReputationTier{
+		{Name: "Unknown", Reputation: 0},
+		{Name: "Known", Reputation: 2000, PriceBonus: 0.05},
+		{Name: "Renowned", Reputation: 5000, PriceBonus: 0.1, ProposalPriority: true},
+	}
+	gameplay.GetObject("reputation_tiers", &res)
+	return res
+}
+
+//Tier highest tier reached by corporation.
+func (corp Corporation) Tier() (res ReputationTier) {
+	res.Name = "Unknown"
+	for _, v := range ReputationTiers() {
+		if corp.Reputation >= v.Reputation && v.Reputation >= res.Reputation {
+			res = v
+		}
+	}
+	return
+}
+
+//NextTier next tier corporation may reach, false when it already reached the highest one.
+func (corp Corporation) NextTier() (ReputationTier, bool) {
+	var res ReputationTier
+	found := false
+	for _, v := range ReputationTiers() {
+		if v.Reputation > corp.Reputation && (!found || v.Reputation < res.Reputation) {
+			res = v
+			found = true
+		}
+	}
+	return res, found
+}
+
+//SalePrice credits earned selling goods worth price, including reputation bonus.
+func (corp Corporation) SalePrice(price float64) int {
+	return int(price * (1 + corp.Tier().PriceBonus))
+}
+
+//Sell credit corporation for goods worth price sold to a city. Returns credits earned.
+func (corp *Corporation) Sell(price float64) int {
+	earned := corp.SalePrice(price)
+	corp.Credits += earned
+	creditsMinted.Add(float64(earned), "sell")
+	return earned
+}
@@ -0,0 +1,100 @@
+package corporation
+
+import (
+	"fmt"
+	"time"
+	"upsilon_cities_go/lib/cities/node"
+	"upsilon_cities_go/lib/cities/storage"
+	"upsilon_cities_go/lib/cities/tools"
+	"upsilon_cities_go/lib/misc/config/gameplay"
+)
+
+//RoadWork road construction (or upgrade) commissioned by a corporation between two cities.
+type RoadWork struct {
+	ID         int
+	FromCityID int
+	ToCityID   int
+	Upgrade    bool
+	Road       node.Path
+	Credits    int
+	Items      map[string]int // quantity consumed by item type.
+	StartTime  time.Time
+	EndTime    time.Time
+}
+
+//String version of a road work
+func (rw RoadWork) String() string {
+	if rw.Upgrade {
+		return fmt.Sprintf("Road upgrade %d: city %d <-> city %d (%d nodes)", rw.ID, rw.FromCityID, rw.ToCityID, len(rw.Road))
+	}
+	return fmt.Sprintf("Road construction %d: city %d <-> city %d (%d nodes)", rw.ID, rw.FromCityID, rw.ToCityID, len(rw.Road))
+}
+
+//NewRoadWork quote a road work along road, cost being the construction cost of worked nodes.
+func NewRoadWork(fromCityID, toCityID int, upgrade bool, road node.Path, cost int, nodes int) (res RoadWork) {
+	res.FromCityID = fromCityID
+	res.ToCityID = toCityID
+	res.Upgrade = upgrade
+	res.Road = road
+	res.Credits = cost * gameplay.GetInt("road_credits_by_cost", 10)
+
+	byNode := map[string]int{"Bois": 1}
+	gameplay.GetObject("road_items_by_node", &byNode)
+	res.Items = make(map[string]int)
+	for k, v := range byNode {
+		if v*nodes > 0 {
+			res.Items[k] = v * nodes
+		}
+	}
+	return
+}
+
+//Delay cycles needed to complete road work.
+func (rw RoadWork) Delay() int {
+	return tools.Max(len(rw.Road)*gameplay.GetInt("road_cycles_by_node", 1), 1)
+}
+
+//CommissionRoad pay road work with credits and warehouse goods, and start it.
+func (corp *Corporation) CommissionRoad(work RoadWork, now time.Time) (RoadWork, error) {
+	if corp.Credits < work.Credits {
+		return work, fmt.Errorf("not enough credits to build road, need %d", work.Credits)
+	}
+	for k, v := range work.Items {
+		if corp.Warehouse.CountAll(storage.ByType(k)) < v {
+			return work, fmt.Errorf("not enough %s in warehouse to build road, need %d", k, v)
+		}
+	}
+
+	for k, v := range work.Items {
+		for _, it := range corp.Warehouse.All(storage.ByType(k)) {
+			if v <= 0 {
+				break
+			}
+			nb := tools.Min(v, it.Quantity)
+			corp.Warehouse.Remove(it.ID, nb)
+			v -= nb
+		}
+	}
+	corp.Credits -= work.Credits
+
+	corp.CurrentRoadWorkID++
+	work.ID = corp.CurrentRoadWorkID
+	work.StartTime = tools.RoundTime(now)
+	work.EndTime = tools.AddCycles(work.StartTime, work.Delay())
+	corp.RoadWorks = append(corp.RoadWorks, work)
+	return work, nil
+}
+
+//CompleteRoadWorks remove finished road works and return them, so that they may be applied to the map.
+func (corp *Corporation) CompleteRoadWorks(now time.Time) (done []RoadWork) {
+	pending := make([]RoadWork, 0, len(corp.RoadWorks))
+	for _, v := range corp.RoadWorks {
+		if v.EndTime.After(now) {
+			pending = append(pending, v)
+			continue
+		}
+		done = append(done, v)
+	}
+	corp.RoadWorks = pending
+	return
+}
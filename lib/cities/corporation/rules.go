@@ -0,0 +1,120 @@
+package corporation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+//ProposalRule standing rule answering incoming caravan proposals while corporation owner is away.
+//Every non zero condition must match for the rule to apply; first matching rule wins.
+type ProposalRule struct {
+	ID            int
+	Accept        bool    // false means reject.
+	FromCorpID    int     // 0 matches any corporation.
+	ItemName      string  // empty matches any item.
+	MinRate       float64 // minimum goods received per good given, 0 ignores exchange rate.
+	MinQuality    int     // received quality must be at least this, 0 ignores.
+	BelowQuality  int     // received quality must be under this, 0 ignores.
+	NotProducible bool    // received item mustn't be producible by targeted city.
+}
+
+//Proposal facts about an incoming proposal as seen by the targeted corporation.
+type Proposal struct {
+	FromCorpID   int
+	ItemName     string
+	Quality      int     // minimum quality of goods received.
+	ExchangeRate float64 // goods received per good given.
+	Producible   bool    // received item can already be produced by targeted city.
+}
+
+//String version of a rule
+func (r ProposalRule) String() string {
+	conds := make([]string, 0)
+	if r.FromCorpID != 0 {
+		conds = append(conds, fmt.Sprintf("from corporation %d", r.FromCorpID))
+	}
+	if r.ItemName != "" {
+		conds = append(conds, fmt.Sprintf("item is %s", r.ItemName))
+	}
+	if r.MinRate > 0 {
+		conds = append(conds, fmt.Sprintf("exchange rate >= %.2f", r.MinRate))
+	}
+	if r.MinQuality > 0 {
+		conds = append(conds, fmt.Sprintf("quality >= %d", r.MinQuality))
+	}
+	if r.BelowQuality > 0 {
+		conds = append(conds, fmt.Sprintf("quality < %d", r.BelowQuality))
+	}
+	if r.NotProducible {
+		conds = append(conds, "item isn't producible here")
+	}
+
+	action := "reject"
+	if r.Accept {
+		action = "accept"
+	}
+	if len(conds) == 0 {
+		return fmt.Sprintf("Rule %d: %s everything", r.ID, action)
+	}
+	return fmt.Sprintf("Rule %d: %s if %s", r.ID, action, strings.Join(conds, " and "))
+}
+
+//Match tell whether rule applies to proposal.
+func (r ProposalRule) Match(p Proposal) bool {
+	if r.FromCorpID != 0 && r.FromCorpID != p.FromCorpID {
+		return false
+	}
+	if r.ItemName != "" && r.ItemName != p.ItemName {
+		return false
+	}
+	if r.MinRate > 0 && p.ExchangeRate < r.MinRate {
+		return false
+	}
+	if r.MinQuality > 0 && p.Quality < r.MinQuality {
+		return false
+	}
+	if r.BelowQuality > 0 && p.Quality >= r.BelowQuality {
+		return false
+	}
+	if r.NotProducible && p.Producible {
+		return false
+	}
+	return true
+}
+
+//EvaluateProposal seek first rule matching proposal.
+func (corp Corporation) EvaluateProposal(p Proposal) (ProposalRule, bool) {
+	for _, v := range corp.ProposalRules {
+		if v.Match(p) {
+			return v, true
+		}
+	}
+	return ProposalRule{}, false
+}
+
+//AddRule append a rule to corporation standing rules.
+func (corp *Corporation) AddRule(r ProposalRule) (ProposalRule, error) {
+	if r.MinQuality > 0 && r.BelowQuality > 0 && r.MinQuality >= r.BelowQuality {
+		return r, errors.New("quality range of rule can't match anything")
+	}
+	if r.MinRate < 0 {
+		return r, errors.New("exchange rate can't be negative")
+	}
+
+	corp.CurrentRuleID++
+	r.ID = corp.CurrentRuleID
+	corp.ProposalRules = append(corp.ProposalRules, r)
+	return r, nil
+}
+
+//DropRule remove a rule from corporation standing rules.
+func (corp *Corporation) DropRule(id int) error {
+	for idx, v := range corp.ProposalRules {
+		if v.ID == id {
+			corp.ProposalRules = append(corp.ProposalRules[:idx], corp.ProposalRules[idx+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown rule %d", id)
+}
@@ -0,0 +1,151 @@
+package corporation
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"upsilon_cities_go/lib/cities/item"
+	"upsilon_cities_go/lib/cities/storage"
+	"upsilon_cities_go/lib/cities/tools"
+	"upsilon_cities_go/lib/misc/config/gameplay"
+	"upsilon_cities_go/lib/misc/logger"
+)
+
+//Transfer goods traveling between an owned city and the corporation warehouse.
+type Transfer struct {
+	ID          int
+	CityID      int
+	CityName    string
+	ToWarehouse bool // false means goods are sent back to the city.
+	Items       []item.Item
+	Reservation int64 // space reserved in destination storage.
+	StartTime   time.Time
+	EndTime     time.Time
+}
+
+//String version of a transfer
+func (t Transfer) String() string {
+	qty := 0
+	for _, v := range t.Items {
+		qty += v.Quantity
+	}
+	if t.ToWarehouse {
+		return fmt.Sprintf("Transfer %d: %d goods from %s to warehouse", t.ID, qty, t.CityName)
+	}
+	return fmt.Sprintf("Transfer %d: %d goods from warehouse to %s", t.ID, qty, t.CityName)
+}
+
+//NewWarehouse create an empty warehouse storage.
+func NewWarehouse() (res *storage.Storage) {
+	res = storage.New()
+	res.Capacity = gameplay.GetInt("warehouse_init_capacity", 50)
+	return
+}
+
+//WarehouseUpgradeCost tell how much it cost to upgrade warehouse capacity.
+func (corp Corporation) WarehouseUpgradeCost() int {
+	step := tools.Max(gameplay.GetInt("warehouse_upgrade_capacity", 50), 1)
+	return gameplay.GetInt("warehouse_upgrade_cost", 100) * tools.Max(corp.Warehouse.Capacity/step, 1)
+}
+
+//UpgradeWarehouse increase warehouse capacity paid with corporation credits.
+func (corp *Corporation) UpgradeWarehouse() error {
+	cost := corp.WarehouseUpgradeCost()
+	if corp.Credits < cost {
+		return fmt.Errorf("not enough credits to upgrade warehouse, need %d", cost)
+	}
+	corp.Credits -= cost
+	corp.Warehouse.SetSize(corp.Warehouse.Capacity + gameplay.GetInt("warehouse_upgrade_capacity", 50))
+	return nil
+}
+
+//TransferDelay tell how many cycles goods take to travel provided road distance.
+func TransferDelay(distance int) int {
+	return tools.Max(distance*gameplay.GetInt("warehouse_transfer_speed", 1), 1)
+}
+
+//StartTransfer register goods on their way. Destination space must already be reserved.
+func (corp *Corporation) StartTransfer(cityID int, cityName string, toWarehouse bool, items []item.Item, reservation int64, distance int, now time.Time) (res Transfer, err error) {
+	if !tools.InList(cityID, corp.CitiesID) {
+		return res, errors.New("unable to transfer goods with a city not owned by corporation")
+	}
+
+	corp.CurrentTransferID++
+	res.ID = corp.CurrentTransferID
+	res.CityID = cityID
+	res.CityName = cityName
+	res.ToWarehouse = toWarehouse
+	res.Items = items
+	res.Reservation = reservation
+	res.StartTime = tools.RoundTime(now)
+	res.EndTime = tools.AddCycles(res.StartTime, TransferDelay(distance))
+
+	corp.Transfers = append(corp.Transfers, res)
+	return res, nil
+}
+
+//Retry transfer of goods that couldn't be stored at destination, they'll be attempted again next cycle.
+func (t Transfer) Retry(left []item.Item, now time.Time) Transfer {
+	t.Items = left
+	t.Reservation = 0 // reservations start at 1, goods will use free space.
+	t.StartTime = tools.RoundTime(now)
+	t.EndTime = tools.AddCycles(t.StartTime, 1)
+	return t
+}
+
+//Delay add a transfer that has to wait before delivery, see Retry.
+func (corp *Corporation) Delay(t Transfer) {
+	corp.Transfers = append(corp.Transfers, t)
+}
+
+//CompleteTransfers finishes transfers that reached their destination by now.
+//Goods heading to warehouse are stored right away, goods heading to cities are returned to be stored by caller.
+func (corp *Corporation) CompleteTransfers(now time.Time) (toCities []Transfer) {
+	pending := make([]Transfer, 0, len(corp.Transfers))
+
+	for _, v := range corp.Transfers {
+		if v.EndTime.After(now) {
+			pending = append(pending, v)
+			continue
+		}
+
+		// perishable goods decay while traveling.
+		aged := make([]item.Item, 0, len(v.Items))
+		for _, it := range v.Items {
+			nit, _ := it.Age(tools.CyclesBetween(v.StartTime, v.EndTime))
+			if nit.Quantity > 0 {
+				aged = append(aged, nit)
+			}
+		}
+		v.Items = aged
+
+		if v.ToWarehouse {
+			if left := corp.Warehouse.ClaimOrAdd(v.Reservation, v.Items); len(left) > 0 {
+				logger.Warnf("Corporation", "%s: warehouse is full, %d stacks wait for space", v.String(), len(left))
+				pending = append(pending, v.Retry(left, now))
+			}
+		} else {
+			toCities = append(toCities, v)
+		}
+	}
+
+	corp.Transfers = pending
+	return
+}
+
+//DecayWarehouse ages perishable goods stored in warehouse up to now. Returns the number of items that spoiled.
+func (corp *Corporation) DecayWarehouse(now time.Time) (spoiled int) {
+	now = tools.RoundTime(now)
+	if corp.WarehouseLastUpdate.IsZero() {
+		corp.WarehouseLastUpdate = now
+		return 0
+	}
+
+	cycles := tools.CyclesBetween(corp.WarehouseLastUpdate, now)
+	if cycles <= 0 {
+		return 0
+	}
+
+	corp.WarehouseLastUpdate = tools.AddCycles(corp.WarehouseLastUpdate, cycles)
+	return corp.Warehouse.Decay(cycles)
+}
@@ -0,0 +1,402 @@
+//Package corporation_ai drives corporations that aren't owned by any user.
+package corporation_ai
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"upsilon_cities_go/lib/cities/caravan"
+	"upsilon_cities_go/lib/cities/caravan_manager"
+	"upsilon_cities_go/lib/cities/city"
+	"upsilon_cities_go/lib/cities/city/producer"
+	"upsilon_cities_go/lib/cities/city_manager"
+	"upsilon_cities_go/lib/cities/corporation"
+	"upsilon_cities_go/lib/cities/corporation_manager"
+	city_evolution "upsilon_cities_go/lib/cities/evolution/city"
+	"upsilon_cities_go/lib/cities/item"
+	"upsilon_cities_go/lib/cities/tools"
+	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/config/gameplay"
+	"upsilon_cities_go/lib/misc/logger"
+)
+
+//Profile describe how an AI corporation behaves. Profiles may be overridden by "ai_profiles" in gameplay.json
+type Profile struct {
+	SellThreshold    float64 // storage usage ratio above which surplus gets sold.
+	SellRatio        float64 // ratio of surplus sold each tick.
+	ProposalChance   int     // percent chance to propose a caravan each tick.
+	MaxCaravans      int     // max active caravans at once.
+	AcceptChance     int     // percent chance to accept an incoming proposal.
+	UpgradeProducers bool
+	LevelCities      bool
+}
+
+var profiles map[string]Profile
+
+//Init loads AI profiles, defaults are overridden by gameplay configuration.
+func Init() {
+	profiles = map[string]Profile{
+		"easy": {
+			SellThreshold:    0.9,
+			SellRatio:        0.2,
+			ProposalChance:   5,
+			MaxCaravans:      1,
+			AcceptChance:     50,
+			UpgradeProducers: false,
+			LevelCities:      false,
+		},
+		"normal": {
+			SellThreshold:    0.7,
+			SellRatio:        0.5,
+			ProposalChance:   15,
+			MaxCaravans:      2,
+			AcceptChance:     75,
+			UpgradeProducers: true,
+			LevelCities:      false,
+		},
+		"hard": {
+			SellThreshold:    0.5,
+			SellRatio:        0.8,
+			ProposalChance:   30,
+			MaxCaravans:      4,
+			AcceptChance:     90,
+			UpgradeProducers: true,
+			LevelCities:      true,
+		},
+	}
+
+	custom := make(map[string]Profile)
+	if gameplay.GetObject("ai_profiles", &custom) {
+		for k, v := range custom {
+			profiles[k] = v
+		}
+	}
+}
+
+//CurrentProfile profile used by AI corporations, see "ai_profile" in gameplay.json
+func CurrentProfile() Profile {
+	if profiles == nil {
+		Init()
+	}
+	name := gameplay.Get("ai_profile", "normal")
+	if p, found := profiles[name]; found {
+		return p
+	}
+	logger.Infof("AI", "Unknown profile %s, fallback on normal", name)
+	return profiles["normal"]
+}
+
+//Play let every unowned corporation of the map take its turn.
+func Play(mapID int) {
+	if !gameplay.GetBool("ai_enabled", true) {
+		return
+	}
+
+	profile := CurrentProfile()
+	dbh := db.New()
+	defer dbh.Close()
+
+	for _, cm := range corporation_manager.GetCorporationHandlersByMapID(mapID) {
+		if cm.Get().OwnerID != 0 || cm.Get().Eliminated {
+			continue
+		}
+
+		answerProposals(dbh, cm, profile)
+
+		for _, v := range cm.Get().CitiesID {
+			ctm, err := city_manager.GetCityHandler(v)
+			if err != nil {
+				continue
+			}
+			sellSurplus(dbh, cm, ctm, profile)
+			if profile.UpgradeProducers {
+				upgradeProducers(dbh, ctm)
+			}
+			if profile.LevelCities {
+				levelCity(dbh, cm, ctm)
+			}
+		}
+
+		if rand.Intn(100) < profile.ProposalChance && activeCaravans(cm) < profile.MaxCaravans {
+			proposeCaravan(dbh, cm)
+		}
+
+		cm.Call(func(corp *corporation.Corporation) {
+			corp.Update(dbh)
+		})
+	}
+}
+
+// answerProposals accept or refuse proposals waiting on corporation.
+func answerProposals(dbh *db.Handler, cm *corporation_manager.Handler, profile Profile) {
+	corpID := cm.ID()
+	for _, v := range cm.Get().CaravanID {
+		crm, err := caravan_manager.GetCaravanHandler(v)
+		if err != nil {
+			continue
+		}
+
+		// proposals of renowned corporations are favoured.
+		chance := profile.AcceptChance
+		other := crm.Get().CorpOriginID
+		if other == corpID {
+			other = crm.Get().CorpTargetID
+		}
+		if om, err := corporation_manager.GetCorporationHandler(other); err == nil && other != corpID && om.Get().Tier().ProposalPriority {
+			chance += gameplay.GetInt("reputation_priority_accept_chance", 20)
+		}
+
+		crm.Call(func(crv *caravan.Caravan) {
+			if !waitingOn(crv, corpID) {
+				return
+			}
+
+			if rand.Intn(100) < chance {
+				if err := crv.Accept(dbh, corpID); err != nil {
+					logger.Errorf("AI", "Corporation %d failed to accept %s: %s", corpID, crv.String(), err)
+				}
+				return
+			}
+			if err := crv.Refuse(dbh, corpID); err != nil {
+				logger.Errorf("AI", "Corporation %d failed to refuse %s: %s", corpID, crv.String(), err)
+			}
+		})
+	}
+}
+
+// waitingOn tell whether caravan waits for an answer of corporation.
+func waitingOn(crv *caravan.Caravan, corpID int) bool {
+	return (crv.State == caravan.CRVProposal && crv.CorpTargetID == corpID) || (crv.State == caravan.CRVCounterProposal && crv.CorpOriginID == corpID)
+}
+
+// sell remove surplus from city storage, returns value of what got sold. Priced as a player sale would be.
+func sell(cty *city.City, profile Profile) (value float64) {
+	limit := int(math.Floor(float64(cty.Storage.Capacity) * profile.SellThreshold))
+	surplus := int(math.Ceil(float64(cty.Storage.Count()-limit) * profile.SellRatio))
+
+	for _, it := range cty.Storage.All(func(item.Item) bool { return true }) {
+		if surplus <= 0 {
+			break
+		}
+		qty := tools.Min(surplus, it.Quantity)
+		cty.Storage.Remove(it.ID, qty)
+		surplus -= qty
+		value += cty.SaleValue(it, qty)
+	}
+	return
+}
+
+// sellSurplus sell part of storage content when city storage gets too crowded.
+func sellSurplus(dbh *db.Handler, cm *corporation_manager.Handler, ctm *city_manager.Handler, profile Profile) {
+	value := 0.0
+	ctm.Call(func(cty *city.City) {
+		value = sell(cty, profile)
+		if value > 0 {
+			cty.Update(dbh)
+		}
+	})
+
+	if value > 0 {
+		cm.Call(func(corp *corporation.Corporation) {
+			earned := corp.Sell(value)
+			logger.Infof("AI", "Corporation %d sold surplus for %d credits", corp.ID, earned)
+		})
+	}
+}
+
+// upgradeProducers spend every upgrade point available.
+func upgradeProducers(dbh *db.Handler, ctm *city_manager.Handler) {
+	ctm.Call(func(cty *city.City) {
+		changed := false
+		for _, v := range cty.RessourceProducers {
+			changed = v.UpgradeAll() || changed
+		}
+		for _, v := range cty.ProductFactories {
+			changed = v.UpgradeAll() || changed
+		}
+		if changed {
+			cty.Update(dbh)
+		}
+	})
+}
+
+// pay debit credits when corporation can afford them.
+func pay(corp *corporation.Corporation, credits int) bool {
+	if corp.Credits < credits {
+		return false
+	}
+	corp.Credits -= credits
+	return true
+}
+
+// levelUp level up city when corporation is famous enough there.
+func levelUp(cty *city.City, corpID int, fame int, upgrade int) error {
+	if cty.Fame[corpID] < fame {
+		return fmt.Errorf("not famous enough, requires %d fame", fame)
+	}
+	return city_evolution.LevelUp(&cty.State, upgrade)
+}
+
+// levelCity level up city when corporation can afford it. Credits are locked before hand, refunded when level up fails.
+func levelCity(dbh *db.Handler, cm *corporation_manager.Handler, ctm *city_manager.Handler) {
+	credits, fame, _ := city_evolution.NextLevelRequirements(ctm.Get().State)
+	corpID := cm.ID()
+
+	paid := false
+	cm.Call(func(corp *corporation.Corporation) {
+		paid = pay(corp, credits)
+	})
+	if !paid {
+		return
+	}
+
+	var err error
+	ctm.Call(func(cty *city.City) {
+		err = levelUp(cty, corpID, fame, rand.Intn(city_evolution.CSProductionRate+1))
+		if err == nil {
+			cty.Update(dbh)
+		}
+	})
+
+	if err != nil {
+		cm.Call(func(corp *corporation.Corporation) {
+			corp.Credits += credits
+		})
+		logger.Debugf("AI", "Corporation %d can't level up city: %s", corpID, err)
+	}
+}
+
+// activeCaravans count caravans still running for corporation.
+func activeCaravans(cm *corporation_manager.Handler) (res int) {
+	for _, v := range cm.Get().CaravanID {
+		crm, err := caravan_manager.GetCaravanHandler(v)
+		if err != nil {
+			continue
+		}
+		crv := crm.Get()
+		if crv.IsActive() || crv.State == caravan.CRVProposal || crv.State == caravan.CRVCounterProposal {
+			res++
+		}
+	}
+	return
+}
+
+// randomProduct pick a random product city is able to produce.
+func randomProduct(cty city.City) (res producer.Product, found bool) {
+	products := make([]producer.Product, 0)
+	for _, v := range cty.RessourceProducers {
+		for _, w := range v.Products {
+			products = append(products, w)
+		}
+	}
+	for _, v := range cty.ProductFactories {
+		for _, w := range v.Products {
+			products = append(products, w)
+		}
+	}
+	if len(products) == 0 {
+		return res, false
+	}
+	return products[rand.Intn(len(products))], true
+}
+
+// proposal caravan exchanging random products of origin and target cities, proposed by corporation.
+func proposal(origin city.City, target city.City, corpID int) (crv *caravan.Caravan, found bool) {
+	exported, found := randomProduct(origin)
+	if !found {
+		return nil, false
+	}
+	imported, found := randomProduct(target)
+	if !found {
+		return nil, false
+	}
+
+	crv = caravan.New()
+	crv.MapID = origin.MapID
+	crv.CityOriginID = origin.ID
+	crv.CorpOriginID = corpID
+	crv.CityTargetID = target.ID
+	crv.CorpTargetID = target.CorporationID
+
+	crv.Exported.ItemType = exported.ItemTypes
+	crv.Exported.ItemName = exported.ItemName
+	crv.Exported.Quantity = tools.IntRange{Min: 1, Max: tools.Max(exported.GetQuantity().Max, 1) * 5}
+	crv.Exported.Quality = tools.IntRange{Min: 0, Max: exported.GetQuality().Max}
+
+	crv.Imported.ItemType = imported.ItemTypes
+	crv.Imported.ItemName = imported.ItemName
+	crv.Imported.Quantity = tools.IntRange{Min: 1, Max: tools.Max(imported.GetQuantity().Max, 1) * 5}
+	crv.Imported.Quality = tools.IntRange{Min: 0, Max: imported.GetQuality().Max}
+	return crv, true
+}
+
+// proposeCaravan propose a caravan between one of corporation cities and a neighbour owned by another corporation.
+func proposeCaravan(dbh *db.Handler, cm *corporation_manager.Handler) {
+	corpID := cm.ID()
+	citiesID := cm.Get().CitiesID
+	if len(citiesID) == 0 {
+		return
+	}
+
+	origin, err := city_manager.GetCityHandler(citiesID[rand.Intn(len(citiesID))])
+	if err != nil {
+		return
+	}
+
+	candidates := make([]*city_manager.Handler, 0)
+	for _, v := range origin.Get().NeighboursID {
+		ctm, err := city_manager.GetCityHandler(v)
+		if err != nil {
+			continue
+		}
+		if ctm.Get().CorporationID != 0 && ctm.Get().CorporationID != corpID {
+			candidates = append(candidates, ctm)
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+	target := candidates[rand.Intn(len(candidates))]
+
+	crv, found := proposal(origin.Get(), target.Get(), corpID)
+	if !found {
+		return
+	}
+
+	err = crv.Insert(dbh)
+	if err != nil {
+		logger.Errorf("AI", "Corporation %d failed to propose caravan: %s", corpID, err)
+		return
+	}
+	crv.Reload(dbh)
+	caravan_manager.GenerateHandler(crv)
+
+	origin.Call(func(cty *city.City) {
+		cty.CaravanID = append(cty.CaravanID, crv.ID)
+	})
+	target.Call(func(cty *city.City) {
+		cty.CaravanID = append(cty.CaravanID, crv.ID)
+	})
+	cm.Call(func(corp *corporation.Corporation) {
+		corp.CaravanID = append(corp.CaravanID, crv.ID)
+	})
+
+	targetCorp, err := corporation_manager.GetCorporationHandler(crv.CorpTargetID)
+	if err != nil {
+		return
+	}
+	targetCorp.Call(func(corp *corporation.Corporation) {
+		corp.CaravanID = append(corp.CaravanID, crv.ID)
+	})
+
+	// accepting escrows penalties, can't be done while holding target corporation.
+	targetCity := target.Get()
+	tcorp := targetCorp.Get()
+	if crm, err := caravan_manager.GetCaravanHandler(crv.ID); err == nil {
+		crm.Call(func(crv *caravan.Caravan) {
+			crv.ApplyProposalRules(dbh, &tcorp, &targetCity)
+		})
+	}
+
+	logger.Infof("AI", "Corporation %d proposed %s", corpID, crv.String())
+}
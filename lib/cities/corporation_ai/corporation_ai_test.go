@@ -0,0 +1,147 @@
+package corporation_ai
+
+import (
+	"testing"
+	"upsilon_cities_go/lib/cities/caravan"
+	"upsilon_cities_go/lib/cities/city"
+	"upsilon_cities_go/lib/cities/city/producer"
+	"upsilon_cities_go/lib/cities/corporation"
+	city_evolution "upsilon_cities_go/lib/cities/evolution/city"
+	"upsilon_cities_go/lib/cities/item"
+	"upsilon_cities_go/lib/cities/tools"
+)
+
+func TestDefaultProfiles(t *testing.T) {
+	Init()
+
+	for _, v := range []string{"easy", "normal", "hard"} {
+		if _, found := profiles[v]; !found {
+			t.Errorf("Expected default profile %s to exist", v)
+			return
+		}
+	}
+
+	if CurrentProfile() != profiles["normal"] {
+		t.Errorf("Expected normal profile to be used by default")
+	}
+}
+
+func generateItem(name string, qty int) (res item.Item) {
+	res.Name = name
+	res.Type = []string{"Some Item type"}
+	res.Quality = 100
+	res.Quantity = qty
+	res.BasePrice = 10
+	return
+}
+
+func producing(cty *city.City, name string) {
+	var prod producer.Producer
+	prod.Products = map[int]producer.Product{0: {ItemName: name, ItemTypes: []string{"Some Item type"}, Quality: tools.IntRange{Min: 10, Max: 20}, Quantity: tools.IntRange{Min: 1, Max: 2}}}
+	cty.RessourceProducers[len(cty.RessourceProducers)] = &prod
+}
+
+func TestSellSurplus(t *testing.T) {
+	cty := city.New()
+	producing(cty, "Produced")
+	cty.Storage.Add(generateItem("Produced", 5))
+	cty.Storage.Add(generateItem("Imported", 5))
+
+	value := sell(cty, Profile{SellThreshold: 0.5, SellRatio: 1})
+	if cty.Storage.Count() != 5 {
+		t.Errorf("Expected storage to be back to threshold (expected: 5, got %d)", cty.Storage.Count())
+		return
+	}
+
+	// each unit is worth 10, half price for goods city produces.
+	if value != 25 && value != 50 {
+		t.Errorf("Expected sale to be priced as a player sale, got %f", value)
+		return
+	}
+
+	if sell(cty, Profile{SellThreshold: 0.5, SellRatio: 1}) != 0 {
+		t.Errorf("Expected nothing to be sold below threshold")
+	}
+}
+
+func TestProposal(t *testing.T) {
+	tools.InitCycle()
+	origin := city.New()
+	origin.ID = 1
+	origin.MapID = 3
+	target := city.New()
+	target.ID = 2
+	target.CorporationID = 5
+
+	if _, found := proposal(*origin, *target, 4); found {
+		t.Errorf("Expected no proposal when cities produce nothing")
+		return
+	}
+
+	producing(origin, "Exported")
+	producing(target, "Imported")
+	crv, found := proposal(*origin, *target, 4)
+	if !found {
+		t.Errorf("Expected a proposal to be built")
+		return
+	}
+
+	if crv.MapID != 3 || crv.CityOriginID != 1 || crv.CorpOriginID != 4 || crv.CityTargetID != 2 || crv.CorpTargetID != 5 {
+		t.Errorf("Expected caravan to link origin and target, got %s", crv.String())
+		return
+	}
+	if crv.Exported.ItemName != "Exported" || crv.Imported.ItemName != "Imported" || crv.Exported.Quantity.Max != 10 {
+		t.Errorf("Expected caravan to exchange products of both cities")
+	}
+}
+
+func TestWaitingOn(t *testing.T) {
+	tools.InitCycle()
+	crv := caravan.New()
+	crv.CorpOriginID = 1
+	crv.CorpTargetID = 2
+
+	if !waitingOn(crv, 2) || waitingOn(crv, 1) {
+		t.Errorf("Expected proposal to wait on target only")
+		return
+	}
+
+	crv.State = caravan.CRVCounterProposal
+	if !waitingOn(crv, 1) || waitingOn(crv, 2) {
+		t.Errorf("Expected counter proposal to wait on origin only")
+		return
+	}
+
+	crv.State = caravan.CRVWaitingOriginLoad
+	if waitingOn(crv, 1) || waitingOn(crv, 2) {
+		t.Errorf("Expected accepted caravan to wait on no one")
+	}
+}
+
+func TestLevelCity(t *testing.T) {
+	city_evolution.CSInit()
+	credits, fame, _ := city_evolution.NextLevelRequirements(city.State{})
+
+	corp := corporation.New(1, "Test")
+	corp.Credits = credits - 1
+	if pay(corp, credits) || corp.Credits != credits-1 {
+		t.Errorf("Expected poor corporation not to be debited")
+		return
+	}
+	corp.Credits = credits
+	if !pay(corp, credits) || corp.Credits != 0 {
+		t.Errorf("Expected corporation to be debited")
+		return
+	}
+
+	cty := city.New()
+	if levelUp(cty, 1, fame, city_evolution.CSStorage) == nil || cty.State.CurrentLevel != 0 {
+		t.Errorf("Expected city not to level up without fame")
+		return
+	}
+
+	cty.Fame[1] = fame
+	if err := levelUp(cty, 1, fame, city_evolution.CSStorage); err != nil || cty.State.CurrentLevel != 1 {
+		t.Errorf("Expected city to level up: %v", err)
+	}
+}
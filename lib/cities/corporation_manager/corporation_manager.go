@@ -2,8 +2,11 @@ package corporation_manager
 
 import (
 	"errors"
+	"time"
 	"upsilon_cities_go/lib/cities/corporation"
+	"upsilon_cities_go/lib/db"
 	"upsilon_cities_go/lib/misc/actor"
+	"upsilon_cities_go/lib/misc/logger"
 )
 
 //Handler own the carava, they're to be called upon to provide access to the carava
@@ -77,6 +80,17 @@ func GetCorporationHandlerByCityID(cityID int) (res *Handler, err error) {
 	return nil, errors.New("unknown city, no corp")
 }
 
+//GetCorporationHandlersByMapID Fetches all corporations of a map from memory
+func GetCorporationHandlersByMapID(mapID int) (res []*Handler) {
+	for _, v := range manager.ByMapID[mapID] {
+		cm, err := GetCorporationHandler(v)
+		if err == nil {
+			res = append(res, cm)
+		}
+	}
+	return
+}
+
 //DropCorporationHandler from memory
 func DropCorporationHandler(id int) error {
 	cm, found := manager.handlers[id]
@@ -92,6 +106,44 @@ func DropCorporationHandler(id int) error {
 	return nil
 }
 
+//Persist drain mailbox of every loaded corporation and save it, returns how many failed to be saved.
+func Persist(dbh *db.Handler) (failed int) {
+	var handlers []*Handler
+	manager.Call(func() {
+		for _, v := range manager.handlers {
+			handlers = append(handlers, v)
+		}
+	})
+
+	for _, v := range handlers {
+		v.Call(func(corp *corporation.Corporation) {
+			if err := corp.Update(dbh); err != nil {
+				logger.Errorf("CorpMgr", "Failed to persist corporation %d: %s", corp.ID, err)
+				failed++
+			}
+		})
+	}
+	logger.Infof("CorpMgr", "Persisted %d corporations, %d failed", len(handlers)-failed, failed)
+	return
+}
+
+//Stats how many corporations are loaded and how many functions wait in mailboxes, manager's included.
+func Stats() (loaded int, queued int) {
+	queued = manager.Queued()
+	manager.Call(func() {
+		loaded = len(manager.handlers)
+		for _, v := range manager.handlers {
+			queued += v.Queued()
+		}
+	})
+	return
+}
+
+//Ping tell whether manager is responsive.
+func Ping(timeout time.Duration) bool {
+	return manager.Ping(timeout)
+}
+
 //Cast send and forget. Will provide access to protected grid.
 // If you want a reply, dont forget to provide your function a chan
 // If you do so, DONT FORGET TO call defer close(<your chan>)
@@ -67,7 +67,7 @@ func Init(state *city.State) {
 	state.MaxFactories = gameplay.GetInt("init_city_max_factories", 3)
 	state.MaxResellers = gameplay.GetInt("init_city_max_resellers", 3)
 	state.MaxStorageSpace = gameplay.GetInt("init_city_storage_space", 3)
-	state.ProductionRate = gameplay.GetFloat("init_city_production_rate", 3)
+	state.ProductionRate = float32(gameplay.GetFloat("init_city_production_rate", 3))
 }
 
 //NextLevelRequirements specify what's required to perform a level up.
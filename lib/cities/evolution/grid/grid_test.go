@@ -1 +1,41 @@
 package grid_evolution_test
+
+import (
+	"testing"
+	"time"
+	"upsilon_cities_go/lib/cities/corporation"
+	grid_evolution "upsilon_cities_go/lib/cities/evolution/grid"
+)
+
+func TestVictoryConditions(t *testing.T) {
+	now := time.Now().UTC()
+	corps := []corporation.Corporation{
+		{ID: 1, Name: "Poor", Credits: 10, CitiesID: []int{1, 2, 3}},
+		{ID: 2, Name: "Rich", Credits: 1000, CitiesID: []int{4, 5}},
+	}
+
+	vc := grid_evolution.VictoryConditions{Credits: 2000, Cities: 4}
+	if _, _, ended := vc.Check(1, corps, now, now); ended {
+		t.Errorf("Map shouldn't end yet")
+		return
+	}
+
+	vc.Credits = 500
+	if winner, _, ended := vc.Check(1, corps, now, now); !ended || winner != 2 {
+		t.Errorf("Expected rich corporation to win, got %d", winner)
+		return
+	}
+
+	vc = grid_evolution.VictoryConditions{Cycles: 10}
+	if winner, _, ended := vc.Check(1, corps, now.Add(-time.Hour*24*365), now); !ended || winner != 1 {
+		t.Errorf("Expected corporation with most cities to win on time limit, got %d", winner)
+		return
+	}
+
+	corps[0].Eliminated = true
+	vc = grid_evolution.VictoryConditions{}
+	if winner, _, ended := vc.Check(1, corps, now, now); !ended || winner != 2 {
+		t.Errorf("Expected last corporation standing to win, got %d", winner)
+		return
+	}
+}
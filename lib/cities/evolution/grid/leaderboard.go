@@ -0,0 +1,75 @@
+package grid_evolution
+
+import (
+	"time"
+	"upsilon_cities_go/lib/cities/city"
+	"upsilon_cities_go/lib/cities/city_manager"
+	"upsilon_cities_go/lib/cities/corporation_manager"
+	"upsilon_cities_go/lib/cities/map/grid"
+	"upsilon_cities_go/lib/cities/tools"
+	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/config/gameplay"
+	"upsilon_cities_go/lib/misc/logger"
+)
+
+//Cities copies of all cities of the map.
+func Cities(grd *grid.Grid) []*city.City {
+	res := make([]*city.City, 0, len(grd.Cities))
+	for k := range grd.Cities {
+		cm, err := city_manager.GetCityHandler(k)
+		if err != nil {
+			continue
+		}
+		cty := cm.Get()
+		res = append(res, &cty)
+	}
+	return res
+}
+
+//ComputeLeaderboard rank corporations of the map based on their current state.
+func ComputeLeaderboard(grd *grid.Grid, cities []*city.City, now time.Time) (res grid.Leaderboard) {
+	res.MapID = grd.ID
+	res.TakenAt = now
+	res.Entries = make([]grid.LeaderboardEntry, 0)
+
+	for _, cm := range corporation_manager.GetCorporationHandlersByMapID(grd.ID) {
+		corp := cm.Get()
+		entry := grid.LeaderboardEntry{
+			CorporationID:     corp.ID,
+			CorporationName:   corp.Name,
+			Credits:           corp.Credits,
+			Cities:            len(corp.CitiesID),
+			Fame:              city.Reputation(cities, corp.ID),
+			CaravansCompleted: corp.CaravansCompleted,
+			Eliminated:        corp.Eliminated,
+		}
+
+		for _, v := range cities {
+			entry.GoodsProduced += v.Produced[corp.ID]
+		}
+
+		res.Entries = append(res.Entries, entry)
+	}
+	return
+}
+
+//UpdateLeaderboard refresh leaderboard of the map, a snapshot gets persisted periodically or when forced.
+//Performed from within grid thread.
+func UpdateLeaderboard(grd *grid.Grid, cities []*city.City, now time.Time, force bool) {
+	grd.Leaderboard = ComputeLeaderboard(grd, cities, now)
+
+	next := tools.AddCycles(grd.LastSnapshot, gameplay.GetInt("leaderboard_snapshot_cycles", 100))
+	if !force && !grd.LastSnapshot.IsZero() && next.After(now) {
+		return
+	}
+
+	dbh := db.New()
+	defer dbh.Close()
+
+	if err := grd.Leaderboard.Insert(dbh); err != nil {
+		logger.Errorf("Grid", "Failed to snapshot leaderboard of map %d: %s", grd.ID, err)
+		return
+	}
+	grd.LastSnapshot = now
+	grd.Update(dbh)
+}
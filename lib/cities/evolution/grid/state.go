@@ -1,18 +1,28 @@
 package grid_evolution
 
 import (
-	"log"
+	"fmt"
 	"time"
 	"upsilon_cities_go/lib/cities/caravan"
 	"upsilon_cities_go/lib/cities/caravan_manager"
 	"upsilon_cities_go/lib/cities/city"
 	"upsilon_cities_go/lib/cities/city_manager"
+	"upsilon_cities_go/lib/cities/corporation"
+	"upsilon_cities_go/lib/cities/corporation_ai"
 	"upsilon_cities_go/lib/cities/corporation_manager"
+	"upsilon_cities_go/lib/cities/item"
 	"upsilon_cities_go/lib/cities/map/grid"
+	"upsilon_cities_go/lib/cities/node"
 	"upsilon_cities_go/lib/cities/tools"
+	"upsilon_cities_go/lib/cities/user_log"
 	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/config/gameplay"
+	"upsilon_cities_go/lib/misc/logger"
+	"upsilon_cities_go/lib/misc/metrics"
 )
 
+var regionUpdateDuration = metrics.NewHistogram("upsilon_region_update_duration_seconds", "Duration of region updates, by map.", []float64{0.01, 0.05, 0.1, 0.5, 1, 2.5, 5, 10, 30, 60}, "map_id")
+
 //LoadEvolution restore state of the grid
 // will seek out every evolving parameter of the grid and keep track of the next important date.
 // That is (for the moment) next caravan reaching a destination.
@@ -34,7 +44,7 @@ func SeekNextCaravan(grid *grid.Grid) {
 		// we only need id ;)
 		chs, err := caravan_manager.GetCaravanHandlerByCityID(k)
 		if err != nil {
-			log.Printf("grid.Grid: Evolution loading ... failed to access to city %d caravans %s", k, err)
+			logOf(grid).Errorf("Evolution loading ... failed to access to city %d caravans %s", k, err)
 			continue
 		}
 
@@ -60,21 +70,28 @@ func UpdateRegion(grid *grid.Grid) {
 
 	if rnow.Equal(grid.LastUpdate) {
 		// nothing to do anyway
-		log.Printf("grid.Grid: No last update is too recent.")
+		logOf(grid).Infof("No last update is too recent.")
+		return
+	}
+
+	if grid.IsEnded() {
+		// closed maps no longer evolve.
 		return
 	}
 
-	log.Printf("##### ABOUT TO MASSIVELY UPDATE MAP %d #####", grid.ID)
+	defer regionUpdateDuration.Since(time.Now(), fmt.Sprint(grid.ID))
+
+	logOf(grid).Debugf("##### ABOUT TO MASSIVELY UPDATE MAP %d #####", grid.ID)
 
 	// check if a caravan will be finished before now, and so long now isn't reached continue on.
 
 	nextStop := tools.MinTime(rnow, grid.Evolution.NextCaravan)
 	for nextStop.Before(rnow) {
-		log.Printf("grid.Grid: Next Stop: %s vs Now %s", nextStop.Format(time.RFC3339), rnow.Format(time.RFC3339))
+		logOf(grid).Infof("Next Stop: %s vs Now %s", nextStop.Format(time.RFC3339), rnow.Format(time.RFC3339))
 
 		crv, err := caravan_manager.GetCaravanHandler(grid.Evolution.NextCaravanID)
 		if err != nil {
-			log.Printf("grid.Grid: Unable to find caravan to update ..")
+			logOf(grid).Errorf("Unable to find caravan to update ..")
 		}
 		for k := range grid.Cities {
 			cm, _ := city_manager.GetCityHandler(k)
@@ -89,7 +106,7 @@ func UpdateRegion(grid *grid.Grid) {
 			hrhs, _ := city_manager.GetCityHandler(caravan.CityTargetID)
 			clhs, _ := corporation_manager.GetCorporationHandler(caravan.CorpOriginID)
 			crhs, _ := corporation_manager.GetCorporationHandler(caravan.CorpTargetID)
-			caravan.PerformNextStep(hlhs, hrhs, clhs, crhs, nextStop)
+			caravan.PerformNextStep(hlhs, hrhs, clhs, crhs, RoadOf(grid, caravan), nextStop)
 		})
 
 		SeekNextCaravan(grid)
@@ -100,34 +117,266 @@ func UpdateRegion(grid *grid.Grid) {
 		}
 	}
 
+	UpdateWarehouses(grid, rnow)
+	UpdateRoads(grid, rnow)
+	RenewCaravans(grid)
+
+	// unowned corporations take their turn.
+	corporation_ai.Play(grid.ID)
+
 	for k := range grid.Cities {
 		cm, _ := city_manager.GetCityHandler(k)
 		dbh := db.New()
 		defer dbh.Close()
 		cm.Call(func(city *city.City) {
+			city.DecayFame(dbh, rnow)
 			city.CheckCityOwnership(dbh)
+			city.ResolveClaims(dbh, rnow)
 			city.Update(dbh)
 		})
 	}
 
+	cities := Cities(grid)
+	UpdateReputations(cities)
+	ended := CheckEndOfGame(grid, rnow)
+	UpdateLeaderboard(grid, cities, rnow, ended)
+
 	grid.LastUpdate = rnow
 	SeekNextCaravan(grid)
-	log.Printf("#### grid.Grid: Update done, next caravan: %s ####", grid.Evolution.NextCaravan.Format(time.RFC3339))
+	logOf(grid).Infof("Update done, next caravan: %s ####", grid.Evolution.NextCaravan.Format(time.RFC3339))
+}
+
+//UpdateReputations aggregate fame of corporations over all cities of the map.
+func UpdateReputations(cities []*city.City) {
+	known := make(map[int]bool)
+	for _, v := range cities {
+		for corpID := range v.Fame {
+			known[corpID] = true
+		}
+	}
+
+	dbh := db.New()
+	defer dbh.Close()
+	for corpID := range known {
+		cm, err := corporation_manager.GetCorporationHandler(corpID)
+		if err != nil {
+			continue
+		}
+		reputation := city.Reputation(cities, corpID)
+		cm.Call(func(corp *corporation.Corporation) {
+			if corp.Reputation == reputation {
+				return
+			}
+			before := corp.Tier()
+			corp.Reputation = reputation
+			if after := corp.Tier(); after.Name != before.Name {
+				user_log.NewFromCorp(corp.ID, user_log.UL_Info, fmt.Sprintf("Corporation is now %s (reputation %d)", after.Name, reputation))
+			}
+			corp.Update(dbh)
+		})
+	}
+}
+
+//RoadOf nodes traversed by caravan between its origin and target, through the whole road network.
+func RoadOf(grid *grid.Grid, crv *caravan.Caravan) (res []node.Node) {
+	origin, found := grid.Cities[crv.CityOriginID]
+	if !found {
+		return
+	}
+	target, found := grid.Cities[crv.CityTargetID]
+	if !found {
+		return
+	}
+	for _, p := range grid.RoadPath(origin.Location, target.Location) {
+		if nd := grid.Get(p); nd != nil {
+			res = append(res, *nd)
+		}
+	}
+	return
+}
+
+//UpdateRoads apply finished road works to the map and to the cities they connect.
+func UpdateRoads(grid *grid.Grid, now time.Time) {
+	dbh := db.New()
+	defer dbh.Close()
+
+	changed := false
+	maxLevel := gameplay.GetInt("road_max_level", 3)
+	for _, cm := range corporation_manager.GetCorporationHandlersByMapID(grid.ID) {
+		var done []corporation.RoadWork
+		corpID := 0
+		cm.Call(func(corp *corporation.Corporation) {
+			corpID = corp.ID
+			done = corp.CompleteRoadWorks(now)
+			if len(done) > 0 {
+				corp.Update(dbh)
+			}
+		})
+
+		for _, rw := range done {
+			for _, p := range rw.Road {
+				nd := grid.Get(p)
+				nd.IsRoad = true
+				if rw.Upgrade && nd.RoadLevel < maxLevel {
+					nd.RoadLevel++
+				}
+			}
+			connectCities(dbh, rw.FromCityID, rw.ToCityID, rw.Road)
+			connectCities(dbh, rw.ToCityID, rw.FromCityID, rw.Road.Reverse())
+			changed = true
+			user_log.NewFromCorp(corpID, user_log.UL_Good, fmt.Sprintf("%s completed", rw.String()))
+		}
+	}
+
+	if changed {
+		grid.Update(dbh)
+	}
+}
+
+// connectCities register road from a city to another, replacing previous one.
+func connectCities(dbh *db.Handler, fromID int, toID int, road node.Path) {
+	cm, err := city_manager.GetCityHandler(fromID)
+	if err != nil {
+		return
+	}
+	cm.Call(func(cty *city.City) {
+		roads := make([]node.Pathway, 0, len(cty.Roads)+1)
+		for _, v := range cty.Roads {
+			if v.ToCityID != toID {
+				roads = append(roads, v)
+			}
+		}
+		cty.Roads = append(roads, node.Pathway{Road: road, FromCityID: fromID, ToCityID: toID})
+		if !tools.InList(toID, cty.NeighboursID) {
+			cty.NeighboursID = append(cty.NeighboursID, toID)
+		}
+		cty.Update(dbh)
+	})
+}
+
+//UpdateWarehouses complete corporation transfers up to now and age warehouses content.
+func UpdateWarehouses(grid *grid.Grid, now time.Time) {
+	dbh := db.New()
+	defer dbh.Close()
+
+	for _, cm := range corporation_manager.GetCorporationHandlersByMapID(grid.ID) {
+		var toCities []corporation.Transfer
+		cm.Call(func(corp *corporation.Corporation) {
+			toCities = corp.CompleteTransfers(now)
+			if spoiled := corp.DecayWarehouse(now); spoiled > 0 {
+				user_log.NewFromCorp(corp.ID, user_log.UL_Warn, fmt.Sprintf("Warehouse: %d goods spoiled", spoiled))
+			}
+			corp.Update(dbh)
+		})
+
+		retries := make([]corporation.Transfer, 0)
+		for _, v := range toCities {
+			ctm, err := city_manager.GetCityHandler(v.CityID)
+			if err != nil {
+				logOf(grid).Errorf("Unable to deliver %s, city is gone", v.String())
+				continue
+			}
+			transfer := v
+			var left []item.Item
+			ctm.Call(func(city *city.City) {
+				left = city.Storage.ClaimOrAdd(transfer.Reservation, transfer.Items)
+				city.Update(dbh)
+			})
+			if len(left) > 0 {
+				// city is full, goods wait on the road for space.
+				retries = append(retries, v.Retry(left, now))
+				user_log.NewFromCorp(cm.ID(), user_log.UL_Warn, fmt.Sprintf("%s: city is full, %d stacks wait for space", v.String(), len(left)))
+				continue
+			}
+			user_log.NewFromCorp(cm.ID(), user_log.UL_Info, fmt.Sprintf("%s has been delivered", v.String()))
+		}
+
+		// cities may call their corporation, so delivery can't happen while holding it: delayed transfers are stored afterward.
+		if len(retries) > 0 {
+			cm.Call(func(corp *corporation.Corporation) {
+				for _, v := range retries {
+					corp.Delay(v)
+				}
+				corp.Update(dbh)
+			})
+		}
+	}
+}
+
+//RenewCaravans renew terminated caravans when both corporations opted in.
+func RenewCaravans(grid *grid.Grid) {
+	dbh := db.New()
+	defer dbh.Close()
+
+	for k := range grid.Cities {
+		chs, _ := caravan_manager.GetCaravanHandlerByCityID(k)
+		for _, ch := range chs {
+			crv := ch.Get()
+			if crv.CityOriginID != k || !crv.ShouldAutoRenew() {
+				continue
+			}
+
+			// contract was between these corporations, they must still own both cities.
+			origin, err := city_manager.GetCityHandler(crv.CityOriginID)
+			if err != nil || origin.Get().CorporationID != crv.CorpOriginID {
+				continue
+			}
+			target, err := city_manager.GetCityHandler(crv.CityTargetID)
+			if err != nil || target.Get().CorporationID != crv.CorpTargetID {
+				continue
+			}
+
+			var renewal *caravan.Caravan
+			ch.Call(func(caravan *caravan.Caravan) {
+				if caravan.ShouldAutoRenew() {
+					renewal, _ = caravan.StartRenewal()
+				}
+			})
+			if renewal == nil {
+				// renewed meanwhile.
+				continue
+			}
+
+			renewed, err := caravan_manager.Register(dbh, renewal)
+			if err != nil {
+				ch.Call(func(caravan *caravan.Caravan) {
+					caravan.EndRenewal(0)
+				})
+				logOf(grid).Errorf("Failed to renew %s: %s", crv.String(), err)
+				continue
+			}
+
+			renewedID := renewed.ID()
+			renewed.Call(func(caravan *caravan.Caravan) {
+				if err := caravan.Accept(dbh, caravan.CorpTargetID); err != nil {
+					logOf(grid).Errorf("Failed to accept renewal %s: %s", caravan.String(), err)
+				}
+			})
+
+			ch.Call(func(caravan *caravan.Caravan) {
+				caravan.EndRenewal(renewedID)
+				caravan.Update(dbh)
+			})
+
+			user_log.NewFromCorp(crv.CorpOriginID, user_log.UL_Good, fmt.Sprintf("%s has been renewed automatically", crv.String()))
+			user_log.NewFromCorp(crv.CorpTargetID, user_log.UL_Good, fmt.Sprintf("%s has been renewed automatically", crv.String()))
+		}
+	}
 }
 
 //RegionUpdateNeeded tell whether the whole region need to get updated for this city to get updated...
 func RegionUpdateNeeded(grid *grid.Grid, cityID int) bool {
 	cm, err := city_manager.GetCityHandler(cityID)
 	if err != nil {
-		log.Printf("grid.Grid: Unable to check state of city %d", cityID)
+		logOf(grid).Errorf("Unable to check state of city %d", cityID)
 		return false
 	}
 
 	rnow := tools.RoundNow()
 
-	if rnow.Equal(grid.LastUpdate) {
+	if rnow.Equal(grid.LastUpdate) || grid.IsEnded() {
 		// nothing to do anyway
-		log.Printf("grid.Grid: No last update is too recent.")
+		logOf(grid).Infof("No last update is too recent.")
 		return false
 	}
 
@@ -142,3 +391,8 @@ func RegionUpdateNeeded(grid *grid.Grid, cityID int) bool {
 	// okay so next update for this city comes after next caravan so that might be okay ...
 	return true // simpler that way ...
 }
+
+//logOf logger bearing map id.
+func logOf(grid *grid.Grid) *logger.Logger {
+	return logger.For("Grid").With("map_id", grid.ID)
+}
@@ -0,0 +1,188 @@
+package grid_evolution
+
+import (
+	"fmt"
+	"time"
+	"upsilon_cities_go/lib/cities/caravan"
+	"upsilon_cities_go/lib/cities/caravan_manager"
+	"upsilon_cities_go/lib/cities/city"
+	"upsilon_cities_go/lib/cities/city_manager"
+	"upsilon_cities_go/lib/cities/corporation"
+	"upsilon_cities_go/lib/cities/corporation_manager"
+	"upsilon_cities_go/lib/cities/map/grid"
+	"upsilon_cities_go/lib/cities/tools"
+	"upsilon_cities_go/lib/cities/user_log"
+	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/config/gameplay"
+	"upsilon_cities_go/lib/misc/logger"
+)
+
+//VictoryConditions closing a map, a zero value disables a condition. See grid.VictoryConditions
+type VictoryConditions grid.VictoryConditions
+
+//LoadVictoryConditions conditions new maps get, all disabled unless set by "victory_conditions" in gameplay.json
+func LoadVictoryConditions() VictoryConditions {
+	var res VictoryConditions
+	gameplay.GetObject("victory_conditions", &res)
+	return res
+}
+
+//Check tell whether a corporation won, and why. Map ends when a corporation meets a condition,
+//when a single one remains or when time runs out.
+func (vc VictoryConditions) Check(mapID int, corps []corporation.Corporation, started time.Time, now time.Time) (winner int, reason string, ended bool) {
+	remaining := make([]corporation.Corporation, 0, len(corps))
+	for _, v := range corps {
+		if !v.Eliminated {
+			remaining = append(remaining, v)
+		}
+	}
+
+	if len(remaining) == 0 {
+		return 0, "every corporation got eliminated", true
+	}
+
+	ranked := grid.RankCorporations(mapID, 0, remaining, now)
+	best := ranked[0]
+
+	if len(remaining) == 1 && len(corps) > 1 {
+		return best.CorporationID, fmt.Sprintf("%s is the last corporation standing", best.CorporationName), true
+	}
+
+	if vc.Cities > 0 {
+		for _, v := range ranked {
+			if v.Cities >= vc.Cities {
+				return v.CorporationID, fmt.Sprintf("%s owns %d cities", v.CorporationName, v.Cities), true
+			}
+		}
+	}
+
+	if vc.Credits > 0 {
+		rich := grid.Standing{}
+		for _, v := range ranked {
+			if v.Credits >= vc.Credits && v.Credits > rich.Credits {
+				rich = v
+			}
+		}
+		if rich.CorporationID != 0 {
+			return rich.CorporationID, fmt.Sprintf("%s gathered %d credits", rich.CorporationName, rich.Credits), true
+		}
+	}
+
+	if vc.Cycles > 0 && !started.IsZero() && !tools.AddCycles(started, vc.Cycles).After(now) {
+		return best.CorporationID, fmt.Sprintf("time is up, %s leads", best.CorporationName), true
+	}
+
+	return 0, "", false
+}
+
+//EliminateCorporation take corporation out of the game: its caravans are stopped, its cities released and its owner freed.
+//Performed from within grid thread.
+func EliminateCorporation(dbh *db.Handler, cm *corporation_manager.Handler, now time.Time) {
+	corp := cm.Get()
+	logger.Infof("Grid", "Eliminating corporation %d %s", corp.ID, corp.Name)
+
+	for _, v := range corp.CaravanID {
+		crm, err := caravan_manager.GetCaravanHandler(v)
+		if err != nil {
+			continue
+		}
+		crm.Call(func(crv *caravan.Caravan) {
+			if crv.CorpOriginID == corp.ID {
+				crv.OriginAutoRenew = false
+			}
+			if crv.CorpTargetID == corp.ID {
+				crv.TargetAutoRenew = false
+			}
+
+			var err error
+			switch {
+			case crv.State == caravan.CRVProposal || crv.State == caravan.CRVCounterProposal:
+				err = crv.Withdraw(dbh, corp.ID)
+			case crv.IsActive() && !crv.IsAborted():
+				err = crv.Abort(dbh, corp.ID)
+			default:
+				err = crv.Update(dbh)
+			}
+			if err != nil {
+				logger.Errorf("Grid", "Failed to stop %s of eliminated corporation: %s", crv.String(), err)
+			}
+		})
+
+		// stopped caravans won't be stepped again, release their escrows now. Caravans still on the road settle on return.
+		crm.Call(func(crv *caravan.Caravan) {
+			crv.Settle(dbh)
+		})
+	}
+
+	for _, v := range corp.CitiesID {
+		ctm, err := city_manager.GetCityHandler(v)
+		if err != nil {
+			continue
+		}
+		ctm.Call(func(cty *city.City) {
+			if cty.CorporationID != corp.ID {
+				return
+			}
+			cty.CorporationID = 0
+			cty.CorporationName = "Uncorporated"
+			cty.Update(dbh)
+		})
+	}
+
+	cm.Call(func(corp *corporation.Corporation) {
+		corp.CitiesID = make([]int, 0)
+		corp.Eliminated = true
+		corp.EliminatedAt = now
+		if corp.OwnerID == 0 {
+			corp.Update(dbh)
+			return
+		}
+
+		user_log.New(corp.OwnerID, user_log.UL_Bad, fmt.Sprintf("Corporation %s has been eliminated from the region", corp.Name))
+		if err := corporation.Release(dbh, corp); err != nil {
+			logger.Errorf("Grid", "Failed to release corporation %d: %s", corp.ID, err)
+		}
+	})
+}
+
+//CheckEndOfGame eliminate corporations that can't go on, then close map once a victory condition is met.
+//Performed from within grid thread.
+func CheckEndOfGame(grd *grid.Grid, now time.Time) bool {
+	dbh := db.New()
+	defer dbh.Close()
+
+	handlers := corporation_manager.GetCorporationHandlersByMapID(grd.ID)
+	for _, cm := range handlers {
+		corp := cm.Get()
+		if !corp.Eliminated && !corp.IsViable() {
+			EliminateCorporation(dbh, cm, now)
+		}
+	}
+
+	corps := make([]corporation.Corporation, 0, len(handlers))
+	for _, cm := range handlers {
+		corps = append(corps, cm.Get())
+	}
+
+	winner, reason, ended := VictoryConditions(grd.Victory).Check(grd.ID, corps, grd.CreatedAt, now)
+	if !ended {
+		return false
+	}
+
+	grd.EndedAt = now
+	grd.WinnerID = winner
+	grd.EndReason = reason
+
+	standings := grid.RankCorporations(grd.ID, winner, corps, now)
+	if err := grd.End(dbh, standings); err != nil {
+		logger.Errorf("Grid", "Failed to close map %d: %s", grd.ID, err)
+	}
+
+	logger.Infof("Grid", "Map %d closed: %s", grd.ID, reason)
+	for _, v := range standings {
+		if v.UserID != 0 {
+			user_log.New(v.UserID, user_log.UL_Info, fmt.Sprintf("Region %s closed: %s. %s ranked %d", grd.Name, reason, v.CorporationName, v.Rank))
+		}
+	}
+	return true
+}
@@ -2,9 +2,9 @@ package item
 
 import (
 	"fmt"
-	"log"
 	"strings"
 	"upsilon_cities_go/lib/cities/tools"
+	"upsilon_cities_go/lib/misc/logger"
 )
 
 //Item is a beautifull item
@@ -15,6 +15,14 @@ type Item struct {
 	Quality   int
 	Quantity  int
 	BasePrice int // at quality 100
+	Decay     Decay
+}
+
+//Decay describe how an item perish over time. A zero PerCycle means item doesn't perish.
+type Decay struct {
+	PerCycle   int    // quality lost each cycle
+	MinQuality int    // once reached, item spoils
+	SpoilTo    string // name of the item it spoils to, empty means it simply vanishes.
 }
 
 //Price compute a price, should provide an segmented valuation stuff ;)
@@ -27,6 +35,38 @@ func (it Item) Match(rhs Item) bool {
 	return tools.StringListMatchAll(it.Type, rhs.Type) && it.Name == rhs.Name && it.Quality == rhs.Quality
 }
 
+//IsPerishable tell whether item quality decays over time.
+func (it Item) IsPerishable() bool {
+	return it.Decay.PerCycle > 0
+}
+
+//Age item by provided number of cycles. Returns aged item and whether it spoiled.
+//A spoiled item with no SpoilTo ends up with a Quantity of 0.
+func (it Item) Age(cycles int) (res Item, spoiled bool) {
+	res = it
+	if !it.IsPerishable() || cycles <= 0 {
+		return res, false
+	}
+
+	res.Quality = it.Quality - it.Decay.PerCycle*cycles
+	if res.Quality > it.Decay.MinQuality {
+		return res, false
+	}
+
+	if it.Decay.SpoilTo == "" {
+		res.Quality = it.Decay.MinQuality
+		res.Quantity = 0
+		return res, true
+	}
+
+	res.Name = it.Decay.SpoilTo
+	res.Type = []string{it.Decay.SpoilTo}
+	res.Quality = it.Decay.MinQuality
+	res.BasePrice = 0
+	res.Decay = Decay{}
+	return res, true
+}
+
 //Pretty string
 func (it Item) Pretty() string {
 	return fmt.Sprintf("%d: %s (%s) Q[%d] x %d", it.ID, it.Name, it.Type, it.Quality, it.Quantity)
@@ -44,5 +84,5 @@ func (it Item) PrettyTypes() string {
 
 //State print item
 func (it Item) State() {
-	log.Printf("Item: %s", it.Pretty())
+	logger.Debugf("Item", "Item: %s", it.Pretty())
 }
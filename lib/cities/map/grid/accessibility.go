@@ -2,11 +2,11 @@ package grid
 
 import (
 	"fmt"
-	"log"
 	"upsilon_cities_go/lib/cities/map/pattern"
 	"upsilon_cities_go/lib/cities/node"
 	"upsilon_cities_go/lib/cities/nodetype"
 	"upsilon_cities_go/lib/cities/tools"
+	"upsilon_cities_go/lib/misc/logger"
 )
 
 //AccessibilityGridStruct describe what's accessible
@@ -119,10 +119,10 @@ func (gd Grid) AccessibilityGrid(fillRatio float64) (res AccessibilityGridStruct
 		nd := res.AvailableCells[0]
 		total, rest, used := countConnected(nd, res.AvailableCells[1:])
 		// is found cluster ... relevant ? => means is it the biggest one available right now ?
-		// log.Printf("Accessibility: fillrate check: %f > %f total %d vs %d", (float64(total) / float64(gd.Size*gd.Size)), fillRatio, total, res.NbAvailable/2)
+		// logger.Infof("Grid", "Accessibility: fillrate check: %f > %f total %d vs %d", (float64(total) / float64(gd.Size*gd.Size)), fillRatio, total, res.NbAvailable/2)
 		if total >= res.NbAvailable/2 {
 			// is it still significant enough
-			log.Printf("Accessibility: fillrate check: %f(%d) > %f", float64(total)/float64(gd.Size*gd.Size), total, fillRatio)
+			logger.Infof("Grid", "Accessibility: fillrate check: %f(%d) > %f", float64(total)/float64(gd.Size*gd.Size), total, fillRatio)
 
 			if (float64(total) / float64(gd.Size*gd.Size)) > fillRatio {
 				res.AvailableCells = used
@@ -2,7 +2,6 @@ package grid
 
 import (
 	"fmt"
-	"log"
 	"math/rand"
 	"runtime/debug"
 	"time"
@@ -14,6 +13,7 @@ import (
 	"upsilon_cities_go/lib/cities/tools"
 	"upsilon_cities_go/lib/db"
 	"upsilon_cities_go/lib/misc/generator"
+	"upsilon_cities_go/lib/misc/logger"
 )
 
 //State used by grid evolution
@@ -22,6 +22,13 @@ type State struct {
 	NextCaravanID int
 }
 
+//VictoryConditions closing the map, a zero value disables a condition.
+type VictoryConditions struct {
+	Credits int // credits a corporation must gather.
+	Cities  int // cities a corporation must own.
+	Cycles  int // cycles after which map closes, most successful corporation wins.
+}
+
 //Grid content of map, note `json:"-"` means it won't be exported as json ...
 //Note This is the main holder for most items of a Map ;)
 type Grid struct {
@@ -33,10 +40,23 @@ type Grid struct {
 	Cities     map[int]*city.City
 	Size       int
 	Base       nodetype.GroundType
+	CreatedAt  time.Time
+
+	// conditions closing this map, set on creation.
+	Victory VictoryConditions
+
+	// set once a victory condition is met, map no longer evolves.
+	EndedAt   time.Time
+	WinnerID  int
+	EndReason string
+
+	// last snapshot of leaderboard persisted.
+	LastSnapshot time.Time
 
 	// Helpers
 	LocationToCity map[int]*city.City `json:"-"`
 	Evolution      State              `json:"-"`
+	Leaderboard    Leaderboard        `json:"-"`
 }
 
 //ShortGrid only provide most basic of informations (for index stuff)
@@ -45,6 +65,12 @@ type ShortGrid struct {
 	Name       string
 	RegionType string
 	LastUpdate time.Time
+	Ended      bool
+}
+
+//IsEnded tell whether map has been closed.
+func (grid *Grid) IsEnded() bool {
+	return !grid.EndedAt.IsZero()
 }
 
 //Clear a grid
@@ -175,7 +201,7 @@ func assignNeighboursCorp(neighbours []*city.City, cities map[int]*city.City, co
 
 		neighbours = neighbours[1:]
 
-		log.Printf("Grid: Sub Assigning corp %d to city %d ", corp.ID, cty.ID)
+		logger.Infof("Grid", "Sub Assigning corp %d to city %d", corp.ID, cty.ID)
 		for _, v := range cty.NeighboursID {
 			n := cities[v]
 			if n.CorporationID == 0 {
@@ -262,17 +288,17 @@ func assignCorps(cities map[int]*city.City, toSet []*corporation.Corporation) []
 func (grid *Grid) Get(location node.Point) *node.Node {
 	if location.X > grid.Size-1 {
 		debug.PrintStack()
-		log.Fatalf("Grid: Get: X %d out of bound %d", location.X, grid.Size)
+		logger.Fatalf("Grid", "Get: X %d out of bound %d", location.X, grid.Size)
 		return nil
 	}
 	if location.Y > grid.Size-1 {
 		debug.PrintStack()
-		log.Fatalf("Grid: Get: Y %d out of bound %d", location.Y, grid.Size)
+		logger.Fatalf("Grid", "Get: Y %d out of bound %d", location.Y, grid.Size)
 		return nil
 	}
 	if grid.Size*location.Y+location.X >= len(grid.Nodes) {
 		debug.PrintStack()
-		log.Fatalf("Grid: Get: Location %d out of bound %d", grid.Size*location.Y+location.X, len(grid.Nodes))
+		logger.Fatalf("Grid", "Get: Location %d out of bound %d", grid.Size*location.Y+location.X, len(grid.Nodes))
 		return nil
 	}
 	return &grid.Nodes[grid.Size*location.Y+location.X]
@@ -281,15 +307,15 @@ func (grid *Grid) Get(location node.Point) *node.Node {
 //GetP will seek out a node.
 func (grid *Grid) GetP(x int, y int) *node.Node {
 	if !tools.InEq(x, 0, grid.Size-1) {
-		log.Fatalf("Grid: GetP: X %d out of bound %d", x, grid.Size)
+		logger.Fatalf("Grid", "GetP: X %d out of bound %d", x, grid.Size)
 		return nil
 	}
 	if !tools.InEq(y, 0, grid.Size-1) {
-		log.Fatalf("Grid: GetP: Y %d out of bound %d", y, grid.Size)
+		logger.Fatalf("Grid", "GetP: Y %d out of bound %d", y, grid.Size)
 		return nil
 	}
 	if grid.Size*y+x >= len(grid.Nodes) {
-		log.Fatalf("Grid: GetP: Location %d out of bound %d", grid.Size*y+x, len(grid.Nodes))
+		logger.Fatalf("Grid", "GetP: Location %d out of bound %d", grid.Size*y+x, len(grid.Nodes))
 
 		return nil
 	}
@@ -423,6 +449,45 @@ func (grid *Grid) RoadDistanceBetween(p1, p2 node.Point) (int, error) {
 	return -1, fmt.Errorf("not found")
 }
 
+//ReachableCities road distance to every city reachable from location within maxDistance, by city id.
+func (grid *Grid) ReachableCities(from node.Point, maxDistance int) (res map[int]int) {
+	res = make(map[int]int)
+	dm := grid.buildDistanceMap(from)
+	for id, cty := range grid.Cities {
+		if cty.Location.IsEq(from) {
+			continue
+		}
+		if d, has := dm[cty.Location.ToInt(grid.Size)]; has && d <= maxDistance {
+			res[id] = d
+		}
+	}
+	return
+}
+
+//RoadPath shortest path on roads from p1 to p2, both included. Empty when p2 can't be reached by road from p1.
+func (grid *Grid) RoadPath(p1, p2 node.Point) (res []node.Point) {
+	dm := grid.buildDistanceMap(p1)
+	distance, has := dm[p2.ToInt(grid.Size)]
+	if !has {
+		return
+	}
+
+	// walk back from p2, each step picks a road one step closer to p1.
+	res = make([]node.Point, distance+1)
+	current := p2
+	res[distance] = current
+	for d := distance - 1; d >= 0; d-- {
+		for _, adj := range grid.SelectPattern(current, pattern.Adjascent) {
+			if v, has := dm[adj.Location.ToInt(grid.Size)]; has && v == d {
+				current = adj.Location
+				break
+			}
+		}
+		res[d] = current
+	}
+	return
+}
+
 //RoadDistanceBetweenTargets computes distance between each points using roads. Note: either point must be on a road, otherwise distance will be set to -1
 //note also, can fail due to road system not encompassing both roads.
 //@return map(cityLocation->distance)
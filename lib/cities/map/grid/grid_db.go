@@ -4,10 +4,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"time"
 	"upsilon_cities_go/lib/cities/city"
 	"upsilon_cities_go/lib/cities/node"
 	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/logger"
 )
 
 // DB FUNCTIONS
@@ -16,13 +17,13 @@ import (
 func (grid *Grid) Insert(dbh *db.Handler) error {
 	json, err := grid.dbjsonify()
 	if err != nil {
-		log.Fatalf("Grid: Failed to jsonify data for database. %s", err)
+		logger.Fatalf("Grid", "Failed to jsonify data for database. %s", err)
 		return err
 	}
 
 	rows, err := dbh.Query("insert into maps(region_name, data) values($1,$2) returning map_id", grid.Name, json)
 	if err != nil {
-		log.Fatalf("Grid DB: Failed to Insert. %s", err)
+		logger.Fatalf("Grid", "Failed to Insert. %s", err)
 		return err
 	}
 	for rows.Next() {
@@ -31,7 +32,7 @@ func (grid *Grid) Insert(dbh *db.Handler) error {
 
 	rows.Close()
 
-	log.Printf("Grid: Grid %d Inserted", grid.ID)
+	logger.Infof("Grid", "Grid %d Inserted", grid.ID)
 
 	return nil
 }
@@ -44,7 +45,7 @@ func (grid *Grid) Update(dbh *db.Handler) error {
 
 	json, err := grid.dbjsonify()
 	if err != nil {
-		log.Fatalf("Grid: Failed to jsonify data for database. %s", err)
+		logger.Fatalf("Grid", "Failed to jsonify data for database. %s", err)
 		return err
 	}
 
@@ -58,10 +59,26 @@ func (grid *Grid) Update(dbh *db.Handler) error {
 		return fmt.Errorf("Grid DB: Failed to Update Map. %s", err)
 	}
 	query.Close()
-	log.Printf("Grid: Grid %d Updated", grid.ID)
+	logger.Infof("Grid", "Grid %d Updated", grid.ID)
 	return nil
 }
 
+//End close map in database, standings are archived.
+func (grid *Grid) End(dbh *db.Handler, standings []Standing) error {
+	for idx := range standings {
+		if err := standings[idx].Insert(dbh); err != nil {
+			return err
+		}
+	}
+
+	query, err := dbh.Query("update maps set ended_at=$1 where map_id=$2", grid.EndedAt, grid.ID)
+	if err != nil {
+		return fmt.Errorf("Grid DB: Failed to End Map. %s", err)
+	}
+	query.Close()
+	return grid.Update(dbh)
+}
+
 //Drop grid from database
 func (grid *Grid) Drop(dbh *db.Handler) error {
 	query, err := dbh.Query("delete from maps where map_id=$1", grid.ID)
@@ -69,7 +86,7 @@ func (grid *Grid) Drop(dbh *db.Handler) error {
 		return fmt.Errorf("Grid DB: Failed to Drop. %s", err)
 	}
 	query.Close()
-	log.Printf("Grid: Grid %d Deleted", grid.ID)
+	logger.Infof("Grid", "Grid %d Deleted", grid.ID)
 	grid.ID = 0
 
 	return nil
@@ -82,14 +99,14 @@ func DropByID(dbh *db.Handler, id int) error {
 		return fmt.Errorf("Grid DB: Failed to DropByID. %s", err)
 	}
 	query.Close()
-	log.Printf("Grid: Grid %d Deleted", id)
+	logger.Infof("Grid", "Grid %d Deleted", id)
 
 	return nil
 }
 
 //ByID seek a grid by ID
 func ByID(dbh *db.Handler, id int) (grid *Grid, err error) {
-	rows, err := dbh.Query("select region_name, region_type, updated_at, data from maps where map_id=$1", id)
+	rows, err := dbh.Query("select region_name, region_type, created_at, updated_at, data from maps where map_id=$1", id)
 	if err != nil {
 		return nil, fmt.Errorf("Grid DB: Failed to select map ByID. %s", err)
 	}
@@ -98,7 +115,7 @@ func ByID(dbh *db.Handler, id int) (grid *Grid, err error) {
 		grid.Clear()
 
 		var json []byte
-		rows.Scan(&grid.Name, &grid.RegionType, &grid.LastUpdate, &json)
+		rows.Scan(&grid.Name, &grid.RegionType, &grid.CreatedAt, &grid.LastUpdate, &json)
 		grid.ID = id
 		grid.dbunjsonify(json)
 
@@ -168,15 +185,30 @@ func IDByCityID(dbh *db.Handler, cityID int) (id int, err error) {
 	return 0, errors.New("City doesn't exist")
 }
 
+//IsEndedByID tell whether map has been closed.
+func IsEndedByID(dbh *db.Handler, id int) bool {
+	rows, err := dbh.Query("select ended_at is not NULL from maps where map_id=$1", id)
+	if err != nil {
+		return false
+	}
+	defer rows.Close()
+
+	ended := false
+	for rows.Next() {
+		rows.Scan(&ended)
+	}
+	return ended
+}
+
 //AllShortened seek all grids id and names ;)
 func AllShortened(dbh *db.Handler) (grids []*ShortGrid, err error) {
-	rows, err := dbh.Exec("select map_id, region_name, region_type, updated_at from maps")
+	rows, err := dbh.Exec("select map_id, region_name, region_type, updated_at, ended_at is not NULL from maps")
 	if err != nil {
 		return nil, fmt.Errorf("Grid DB: Failed to select map AllShortened. %s", err)
 	}
 	for rows.Next() {
 		grid := new(ShortGrid)
-		rows.Scan(&grid.ID, &grid.Name, &grid.RegionType, &grid.LastUpdate)
+		rows.Scan(&grid.ID, &grid.Name, &grid.RegionType, &grid.LastUpdate, &grid.Ended)
 		grids = append(grids, grid)
 	}
 
@@ -185,14 +217,25 @@ func AllShortened(dbh *db.Handler) (grids []*ShortGrid, err error) {
 }
 
 type dbGrid struct {
-	Nodes []node.Node `json:"nodes"`
-	Size  int         `json:"size"`
+	Nodes     []node.Node       `json:"nodes"`
+	Size      int               `json:"size"`
+	Victory   VictoryConditions `json:"victory"`
+	EndedAt   time.Time         `json:"ended_at"`
+	WinnerID  int               `json:"winner_id"`
+	EndReason string            `json:"end_reason"`
+
+	LastSnapshot time.Time `json:"last_snapshot"`
 }
 
 func (grid *Grid) dbjsonify() ([]byte, error) {
 	var db dbGrid
 	db.Nodes = grid.Nodes
 	db.Size = grid.Size
+	db.Victory = grid.Victory
+	db.EndedAt = grid.EndedAt
+	db.WinnerID = grid.WinnerID
+	db.EndReason = grid.EndReason
+	db.LastSnapshot = grid.LastSnapshot
 
 	return json.Marshal(db)
 }
@@ -206,5 +249,10 @@ func (grid *Grid) dbunjsonify(fromJSON []byte) error {
 
 	grid.Nodes = db.Nodes
 	grid.Size = db.Size
+	grid.Victory = db.Victory
+	grid.EndedAt = db.EndedAt
+	grid.WinnerID = db.WinnerID
+	grid.EndReason = db.EndReason
+	grid.LastSnapshot = db.LastSnapshot
 	return nil
 }
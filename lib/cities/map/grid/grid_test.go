@@ -2,8 +2,10 @@ package grid
 
 import (
 	"testing"
+	"upsilon_cities_go/lib/cities/city"
 	"upsilon_cities_go/lib/cities/node"
 	"upsilon_cities_go/lib/cities/nodetype"
+	"upsilon_cities_go/lib/cities/tools"
 )
 
 func TestSetValueOfGridNode(t *testing.T) {
@@ -190,3 +192,87 @@ func TestCompoundedGridIsFilled(t *testing.T) {
 	}
 
 }
+
+func TestReachableCities(t *testing.T) {
+	gd := Create(10, nodetype.Plain)
+
+	origin := city.New()
+	origin.ID = 1
+	origin.Location = node.NP(0, 0)
+	near := city.New()
+	near.ID = 2
+	near.Location = node.NP(3, 0)
+	far := city.New()
+	far.ID = 3
+	far.Location = node.NP(8, 0)
+	gd.Cities[1] = origin
+	gd.Cities[2] = near
+	gd.Cities[3] = far
+
+	for x := 0; x <= 8; x++ {
+		gd.GetP(x, 0).IsRoad = true
+	}
+
+	res := gd.ReachableCities(origin.Location, 5)
+	if len(res) != 1 || res[2] != 3 {
+		t.Errorf("Expected only near city to be reachable at distance 3, got %v", res)
+		return
+	}
+
+	res = gd.ReachableCities(origin.Location, 10)
+	if len(res) != 2 || res[3] != 8 {
+		t.Errorf("Expected far city to be reachable at distance 8, got %v", res)
+	}
+}
+
+func TestRoadPath(t *testing.T) {
+	gd := Create(10, nodetype.Plain)
+
+	// road goes down then right, with a dead end branch.
+	for y := 0; y <= 4; y++ {
+		gd.GetP(0, y).IsRoad = true
+	}
+	for x := 1; x <= 4; x++ {
+		gd.GetP(x, 4).IsRoad = true
+	}
+	gd.GetP(1, 2).IsRoad = true
+
+	path := gd.RoadPath(node.NP(0, 0), node.NP(4, 4))
+	if len(path) != 9 || !path[0].IsEq(node.NP(0, 0)) || !path[8].IsEq(node.NP(4, 4)) {
+		t.Errorf("Expected path of 9 nodes from origin to target, got %v", path)
+		return
+	}
+	for i := 1; i < len(path); i++ {
+		if !gd.Get(path[i]).IsRoad || tools.Abs(path[i].X-path[i-1].X)+tools.Abs(path[i].Y-path[i-1].Y) != 1 {
+			t.Errorf("Expected path to follow adjacent roads, got %v", path)
+			return
+		}
+	}
+
+	if path := gd.RoadPath(node.NP(0, 0), node.NP(8, 8)); len(path) != 0 {
+		t.Errorf("Expected no path to an unreachable point, got %v", path)
+	}
+}
+
+func TestLeaderboardSortBy(t *testing.T) {
+	lb := Leaderboard{Entries: []LeaderboardEntry{
+		{CorporationID: 1, Credits: 100, Cities: 5},
+		{CorporationID: 2, Credits: 500, Cities: 2},
+		{CorporationID: 3, Credits: 1000, Cities: 9, Eliminated: true},
+	}}
+
+	if res := lb.SortBy(LBCredits); res[0].CorporationID != 2 || res[2].CorporationID != 3 {
+		t.Errorf("Expected richest corporation first and eliminated last, got %+v", res)
+		return
+	}
+
+	if res := lb.SortBy(LBCities); res[0].CorporationID != 1 {
+		t.Errorf("Expected corporation with most cities first, got %+v", res)
+		return
+	}
+
+	if lb.Entries[0].CorporationID != 1 {
+		t.Errorf("Sorting shouldn't alter leaderboard")
+		return
+	}
+}
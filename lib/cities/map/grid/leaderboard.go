@@ -0,0 +1,63 @@
+package grid
+
+import (
+	"sort"
+	"time"
+)
+
+//Leaderboard criteria
+const (
+	LBCredits  string = "credits"
+	LBCities   string = "cities"
+	LBFame     string = "fame"
+	LBCaravans string = "caravans"
+	LBGoods    string = "goods"
+)
+
+//LeaderboardEntry how well a corporation does on a map.
+type LeaderboardEntry struct {
+	CorporationID     int
+	CorporationName   string
+	Credits           int
+	Cities            int
+	Fame              int // fame summed over all cities.
+	CaravansCompleted int
+	GoodsProduced     int
+	Eliminated        bool
+}
+
+//Leaderboard ranking of corporations of a map at a given time.
+type Leaderboard struct {
+	ID      int
+	MapID   int
+	TakenAt time.Time
+	Entries []LeaderboardEntry
+}
+
+//Value of entry for criteria, credits by default.
+func (entry LeaderboardEntry) Value(criteria string) int {
+	switch criteria {
+	case LBCities:
+		return entry.Cities
+	case LBFame:
+		return entry.Fame
+	case LBCaravans:
+		return entry.CaravansCompleted
+	case LBGoods:
+		return entry.GoodsProduced
+	}
+	return entry.Credits
+}
+
+//SortBy entries ranked by criteria, eliminated corporations last.
+func (lb Leaderboard) SortBy(criteria string) []LeaderboardEntry {
+	res := make([]LeaderboardEntry, len(lb.Entries))
+	copy(res, lb.Entries)
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].Eliminated != res[j].Eliminated {
+			return !res[i].Eliminated
+		}
+		return res[i].Value(criteria) > res[j].Value(criteria)
+	})
+	return res
+}
@@ -0,0 +1,62 @@
+package grid
+
+import (
+	"encoding/json"
+	"fmt"
+	"upsilon_cities_go/lib/db"
+)
+
+//Insert a leaderboard snapshot in database
+func (lb *Leaderboard) Insert(dbh *db.Handler) error {
+	data, err := json.Marshal(lb.Entries)
+	if err != nil {
+		return err
+	}
+
+	rows, err := dbh.Query("insert into leaderboards(map_id, taken_at, data) values($1,$2,$3) returning leaderboard_id", lb.MapID, lb.TakenAt, data)
+	if err != nil {
+		return fmt.Errorf("Leaderboard DB : Failed to insert snapshot: %s", err)
+	}
+	for rows.Next() {
+		rows.Scan(&lb.ID)
+	}
+	rows.Close()
+	return nil
+}
+
+//LeaderboardsByMapID fetches snapshots of a map, most recent first, without their entries.
+func LeaderboardsByMapID(dbh *db.Handler, mapID int) (res []Leaderboard, err error) {
+	rows, err := dbh.Query("select leaderboard_id, map_id, taken_at from leaderboards where map_id=$1 order by taken_at desc", mapID)
+	if err != nil {
+		return nil, fmt.Errorf("Leaderboard DB : Failed to select snapshots (ByMapID): %s", err)
+	}
+	defer rows.Close()
+
+	res = make([]Leaderboard, 0)
+	for rows.Next() {
+		var lb Leaderboard
+		rows.Scan(&lb.ID, &lb.MapID, &lb.TakenAt)
+		res = append(res, lb)
+	}
+	return
+}
+
+//LeaderboardByID fetches a snapshot with its entries.
+func LeaderboardByID(dbh *db.Handler, id int) (lb Leaderboard, err error) {
+	rows, err := dbh.Query("select leaderboard_id, map_id, taken_at, data from leaderboards where leaderboard_id=$1", id)
+	if err != nil {
+		return lb, fmt.Errorf("Leaderboard DB : Failed to select snapshot (ByID): %s", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data []byte
+		rows.Scan(&lb.ID, &lb.MapID, &lb.TakenAt, &data)
+		err = json.Unmarshal(data, &lb.Entries)
+		if err != nil {
+			return lb, fmt.Errorf("Leaderboard DB : Failed to parse snapshot %d: %s", lb.ID, err)
+		}
+		return lb, nil
+	}
+	return lb, fmt.Errorf("Leaderboard DB : Snapshot %d not found", id)
+}
@@ -0,0 +1,67 @@
+package grid
+
+import (
+	"sort"
+	"time"
+	"upsilon_cities_go/lib/cities/corporation"
+)
+
+//Standing rank of a corporation once map got closed.
+type Standing struct {
+	ID              int
+	MapID           int
+	Rank            int
+	CorporationID   int
+	CorporationName string
+	UserID          int
+	Credits         int
+	Cities          int
+	Reputation      int
+	Eliminated      bool
+	Winner          bool
+	ArchivedAt      time.Time
+}
+
+//RankCorporations standings of corporations, winner first then remaining ones by cities, credits and reputation.
+func RankCorporations(mapID int, winnerID int, corps []corporation.Corporation, now time.Time) (res []Standing) {
+	for _, v := range corps {
+		owner := v.OwnerID
+		if owner == 0 {
+			owner = v.FormerOwnerID
+		}
+		res = append(res, Standing{
+			MapID:           mapID,
+			CorporationID:   v.ID,
+			CorporationName: v.Name,
+			UserID:          owner,
+			Credits:         v.Credits,
+			Cities:          len(v.CitiesID),
+			Reputation:      v.Reputation,
+			Eliminated:      v.Eliminated,
+			Winner:          v.ID == winnerID,
+			ArchivedAt:      now,
+		})
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		lhs, rhs := res[i], res[j]
+		if lhs.Winner != rhs.Winner {
+			return lhs.Winner
+		}
+		if lhs.Eliminated != rhs.Eliminated {
+			return !lhs.Eliminated
+		}
+		if lhs.Cities != rhs.Cities {
+			return lhs.Cities > rhs.Cities
+		}
+		if lhs.Credits != rhs.Credits {
+			return lhs.Credits > rhs.Credits
+		}
+		return lhs.Reputation > rhs.Reputation
+	})
+
+	for idx := range res {
+		res[idx].Rank = idx + 1
+	}
+	return
+}
@@ -0,0 +1,44 @@
+package grid
+
+import (
+	"fmt"
+	"upsilon_cities_go/lib/db"
+)
+
+//Insert a standing in database
+func (st *Standing) Insert(dbh *db.Handler) error {
+	var userID interface{}
+	if st.UserID != 0 {
+		userID = st.UserID
+	}
+
+	rows, err := dbh.Query(`insert into map_standings(map_id, rank, corporation_id, corporation_name, user_id, credits, cities, reputation, eliminated, winner, archived_at)
+		values($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11) returning map_standing_id`,
+		st.MapID, st.Rank, st.CorporationID, st.CorporationName, userID, st.Credits, st.Cities, st.Reputation, st.Eliminated, st.Winner, st.ArchivedAt)
+	if err != nil {
+		return fmt.Errorf("Standing DB : Failed to insert standing: %s", err)
+	}
+	for rows.Next() {
+		rows.Scan(&st.ID)
+	}
+	rows.Close()
+	return nil
+}
+
+//StandingsByMapID fetches archived standings of a map by rank
+func StandingsByMapID(dbh *db.Handler, mapID int) (res []Standing, err error) {
+	rows, err := dbh.Query(`select map_standing_id, map_id, rank, corporation_id, corporation_name, coalesce(user_id, 0), credits, cities, reputation, eliminated, winner, archived_at
+		from map_standings where map_id=$1 order by rank`, mapID)
+	if err != nil {
+		return nil, fmt.Errorf("Standing DB : Failed to select standings (ByMapID): %s", err)
+	}
+	defer rows.Close()
+
+	res = make([]Standing, 0)
+	for rows.Next() {
+		var st Standing
+		rows.Scan(&st.ID, &st.MapID, &st.Rank, &st.CorporationID, &st.CorporationName, &st.UserID, &st.Credits, &st.Cities, &st.Reputation, &st.Eliminated, &st.Winner, &st.ArchivedAt)
+		res = append(res, st)
+	}
+	return
+}
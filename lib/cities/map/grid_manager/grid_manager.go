@@ -2,7 +2,6 @@ package grid_manager
 
 import (
 	"errors"
-	"log"
 	"time"
 	"upsilon_cities_go/lib/cities/caravan"
 	"upsilon_cities_go/lib/cities/caravan_manager"
@@ -15,6 +14,8 @@ import (
 	"upsilon_cities_go/lib/cities/tools"
 	"upsilon_cities_go/lib/db"
 	"upsilon_cities_go/lib/misc/actor"
+	"upsilon_cities_go/lib/misc/config/gameplay"
+	"upsilon_cities_go/lib/misc/logger"
 )
 
 //Handler own the grid, they're to be called upon to provide access to the grid
@@ -23,6 +24,7 @@ type Handler struct {
 	grid    *grid.Grid
 	Ticker  *time.Ticker
 	Deleted bool
+	stopped bool // ticker got stopped for shutdown, pending tick must be ignored.
 }
 
 //Manager keeps track of grid handlers out there.
@@ -61,13 +63,16 @@ func GenerateGridHandler(gd *grid.Grid) {
 			select {
 			case <-grd.Ticker.C:
 				if grd.Deleted {
-					log.Fatalf("GridMgr: Should have been deleted but wasn't ... %d", grd.ID())
+					logger.Fatalf("GridMgr", "Should have been deleted but wasn't ... %d", grd.ID())
 					return
 				}
+				if grd.stopped {
+					continue
+				}
 				grid_evolution.UpdateRegion(grd.grid)
 			case f := <-grd.Actionc:
 				if grd.Deleted {
-					log.Fatalf("GridMgr: Should have been deleted but wasn't ... %d", grd.ID())
+					logger.Fatalf("GridMgr", "Should have been deleted but wasn't ... %d", grd.ID())
 					return
 				}
 				f()
@@ -81,25 +86,26 @@ func GenerateGridHandler(gd *grid.Grid) {
 	dbh := db.New()
 	defer dbh.Close()
 
+	logs := logger.For("Grid").With("map_id", gd.ID)
 	gd.Cities, _ = city.ByMap(dbh, grd.ID())
 
 	for _, v := range gd.Cities {
 		city_manager.GenerateHandler(v)
-		log.Printf("Grid: Created City Handler %d %s", v.ID, v.Name)
+		logs.Debugf("Created City Handler %d %s", v.ID, v.Name)
 	}
 
 	caravans, _ := caravan.ByMapID(dbh, gd.ID)
 
 	for _, v := range caravans {
 		caravan_manager.GenerateHandler(v)
-		log.Printf("Grid: Created Caravan Handler %d", v.ID)
+		logs.Debugf("Created Caravan Handler %d", v.ID)
 	}
 
 	corps, _ := corporation.ByMapID(dbh, gd.ID)
 
 	for _, v := range corps {
 		corporation_manager.GenerateHandler(v)
-		log.Printf("Grid: Created Corp Handler %d %s", v.ID, v.Name)
+		logs.Debugf("Created Corp Handler %d %s", v.ID, v.Name)
 	}
 
 	// ensure evolution gets kicked in.
@@ -133,6 +139,26 @@ func GetGridHandler(id int) (*Handler, error) {
 	return grd, nil
 }
 
+//ReachableCities cities reachable by road from city within "caravan_max_road_distance", with their road distance.
+func ReachableCities(cityID int) (map[int]int, error) {
+	cm, err := city_manager.GetCityHandler(cityID)
+	if err != nil {
+		return nil, err
+	}
+	cty := cm.Get()
+
+	gm, err := GetGridHandler(cty.MapID)
+	if err != nil {
+		return nil, err
+	}
+
+	var res map[int]int
+	gm.Call(func(gd *grid.Grid) {
+		res = gd.ReachableCities(cty.Location, gameplay.GetInt("caravan_max_road_distance", 40))
+	})
+	return res, nil
+}
+
 //DropGridHandler from memory
 func DropGridHandler(id int) error {
 	grid, found := manager.handlers[id]
@@ -150,6 +176,87 @@ func DropGridHandler(id int) error {
 	return nil
 }
 
+//Shutdown stop every grid ticker, waiting for running region updates to complete.
+//When persist is requested, caravans, cities, corporations and grids get saved once their mailbox got drained.
+//Returns how many entities failed to be saved.
+func Shutdown(persist bool) (failed int) {
+	var handlers []*Handler
+	manager.Call(func() {
+		for _, v := range manager.handlers {
+			handlers = append(handlers, v)
+		}
+	})
+
+	for _, v := range handlers {
+		grd := v
+		grd.Actor.Call(func() {
+			grd.Ticker.Stop()
+			grd.stopped = true
+		})
+	}
+	logger.Infof("GridMgr", "Stopped %d grid tickers", len(handlers))
+
+	if !persist {
+		return
+	}
+
+	dbh := db.New()
+	defer dbh.Close()
+
+	failed += caravan_manager.Persist(dbh)
+	failed += city_manager.Persist(dbh)
+	failed += corporation_manager.Persist(dbh)
+
+	for _, v := range handlers {
+		v.Call(func(gd *grid.Grid) {
+			if err := gd.Update(dbh); err != nil {
+				logger.Errorf("GridMgr", "Failed to persist grid %d: %s", gd.ID, err)
+				failed++
+			}
+		})
+	}
+	logger.Infof("GridMgr", "Persisted %d grids", len(handlers))
+	return
+}
+
+//Stats how many grids are loaded and how many functions wait in mailboxes, manager's included.
+func Stats() (loaded int, queued int) {
+	queued = manager.Queued()
+	manager.Call(func() {
+		loaded = len(manager.handlers)
+		for _, v := range manager.handlers {
+			queued += v.Queued()
+		}
+	})
+	return
+}
+
+//Ping tell whether manager is responsive and how many loaded grids failed to reply within timeout.
+//Grids are pinged concurrently so it won't take much longer than twice the timeout.
+func Ping(timeout time.Duration) (stalled int, ok bool) {
+	if !manager.Ping(timeout) {
+		return 0, false
+	}
+
+	var handlers []*Handler
+	manager.Call(func() {
+		for _, v := range manager.handlers {
+			handlers = append(handlers, v)
+		}
+	})
+
+	replies := make(chan bool, len(handlers))
+	for _, v := range handlers {
+		go func(h *Handler) { replies <- h.Actor.Ping(timeout) }(v)
+	}
+	for range handlers {
+		if !<-replies {
+			stalled++
+		}
+	}
+	return stalled, true
+}
+
 //Cast send and forget. Will provide access to protected grid.
 // If you want a reply, dont forget to provide your function a chan
 // If you do so, DONT FORGET TO call defer close(<your chan>)
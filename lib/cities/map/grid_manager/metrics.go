@@ -0,0 +1,40 @@
+package grid_manager
+
+import (
+	"upsilon_cities_go/lib/cities/caravan_manager"
+	"upsilon_cities_go/lib/cities/city_manager"
+	"upsilon_cities_go/lib/cities/corporation_manager"
+	"upsilon_cities_go/lib/misc/metrics"
+)
+
+//managerStats of every manager by name.
+func managerStats() map[string][2]int {
+	res := make(map[string][2]int)
+	for name, stats := range map[string]func() (int, int){
+		"grid":        Stats,
+		"city":        city_manager.Stats,
+		"caravan":     caravan_manager.Stats,
+		"corporation": corporation_manager.Stats,
+	} {
+		loaded, queued := stats()
+		res[name] = [2]int{loaded, queued}
+	}
+	return res
+}
+
+var (
+	_ = metrics.NewGaugeFunc("upsilon_loaded_entities", "Entities loaded in memory.", "kind", func() map[string]float64 {
+		res := make(map[string]float64)
+		for k, v := range managerStats() {
+			res[k] = float64(v[0])
+		}
+		return res
+	})
+	_ = metrics.NewGaugeFunc("upsilon_actor_mailbox_depth", "Functions waiting to be executed by actors of a manager.", "manager", func() map[string]float64 {
+		res := make(map[string]float64)
+		for k, v := range managerStats() {
+			res[k] = float64(v[1])
+		}
+		return res
+	})
+)
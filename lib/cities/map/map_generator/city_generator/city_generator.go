@@ -1,7 +1,6 @@
 package city_generator
 
 import (
-	"log"
 	"time"
 	"upsilon_cities_go/lib/cities/city"
 	"upsilon_cities_go/lib/cities/city/producer_generator"
@@ -12,6 +11,7 @@ import (
 	"upsilon_cities_go/lib/cities/tools"
 	"upsilon_cities_go/lib/db"
 	"upsilon_cities_go/lib/misc/generator"
+	"upsilon_cities_go/lib/misc/logger"
 )
 
 const (
@@ -70,7 +70,7 @@ func (mg CityGenerator) generateCityPrepare(gd *grid.CompoundedGrid, dbh *db.Han
 	cty.State.MaxStorageSpace = mg.InitStorageSpace
 
 	cty.State.Influence = pattern.GenerateAdjascentPattern(mg.InfluenceRange.Roll())
-	log.Printf("GC: Added city to %v", loc)
+	logger.Debugf("GC", "Added city to %v", loc)
 
 	return
 }
@@ -79,7 +79,7 @@ func (mg CityGenerator) generateCity(gd *grid.CompoundedGrid, dbh *db.Handler, l
 	cty := mg.generateCityPrepare(gd, dbh, loc)
 	// list exploitable resources
 
-	log.Printf("%d cities in delta", len(gd.Delta.Cities))
+	logger.Debugf("GC", "%d cities in delta", len(gd.Delta.Cities))
 
 	ar := make(map[string]bool, 0)
 	for _, v := range gd.SelectPattern(loc, cty.State.Influence) {
@@ -96,10 +96,10 @@ func (mg CityGenerator) generateCity(gd *grid.CompoundedGrid, dbh *db.Handler, l
 	// select a fabric that may use any of the resource, this one will be forcibly added.
 	// its resources as well.
 
-	log.Printf("GC: got active Resources: %v", activeResources)
+	logger.Debugf("GC", "got active Resources: %v", activeResources)
 
 	candidateResources := producer_generator.ResourceProducerProducingTypes(activeResources)
-	log.Printf("GC: got candidate resources: %v", candidateResources)
+	logger.Debugf("GC", "got candidate resources: %v", candidateResources)
 
 	builtResources := 0
 
@@ -109,7 +109,7 @@ func (mg CityGenerator) generateCity(gd *grid.CompoundedGrid, dbh *db.Handler, l
 		for i := 0; i < cty.State.MaxRessources; i++ {
 			idx := tools.RandInt(0, len(candidateResources)-1)
 			fact := candidateResources[idx].Create()
-			log.Printf("GC: Adding resource generator: %v %v", candidateResources[idx], fact)
+			logger.Debugf("GC", "Adding resource generator: %v %v", candidateResources[idx], fact)
 
 			fact.ID = cty.CurrentMaxID
 			cty.RessourceProducers[cty.CurrentMaxID] = fact
@@ -118,27 +118,27 @@ func (mg CityGenerator) generateCity(gd *grid.CompoundedGrid, dbh *db.Handler, l
 			buildResourcesTypes = append(buildResourcesTypes, candidateResources[idx].Products[0].ItemTypes...)
 		}
 	} else {
-		log.Printf("CG: Weird got no candidates factories for resources: %v", activeResources)
+		logger.Warnf("CG", "Weird got no candidates factories for resources: %v", activeResources)
 	}
 
 	// for remaining resources, add at random
 	// for remaining fabrics, add at random
 
 	candidateProducts := producer_generator.ProducerRequiringTypes(buildResourcesTypes, true)
-	log.Printf("GC: got candidate products: %v", candidateProducts)
+	logger.Debugf("GC", "got candidate products: %v", candidateProducts)
 
 	if len(candidateProducts) > 0 {
 		for i := 0; i < cty.State.MaxFactories; i++ {
 			idx := tools.RandInt(0, len(candidateProducts)-1)
 			fact := candidateProducts[idx].Create()
-			log.Printf("GC: Adding product generator: %v %v", candidateProducts[idx], fact)
+			logger.Debugf("GC", "Adding product generator: %v %v", candidateProducts[idx], fact)
 
 			fact.ID = cty.CurrentMaxID
 			cty.ProductFactories[cty.CurrentMaxID] = fact
 			cty.CurrentMaxID++
 		}
 	} else {
-		log.Printf("CG: Weird got no candidates factories for products: %v", candidateProducts)
+		logger.Warnf("CG", "Weird got no candidates factories for products: %v", candidateProducts)
 	}
 
 	cty.CheckActivity(time.Now().UTC())
@@ -151,7 +151,7 @@ func (mg CityGenerator) Generate(gd *grid.CompoundedGrid, dbh *db.Handler) error
 	size := gd.Base.Size
 	nb := (size / 10) * density
 
-	log.Printf("CityGenerator: Attempting to add Cities to map density: %d Size: %d => number of cities to add: %d", density, size, nb)
+	logger.Infof("CityGenerator", "Attempting to add Cities to map density: %d Size: %d => number of cities to add: %d", density, size, nb)
 
 	acc := gd.AccessibilityGrid()
 
@@ -183,7 +183,7 @@ func (mg CityGenerator) Generate(gd *grid.CompoundedGrid, dbh *db.Handler) error
 		}
 	}
 
-	log.Printf("CG: City generator acc map: \n%s", acc.String())
+	logger.Debugf("CG", "City generator acc map: \n%s", acc.String())
 
 	square := pattern.GenerateSquarePattern(2)
 	refuse := pattern.GenerateAdjascentPattern(5)
@@ -201,7 +201,7 @@ func (mg CityGenerator) Generate(gd *grid.CompoundedGrid, dbh *db.Handler) error
 						candidate.X = tools.EnsureIn(candidate.X, 0, gd.Base.Size-1)
 						candidate.Y = tools.EnsureIn(candidate.Y, 0, gd.Base.Size-1)
 
-						log.Printf("GC: Zone center: %v, candidate %v", node.NP(col, row), candidate)
+						logger.Debugf("GC", "Zone center: %v, candidate %v", node.NP(col, row), candidate)
 
 						if acc.GetData(candidate) == available {
 							acc.Apply(candidate, refuse, func(n *node.Node, od int) (nd int) {
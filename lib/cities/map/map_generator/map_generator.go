@@ -2,12 +2,12 @@ package map_generator
 
 import (
 	"fmt"
-	"log"
 	"upsilon_cities_go/lib/cities/map/grid"
 	"upsilon_cities_go/lib/cities/map/map_generator/map_level"
 	"upsilon_cities_go/lib/cities/nodetype"
 	"upsilon_cities_go/lib/db"
 	"upsilon_cities_go/lib/misc/generator"
+	"upsilon_cities_go/lib/misc/logger"
 )
 
 //MapSubGenerator build
@@ -55,7 +55,7 @@ func (mg MapGenerator) Generate(dbh *db.Handler, regionType string) (g *grid.Gri
 				for try < 3 {
 					err := v.Generate(&cg, dbh)
 					if err != nil {
-						log.Printf("MapGenerator: Failed to apply Generator Lvl: %d %s", level, v.Name())
+						logger.Errorf("MapGenerator", "Failed to apply Generator Lvl: %d %s", level, v.Name())
 						try++
 						failed = true
 					} else {
@@ -1,7 +1,6 @@
 package mountain_generator
 
 import (
-	"log"
 	"math"
 	"upsilon_cities_go/lib/cities/map/grid"
 	"upsilon_cities_go/lib/cities/map/map_generator/map_level"
@@ -9,6 +8,7 @@ import (
 	"upsilon_cities_go/lib/cities/nodetype"
 	"upsilon_cities_go/lib/cities/tools"
 	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/logger"
 )
 
 //MountainGenerator generate mountains ahah
@@ -44,14 +44,14 @@ func (mg MountainGenerator) Generate(gd *grid.CompoundedGrid, dbh *db.Handler) e
 	// test 3 times to get the right place for a nice mountain, failure ? don't care ... :)
 	for test < 3 {
 		nd := node.NP(pt.Roll(), pt.Roll())
-		log.Printf("MountainGenerator: Base %d set to %s", test+1, nd.String())
+		logger.Debugf("MountainGenerator", "Base %d set to %s", test+1, nd.String())
 		if !gd.IsFilled(nd, nodetype.Landscape) {
 			targets := node.PointsAtDistance(nd, rg, gd.Base.Size)
 			lentarget := len(targets)
-			log.Printf("MountainGenerator: Found %d potential targets", lentarget)
+			logger.Debugf("MountainGenerator", "Found %d potential targets", lentarget)
 			for i := 0; i < lentarget; i++ {
 				target := targets[tools.RandInt(0, lentarget-1)]
-				log.Printf("MountainGenerator: Trying with target %s", target.String())
+				logger.Debugf("MountainGenerator", "Trying with target %s", target.String())
 				if !gd.IsFilled(target, nodetype.Landscape) {
 
 					dist := math.Sqrt(math.Pow(float64(target.X-nd.X), 2) + math.Pow(float64(target.Y-nd.Y), 2))
@@ -59,13 +59,13 @@ func (mg MountainGenerator) Generate(gd *grid.CompoundedGrid, dbh *db.Handler) e
 					// unit vector = { X/V(X²+Y²), Y/V(X²+Y²) }
 					unitX := float64(target.X-nd.X) / dist
 					unitY := float64(target.Y-nd.Y) / dist
-					log.Printf("MountainGenerator: dist: %f, UnitX: %f UnitY %f", dist, unitX, unitY)
+					logger.Debugf("MountainGenerator", "dist: %f, UnitX: %f UnitY %f", dist, unitX, unitY)
 
 					for idx := width - mg.Disparity; idx < (rg - (width - mg.Disparity)); idx = idx + width + mg.Disparity {
 						center := node.NP(int(unitX*float64(idx)), int(unitY*float64(idx)))
 						center.X = center.X + nd.X
 						center.Y = center.Y + nd.Y
-						log.Printf("MountainGenerator: Adding circle of mountains at: %s", center.String())
+						logger.Debugf("MountainGenerator", "Adding circle of mountains at: %s", center.String())
 
 						for _, nd := range node.PointsWithinInCircle(center, width, gd.Base.Size) {
 							if gd.Get(nd).Landscape != nodetype.River { // refuse mountain over river.
@@ -74,17 +74,17 @@ func (mg MountainGenerator) Generate(gd *grid.CompoundedGrid, dbh *db.Handler) e
 						}
 					}
 
-					log.Printf("MountainGenerator: Successfully added mountain width: %d, range %d, from %s, to %s", width, rg, nd.String(), target.String())
+					logger.Debugf("MountainGenerator", "Successfully added mountain width: %d, range %d, from %s, to %s", width, rg, nd.String(), target.String())
 					return nil
 				}
 			}
 		} else {
-			log.Printf("MountainGenerator: Already filled, trying something else")
+			logger.Debugf("MountainGenerator", "Already filled, trying something else")
 		}
 		test++
 	}
 
-	log.Printf("MountainGenerator: Failed to add mountain width: %d, range %d ", width, rg)
+	logger.Errorf("MountainGenerator", "Failed to add mountain width: %d, range %d", width, rg)
 	// tried three times to add a mountain range, but couldn't ... that's okay.
 	return nil
 }
@@ -105,6 +105,11 @@ func Load() {
 	}
 }
 
+// Loaded number of regions known.
+func Loaded() int {
+	return len(regions)
+}
+
 // Generate a map generator based on region name
 func Generate(name string) (*map_generator.MapGenerator, error) {
 	reg, has := regions[name]
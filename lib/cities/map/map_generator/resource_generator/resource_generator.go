@@ -1,13 +1,13 @@
 package resource_generator
 
 import (
-	"log"
 	"upsilon_cities_go/lib/cities/city/resource"
 	rg "upsilon_cities_go/lib/cities/city/resource_generator"
 	"upsilon_cities_go/lib/cities/map/grid"
 	"upsilon_cities_go/lib/cities/map/map_generator/map_level"
 	"upsilon_cities_go/lib/cities/tools"
 	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/logger"
 )
 
 //ResourceGenerator generate resource ahah
@@ -56,8 +56,8 @@ func (mg ResourceGenerator) Generate(gd *grid.CompoundedGrid, dbh *db.Handler) e
 		rsce := expandedResources[tidx]
 		nd := gd.Get(gd.Base.Nodes[idx].Location)
 
-		log.Printf("RG: Node %v: available resources: %d", nd.Location.String(), len(availableResources))
-		log.Printf("RG: activated: %s", rsce.Type)
+		logger.Debugf("RG", "Node %v: available resources: %d", nd.Location.String(), len(availableResources))
+		logger.Debugf("RG", "activated: %s", rsce.Type)
 
 		if rsce.Type == "None" {
 			// shame ;)
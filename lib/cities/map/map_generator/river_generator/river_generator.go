@@ -200,7 +200,7 @@ func (mg RiverGenerator) findNextCandidate(gd *grid.CompoundedGrid, tempGrid *gr
 
 			score := tempGrid.GetData(v.Location)
 
-			//log.Printf("RiverGenerator: Candidate: %v Score %d randreach %f ", v.Location.String(), score, randreach)
+			//logger.Debugf("RiverGenerator", "Candidate: %v Score %d randreach %f", v.Location.String(), score, randreach)
 
 			if currentScore > score {
 				continue
@@ -223,7 +223,7 @@ func (mg RiverGenerator) findNextCandidate(gd *grid.CompoundedGrid, tempGrid *gr
 			smallestScore := 99999
 			for _, v := range *candidates {
 				score := tempGrid.GetData(v.Location)
-				//log.Printf("RiverGenerator: Candidate: %v Score %d  ", v.Location.String(), score)
+				//logger.Debugf("RiverGenerator", "Candidate: %v Score %d", v.Location.String(), score)
 
 				if smallestScore > score {
 					smallest = v.Location
@@ -239,7 +239,7 @@ func (mg RiverGenerator) findNextCandidate(gd *grid.CompoundedGrid, tempGrid *gr
 	} else {
 		for _, v := range *candidates {
 			score := tempGrid.GetData(v.Location)
-			//log.Printf("RiverGenerator: Candidate: %v Score %d  ", v.Location.String(), score)
+			//logger.Debugf("RiverGenerator", "Candidate: %v Score %d", v.Location.String(), score)
 
 			if currentScore > score {
 				previous = current
@@ -282,15 +282,15 @@ func (mg RiverGenerator) Generate(gd *grid.CompoundedGrid, dbh *db.Handler) erro
 	// * sea
 	// * border
 
-	//log.Printf("RiverGenerator: Begin")
+	//logger.Debugf("RiverGenerator", "Begin")
 	length := mg.Length.Roll()
 	// x5: a meander measure at least 5 cells.
 	directness := mg.Directness.Roll() * 5
 
-	//log.Printf("RiverGenerator: Begin Search Path")
+	//logger.Debugf("RiverGenerator", "Begin Search Path")
 	// assuredly this one is needed.
 	path := mg.searchPaths(gd, length)
-	//log.Printf("RiverGenerator: End Search Path")
+	//logger.Debugf("RiverGenerator", "End Search Path")
 
 	if len(path) == 0 {
 		// just no options using moutains to sea ...
@@ -299,30 +299,30 @@ func (mg RiverGenerator) Generate(gd *grid.CompoundedGrid, dbh *db.Handler) erro
 
 	// select a random couple origin -> target
 
-	//log.Printf("RiverGenerator: Begin build accessibility grid")
+	//logger.Debugf("RiverGenerator", "Begin build accessibility grid")
 	tempGrid := gd.AccessibilityGrid()
-	//log.Printf("RiverGenerator: End build accessibility grid")
+	//logger.Debugf("RiverGenerator", "End build accessibility grid")
 
 	tries := 3
 	for tries > 0 {
 
-		//log.Printf("RiverGenerator: Begin solver")
+		//logger.Debugf("RiverGenerator", "Begin solver")
 
 		tries--
 		retry := false
 
-		//log.Printf("RiverGenerator: Begin select path")
+		//logger.Debugf("RiverGenerator", "Begin select path")
 		origin, target := mg.selectPath(gd, &path)
-		//log.Printf("RiverGenerator: End selct path")
+		//logger.Debugf("RiverGenerator", "End selct path")
 
-		//log.Printf("RiverGenerator: Selected an origin: %v -> Target: %v", origin.String(), target.String())
-		//log.Printf("RiverGenerator: Distance: %d, real %f", node.Distance(origin, target), node.RealDistance(origin, target))
-		//log.Printf("RiverGenerator: Targeted distance: %d", length)
+		//logger.Debugf("RiverGenerator", "Selected an origin: %v -> Target: %v", origin.String(), target.String())
+		//logger.Debugf("RiverGenerator", "Distance: %d, real %f", node.Distance(origin, target), node.RealDistance(origin, target))
+		//logger.Debugf("RiverGenerator", "Targeted distance: %d", length)
 
 		// generate a AStar based on this.
-		//log.Printf("RiverGenerator: Begin AStar")
+		//logger.Debugf("RiverGenerator", "Begin AStar")
 		mg.astarGrid(gd, &tempGrid, origin, target)
-		//log.Printf("RiverGenerator: End AStar")
+		//logger.Debugf("RiverGenerator", "End AStar")
 
 		// AStar completed !
 		river := make([]node.Point, 0)
@@ -335,9 +335,9 @@ func (mg RiverGenerator) Generate(gd *grid.CompoundedGrid, dbh *db.Handler) erro
 			currentScore := tempGrid.GetData(origin)
 			used := make(map[int]bool)
 
-			//log.Printf("RiverGenerator: Total Distance: %d", targetLength)
+			//logger.Debugf("RiverGenerator", "Total Distance: %d", targetLength)
 
-			//log.Printf("RiverGenerator: Begin River generation")
+			//logger.Debugf("RiverGenerator", "Begin River generation")
 
 			maxIterations := targetLength + 3
 
@@ -347,21 +347,21 @@ func (mg RiverGenerator) Generate(gd *grid.CompoundedGrid, dbh *db.Handler) erro
 				if maxIterations == 0 {
 					retry = true
 
-					//log.Printf("RiverGenerator: End River generation - Max iteration reached !")
+					//logger.Debugf("RiverGenerator", "End River generation - Max iteration reached !")
 					break
 				}
 
-				//log.Printf("RiverGenerator: Current Cell: %v Current Score: %d", current.String(), currentScore)
+				//logger.Debugf("RiverGenerator", "Current Cell: %v Current Score: %d", current.String(), currentScore)
 				foundOne := false
-				//log.Printf("RiverGenerator: River generation New Iteration")
+				//logger.Debugf("RiverGenerator", "River generation New Iteration")
 
 				candidates := mg.selectCandidates(gd, &tempGrid, &used, current)
 
-				//log.Printf("RiverGenerator: len candidates: %d", len(candidates))
+				//logger.Debugf("RiverGenerator", "len candidates: %d", len(candidates))
 
-				//log.Printf("RiverGenerator: Begin Find Next candidates")
+				//logger.Debugf("RiverGenerator", "Begin Find Next candidates")
 				previous, current, currentScore, foundOne = mg.findNextCandidate(gd, &tempGrid, &candidates, current, currentScore, targetLength)
-				//log.Printf("RiverGenerator: End Find Next candidates")
+				//logger.Debugf("RiverGenerator", "End Find Next candidates")
 				if foundOne {
 					targetLength--
 					river = append(river, current)
@@ -369,7 +369,7 @@ func (mg RiverGenerator) Generate(gd *grid.CompoundedGrid, dbh *db.Handler) erro
 				}
 
 				if !foundOne {
-					//log.Printf("RiverGenerator: Unable to find a next cell")
+					//logger.Errorf("RiverGenerator", "Unable to find a next cell")
 					// that's super weird, it means that we didn't find any acceptable next node in current stuff.
 					retry = true
 					break
@@ -383,7 +383,7 @@ func (mg RiverGenerator) Generate(gd *grid.CompoundedGrid, dbh *db.Handler) erro
 				}
 
 				if currentScore == 0 {
-					//log.Printf("RiverGenerator: Reached end of path successfully: target length ? %d", targetLength)
+					//logger.Debugf("RiverGenerator", "Reached end of path successfully: target length ? %d", targetLength)
 
 					if targetLength < -3 || targetLength > 3 {
 						// well, we're way out of expected bounds.
@@ -393,11 +393,11 @@ func (mg RiverGenerator) Generate(gd *grid.CompoundedGrid, dbh *db.Handler) erro
 				}
 			}
 
-			//log.Printf("RiverGenerator: End River generation")
+			//logger.Debugf("RiverGenerator", "End River generation")
 
 		}
 
-		//log.Printf("RiverGenerator: End solver")
+		//logger.Debugf("RiverGenerator", "End solver")
 		if retry {
 			// reselect a path and try again
 			continue
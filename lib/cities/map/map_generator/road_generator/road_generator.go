@@ -2,7 +2,6 @@ package road_generator
 
 import (
 	"fmt"
-	"log"
 	"math"
 	"math/rand"
 	"sort"
@@ -14,6 +13,7 @@ import (
 	"upsilon_cities_go/lib/cities/nodetype"
 	"upsilon_cities_go/lib/cities/tools"
 	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/logger"
 )
 
 const (
@@ -269,12 +269,12 @@ func (rg RoadGenerator) Generate(gd *grid.CompoundedGrid, dbh *db.Handler) error
 
 		targetCity := gd.Base.Cities[targetCities[tools.RandInt(0, len(targetCities)-1)]]
 		if targetCity == nil {
-			log.Fatalf("Weird nil target city")
+			logger.Fatalf("RG", "Weird nil target city")
 		}
 		for targetCity.ID == k {
 			targetCity = gd.Base.Cities[targetCities[tools.RandInt(0, len(targetCities)-1)]]
 			if targetCity == nil {
-				log.Fatalf("Weird nil target city")
+				logger.Fatalf("RG", "Weird nil target city")
 			}
 		}
 
@@ -322,7 +322,7 @@ func (rg RoadGenerator) printRoad(gd *grid.CompoundedGrid, acc *grid.Accessibili
 		}
 	}
 	if rg.ShowLog {
-		log.Printf("RG: \n%s", res)
+		logger.Debugf("RG", "\n%s", res)
 	}
 }
 func (rg RoadGenerator) printOtherRoad(gd *grid.CompoundedGrid, acc *grid.AccessibilityGridStruct, road generatedRoad, roadOther generatedRoad, endp node.Point) {
@@ -364,12 +364,12 @@ func (rg RoadGenerator) printOtherRoad(gd *grid.CompoundedGrid, acc *grid.Access
 		}
 	}
 	if rg.ShowLog {
-		log.Printf("RG: \n%s", res)
+		logger.Debugf("RG", "\n%s", res)
 	}
 }
 
 func (rg *RoadGenerator) generateRoad(gd *grid.CompoundedGrid, originCity *city.City, targetCity *city.City) error {
-	log.Printf("RG: Generating road options %s -> %s", originCity.Location.String(), targetCity.Location.String())
+	logger.Debugf("RG", "Generating road options %s -> %s", originCity.Location.String(), targetCity.Location.String())
 
 	acc := gd.AccessibilityGrid()
 	for x := 0; x < gd.Base.Size; x++ {
@@ -527,7 +527,7 @@ func (rg *RoadGenerator) generateRoad(gd *grid.CompoundedGrid, originCity *city.
 	}
 
 	if rg.ShowLog {
-		log.Printf("Map: \n%s", gd.Delta.String())
+		logger.Debugf("Map", "\n%s", gd.Delta.String())
 	}
 	return nil
 }
@@ -551,7 +551,7 @@ func (rg *RoadGenerator) roadFound(gd *grid.CompoundedGrid, acc *grid.Accessibil
 		rg.Roads[ridx] = v
 		// merged current road with existing road.
 		validRoadFound = true
-		log.Printf("GR: joining another road and meet target city")
+		logger.Debugf("GR", "joining another road and meet target city")
 		rg.printOtherRoad(gd, acc, *gr, v, targetCity.Location)
 		return
 	}
@@ -588,7 +588,7 @@ func (rg *RoadGenerator) roadFound(gd *grid.CompoundedGrid, acc *grid.Accessibil
 		return false, false, currentLocation
 	}
 
-	log.Printf("GR: joining another road")
+	logger.Debugf("GR", "joining another road")
 	rg.printOtherRoad(gd, acc, *gr, v, currentTarget)
 
 	oneFound = true
@@ -625,24 +625,24 @@ func (rg *RoadGenerator) generateNeighbours(gd *grid.CompoundedGrid, dbh *db.Han
 	for k, v := range gd.Base.Cities {
 		targetNeighbours := rg.Neighbours.Roll()
 
-		log.Printf("RG: city locations: %v", citiesLocations)
+		logger.Debugf("RG", "city locations: %v", citiesLocations)
 		//distNgb: citylocation -> dist
 		distNgb, err := gd.Delta.RoadDistanceBetweenTargets(v.Location, citiesLocations)
 		if err != nil {
 			// not shouldn't error here :)
-			log.Fatalf("RG: Shouldn't have errored here...: %s", err)
+			logger.Fatalf("RG", "Shouldn't have errored here...: %s", err)
 		}
 
 		delete(distNgb, v.Location.ToInt(gd.Base.Size))
 
-		log.Printf("RG: Got distances from city %v to all cities: %v", v.Location, distNgb)
+		logger.Debugf("RG", "Got distances from city %v to all cities: %v", v.Location, distNgb)
 
 		rDistNgb := make(map[int]*city.City)
 		orderedDist := make([]int, 0, len(distNgb))
 		for location, distance := range distNgb {
 			cty := gd.Base.GetCityByLocation(node.FromInt(location, gd.Base.Size))
 			if cty == nil {
-				log.Fatalf("RG: Expected to find city at location: %d (%v) but got nil", location, node.FromInt(location, gd.Base.Size))
+				logger.Fatalf("RG", "Expected to find city at location: %d (%v) but got nil", location, node.FromInt(location, gd.Base.Size))
 			}
 			_, has := rDistNgb[distance]
 			for has {
@@ -680,3 +680,78 @@ func (rg *RoadGenerator) generateNeighbours(gd *grid.CompoundedGrid, dbh *db.Han
 	}
 	return nil
 }
+
+//Plan seek a road between two locations of an existing map using generation cost model. Map isn't altered.
+func (rg RoadGenerator) Plan(gd *grid.Grid, origin, target node.Point) (node.Path, error) {
+	cg := grid.CompoundedGrid{Base: gd, Delta: grid.Create(gd.Size, nodetype.NoGround)}
+
+	acc := cg.AccessibilityGrid()
+	for x := 0; x < gd.Size; x++ {
+		for y := 0; y < gd.Size; y++ {
+			acc.SetData(node.NP(x, y), 0)
+		}
+	}
+
+	for x := 0; x < gd.Size; x++ {
+		for y := 0; y < gd.Size; y++ {
+			rg.computeCost(cg.GetP(x, y), acc)
+		}
+	}
+
+	rg.astarGrid(&cg, &acc, origin, target)
+
+	road := node.Path{origin}
+	visited := make(map[int]bool)
+	visited[origin.ToInt(gd.Size)] = true
+	currentLocation := origin
+
+	for !currentLocation.IsEq(target) {
+		currentLowest := 999
+		var currentPoint node.Point
+		for _, targetNode := range acc.SelectPattern(currentLocation, pattern.Adjascent) {
+			if targetNode.IsEq(target) {
+				currentLowest = acc.GetData(targetNode)
+				currentPoint = targetNode
+				break
+			}
+			if visited[targetNode.ToInt(gd.Size)] {
+				continue
+			}
+			if val := acc.GetData(targetNode); val < currentLowest {
+				currentLowest = val
+				currentPoint = targetNode
+			}
+		}
+
+		if currentLowest >= 999 {
+			if len(road) == 1 {
+				return nil, fmt.Errorf("no road options %s -> %s", origin.String(), target.String())
+			}
+			// dead end, go backward and avoid it.
+			acc.SetData(currentLocation, 999)
+			road = road[:len(road)-1]
+			currentLocation = road[len(road)-1]
+			continue
+		}
+
+		road = append(road, currentPoint)
+		visited[currentPoint.ToInt(gd.Size)] = true
+		currentLocation = currentPoint
+	}
+
+	return road, nil
+}
+
+//Cost of working along road: ground and landscape depth of every node not already a road, or of every node when upgrading.
+//Also tells how many nodes are worked.
+func (rg RoadGenerator) Cost(gd *grid.Grid, road node.Path, upgrade bool) (cost int, nodes int) {
+	for _, p := range road {
+		nd := gd.Get(p)
+		if nd.IsRoad && !upgrade {
+			continue
+		}
+		cost += 1 + rg.fetchCost(nd.Ground) + rg.fetchLTCost(nd.Landscape)
+		nodes++
+	}
+	return
+}
@@ -28,6 +28,7 @@ type Node struct {
 	Ground      nodetype.GroundType
 	Landscape   nodetype.LandscapeType
 	IsRoad      bool
+	RoadLevel   int // raised by road upgrades.
 	IsStructure bool
 	Potential   []resource.Resource
 	Activated   []resource.Resource
@@ -80,6 +81,9 @@ func (n *Node) Update(rhs *Node) {
 	if rhs.IsRoad {
 		n.IsRoad = rhs.IsRoad
 	}
+	if rhs.RoadLevel > n.RoadLevel {
+		n.RoadLevel = rhs.RoadLevel
+	}
 	if rhs.IsStructure {
 		n.IsStructure = rhs.IsStructure
 	}
@@ -387,6 +391,15 @@ func (path Path) Contains(pt Point) bool {
 	return false
 }
 
+//Reverse path, from its end to its beginning.
+func (path Path) Reverse() (res Path) {
+	res = make(Path, 0, len(path))
+	for idx := len(path) - 1; idx >= 0; idx-- {
+		res = append(res, path[idx])
+	}
+	return
+}
+
 //Similar tell whether a pathway contains another, with at most deviation
 func (path Path) Similar(other Path, deviation int) (similar bool, totallyIncluded bool, includeOther bool) {
 	deviated := 0
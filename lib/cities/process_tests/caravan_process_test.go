@@ -7,22 +7,25 @@ import (
 	"upsilon_cities_go/lib/cities/caravan"
 	"upsilon_cities_go/lib/cities/caravan_manager"
 	"upsilon_cities_go/lib/cities/city"
-	"upsilon_cities_go/lib/cities/city/producer"
+	"upsilon_cities_go/lib/cities/city/producer_generator"
+	"upsilon_cities_go/lib/cities/city/resource_generator"
 	"upsilon_cities_go/lib/cities/city_manager"
+	"upsilon_cities_go/lib/cities/corporation"
 	"upsilon_cities_go/lib/cities/corporation_manager"
-	"upsilon_cities_go/lib/cities/grid"
-	"upsilon_cities_go/lib/cities/grid_manager"
+	"upsilon_cities_go/lib/cities/map/grid"
+	"upsilon_cities_go/lib/cities/map/grid_manager"
+	"upsilon_cities_go/lib/cities/map/map_generator/region"
 	"upsilon_cities_go/lib/cities/item"
 	"upsilon_cities_go/lib/cities/tools"
 	"upsilon_cities_go/lib/db"
 	"upsilon_cities_go/lib/misc/generator"
 )
 
-func TestFullFlowCaravan(t *testing.T) {
-	dbh := db.NewTest()
+// prepareCaravan build a map and a caravan proposal between two neighbour cities of different corporations, both stocked with goods.
+func prepareCaravan() (dbh *db.Handler, crv *caravan_manager.Handler, lhs, rhs *city_manager.Handler, clhs, crhs *corporation_manager.Handler) {
+	dbh = db.NewTest()
 	db.FlushDatabase(dbh)
 	db.MarkSessionAsTest() // forcefully replace all db.New by db.NewTest
-	defer dbh.Close()
 
 	caravan.Init()
 
@@ -35,18 +38,20 @@ func TestFullFlowCaravan(t *testing.T) {
 	corporation_manager.InitManager()
 
 	generator.CreateSampleFile()
-	generator.Init()
+	generator.Load()
 
-	producer.CreateSampleFile()
-	producer.Load()
+	producer_generator.CreateSampleFile()
+	producer_generator.Load()
+	resource_generator.Load()
+	region.Load()
 
-	tgrid := grid.New(dbh)
+	reg, _ := region.Generate("Elvenwood")
+	tgrid, _ := reg.Generate(dbh, "Elvenwood")
+	grid.Store(dbh, tgrid)
 	grid_manager.GenerateGridHandler(tgrid)
 
 	grd, _ := grid_manager.GetGridHandler(tgrid.ID)
 
-	var lhs, rhs *city_manager.Handler
-
 	for _, v := range grd.Get().Cities {
 		found := false
 		if v.CorporationID == 0 {
@@ -67,8 +72,8 @@ func TestFullFlowCaravan(t *testing.T) {
 		}
 	}
 
-	clhs, _ := corporation_manager.GetCorporationHandler(lhs.Get().CorporationID)
-	crhs, _ := corporation_manager.GetCorporationHandler(rhs.Get().CorporationID)
+	clhs, _ = corporation_manager.GetCorporationHandler(lhs.Get().CorporationID)
+	crhs, _ = corporation_manager.GetCorporationHandler(rhs.Get().CorporationID)
 
 	tcrv := caravan.New()
 	tcrv.CorpOriginID = clhs.ID()
@@ -106,7 +111,7 @@ func TestFullFlowCaravan(t *testing.T) {
 	}
 	caravan_manager.GenerateHandler(tcrv)
 
-	crv, _ := caravan_manager.GetCaravanHandler(tcrv.ID)
+	crv, _ = caravan_manager.GetCaravanHandler(tcrv.ID)
 
 	//ensure both parties have got enough items to provide the contract.
 
@@ -133,6 +138,12 @@ func TestFullFlowCaravan(t *testing.T) {
 	rhs.Call(func(city *city.City) {
 		city.Storage.Add(it2)
 	})
+	return
+}
+
+func TestFullFlowCaravan(t *testing.T) {
+	dbh, crv, lhs, rhs, clhs, crhs := prepareCaravan()
+	defer dbh.Close()
 
 	// We're now ready to gooo :)
 
@@ -173,7 +184,7 @@ func TestFullFlowCaravan(t *testing.T) {
 		hrhs, _ := city_manager.GetCityHandler(crvn.CityTargetID)
 		clhs, _ := corporation_manager.GetCorporationHandler(crvn.CorpOriginID)
 		crhs, _ := corporation_manager.GetCorporationHandler(crvn.CorpTargetID)
-		crvn.PerformNextStep(hlhs, hrhs, clhs, crhs, now)
+		crvn.PerformNextStep(hlhs, hrhs, clhs, crhs, nil, now)
 
 	})
 
@@ -194,7 +205,7 @@ func TestFullFlowCaravan(t *testing.T) {
 		hrhs, _ := city_manager.GetCityHandler(crvn.CityTargetID)
 		clhs, _ := corporation_manager.GetCorporationHandler(crvn.CorpOriginID)
 		crhs, _ := corporation_manager.GetCorporationHandler(crvn.CorpTargetID)
-		crvn.PerformNextStep(hlhs, hrhs, clhs, crhs, now)
+		crvn.PerformNextStep(hlhs, hrhs, clhs, crhs, nil, now)
 	})
 
 	// ensure caravan current state is set to 7
@@ -213,7 +224,7 @@ func TestFullFlowCaravan(t *testing.T) {
 		hrhs, _ := city_manager.GetCityHandler(crvn.CityTargetID)
 		clhs, _ := corporation_manager.GetCorporationHandler(crvn.CorpOriginID)
 		crhs, _ := corporation_manager.GetCorporationHandler(crvn.CorpTargetID)
-		crvn.PerformNextStep(hlhs, hrhs, clhs, crhs, now)
+		crvn.PerformNextStep(hlhs, hrhs, clhs, crhs, nil, now)
 	})
 
 	crv.Call(func(crvn *caravan.Caravan) {
@@ -229,7 +240,7 @@ func TestFullFlowCaravan(t *testing.T) {
 		hrhs, _ := city_manager.GetCityHandler(crvn.CityTargetID)
 		clhs, _ := corporation_manager.GetCorporationHandler(crvn.CorpOriginID)
 		crhs, _ := corporation_manager.GetCorporationHandler(crvn.CorpTargetID)
-		crvn.PerformNextStep(hlhs, hrhs, clhs, crhs, now)
+		crvn.PerformNextStep(hlhs, hrhs, clhs, crhs, nil, now)
 	})
 
 	// Must be back to square 4 ;)
@@ -241,3 +252,56 @@ func TestFullFlowCaravan(t *testing.T) {
 	}
 
 }
+
+func TestUnpaidCompensationSettlesCaravan(t *testing.T) {
+	dbh, crv, lhs, rhs, clhs, crhs := prepareCaravan()
+	defer dbh.Close()
+
+	crv.Call(func(caravan *caravan.Caravan) {
+		caravan.Penalty = 100
+		caravan.ExportCompensation = 50
+		caravan.Update(dbh)
+	})
+
+	clhs.Call(func(corp *corporation.Corporation) {
+		corp.Credits = 1000
+	})
+	crhs.Call(func(corp *corporation.Corporation) {
+		corp.Credits = 1000
+	})
+
+	crv.Call(func(caravan *caravan.Caravan) {
+		if err := caravan.Accept(dbh, crhs.ID()); err != nil {
+			t.Errorf("Caravan: Failed to accept %s", err)
+		}
+	})
+
+	// origin spends escrowed compensation meanwhile, and can't top it up.
+	crv.Call(func(caravan *caravan.Caravan) {
+		caravan.OriginCompEscrow = 0
+	})
+	clhs.Call(func(corp *corporation.Corporation) {
+		corp.Credits = 0
+	})
+
+	crv.Call(func(crvn *caravan.Caravan) {
+		now := tools.RoundNow()
+		crvn.NextChange = now
+		crvn.PerformNextStep(lhs, rhs, clhs, crhs, nil, now)
+	})
+
+	res := crv.Get()
+	if res.State != caravan.CRVAborted || res.BreachedBy != clhs.ID() {
+		t.Errorf("Expected caravan to be aborted by origin, got %s breached by %d", res.FullStringState(), res.BreachedBy)
+		return
+	}
+	if !res.Settled || res.Committed(clhs.ID()) != 0 || res.Committed(crhs.ID()) != 0 {
+		t.Errorf("Expected escrows to be settled right away")
+		return
+	}
+
+	// target gets back its penalty and first leg compensation, plus origin penalty.
+	if credits := crhs.Get().Credits; credits != 1000+res.Penalty {
+		t.Errorf("Expected target corporation to receive origin penalty (expected: %d, got %d)", 1000+res.Penalty, credits)
+	}
+}
@@ -297,6 +297,20 @@ func (storage *Storage) Claim(id int64, it []item.Item) (err error) {
 	return nil
 }
 
+//ClaimOrAdd store items in reserved space, or in free space when reservation is unknown.
+//Returns items that couldn't be stored, caller must keep them somewhere.
+func (storage *Storage) ClaimOrAdd(id int64, it []item.Item) (left []item.Item) {
+	// releasing reservation frees exactly the space items were meant to use.
+	delete(storage.Reservations, id)
+
+	for _, v := range it {
+		if err := storage.Add(v); err != nil {
+			left = append(left, v)
+		}
+	}
+	return
+}
+
 //GiveBack releases reserved space for public use.
 func (storage *Storage) GiveBack(id int64) (err error) {
 	_, found := storage.Reservations[id]
@@ -309,6 +323,40 @@ func (storage *Storage) GiveBack(id int64) (err error) {
 	return nil
 }
 
+//Decay ages perishable items by provided number of cycles, returns the number of items that spoiled.
+//Aged items are merged back with matching stacks, space used doesn't change.
+func (storage *Storage) Decay(cycles int) (spoiled int) {
+	if cycles <= 0 {
+		return 0
+	}
+
+	aged := make([]item.Item, 0)
+	for id, it := range storage.Content {
+		if !it.IsPerishable() {
+			continue
+		}
+		nit, hasSpoiled := it.Age(cycles)
+		if hasSpoiled {
+			spoiled += it.Quantity
+		}
+		delete(storage.Content, id)
+		if nit.Quantity > 0 {
+			aged = append(aged, nit)
+		}
+	}
+
+	for _, it := range aged {
+		storedit, err := storage.First(ByMatch(it))
+		if err == nil {
+			storedit.Quantity += it.Quantity
+			storage.Content[storedit.ID] = storedit
+			continue
+		}
+		storage.Content[it.ID] = it
+	}
+	return
+}
+
 //Clear empties a storage ;)
 func (storage *Storage) Clear() {
 	storage.Content = make(map[int64]item.Item)
@@ -224,7 +224,7 @@ func TestFindAllMatching(t *testing.T) {
 	}
 
 	for _, v := range fitm {
-		if !tools.StringListMatch(itm.Type, v.Type) {
+		if !tools.StringListMatchAll(itm.Type, v.Type) {
 			t.Errorf("an item has been found, but doesn't match requirement. %s vs %s", itm.Pretty(), v.Pretty())
 			store.state()
 			return
@@ -291,6 +291,36 @@ func TestClaimStorageSpace(t *testing.T) {
 	}
 }
 
+func TestClaimOrAddKeepsLeftovers(t *testing.T) {
+	store := New()
+
+	id, _ := store.Reserve(5)
+
+	// reservation got lost meanwhile, space got used by someone else.
+	store.GiveBack(id)
+	filler := generateItem()
+	filler.Quantity = 8
+	store.Add(filler)
+
+	fits := generateItem()
+	fits.Quantity = 2
+	overflow := generateItem()
+	overflow.Quantity = 3
+
+	left := store.ClaimOrAdd(id, []item.Item{fits, overflow})
+
+	if len(left) != 1 || left[0].Name != overflow.Name {
+		t.Errorf("Expected only %s to be left over, got %v", overflow.Name, left)
+		store.state()
+		return
+	}
+
+	if store.Count() != 10 {
+		t.Errorf("Expected Store to be full (expected: 10, got %d)", store.Count())
+		return
+	}
+}
+
 func TestGiveBackStoreSpace(t *testing.T) {
 	store := New()
 
@@ -309,3 +339,51 @@ func TestGiveBackStoreSpace(t *testing.T) {
 	}
 
 }
+
+func TestDecayPerishable(t *testing.T) {
+	store := New()
+	store.Capacity = 100
+	itm := generateItem()
+	itm.Decay = item.Decay{PerCycle: 2, MinQuality: 5, SpoilTo: "Rotten"}
+	store.Add(itm)
+
+	if spoiled := store.Decay(1); spoiled != 0 {
+		t.Errorf("Expected nothing to spoil after 1 cycle got %d", spoiled)
+		store.state()
+		return
+	}
+
+	decayed, err := store.First(ByNameNQuality(itm.Name, tools.MakeIntRange(8, 8)))
+	if err != nil || decayed.Quantity != itm.Quantity {
+		t.Errorf("Expected item quality to have dropped to 8")
+		store.state()
+		return
+	}
+
+	if spoiled := store.Decay(5); spoiled != itm.Quantity {
+		t.Errorf("Expected %d items to spoil got %d", itm.Quantity, spoiled)
+		store.state()
+		return
+	}
+
+	if store.CountAll(ByType("Rotten")) != itm.Quantity {
+		t.Errorf("Expected spoiled items to turn into Rotten")
+		store.state()
+		return
+	}
+}
+
+func TestDecayIgnoreNonPerishable(t *testing.T) {
+	store := New()
+	itm := generateItem()
+	store.Add(itm)
+
+	store.Decay(10)
+
+	nitem, found := store.Get(1)
+	if !found || !itm.Match(nitem) {
+		t.Errorf("Expected non perishable item to be left untouched")
+		store.state()
+		return
+	}
+}
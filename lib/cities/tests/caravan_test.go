@@ -449,7 +449,7 @@ func TestCaravanGetLoaded(t *testing.T) {
 	tst.lhs.Storage.Add(it)
 
 	log.Printf("Filling caravan form city's store")
-	err := tst.crv.Fill(tst.dbh, tst.lhs)
+	err := tst.crv.Fill(tst.dbh, tst.lhs, time.Now().UTC())
 	if err != nil {
 		t.Errorf("caravan should have been filled.")
 		return
@@ -515,7 +515,7 @@ func TestCaravanGetLoadedMultipleTimes(t *testing.T) {
 
 	tst.lhs.Storage.Add(it)
 
-	err := tst.crv.Fill(tst.dbh, tst.lhs)
+	err := tst.crv.Fill(tst.dbh, tst.lhs, time.Now().UTC())
 	if err != nil {
 		t.Errorf("caravan should have been filled.")
 		return
@@ -531,7 +531,7 @@ func TestCaravanGetLoadedMultipleTimes(t *testing.T) {
 
 	tst.lhs.Storage.Add(it)
 
-	err = tst.crv.Fill(tst.dbh, tst.lhs)
+	err = tst.crv.Fill(tst.dbh, tst.lhs, time.Now().UTC())
 	if err != nil {
 		t.Errorf("caravan should have been filled.")
 		return
@@ -547,7 +547,7 @@ func TestCaravanGetLoadedMultipleTimes(t *testing.T) {
 
 	tst.lhs.Storage.Add(it)
 
-	err = tst.crv.Fill(tst.dbh, tst.lhs)
+	err = tst.crv.Fill(tst.dbh, tst.lhs, time.Now().UTC())
 	if err != nil {
 		t.Errorf("caravan should have been filled.")
 		return
@@ -574,7 +574,7 @@ func TestCaravanGetLoadedMultipleTimes(t *testing.T) {
 
 	tst.lhs.Storage.Add(it)
 
-	err = tst.crv.Fill(tst.dbh, tst.lhs)
+	err = tst.crv.Fill(tst.dbh, tst.lhs, time.Now().UTC())
 	if err != nil {
 		t.Errorf("caravan should have been filled.")
 		return
@@ -625,7 +625,7 @@ func TestCaravanGetPartiallyLoaded(t *testing.T) {
 
 	tst.lhs.Storage.Add(it)
 
-	err := tst.crv.Fill(tst.dbh, tst.lhs)
+	err := tst.crv.Fill(tst.dbh, tst.lhs, time.Now().UTC())
 	if err != nil {
 		t.Errorf("caravan should have been filled.")
 		return
@@ -690,7 +690,7 @@ func generateFilledCaravan() testContext {
 	tst.crv.LastChange = tools.AddCycles(tst.crv.NextChange, -5)
 	tst.crv.EndOfTerm = tools.AddCycles(tst.crv.NextChange, 10)
 
-	tst.crv.Fill(tst.dbh, tst.lhs)
+	tst.crv.Fill(tst.dbh, tst.lhs, time.Now().UTC())
 
 	// might not have same pointer in city's storage ... so reload ;)
 	tst.crv.Reload(tst.dbh)
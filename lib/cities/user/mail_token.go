@@ -0,0 +1,43 @@
+package user
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+//Mail token purposes.
+const (
+	MailTokenReset  string = "reset"  // forgotten password.
+	MailTokenVerify string = "verify" // email verification at signup.
+)
+
+//MailToken single use, time limited token sent by mail. Only its hash is kept in database.
+type MailToken struct {
+	ID        int
+	UserID    int
+	Purpose   string
+	Hash      string
+	ExpiresAt time.Time
+}
+
+//NewMailToken generate a token for user, valid for ttl. Plain token is returned once and never stored.
+func NewMailToken(userID int, purpose string, ttl time.Duration) (*MailToken, string, error) {
+	if purpose != MailTokenReset && purpose != MailTokenVerify {
+		return nil, "", errors.New("unknown mail token purpose")
+	}
+
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return nil, "", err
+	}
+	plain := hex.EncodeToString(bytes)
+
+	tok := new(MailToken)
+	tok.UserID = userID
+	tok.Purpose = purpose
+	tok.Hash = HashToken(plain)
+	tok.ExpiresAt = time.Now().UTC().Add(ttl)
+	return tok, plain, nil
+}
@@ -0,0 +1,56 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/logger"
+)
+
+//Insert mail token in database
+func (tok *MailToken) Insert(dbh *db.Handler) error {
+	rows, err := dbh.Query(`insert into mail_tokens(user_id, purpose, token_hash, expires_at)
+		values($1, $2, $3, $4) returning mail_token_id`,
+		tok.UserID, tok.Purpose, tok.Hash, tok.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("Mail Token DB: Failed to Insert. %s", err)
+	}
+	for rows.Next() {
+		rows.Scan(&tok.ID)
+	}
+	rows.Close()
+
+	logger.Infof("User", "Created %s mail token %d for user %d", tok.Purpose, tok.ID, tok.UserID)
+	return nil
+}
+
+//ConsumeMailToken mark token as used and return it, provided it's still valid for purpose.
+//Token is used in the same statement it's checked, so it can't be used twice.
+func ConsumeMailToken(dbh *db.Handler, plain string, purpose string, now time.Time) (*MailToken, error) {
+	rows, err := dbh.Query(`update mail_tokens set used_at=$1
+		where token_hash=$2 and purpose=$3 and used_at is null and expires_at > $1
+		returning mail_token_id, user_id, purpose, token_hash, expires_at`,
+		now, HashToken(plain), purpose)
+	if err != nil {
+		return nil, fmt.Errorf("Mail Token DB: Failed to Consume. %s", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, errors.New("unknown, used or expired token")
+	}
+	tok := new(MailToken)
+	rows.Scan(&tok.ID, &tok.UserID, &tok.Purpose, &tok.Hash, &tok.ExpiresAt)
+	return tok, nil
+}
+
+//DropMailTokens drop tokens of user for purpose, used or not.
+func DropMailTokens(dbh *db.Handler, userID int, purpose string) error {
+	query, err := dbh.Query("delete from mail_tokens where user_id=$1 and purpose=$2", userID, purpose)
+	if err != nil {
+		return fmt.Errorf("Mail Token DB: Failed to Drop. %s", err)
+	}
+	query.Close()
+	return nil
+}
@@ -0,0 +1,80 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//Token scopes, a read only token may only be used on safe requests.
+const (
+	TokenRead string = "read"
+	TokenFull string = "full"
+)
+
+// tokenPrefix helps recognize a personal token when it leaks.
+const tokenPrefix = "upc_"
+
+//Token personal API token, only its hash is kept in database.
+type Token struct {
+	ID        int
+	UserID    int
+	Name      string
+	Scope     string
+	Hint      string // first characters of the token, to tell tokens apart.
+	Hash      string `json:"-"`
+	CreatedAt time.Time
+	LastUsed  time.Time
+}
+
+//NewToken generate a token for user. Plain token is returned once and never stored.
+func NewToken(userID int, name string, scope string) (*Token, string, error) {
+	if scope != TokenRead && scope != TokenFull {
+		return nil, "", errors.New("unknown token scope")
+	}
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 50 {
+		return nil, "", errors.New("token name must be between 1 and 50 characters")
+	}
+
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return nil, "", err
+	}
+	plain := tokenPrefix + hex.EncodeToString(bytes)
+
+	tok := new(Token)
+	tok.UserID = userID
+	tok.Name = name
+	tok.Scope = scope
+	tok.Hint = plain[:len(tokenPrefix)+6]
+	tok.Hash = HashToken(plain)
+	tok.CreatedAt = time.Now().UTC()
+	return tok, plain, nil
+}
+
+//HashToken hash used to store and seek a token. Tokens are random enough for a plain sha256 to be sufficient.
+func HashToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+//Allows tell whether token may be used for provided http method.
+func (tok *Token) Allows(method string) bool {
+	if tok.Scope == TokenFull {
+		return true
+	}
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+//PrettyLastUsed stringify last use.
+func (tok *Token) PrettyLastUsed() string {
+	if tok.LastUsed.IsZero() {
+		return "never"
+	}
+	return tok.LastUsed.Format(time.RFC3339)
+}
@@ -0,0 +1,108 @@
+package user
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/logger"
+)
+
+//Insert token in database
+func (tok *Token) Insert(dbh *db.Handler) error {
+	rows, err := dbh.Query(`insert into api_tokens(user_id, name, scope, hint, token_hash, created_at)
+		values($1, $2, $3, $4, $5, $6) returning api_token_id`,
+		tok.UserID, tok.Name, tok.Scope, tok.Hint, tok.Hash, tok.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("Token DB: Failed to Insert. %s", err)
+	}
+	for rows.Next() {
+		rows.Scan(&tok.ID)
+	}
+	rows.Close()
+
+	logger.Infof("User", "Created %s token %d for user %d", tok.Scope, tok.ID, tok.UserID)
+	return nil
+}
+
+//Touch record token usage.
+func (tok *Token) Touch(dbh *db.Handler, now time.Time) error {
+	tok.LastUsed = now
+	query, err := dbh.Query("update api_tokens set last_used_at=$1 where api_token_id=$2", tok.LastUsed, tok.ID)
+	if err != nil {
+		return fmt.Errorf("Token DB: Failed to Touch. %s", err)
+	}
+	query.Close()
+	return nil
+}
+
+//RevokeToken drop token of user from database.
+func RevokeToken(dbh *db.Handler, userID int, id int) error {
+	rows, err := dbh.Query("delete from api_tokens where api_token_id=$1 and user_id=$2 returning api_token_id", id, userID)
+	if err != nil {
+		return fmt.Errorf("Token DB: Failed to Revoke. %s", err)
+	}
+	found := rows.Next()
+	rows.Close()
+	if !found {
+		return errors.New("unknown token")
+	}
+
+	logger.Infof("User", "Revoked token %d of user %d", id, userID)
+	return nil
+}
+
+//RevokeTokens drop all tokens of user from database.
+func RevokeTokens(dbh *db.Handler, userID int) error {
+	query, err := dbh.Query("delete from api_tokens where user_id=$1", userID)
+	if err != nil {
+		return fmt.Errorf("Token DB: Failed to Revoke all. %s", err)
+	}
+	query.Close()
+
+	logger.Infof("User", "Revoked all tokens of user %d", userID)
+	return nil
+}
+
+func convertToken(rows *sql.Rows) *Token {
+	tok := new(Token)
+	var lastUsed *time.Time
+	rows.Scan(&tok.ID, &tok.UserID, &tok.Name, &tok.Scope, &tok.Hint, &tok.Hash, &tok.CreatedAt, &lastUsed)
+	if lastUsed != nil {
+		tok.LastUsed = *lastUsed
+	}
+	return tok
+}
+
+//TokensByUserID all tokens of user, newest first.
+func TokensByUserID(dbh *db.Handler, userID int) ([]*Token, error) {
+	rows, err := dbh.Query(`select api_token_id, user_id, name, scope, hint, token_hash, created_at, last_used_at
+		from api_tokens where user_id=$1 order by created_at desc`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("Token DB: Failed to seek tokens (TokensByUserID) %s", err)
+	}
+	defer rows.Close()
+
+	res := make([]*Token, 0)
+	for rows.Next() {
+		res = append(res, convertToken(rows))
+	}
+	return res, nil
+}
+
+//Authenticate seek token matching provided plain token. Tokens of disabled users are refused.
+func Authenticate(dbh *db.Handler, plain string) (*Token, error) {
+	rows, err := dbh.Query(`select t.api_token_id, t.user_id, t.name, t.scope, t.hint, t.token_hash, t.created_at, t.last_used_at
+		from api_tokens t join users u on u.user_id = t.user_id
+		where t.token_hash=$1 and u.enabled`, HashToken(plain))
+	if err != nil {
+		return nil, fmt.Errorf("Token DB: Failed to seek token (Authenticate) %s", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		return convertToken(rows), nil
+	}
+	return nil, errors.New("invalid token")
+}
@@ -17,9 +17,15 @@ type User struct {
 
 	// admin
 
-	NeedNewPassword bool
-	Enabled         bool
-	Admin           bool
+	NeedNewPassword       bool
+	NeedEmailVerification bool
+	Enabled               bool
+	Admin                 bool
+
+	// brute force protection
+
+	FailedLogins int       // consecutive failed logins.
+	LockedUntil  time.Time // logins are refused until then.
 }
 
 // courtesy to https://gowebexamples.com/password-hashing/
@@ -64,6 +70,80 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
+//IsLocked tell whether logins are refused for now.
+func (user *User) IsLocked(now time.Time) bool {
+	return now.Before(user.LockedUntil)
+}
+
+//LockoutDelay how long an account gets locked after failures consecutive failed logins.
+//First user_lockout_threshold failures are free, then delay doubles from user_lockout_base_seconds up to user_lockout_max_seconds.
+func LockoutDelay(failures int) time.Duration {
+	threshold := int(system.GetFloat("user_lockout_threshold", 5))
+	if failures < threshold {
+		return 0
+	}
+	base := time.Duration(system.GetFloat("user_lockout_base_seconds", 30)) * time.Second
+	max := time.Duration(system.GetFloat("user_lockout_max_seconds", 3600)) * time.Second
+
+	delay := base
+	for i := threshold; i < failures && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+//LoginFailed record a failed login and lock account accordingly.
+func (user *User) LoginFailed(now time.Time) {
+	user.FailedLogins++
+	user.lock(now)
+}
+
+//lock account according to current failed logins.
+func (user *User) lock(now time.Time) {
+	if delay := LockoutDelay(user.FailedLogins); delay > 0 {
+		user.LockedUntil = now.Add(delay)
+	}
+}
+
+//LoginSucceeded forget about previous failures.
+func (user *User) LoginSucceeded() {
+	user.FailedLogins = 0
+	user.LockedUntil = time.Time{}
+}
+
+//VerificationRequired tell whether new users must verify their email before being enabled.
+func VerificationRequired() bool {
+	return system.GetBool("user_verify_email", false)
+}
+
+//ResetTokenTTL how long a forgotten password token remains valid.
+func ResetTokenTTL() time.Duration {
+	return time.Duration(system.GetFloat("user_reset_token_minutes", 60)) * time.Minute
+}
+
+//VerifyTokenTTL how long an email verification token remains valid.
+func VerifyTokenTTL() time.Duration {
+	return time.Duration(system.GetFloat("user_verify_token_hours", 48)) * time.Hour
+}
+
+//RequireEmailVerification keep new user disabled until email gets verified.
+func (user *User) RequireEmailVerification() {
+	user.NeedEmailVerification = true
+	user.Enabled = false
+}
+
+//EmailVerified enable user as it would have been at signup, unless verification wasn't pending anymore.
+func (user *User) EmailVerified() {
+	if !user.NeedEmailVerification {
+		return
+	}
+	user.NeedEmailVerification = false
+	user.Enabled = system.GetBool("user_enabled_by_default", false)
+}
+
 //PrettyLastLogin stringify last login.
 func (user *User) PrettyLastLogin() string {
 	return user.LastLogin.Format(time.RFC3339)
@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"time"
 	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/logger"
+
+	"github.com/lib/pq"
 )
 
 //CheckMailAvailability returns true when email are unknown
@@ -50,7 +53,7 @@ func (user *User) Insert(dbh *db.Handler) error {
 	}
 	rows.Close()
 
-	log.Printf("User: Inserted user %d - %s", user.ID, user.Login)
+	logger.Infof("User", "Inserted user %d - %s", user.ID, user.Login)
 	return user.Update(dbh)
 }
 
@@ -62,7 +65,7 @@ func (user *User) Update(dbh *db.Handler) error {
 
 	js, err := user.dbjsonify()
 	if err != nil {
-		log.Printf("User: Failed to jsonify user data")
+		logger.Errorf("User", "Failed to jsonify user data")
 		return err
 	}
 
@@ -82,7 +85,7 @@ func (user *User) Update(dbh *db.Handler) error {
 		return fmt.Errorf("User DB: Failed to Update. %s", err)
 	}
 
-	log.Printf("User: Updated user %d - %s", user.ID, user.Login)
+	logger.Infof("User", "Updated user %d - %s", user.ID, user.Login)
 	return nil
 }
 
@@ -94,7 +97,7 @@ func (user *User) ShortUpdate(dbh *db.Handler) error {
 
 	js, err := user.dbjsonify()
 	if err != nil {
-		log.Printf("User: Failed to jsonify user data")
+		logger.Errorf("User", "Failed to jsonify user data")
 		return err
 	}
 
@@ -108,7 +111,7 @@ func (user *User) ShortUpdate(dbh *db.Handler) error {
 	}
 	query.Close()
 
-	log.Printf("User: Updated user's data %d - %s", user.ID, user.Login)
+	logger.Infof("User", "Updated user's data %d - %s", user.ID, user.Login)
 	return nil
 }
 
@@ -122,7 +125,7 @@ func (user *User) UpdatePassword(dbh *db.Handler) error {
 
 	js, err := user.dbjsonify()
 	if err != nil {
-		log.Printf("User: Failed to jsonify user data")
+		logger.Errorf("User", "Failed to jsonify user data")
 		return err
 	}
 
@@ -137,7 +140,7 @@ func (user *User) UpdatePassword(dbh *db.Handler) error {
 	}
 	query.Close()
 
-	log.Printf("User: Updated user's password %d - %s", user.ID, user.Login)
+	logger.Infof("User", "Updated user's password %d - %s", user.ID, user.Login)
 	return nil
 }
 
@@ -169,21 +172,101 @@ func (user *User) LogsIn(dbh *db.Handler, id string) error {
 	}
 	query.Close()
 
-	log.Printf("User: Updated Login date of user %d - %s", user.ID, user.Login)
+	// all sessions are kept, so that they can be dropped at once. Expired ones are forgotten.
+	query, err = dbh.Query("delete from user_sessions us where us.user_id=$1 and not exists (select 1 from http_sessions hs where hs.key = us.key)", user.ID)
+	if err != nil {
+		return fmt.Errorf("User DB: Failed to Update LogsIn. %s", err)
+	}
+	query.Close()
+
+	query, err = dbh.Query("insert into user_sessions(user_id, key) values($1, $2) on conflict do nothing", user.ID, id)
+	if err != nil {
+		return fmt.Errorf("User DB: Failed to Update LogsIn. %s", err)
+	}
+	query.Close()
+
+	logger.Infof("User", "Updated Login date of user %d - %s", user.ID, user.Login)
 	return nil
 }
 
-//Drop user from database
-func Drop(dbh *db.Handler, id int) error {
-	log.Printf("User: Dropped user %d", id)
+//UpdateLockout store failed logins and lockout of user.
+func (user *User) UpdateLockout(dbh *db.Handler) error {
+	if user.ID == 0 {
+		return errors.New("can't lock an unknown user")
+	}
+
+	var lockedUntil interface{}
+	if !user.LockedUntil.IsZero() {
+		lockedUntil = user.LockedUntil
+	}
+
+	query, err := dbh.Query("update users set failed_logins=$1, locked_until=$2 where user_id=$3", user.FailedLogins, lockedUntil, user.ID)
+	if err != nil {
+		return fmt.Errorf("User DB: Failed to UpdateLockout. %s", err)
+	}
+	query.Close()
+
+	return nil
+}
+
+//RecordLoginFailure count a failed login in database and lock account accordingly.
+//Counter is incremented by the database so concurrent failures are all accounted for.
+func (user *User) RecordLoginFailure(dbh *db.Handler, now time.Time) error {
+	if user.ID == 0 {
+		return errors.New("can't lock an unknown user")
+	}
+
+	rows, err := dbh.Query("update users set failed_logins=failed_logins+1 where user_id=$1 returning failed_logins", user.ID)
+	if err != nil {
+		return fmt.Errorf("User DB: Failed to RecordLoginFailure. %s", err)
+	}
+	if !rows.Next() {
+		rows.Close()
+		return fmt.Errorf("User DB: Failed to RecordLoginFailure. user %d not found", user.ID)
+	}
+	rows.Scan(&user.FailedLogins)
+	rows.Close()
 
-	query, err := dbh.Query("delete from http_sessions hs USING users usr where (usr.user_id = $1 AND usr.key = hs.key)", id)
+	user.lock(now)
+	if user.LockedUntil.IsZero() {
+		return nil
+	}
+
+	// never shorten a lock set by a concurrent failure.
+	query, err := dbh.Query("update users set locked_until=greatest(locked_until, $1) where user_id=$2", user.LockedUntil, user.ID)
+	if err != nil {
+		return fmt.Errorf("User DB: Failed to RecordLoginFailure. %s", err)
+	}
+	query.Close()
+
+	return nil
+}
+
+//DropSessions log user out of every session it opened.
+func DropSessions(dbh *db.Handler, id int) error {
+	query, err := dbh.Query("delete from http_sessions hs where hs.key in (select key from user_sessions where user_id = $1 union select key from users where user_id = $1)", id)
 	if err != nil {
 		return fmt.Errorf("User DB: Failed to Drop http_sessions. %s", err)
 	}
 	query.Close()
 
-	query, err = dbh.Query("delete from users where user_id=$1", id)
+	query, err = dbh.Query("delete from user_sessions where user_id=$1", id)
+	if err != nil {
+		return fmt.Errorf("User DB: Failed to Drop user_sessions. %s", err)
+	}
+	query.Close()
+	return nil
+}
+
+//Drop user from database
+func Drop(dbh *db.Handler, id int) error {
+	logger.Infof("User", "Dropped user %d", id)
+
+	if err := DropSessions(dbh, id); err != nil {
+		return err
+	}
+
+	query, err := dbh.Query("delete from users where user_id=$1", id)
 	if err != nil {
 		return fmt.Errorf("User DB: Failed to Drop Users. %s", err)
 	}
@@ -196,6 +279,7 @@ func Drop(dbh *db.Handler, id int) error {
 func convert(rows *sql.Rows) (usr *User) {
 
 	var js []byte
+	var lockedUntil pq.NullTime
 
 	usr = new(User)
 	rows.Scan(&usr.ID,
@@ -205,9 +289,14 @@ func convert(rows *sql.Rows) (usr *User) {
 		&usr.Enabled,
 		&usr.Admin,
 		&usr.LastLogin,
-		&js)
+		&js,
+		&usr.FailedLogins,
+		&lockedUntil)
 
 	usr.dbunjsonify(js)
+	if lockedUntil.Valid {
+		usr.LockedUntil = lockedUntil.Time
+	}
 
 	return
 }
@@ -215,7 +304,7 @@ func convert(rows *sql.Rows) (usr *User) {
 //ByLogin seek user by login
 func ByLogin(dbh *db.Handler, login string) (*User, error) {
 
-	rows, err := dbh.Query("select user_id, login, email, password, enabled, admin, last_login, data from users where login=$1", login)
+	rows, err := dbh.Query("select user_id, login, email, password, enabled, admin, last_login, data, failed_logins, locked_until from users where login=$1", login)
 	if err != nil {
 		return nil, fmt.Errorf("User DB: Failed to seek user by login (ByLogin) %s", err)
 	}
@@ -228,10 +317,26 @@ func ByLogin(dbh *db.Handler, login string) (*User, error) {
 	return nil, fmt.Errorf("failed to find requested user %s", login)
 }
 
+//ByEmail seek user by email
+func ByEmail(dbh *db.Handler, email string) (*User, error) {
+
+	rows, err := dbh.Query("select user_id, login, email, password, enabled, admin, last_login, data, failed_logins, locked_until from users where email=$1", email)
+	if err != nil {
+		return nil, fmt.Errorf("User DB: Failed to seek user by email (ByEmail) %s", err)
+	}
+	for rows.Next() {
+		user := convert(rows)
+		rows.Close()
+		return user, nil
+	}
+
+	return nil, fmt.Errorf("failed to find requested user %s", email)
+}
+
 //ByID seek user by login
 func ByID(dbh *db.Handler, id int) (*User, error) {
 
-	rows, err := dbh.Query("select user_id, login, email, password, enabled, admin, last_login, data from users where user_id=$1", id)
+	rows, err := dbh.Query("select user_id, login, email, password, enabled, admin, last_login, data, failed_logins, locked_until from users where user_id=$1", id)
 	if err != nil {
 		return nil, fmt.Errorf("User DB: Failed to seek user by login (ByID) %s", err)
 	}
@@ -245,11 +350,12 @@ func ByID(dbh *db.Handler, id int) (*User, error) {
 }
 
 //All return a listing of all user
-func All(dbh *db.Handler) (res []*User) {
+func All(dbh *db.Handler) (res []*User, err error) {
 
-	rows, err := dbh.Query("select user_id, login, email, password, enabled, admin, last_login, data from users")
+	rows, err := dbh.Query("select user_id, login, email, password, enabled, admin, last_login, data, failed_logins, locked_until from users")
 	if err != nil {
-		log.Fatalf("User DB: Failed to return a listing of all user (All). %s", err)
+		logger.Errorf("User", "Failed to return a listing of all user (All). %s", err)
+		return nil, err
 	}
 	for rows.Next() {
 		user := convert(rows)
@@ -258,16 +364,18 @@ func All(dbh *db.Handler) (res []*User) {
 	}
 	rows.Close()
 
-	return res
+	return res, nil
 }
 
 type dbUser struct {
-	NeedNewPassword bool
+	NeedNewPassword       bool
+	NeedEmailVerification bool
 }
 
 func (user *User) dbjsonify() (res []byte, err error) {
 	var tmp dbUser
 	tmp.NeedNewPassword = user.NeedNewPassword
+	tmp.NeedEmailVerification = user.NeedEmailVerification
 	return json.Marshal(tmp)
 }
 
@@ -279,5 +387,6 @@ func (user *User) dbunjsonify(fromJSON []byte) (err error) {
 	}
 
 	user.NeedNewPassword = db.NeedNewPassword
+	user.NeedEmailVerification = db.NeedEmailVerification
 	return nil
 }
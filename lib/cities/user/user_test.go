@@ -1 +1,133 @@
 package user
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"upsilon_cities_go/lib/misc/config/system"
+)
+
+func TestToken(t *testing.T) {
+	if _, _, err := NewToken(1, "bot", "admin"); err == nil {
+		t.Errorf("Token with unknown scope should be refused")
+		return
+	}
+	if _, _, err := NewToken(1, "  ", TokenRead); err == nil {
+		t.Errorf("Token without name should be refused")
+		return
+	}
+
+	tok, plain, err := NewToken(1, "bot", TokenRead)
+	if err != nil {
+		t.Errorf("Failed to generate token: %s", err)
+		return
+	}
+	if tok.Hash != HashToken(plain) || strings.Contains(tok.Hash, plain) {
+		t.Errorf("Token should only keep hash of plain token")
+		return
+	}
+	if !strings.HasPrefix(plain, tok.Hint) {
+		t.Errorf("Token hint %s should start plain token", tok.Hint)
+		return
+	}
+
+	_, other, _ := NewToken(1, "bot", TokenRead)
+	if other == plain {
+		t.Errorf("Tokens should be random")
+		return
+	}
+
+	if !tok.Allows("GET") || tok.Allows("POST") || tok.Allows("DELETE") {
+		t.Errorf("Read only token should only allow safe methods")
+		return
+	}
+	tok.Scope = TokenFull
+	if !tok.Allows("POST") {
+		t.Errorf("Full token should allow any method")
+		return
+	}
+}
+
+func TestLockout(t *testing.T) {
+	now := time.Now().UTC()
+	usr := New()
+
+	for i := 0; i < 4; i++ {
+		usr.LoginFailed(now)
+	}
+	if usr.IsLocked(now) {
+		t.Errorf("First failures shouldn't lock account")
+		return
+	}
+
+	usr.LoginFailed(now)
+	if !usr.IsLocked(now) || usr.IsLocked(now.Add(30*time.Second)) {
+		t.Errorf("Account should be locked 30s, until %s", usr.LockedUntil)
+		return
+	}
+
+	usr.LoginFailed(now)
+	if usr.LockedUntil != now.Add(time.Minute) {
+		t.Errorf("Lockout should double, locked until %s", usr.LockedUntil)
+		return
+	}
+
+	if LockoutDelay(100) != time.Hour {
+		t.Errorf("Lockout should be capped to an hour, got %s", LockoutDelay(100))
+		return
+	}
+
+	usr.LoginSucceeded()
+	if usr.FailedLogins != 0 || usr.IsLocked(now) {
+		t.Errorf("Successful login should reset lockout")
+	}
+}
+
+func TestMailToken(t *testing.T) {
+	if _, _, err := NewMailToken(1, "login", time.Hour); err == nil {
+		t.Errorf("Mail token with unknown purpose should be refused")
+		return
+	}
+
+	tok, plain, err := NewMailToken(1, MailTokenReset, time.Hour)
+	if err != nil {
+		t.Errorf("Failed to generate mail token: %s", err)
+		return
+	}
+	if tok.Hash != HashToken(plain) || tok.Hash == plain {
+		t.Errorf("Mail token should only keep hash of plain token")
+		return
+	}
+	if tok.ExpiresAt.Before(time.Now().UTC().Add(59 * time.Minute)) {
+		t.Errorf("Mail token should expire in an hour, expires at %s", tok.ExpiresAt)
+		return
+	}
+}
+
+func TestEmailVerification(t *testing.T) {
+	usr := New()
+	usr.EmailVerified()
+	if usr.NeedEmailVerification {
+		t.Errorf("Verifying an already verified user shouldn't change anything")
+		return
+	}
+
+	usr.Enabled = true
+	usr.RequireEmailVerification()
+	if usr.Enabled || !usr.NeedEmailVerification {
+		t.Errorf("User should be disabled until email gets verified")
+		return
+	}
+
+	usr.EmailVerified()
+	if usr.NeedEmailVerification || usr.Enabled != system.GetBool("user_enabled_by_default", false) {
+		t.Errorf("Verified user should be enabled as configured")
+		return
+	}
+
+	usr.Enabled = false
+	usr.EmailVerified()
+	if usr.Enabled {
+		t.Errorf("Verifying again shouldn't enable a disabled user")
+	}
+}
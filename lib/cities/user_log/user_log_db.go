@@ -3,10 +3,10 @@ package user_log
 import (
 	"errors"
 	"fmt"
-	"log"
 	"time"
 	"upsilon_cities_go/lib/cities/corporation_manager"
 	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/logger"
 
 	"github.com/lib/pq"
 )
@@ -62,28 +62,32 @@ func MarkRead(dbh *db.Handler, ids []int) error {
 }
 
 //LastMessages get last unacknowledged messages
-func LastMessages(dbh *db.Handler, userID int) (res []UserLog) {
+func LastMessages(dbh *db.Handler, userID int) (res []UserLog, err error) {
 
 	rows, err := dbh.Query("select user_log_id, user_id, message, gravity, inserted, acknowledged != NULL as ack from user_logs where user_id=$1 order by user_log_id desc", userID)
 	if err != nil {
-		log.Fatalf("User_Log DB : Failed to select last unacknowledged messages (LastMessages) : %s ", err)
+		logger.Errorf("UserLog", "Failed to select last unacknowledged messages (LastMessages) : %s", err)
+		return nil, err
 	}
+	defer rows.Close()
 	for rows.Next() {
 		var ul UserLog
 		rows.Scan(&ul.ID, &ul.UserID, &ul.Message, &ul.Gravity, &ul.Inserted, &ul.Acknowledged)
 		res = append(res, ul)
 	}
 
-	return res
+	return res, nil
 }
 
 //Since get last unacknowledged messages
-func Since(dbh *db.Handler, userID int, date time.Time) (res []UserLog) {
+func Since(dbh *db.Handler, userID int, date time.Time) (res []UserLog, err error) {
 
 	rows, err := dbh.Query("select user_log_id, user_id, message, gravity, inserted, acknowledged != NULL as ack from user_logs where user_id=$1 and inserted > $2 order by user_log_id desc", userID, date)
 	if err != nil {
-		log.Fatalf("User_Log DB : Failed to select last unacknowledged (Since) messages : %s ", err)
+		logger.Errorf("UserLog", "Failed to select last unacknowledged (Since) messages : %s", err)
+		return nil, err
 	}
+	defer rows.Close()
 
 	for rows.Next() {
 		var ul UserLog
@@ -91,5 +95,5 @@ func Since(dbh *db.Handler, userID int, date time.Time) (res []UserLog) {
 		res = append(res, ul)
 	}
 
-	return res
+	return res, nil
 }
@@ -4,7 +4,6 @@ import (
 	"database/sql"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
 	"runtime/debug"
@@ -13,6 +12,8 @@ import (
 	"time"
 
 	"upsilon_cities_go/lib/misc/config/system"
+	"upsilon_cities_go/lib/misc/logger"
+	"upsilon_cities_go/lib/misc/metrics"
 
 	// needed for postgres driver
 	"github.com/lib/pq"
@@ -28,6 +29,29 @@ type Handler struct {
 
 var testMode bool
 
+var (
+	connections   = metrics.NewCounter("upsilon_db_connections_total", "Database handlers opened.")
+	queries       = metrics.NewCounter("upsilon_db_queries_total", "Queries executed, by statement and outcome.", "statement", "outcome")
+	queryDuration = metrics.NewHistogram("upsilon_db_query_duration_seconds", "Duration of queries, by statement.", metrics.DefaultBuckets, "statement")
+)
+
+//observe query duration and outcome.
+func observe(query string, start time.Time, err error) {
+	statement := "other"
+	if fields := strings.Fields(query); len(fields) > 0 {
+		switch verb := strings.ToLower(fields[0]); verb {
+		case "select", "insert", "update", "delete", "with":
+			statement = verb
+		}
+	}
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	queries.Inc(statement, outcome)
+	queryDuration.Since(start, statement)
+}
+
 //Raw return raw db pointer.
 func (dbh *Handler) Raw() *sql.DB {
 	return dbh.db
@@ -50,11 +74,12 @@ func New() *Handler {
 
 	errPing := db.Ping()
 	if err, ok := errPing.(*pq.Error); ok {
-		log.Fatalf("DB: Database failed to be connected: %s", err)
+		logger.Fatalf("DB", "Database failed to be connected: %s", err)
 	} else {
-		log.Printf("DB: Successfully connected to : %s %s", system.Get("db_host", ""), system.Get("db_name", ""))
+		logger.Debugf("DB", "Successfully connected to : %s %s", system.Get("db_host", ""), system.Get("db_name", ""))
 	}
 
+	connections.Inc()
 	handler.db = db
 	handler.open = true
 	handler.Name = system.Get("db_name", "")
@@ -72,11 +97,12 @@ func NewTest() *Handler {
 
 	errPing := db.Ping()
 	if err, ok := errPing.(*pq.Error); ok {
-		log.Fatalf("DB: Database failed to be connected: %s", err)
+		logger.Fatalf("DB", "Database failed to be connected: %s", err)
 	} else {
-		log.Printf("DB: Successfully connected to : %s %s", system.Get("db_host", ""), system.Get("db_name", ""))
+		logger.Debugf("DB", "Successfully connected to : %s %s", system.Get("db_host", ""), system.Get("db_name", ""))
 	}
 
+	connections.Inc()
 	handler.db = db
 	handler.open = true
 	handler.Name = system.Get("db_name", "")
@@ -84,13 +110,42 @@ func NewTest() *Handler {
 	return handler
 }
 
+//Open Create a new handler for database like New() does, but reports connection failure instead of aborting.
+func Open() (*Handler, error) {
+	name := system.Get("db_name", "")
+	if testMode {
+		name = system.Get("db_test_name", "")
+	}
+	dbinfo := fmt.Sprintf("user=%s password=%s dbname=%s sslmode=disable host=%s port=%s",
+		system.Get("db_user", ""), system.Get("db_password", ""), name, system.Get("db_host", ""), system.Get("db_port", ""))
+	db, err := sql.Open("postgres", dbinfo)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	connections.Inc()
+	handler := new(Handler)
+	handler.db = db
+	handler.open = true
+	handler.Name = system.Get("db_name", "")
+	handler.Test = testMode
+	return handler, nil
+}
+
 // Exec executes provided query and check if it's correctly executed or not.
 // Abort app if not.
 // DONT FORGET TO CLOSE RESULT (using result.Close())
 func (dbh *Handler) Exec(query string) (result *sql.Rows, err error) {
 	dbh.CheckState()
-	log.Printf("DB: About to Exec: %s", query)
+	logger.Debugf("DB", "About to Exec: %s", query)
+	start := time.Now()
 	rtnquery, err := dbh.db.Query(query)
+	observe(query, start, err)
 	errorCheck(query, err)
 	return rtnquery, err
 }
@@ -99,8 +154,10 @@ func (dbh *Handler) Exec(query string) (result *sql.Rows, err error) {
 // DONT FORGET TO CLOSE RESULT (using result.Close())
 func (dbh *Handler) Query(format string, a ...interface{}) (result *sql.Rows, err error) {
 	dbh.CheckState()
-	log.Printf("DB: About to Query: %s", format)
+	logger.Debugf("DB", "About to Query: %s", format)
+	start := time.Now()
 	rtnquery, err := dbh.db.Query(format, a...)
+	observe(format, start, err)
 	errorCheck(format, err, a...)
 	return rtnquery, err
 }
@@ -109,12 +166,12 @@ func (dbh *Handler) Query(format string, a ...interface{}) (result *sql.Rows, er
 func (dbh *Handler) CheckState() {
 	if !dbh.open {
 		debug.PrintStack()
-		log.Fatal("DB: Can't use this connection, it's been closed")
+		logger.Fatalf("DB", "Can't use this connection, it's been closed")
 	}
 	err := dbh.db.Ping()
 	if err != nil {
 		debug.PrintStack()
-		log.Fatalf("DB: Can't use this connection, an error occured: %s", err)
+		logger.Fatalf("DB", "Can't use this connection, an error occured: %s", err)
 	}
 }
 
@@ -124,7 +181,7 @@ func (dbh *Handler) Close() {
 		dbh.open = false
 		defer dbh.db.Close()
 	} else {
-		log.Print("DB: Already Closed")
+		logger.Infof("DB", "Already Closed")
 	}
 }
 
@@ -132,13 +189,13 @@ func (dbh *Handler) Close() {
 func errorCheck(query string, err error, a ...interface{}) bool {
 
 	if err != nil {
-		log.Printf("DB: Failed to execute query: %s", query)
-		log.Printf("DB: With params: %v", a)
-		log.Printf("DB: With error: %s", err)
+		logger.Errorf("DB", "Failed to execute query: %s", query)
+		logger.Errorf("DB", "With params: %v", a)
+		logger.Errorf("DB", "With error: %s", err)
 		// fatal aborts app
 		debug.PrintStack()
 		if system.GetBool("db_errors_arefatal", false) {
-			log.Fatalf("DB: Aborting: %s", err)
+			logger.Fatalf("DB", "Aborting: %s", err)
 		}
 		return true
 	}
@@ -150,7 +207,7 @@ func errorCheck(query string, err error, a ...interface{}) bool {
 func CheckVersion(dbh *Handler) {
 
 	dbh.CheckState()
-	log.Printf("DB: About to Query: select * from versions")
+	logger.Debugf("DB", "About to Query: select * from versions")
 	result, err := dbh.db.Query("select applied, file from versions order by applied DESC;")
 
 	// ensure last migration date is way in the past.
@@ -159,19 +216,19 @@ func CheckVersion(dbh *Handler) {
 		// version table doesn't exist: create database.
 		f, ferr := os.Open(system.MakePath(system.Get("db_schema", "db/schema.sql")))
 		if ferr != nil {
-			log.Fatalln("DB: No schema file found can't initialize database")
+			logger.Fatalf("DB", "No schema file found can't initialize database")
 		}
 		schema, ferr := ioutil.ReadAll(f)
 		if ferr != nil {
-			log.Fatalln("DB: Schema found but unable to read it all.")
+			logger.Fatalf("DB", "Schema found but unable to read it all.")
 		}
 		f.Close()
 
 		q, err := dbh.db.Query(string(schema))
 
 		if err != nil {
-			log.Printf("DB: While executing: %s", string(schema))
-			log.Fatalf("DB: Unable to apply schema %s ", err)
+			logger.Errorf("DB", "While executing: %s", string(schema))
+			logger.Fatalf("DB", "Unable to apply schema %s", err)
 		}
 		q.Close()
 
@@ -179,8 +236,8 @@ func CheckVersion(dbh *Handler) {
 
 		q, err = dbh.db.Query("insert into versions(file) values ('schema.sql');")
 		if err != nil {
-			log.Printf("DB: While executing: %s", "insert into versions(file) values ('schema.sql');")
-			log.Fatalf("DB: Unable to create versions table %s ", err)
+			logger.Errorf("DB", "While executing: %s", "insert into versions(file) values ('schema.sql');")
+			logger.Fatalf("DB", "Unable to create versions table %s", err)
 		}
 
 		applied_migrations["schema.sql"] = time.Now().UTC()
@@ -188,7 +245,7 @@ func CheckVersion(dbh *Handler) {
 		// expect schema to be same as all migrations ... ;)
 		err = filepath.Walk(system.MakePath(system.Get("db_migrations", "db/migrations")), func(path string, info os.FileInfo, err error) error {
 			if err != nil {
-				log.Fatalf("DB: prevent panic by handling failure accessing a path %q: %v\n", system.Get("db_migrations", "db/migrations"), err)
+				logger.Fatalf("DB", "prevent panic by handling failure accessing a path %q: %v", system.Get("db_migrations", "db/migrations"), err)
 				return err
 			}
 
@@ -196,7 +253,7 @@ func CheckVersion(dbh *Handler) {
 
 				query, err := dbh.Query("insert into versions(file) values ($1);", path)
 				if err != nil {
-					log.Fatalf("DB: Failed to insert Version (CheckVersion) : %s ", err)
+					logger.Fatalf("DB", "Failed to insert Version (CheckVersion) : %s", err)
 					return err
 				}
 				query.Close()
@@ -222,10 +279,10 @@ func CheckVersion(dbh *Handler) {
 
 	// thus we keep order here ;)
 	var orderedFiles []string
-	log.Printf("DB: Attempting to find migrations in: %s", system.Get("db_migrations", "db/migrations"))
+	logger.Infof("DB", "Attempting to find migrations in: %s", system.Get("db_migrations", "db/migrations"))
 	err = filepath.Walk(system.MakePath(system.Get("db_migrations", "db/migrations")), func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			log.Fatalf("DB: prevent panic by handling failure accessing a path %q: %v\n", system.MakePath(system.Get("db_migrations", "db/migrations")), err)
+			logger.Fatalf("DB", "prevent panic by handling failure accessing a path %q: %v", system.MakePath(system.Get("db_migrations", "db/migrations")), err)
 			return err
 		}
 		if strings.HasSuffix(info.Name(), ".sql") {
@@ -235,10 +292,10 @@ func CheckVersion(dbh *Handler) {
 			_, err := time.Parse("200601021504", dateString)
 
 			if err != nil {
-				log.Fatalf("DB: One one the migration files: %s, has an invalid format. Expected YYYYMMDDHHMM_name.sql", migrationFilename)
+				logger.Fatalf("DB", "One one the migration files: %s, has an invalid format. Expected YYYYMMDDHHMM_name.sql", migrationFilename)
 				return err
 			}
-			log.Printf("DB: Read Migration file: %s", migrationFilename)
+			logger.Infof("DB", "Read Migration file: %s", migrationFilename)
 
 			orderedFiles = append(orderedFiles, path)
 
@@ -255,38 +312,73 @@ func CheckVersion(dbh *Handler) {
 
 	for _, k := range orderedFiles {
 		if _, found := applied_migrations[k]; !found {
-			log.Printf("DB: Applying migration: %s", k)
+			logger.Infof("DB", "Applying migration: %s", k)
 			f, ferr := os.Open(k)
 			if ferr != nil {
-				log.Fatalf("DB: Unable to open migration file %s", k)
+				logger.Fatalf("DB", "Unable to open migration file %s", k)
 			}
 
 			migration, ferr := ioutil.ReadAll(f)
 			if ferr != nil {
-				log.Fatalf("DB: Unable to read migration file %s", k)
+				logger.Fatalf("DB", "Unable to read migration file %s", k)
 			}
 
-			log.Printf("DB: Applying migration: %s", string(migration))
+			logger.Infof("DB", "Applying migration: %s", string(migration))
 			q, err := dbh.db.Query(string(migration))
 
 			if err != nil {
-				log.Fatalf("DB: Unable to apply migration file %s: %s ", k, err)
+				logger.Fatalf("DB", "Unable to apply migration file %s: %s", k, err)
 			}
 			q.Close()
 
 			query, err := dbh.Query("insert into versions(file) values ($1);", k)
 			if err != nil {
-				log.Fatalf("insert into versions(file) values (%s) : %s ", k, err)
+				logger.Fatalf("DB", "insert into versions(file) values (%s) : %s", k, err)
 			}
 			query.Close()
 		}
 	}
-	log.Printf("DB: DB is up to date ! ")
+	logger.Infof("DB", "DB is up to date !")
+}
+
+//PendingMigrations lists migration files CheckVersion would apply, without applying them nor aborting on failure.
+func PendingMigrations(dbh *Handler) (pending []string, err error) {
+	dbh.CheckState()
+	start := time.Now()
+	result, err := dbh.db.Query("select file from versions;")
+	observe("select", start, err)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]bool)
+	for result.Next() {
+		var file string
+		result.Scan(&file)
+		applied[file] = true
+	}
+	result.Close()
+
+	err = filepath.Walk(system.MakePath(system.Get("db_migrations", "db/migrations")), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(info.Name(), ".sql") && !applied[path] {
+			pending = append(pending, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(pending)
+	return pending, nil
 }
 
 //FlushDatabase Clears everyhting from database and reload it.
 func FlushDatabase(dbh *Handler) {
-	log.Printf("DB: Flushing Database %s", dbh.Name)
+	logger.Infof("DB", "Flushing Database %s", dbh.Name)
 	flush := fmt.Sprintf(`DROP SCHEMA public CASCADE;
 						  CREATE SCHEMA public;
 						  GRANT ALL ON SCHEMA public TO %s;
@@ -295,7 +387,7 @@ func FlushDatabase(dbh *Handler) {
 	query, err := dbh.Exec(flush)
 
 	if err != nil {
-		log.Fatalf("DB: Unable to Flushing Database %s : %s ", dbh.Name, err)
+		logger.Fatalf("DB", "Unable to Flushing Database %s : %s", dbh.Name, err)
 	}
 
 	query.Close()
@@ -305,30 +397,30 @@ func FlushDatabase(dbh *Handler) {
 //ApplySeed seek a seed and apply it to db.
 func ApplySeed(dbh *Handler, seed string) error {
 	// thus we keep order here ;)
-	log.Printf("DB: Attempting to find Seed %s in: %s", seed, system.Get("db_seeds", "db/seeds"))
+	logger.Infof("DB", "Attempting to find Seed %s in: %s", seed, system.Get("db_seeds", "db/seeds"))
 	return filepath.Walk(system.MakePath(system.Get("db_seeds", "db/seeds")), func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			log.Fatalf("DB: prevent panic by handling failure accessing a path %q: %v\n", system.Get("db_seeds", "db/seeds"), err)
+			logger.Fatalf("DB", "prevent panic by handling failure accessing a path %q: %v", system.Get("db_seeds", "db/seeds"), err)
 			return err
 		}
 		if strings.HasSuffix(info.Name(), ".sql") && strings.Contains(info.Name(), seed) {
 
-			log.Printf("DB: Applying seed: %s", info.Name())
+			logger.Infof("DB", "Applying seed: %s", info.Name())
 			f, ferr := os.Open(path)
 			if ferr != nil {
-				log.Fatalf("DB: Unable to open seed file %s", path)
+				logger.Fatalf("DB", "Unable to open seed file %s", path)
 			}
 
 			seedContent, ferr := ioutil.ReadAll(f)
 			if ferr != nil {
-				log.Fatalf("DB: Unable to read seed file %s", path)
+				logger.Fatalf("DB", "Unable to read seed file %s", path)
 			}
 
-			log.Printf("DB: Applying seed: %s", string(seedContent))
+			logger.Infof("DB", "Applying seed: %s", string(seedContent))
 			q, err := dbh.db.Query(string(seedContent))
 
 			if err != nil {
-				log.Fatalf("DB: Unable to apply seed file %s: %s ", path, err)
+				logger.Fatalf("DB", "Unable to apply seed file %s: %s", path, err)
 			}
 			q.Close()
 			return nil
@@ -7,6 +7,11 @@
 //  * CALL is a cast with an implicit channel waiting for completion of the function ... dont be abused by it.
 package actor
 
+import (
+	"sync/atomic"
+	"time"
+)
+
 //Actor contains structural informations to build and work with an actor.
 type Actor struct {
 	Running     bool
@@ -15,6 +20,7 @@ type Actor struct {
 	Quitc       chan bool
 	EndCallback chan<- End
 	Loop        func()
+	queued      int32 // functions sent but not yet picked up.
 }
 
 //End is send by endCallback to notify as to why an Actor ended.
@@ -55,7 +61,11 @@ func New(id int, end chan<- End) *Actor {
 // If you want a reply, dont forget to provide your function a chan
 // If you do so, DONT FORGET TO call defer close(<your chan>)
 func (a *Actor) Cast(fn func()) {
-	a.Actionc <- fn
+	atomic.AddInt32(&a.queued, 1)
+	a.Actionc <- func() {
+		atomic.AddInt32(&a.queued, -1)
+		fn()
+	}
 }
 
 //Call send and wait for end of execution.
@@ -68,16 +78,47 @@ func (a *Actor) Call(fn func()) {
 		exited <- true
 	}
 
-	a.Actionc <- fn2
+	a.Cast(fn2)
 
 	<-exited
 }
 
+//Ping tell whether actor executes a function within timeout, without blocking caller any longer.
+//Nothing is left waiting on a stuck actor once timeout is reached.
+func (a *Actor) Ping(timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	done := make(chan bool, 1)
+	atomic.AddInt32(&a.queued, 1)
+	select {
+	case a.Actionc <- func() {
+		atomic.AddInt32(&a.queued, -1)
+		done <- true
+	}:
+	case <-timer.C:
+		atomic.AddInt32(&a.queued, -1)
+		return false
+	}
+
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
 //ID of the Actor
 func (a *Actor) ID() int {
 	return a.Identifier
 }
 
+//Queued number of functions waiting to be executed by the actor.
+func (a *Actor) Queued() int {
+	return int(atomic.LoadInt32(&a.queued))
+}
+
 //IsRunning Tell whether actor is running or not.
 func (a *Actor) IsRunning() bool {
 	return a.Running
@@ -1 +1,54 @@
 package actor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueued(t *testing.T) {
+	a := New(1, make(chan End, 1))
+	a.Start()
+
+	release := make(chan bool)
+	a.Cast(func() { <-release })
+	go a.Cast(func() {})
+
+	deadline := time.Now().Add(time.Second)
+	for a.Queued() != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if a.Queued() != 1 {
+		t.Errorf("Expected 1 queued function, got %d", a.Queued())
+	}
+
+	close(release)
+	a.Call(func() {})
+	if a.Queued() != 0 {
+		t.Errorf("Expected mailbox to be drained, got %d", a.Queued())
+	}
+}
+
+func TestPing(t *testing.T) {
+	a := New(1, make(chan End, 1))
+	a.Start()
+
+	if !a.Ping(time.Second) {
+		t.Errorf("Expected idle actor to reply to ping")
+	}
+
+	release := make(chan bool)
+	a.Cast(func() { <-release })
+	for i := 0; i < 10; i++ {
+		if a.Ping(10 * time.Millisecond) {
+			t.Errorf("Expected busy actor to miss ping")
+		}
+	}
+	if a.Queued() != 0 {
+		t.Errorf("Expected missed pings not to stay queued, got %d", a.Queued())
+	}
+	close(release)
+
+	if !a.Ping(time.Second) {
+		t.Errorf("Expected released actor to reply to ping")
+	}
+}
@@ -3,9 +3,9 @@ package gameplay
 import (
 	"encoding/json"
 	"io/ioutil"
-	"log"
 	"os"
 	"upsilon_cities_go/lib/misc/config/system"
+	"upsilon_cities_go/lib/misc/logger"
 )
 
 var configuration map[string]interface{}
@@ -14,12 +14,12 @@ var configuration map[string]interface{}
 func LoadConf() {
 	f, ferr := os.Open(system.MakePath("config/gameplay.json"))
 	if ferr != nil {
-		log.Fatalf("GameplayConf: No Gameplay conf data file present: %s", ferr)
+		logger.Fatalf("GameplayConf", "No Gameplay conf data file present: %s", ferr)
 	}
 
 	nameJSON, ferr := ioutil.ReadAll(f)
 	if ferr != nil {
-		log.Fatalf("GameplayConf: No Gameplay conf data file found but unable to read it all: %s", ferr)
+		logger.Fatalf("GameplayConf", "No Gameplay conf data file found but unable to read it all: %s", ferr)
 	}
 
 	f.Close()
@@ -36,7 +36,7 @@ func Get(name string, def string) string {
 	if v, found := configuration[name]; found {
 		return v.(string)
 	}
-	log.Printf("Gameplay: Attempting to use unknown rule: %s with default value: %s", name, def)
+	logger.Infof("Gameplay", "Attempting to use unknown rule: %s with default value: %s", name, def)
 	return def
 }
 
@@ -48,7 +48,7 @@ func GetFloat(name string, def float64) float64 {
 	if v, found := configuration[name]; found {
 		return v.(float64)
 	}
-	log.Printf("Gameplay: Attempting to use unknown rule: %s with default value: %f", name, def)
+	logger.Infof("Gameplay", "Attempting to use unknown rule: %s with default value: %f", name, def)
 	return def
 }
 
@@ -60,7 +60,7 @@ func GetInt(name string, def int) int {
 	if v, found := configuration[name]; found {
 		return int(v.(float64))
 	}
-	log.Printf("Gameplay: Attempting to use unknown rule: %s with default value: %d", name, def)
+	logger.Infof("Gameplay", "Attempting to use unknown rule: %s with default value: %d", name, def)
 	return def
 }
 
@@ -72,6 +72,27 @@ func GetBool(name string, def bool) bool {
 	if v, found := configuration[name]; found {
 		return v.(bool)
 	}
-	log.Printf("Gameplay: Attempting to use unknown rule: %s with default value: %v", name, def)
+	logger.Infof("Gameplay", "Attempting to use unknown rule: %s with default value: %v", name, def)
 	return def
 }
+
+//GetObject seeks value in configuration for provided key and fills target with it. Returns false when not found.
+func GetObject(name string, target interface{}) bool {
+	if configuration == nil {
+		return false
+	}
+	if v, found := configuration[name]; found {
+		data, err := json.Marshal(v)
+		if err != nil {
+			logger.Errorf("Gameplay", "Unable to read rule: %s : %s", name, err)
+			return false
+		}
+		if err = json.Unmarshal(data, target); err != nil {
+			logger.Errorf("Gameplay", "Unable to read rule: %s : %s", name, err)
+			return false
+		}
+		return true
+	}
+	logger.Infof("Gameplay", "Attempting to use unknown rule: %s with default value", name)
+	return false
+}
@@ -4,10 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"upsilon_cities_go/lib/misc/logger"
 )
 
 var configuration map[string]interface{}
@@ -16,12 +17,12 @@ var configuration map[string]interface{}
 func LoadConf() {
 	f, ferr := os.Open(MakePath("config/system.json"))
 	if ferr != nil {
-		log.Fatalf("SystemConf: No System conf data file present: %s", ferr)
+		logger.Fatalf("SystemConf", "No System conf data file present: %s", ferr)
 	}
 
 	nameJSON, ferr := ioutil.ReadAll(f)
 	if ferr != nil {
-		log.Fatalf("SystemConf: No System conf data file found but unable to read it all: %s", ferr)
+		logger.Fatalf("SystemConf", "No System conf data file found but unable to read it all: %s", ferr)
 	}
 
 	f.Close()
@@ -46,8 +47,9 @@ func GetFloat(name string, def float32) float32 {
 	if configuration == nil {
 		return def
 	}
-	if v, found := configuration[name]; found {
-		return v.(float32)
+	// json numbers are decoded as float64.
+	if v, found := configuration[name].(float64); found {
+		return float32(v)
 	}
 	return def
 }
@@ -63,6 +65,22 @@ func GetBool(name string, def bool) bool {
 	return def
 }
 
+//GetStringMap seeks an object of strings in configuration for provided key
+func GetStringMap(name string) map[string]string {
+	res := make(map[string]string)
+	if configuration == nil {
+		return res
+	}
+	if v, found := configuration[name].(map[string]interface{}); found {
+		for k, val := range v {
+			if str, ok := val.(string); ok {
+				res[k] = str
+			}
+		}
+	}
+	return res
+}
+
 var root string
 var rootSlash string
 
@@ -87,21 +105,41 @@ func Root() string {
 
 		// keep what's necessary up until last inserted is upsilon_cities_go
 		nsubs := make([]string, 0)
+		found := false
 
 		for _, v := range subs {
 			nsubs = append(nsubs, v)
 			if v == "upsilon_cities_go" {
+				found = true
 				break
 			}
 
 		}
 
 		rootSlash = strings.Join(nsubs, "/")
+		if !found {
+			// checked out under another name, seek module root instead.
+			rootSlash = moduleRoot(dir)
+		}
 		root = filepath.FromSlash(rootSlash)
 	}
 	return root
 }
 
+// moduleRoot closest parent of dir holding go.mod, dir itself when there's none.
+func moduleRoot(dir string) string {
+	for current := dir; ; {
+		if _, err := os.Stat(filepath.FromSlash(current + "/go.mod")); err == nil {
+			return current
+		}
+		parent := path.Dir(current)
+		if parent == current {
+			return dir
+		}
+		current = parent
+	}
+}
+
 //MakePath create a path from root to target.
 func MakePath(to string) string {
 	Root()
@@ -4,12 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"math/rand"
 	"os"
 	"path/filepath"
 	"strings"
 	"upsilon_cities_go/lib/misc/config/system"
+	"upsilon_cities_go/lib/misc/logger"
 )
 
 var nameList map[string]*WordPart
@@ -69,19 +69,19 @@ func Load() {
 
 	filepath.Walk(system.MakePath(system.Get("data_names", "data/names")), func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			log.Fatalf("Name Generator: prevent panic by handling failure accessing a path %q: %v\n", system.MakePath(system.Get("data_names", "data/names")), err)
+			logger.Fatalf("NameGenerator", "prevent panic by handling failure accessing a path %q: %v", system.MakePath(system.Get("data_names", "data/names")), err)
 			return err
 		}
 
 		if strings.HasSuffix(info.Name(), ".json") {
 			f, ferr := os.Open(path)
 			if ferr != nil {
-				log.Fatalln("Name Generator: No Name data file present")
+				logger.Fatalf("NameGenerator", "No Name data file present")
 			}
 
 			nameJSON, ferr := ioutil.ReadAll(f)
 			if ferr != nil {
-				log.Fatalln("Name Generator: Data file found but unable to read it all.")
+				logger.Fatalf("NameGenerator", "Data file found but unable to read it all.")
 			}
 
 			f.Close()
@@ -90,17 +90,22 @@ func Load() {
 			json.Unmarshal(nameJSON, &names)
 
 			filename := strings.TrimSuffix(info.Name(), filepath.Ext(info.Name()))
-			log.Printf("Name Generator: Adding %s to name generator", filename)
+			logger.Infof("NameGenerator", "Adding %s to name generator", filename)
 			nameList[filename] = names
 		}
 
 		return nil
 	})
 
-	log.Printf("Name Generator: Loaded %d file(s)", len(nameList))
+	logger.Infof("NameGenerator", "Loaded %d file(s)", len(nameList))
 
 }
 
+//Loaded number of word parts known.
+func Loaded() int {
+	return len(nameList)
+}
+
 //CityName Generate a new city name
 func CityName() string {
 
@@ -0,0 +1,236 @@
+//Package logger leveled and structured logging.
+//Every entry belongs to a subsystem (the former "Prefix:" of log messages), whose minimum level may be configured in system.json:
+//  "log_level": "info",                       default minimum level.
+//  "log_levels": {"DB": "warn", "Grid": "debug"}, minimum level by subsystem.
+//  "log_format": "text",                      or "json", one object per line.
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Level of an entry.
+type Level int
+
+//Levels from the most verbose.
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+	Fatal
+)
+
+var levelNames = map[Level]string{Debug: "debug", Info: "info", Warn: "warn", Error: "error", Fatal: "fatal"}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+//ParseLevel from its name, Info when unknown.
+func ParseLevel(name string) Level {
+	for k, v := range levelNames {
+		if strings.EqualFold(v, name) {
+			return k
+		}
+	}
+	return Info
+}
+
+//Fields attached to an entry, like map_id, city_id, caravan_id, user_id or request_id.
+type Fields map[string]interface{}
+
+var (
+	lock      sync.Mutex
+	output    io.Writer = os.Stderr
+	jsonOut   bool
+	minLevel  = Info
+	subLevels = make(map[string]Level)
+	exit      = os.Exit
+)
+
+//Configure output format and levels, see package documentation.
+func Configure(format string, level string, levels map[string]string) {
+	lock.Lock()
+	defer lock.Unlock()
+	jsonOut = strings.EqualFold(format, "json")
+	minLevel = ParseLevel(level)
+	subLevels = make(map[string]Level)
+	for k, v := range levels {
+		subLevels[k] = ParseLevel(v)
+	}
+}
+
+//SetOutput of every logger.
+func SetOutput(w io.Writer) {
+	lock.Lock()
+	defer lock.Unlock()
+	output = w
+}
+
+//Enabled tell whether entries of level get written for subsystem.
+func Enabled(subsystem string, level Level) bool {
+	lock.Lock()
+	defer lock.Unlock()
+	if min, found := subLevels[subsystem]; found {
+		return level >= min
+	}
+	return level >= minLevel
+}
+
+//Logger writes entries of a subsystem with its fields.
+type Logger struct {
+	subsystem string
+	fields    Fields
+}
+
+//For subsystem.
+func For(subsystem string) *Logger {
+	return &Logger{subsystem: subsystem}
+}
+
+//With a new logger having an additional field.
+func (l *Logger) With(key string, value interface{}) *Logger {
+	return l.WithFields(Fields{key: value})
+}
+
+//WithFields a new logger having additional fields.
+func (l *Logger) WithFields(fields Fields) *Logger {
+	res := &Logger{subsystem: l.subsystem, fields: make(Fields, len(l.fields)+len(fields))}
+	for k, v := range l.fields {
+		res.fields[k] = v
+	}
+	for k, v := range fields {
+		res.fields[k] = v
+	}
+	return res
+}
+
+//Debugf detailed entries, usually disabled.
+func (l *Logger) Debugf(format string, a ...interface{}) {
+	l.write(Debug, format, a...)
+}
+
+//Infof regular entries.
+func (l *Logger) Infof(format string, a ...interface{}) {
+	l.write(Info, format, a...)
+}
+
+//Warnf something went wrong but got handled.
+func (l *Logger) Warnf(format string, a ...interface{}) {
+	l.write(Warn, format, a...)
+}
+
+//Errorf something went wrong and couldn't be handled.
+func (l *Logger) Errorf(format string, a ...interface{}) {
+	l.write(Error, format, a...)
+}
+
+//Fatalf server can't go on, exits. Must not be used while serving requests.
+func (l *Logger) Fatalf(format string, a ...interface{}) {
+	l.write(Fatal, format, a...)
+	exit(1)
+}
+
+func (l *Logger) write(level Level, format string, a ...interface{}) {
+	if !Enabled(l.subsystem, level) {
+		return
+	}
+	caller := "???"
+	if _, file, line, ok := runtime.Caller(2); ok {
+		caller = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+	}
+	entry := format
+	if len(a) > 0 {
+		entry = fmt.Sprintf(format, a...)
+	}
+	entry = strings.TrimRight(entry, "\n")
+
+	var line []byte
+	now := time.Now()
+	lock.Lock()
+	defer lock.Unlock()
+	if jsonOut {
+		obj := make(map[string]interface{}, len(l.fields)+5)
+		for k, v := range l.fields {
+			if err, ok := v.(error); ok {
+				v = err.Error()
+			}
+			obj[k] = v
+		}
+		obj["time"] = now.Format(time.RFC3339Nano)
+		obj["level"] = level.String()
+		obj["subsystem"] = l.subsystem
+		obj["caller"] = caller
+		obj["msg"] = entry
+		line, _ = json.Marshal(obj)
+	} else {
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "%s %s %-5s %s: %s", now.Format("2006/01/02 15:04:05.000000"), caller, strings.ToUpper(level.String()), l.subsystem, entry)
+		keys := make([]string, 0, len(l.fields))
+		for k := range l.fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(&sb, " %s=%v", k, l.fields[k])
+		}
+		line = []byte(sb.String())
+	}
+	output.Write(append(line, '\n'))
+}
+
+//Debugf of subsystem.
+func Debugf(subsystem string, format string, a ...interface{}) {
+	For(subsystem).write(Debug, format, a...)
+}
+
+//Infof of subsystem.
+func Infof(subsystem string, format string, a ...interface{}) {
+	For(subsystem).write(Info, format, a...)
+}
+
+//Warnf of subsystem.
+func Warnf(subsystem string, format string, a ...interface{}) {
+	For(subsystem).write(Warn, format, a...)
+}
+
+//Errorf of subsystem.
+func Errorf(subsystem string, format string, a ...interface{}) {
+	For(subsystem).write(Error, format, a...)
+}
+
+//Fatalf of subsystem, exits. Must not be used while serving requests.
+func Fatalf(subsystem string, format string, a ...interface{}) {
+	For(subsystem).write(Fatal, format, a...)
+	exit(1)
+}
+
+//stdWriter turns entries of standard log package, used by libraries, into Info entries.
+type stdWriter struct{}
+
+func (stdWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\n")
+	subsystem := "Std"
+	if idx := strings.Index(msg, ": "); idx > 0 && !strings.ContainsAny(msg[:idx], " \t") {
+		subsystem, msg = msg[:idx], msg[idx+2:]
+	}
+	For(subsystem).write(Info, "%s", msg)
+	return len(p), nil
+}
+
+//CaptureStd redirect standard log package to logger.
+func CaptureStd() {
+	log.SetFlags(0)
+	log.SetOutput(stdWriter{})
+}
@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func capture(t *testing.T, format string, level string, levels map[string]string) *bytes.Buffer {
+	var buf bytes.Buffer
+	Configure(format, level, levels)
+	SetOutput(&buf)
+	t.Cleanup(func() { Configure("text", "info", nil) })
+	return &buf
+}
+
+func TestLevels(t *testing.T) {
+	buf := capture(t, "text", "info", map[string]string{"DB": "warn", "Grid": "debug"})
+
+	Debugf("City", "hidden")
+	Infof("City", "shown %d", 1)
+	Infof("DB", "hidden")
+	Warnf("DB", "shown %d", 2)
+	Debugf("Grid", "shown %d", 3)
+
+	res := buf.String()
+	if strings.Contains(res, "hidden") {
+		t.Errorf("Expected entries below level to be dropped, got:\n%s", res)
+	}
+	for _, v := range []string{"INFO  City: shown 1", "WARN  DB: shown 2", "DEBUG Grid: shown 3"} {
+		if !strings.Contains(res, v) {
+			t.Errorf("Expected %q in:\n%s", v, res)
+		}
+	}
+}
+
+func TestFields(t *testing.T) {
+	buf := capture(t, "text", "info", nil)
+
+	l := For("Caravan").With("map_id", 3)
+	l.With("caravan_id", 9).Infof("moving")
+	l.Infof("alone")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+	if !strings.HasSuffix(lines[0], "Caravan: moving caravan_id=9 map_id=3") {
+		t.Errorf("Expected sorted fields, got %s", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], "Caravan: alone map_id=3") {
+		t.Errorf("Expected parent logger to be left untouched, got %s", lines[1])
+	}
+}
+
+func TestJSON(t *testing.T) {
+	buf := capture(t, "json", "debug", nil)
+
+	For("Web").WithFields(Fields{"request_id": "abc", "user_id": 4}).Errorf("failed: %s", "boom")
+
+	var res map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &res); err != nil {
+		t.Fatalf("Expected a JSON object, got %s: %s", buf.String(), err)
+	}
+	if res["level"] != "error" || res["subsystem"] != "Web" || res["msg"] != "failed: boom" || res["request_id"] != "abc" || res["user_id"] != float64(4) {
+		t.Errorf("Unexpected entry: %v", res)
+	}
+	if !strings.HasPrefix(res["caller"].(string), "logger_test.go:") {
+		t.Errorf("Expected caller to be test file, got %v", res["caller"])
+	}
+}
+
+func TestFatal(t *testing.T) {
+	buf := capture(t, "text", "info", nil)
+	code := 0
+	exit = func(c int) { code = c }
+	defer func() { exit = os.Exit }()
+
+	Fatalf("Main", "can't go on")
+	if code != 1 || !strings.Contains(buf.String(), "FATAL Main: can't go on") {
+		t.Errorf("Expected fatal entry and exit code 1, got %d: %s", code, buf.String())
+	}
+}
+
+func TestCaptureStd(t *testing.T) {
+	buf := capture(t, "text", "info", map[string]string{"Lib": "warn"})
+	CaptureStd()
+
+	log.Printf("Lib: hidden")
+	log.Printf("Other: shown")
+	log.Printf("no prefix here")
+
+	res := buf.String()
+	if strings.Contains(res, "hidden") || !strings.Contains(res, "INFO  Other: shown") || !strings.Contains(res, "INFO  Std: no prefix here") {
+		t.Errorf("Unexpected capture of standard log:\n%s", res)
+	}
+}
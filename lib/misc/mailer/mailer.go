@@ -0,0 +1,126 @@
+//Package mailer sends mails through a configurable transport: SMTP in production, stdout or a file for dev and tests.
+package mailer
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"upsilon_cities_go/lib/misc/logger"
+)
+
+//Mail to be sent, body is plain text.
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+//Mailer transport of mails.
+type Mailer interface {
+	Send(from string, m Mail) error
+}
+
+//SMTP sends mails through an SMTP server, authenticating when User is set.
+type SMTP struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+}
+
+//Send mail through SMTP server.
+func (s *SMTP) Send(from string, m Mail) error {
+	var auth smtp.Auth
+	if s.User != "" {
+		auth = smtp.PlainAuth("", s.User, s.Password, s.Host)
+	}
+	return smtp.SendMail(net.JoinHostPort(s.Host, s.Port), auth, from, []string{m.To}, format(from, m))
+}
+
+//Writer writes mails one after another, meant for dev and tests.
+type Writer struct {
+	lock sync.Mutex
+	w    io.Writer
+}
+
+//NewWriter writes mails to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+//Send write mail.
+func (wr *Writer) Send(from string, m Mail) error {
+	wr.lock.Lock()
+	defer wr.lock.Unlock()
+	_, err := fmt.Fprintf(wr.w, "%s\r\n.\r\n", format(from, m))
+	return err
+}
+
+//format mail as RFC 822 message.
+func format(from string, m Mail) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", m.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", m.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().UTC().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(m.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+var current Mailer = NewWriter(os.Stdout)
+var sender = "upsilon@localhost"
+var currentLock sync.RWMutex
+
+//Set transport and sender of mails.
+func Set(m Mailer, from string) {
+	currentLock.Lock()
+	defer currentLock.Unlock()
+	current = m
+	sender = from
+}
+
+//Configure transport by name: "smtp" (settings: host, port, user, password), "file" (settings: path) or "stdout".
+func Configure(transport string, from string, settings map[string]string) error {
+	switch transport {
+	case "smtp":
+		if settings["host"] == "" {
+			return fmt.Errorf("smtp transport requires a host")
+		}
+		port := settings["port"]
+		if port == "" {
+			port = "25"
+		}
+		Set(&SMTP{Host: settings["host"], Port: port, User: settings["user"], Password: settings["password"]}, from)
+	case "file":
+		f, err := os.OpenFile(settings["path"], os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			return fmt.Errorf("unable to open mail file: %s", err)
+		}
+		Set(NewWriter(f), from)
+	case "", "stdout":
+		Set(NewWriter(os.Stdout), from)
+	default:
+		return fmt.Errorf("unknown mail transport %s", transport)
+	}
+	logger.Infof("Mailer", "Sending mails through %s as %s", transport, from)
+	return nil
+}
+
+//Send mail through configured transport.
+func Send(m Mail) error {
+	currentLock.RLock()
+	defer currentLock.RUnlock()
+	if err := current.Send(sender, m); err != nil {
+		logger.Errorf("Mailer", "Failed to send %s to %s: %s", m.Subject, m.To, err)
+		return err
+	}
+	logger.Infof("Mailer", "Sent %s to %s", m.Subject, m.To)
+	return nil
+}
@@ -0,0 +1,57 @@
+package mailer
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	Set(NewWriter(&buf), "noreply@upsilon")
+	defer Set(NewWriter(os.Stdout), "upsilon@localhost")
+
+	if err := Send(Mail{To: "player@upsilon", Subject: "Hello", Body: "first line\nsecond line"}); err != nil {
+		t.Errorf("Failed to send mail: %s", err)
+		return
+	}
+
+	res := buf.String()
+	for _, expected := range []string{"From: noreply@upsilon\r\n", "To: player@upsilon\r\n", "Subject: Hello\r\n", "\r\n\r\nfirst line\r\nsecond line\r\n.\r\n"} {
+		if !strings.Contains(res, expected) {
+			t.Errorf("Expected %q in mail:\n%s", expected, res)
+		}
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	defer Set(NewWriter(os.Stdout), "upsilon@localhost")
+
+	if err := Configure("pigeon", "a@b", nil); err == nil {
+		t.Errorf("Unknown transport should be refused")
+	}
+	if err := Configure("smtp", "a@b", map[string]string{}); err == nil {
+		t.Errorf("SMTP without host should be refused")
+	}
+
+	dir, err := ioutil.TempDir("", "mailer")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "mails.txt")
+
+	if err := Configure("file", "a@b", map[string]string{"path": path}); err != nil {
+		t.Errorf("Failed to configure file transport: %s", err)
+		return
+	}
+	Send(Mail{To: "c@d", Subject: "Filed", Body: "body"})
+
+	content, _ := ioutil.ReadFile(path)
+	if !strings.Contains(string(content), "Subject: Filed") {
+		t.Errorf("Expected mail to be written to file, got %q", content)
+	}
+}
@@ -0,0 +1,272 @@
+//Package metrics collects counters, gauges and histograms and exposes them in Prometheus text format.
+//Metrics are declared by packages owning them, usually as package vars, and registered once and for all.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//DefaultBuckets upper bounds in seconds fitting web requests and db queries.
+var DefaultBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type collector interface {
+	name() string
+	write(w io.Writer)
+}
+
+var (
+	registryLock sync.Mutex
+	registry     = make(map[string]collector)
+)
+
+func register(c collector) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if _, found := registry[c.name()]; found {
+		panic(fmt.Sprintf("metrics: %s registered twice", c.name()))
+	}
+	registry[c.name()] = c
+}
+
+//family common to every metric kind: name, help and label names.
+type family struct {
+	metric string
+	help   string
+	labels []string
+}
+
+func (f family) name() string {
+	return f.metric
+}
+
+func (f family) header(w io.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.metric, escape(f.help, false), f.metric, kind)
+}
+
+//key identifies a set of label values, number of values must match number of labels.
+func (f family) key(values []string) string {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.metric, len(f.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+//format labels as {a="1",b="2"}, extra label appended as is (used for histogram buckets).
+func (f family) format(key string, extra string) string {
+	var parts []string
+	if len(f.labels) > 0 {
+		for i, v := range strings.Split(key, "\xff") {
+			parts = append(parts, fmt.Sprintf(`%s="%s"`, f.labels[i], escape(v, true)))
+		}
+	}
+	if extra != "" {
+		parts = append(parts, extra)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func escape(s string, quoted bool) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	if quoted {
+		s = strings.Replace(s, `"`, `\"`, -1)
+	}
+	return s
+}
+
+func value(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return fmt.Sprint(v)
+}
+
+func sortedKeys(m map[string]float64) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
+
+//Counter only goes up.
+type Counter struct {
+	family
+	lock   sync.Mutex
+	values map[string]float64
+}
+
+//NewCounter register a new counter.
+func NewCounter(name string, help string, labels ...string) *Counter {
+	c := &Counter{family: family{name, help, labels}, values: make(map[string]float64)}
+	register(c)
+	return c
+}
+
+//Add v to counter, v must be positive.
+func (c *Counter) Add(v float64, labels ...string) {
+	if v < 0 {
+		return
+	}
+	key := c.key(labels)
+	c.lock.Lock()
+	c.values[key] += v
+	c.lock.Unlock()
+}
+
+//Inc add one to counter.
+func (c *Counter) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+//Value of counter, mostly for tests.
+func (c *Counter) Value(labels ...string) float64 {
+	key := c.key(labels)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.values[key]
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.header(w, "counter")
+	for _, k := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.metric, c.format(k, ""), value(c.values[k]))
+	}
+}
+
+//GaugeFunc value computed on each scrape, fn returns values by label value of its single label.
+type GaugeFunc struct {
+	family
+	fn func() map[string]float64
+}
+
+//NewGaugeFunc register a gauge computed on scrape. When label is empty, fn must return its value under "".
+func NewGaugeFunc(name string, help string, label string, fn func() map[string]float64) *GaugeFunc {
+	g := &GaugeFunc{family: family{metric: name, help: help}, fn: fn}
+	if label != "" {
+		g.labels = []string{label}
+	}
+	register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(w io.Writer) {
+	values := g.fn()
+	g.header(w, "gauge")
+	for _, k := range sortedKeys(values) {
+		fmt.Fprintf(w, "%s%s %s\n", g.metric, g.format(k, ""), value(values[k]))
+	}
+}
+
+type histogramValue struct {
+	counts []uint64 // per bucket, not cumulated.
+	count  uint64
+	sum    float64
+}
+
+//Histogram counts observations within buckets.
+type Histogram struct {
+	family
+	buckets []float64
+	lock    sync.Mutex
+	values  map[string]*histogramValue
+}
+
+//NewHistogram register a new histogram, buckets are sorted upper bounds; +Inf is implicit.
+func NewHistogram(name string, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{family: family{name, help, labels}, buckets: buckets, values: make(map[string]*histogramValue)}
+	register(h)
+	return h
+}
+
+//Observe v.
+func (h *Histogram) Observe(v float64, labels ...string) {
+	key := h.key(labels)
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	hv, found := h.values[key]
+	if !found {
+		hv = &histogramValue{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hv
+	}
+	for i, b := range h.buckets {
+		if v <= b {
+			hv.counts[i]++
+			break
+		}
+	}
+	hv.count++
+	hv.sum += v
+}
+
+//Since observe seconds elapsed since start.
+func (h *Histogram) Since(start time.Time, labels ...string) {
+	h.Observe(time.Since(start).Seconds(), labels...)
+}
+
+//Count of observations, mostly for tests.
+func (h *Histogram) Count(labels ...string) uint64 {
+	key := h.key(labels)
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if hv, found := h.values[key]; found {
+		return hv.count
+	}
+	return 0
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.header(w, "histogram")
+	keys := make([]string, 0, len(h.values))
+	for k := range h.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		hv := h.values[k]
+		var cumulated uint64
+		for i, b := range h.buckets {
+			cumulated += hv.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.metric, h.format(k, fmt.Sprintf(`le="%s"`, value(b))), cumulated)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.metric, h.format(k, `le="+Inf"`), hv.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.metric, h.format(k, ""), value(hv.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.metric, h.format(k, ""), hv.count)
+	}
+}
+
+//Write every registered metric sorted by name.
+func Write(w io.Writer) {
+	registryLock.Lock()
+	collectors := make([]collector, 0, len(registry))
+	for _, v := range registry {
+		collectors = append(collectors, v)
+	}
+	registryLock.Unlock()
+
+	sort.Slice(collectors, func(i, j int) bool { return collectors[i].name() < collectors[j].name() })
+	for _, v := range collectors {
+		v.write(w)
+	}
+}
+
+//Handler serves registered metrics to Prometheus.
+func Handler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	Write(w)
+}
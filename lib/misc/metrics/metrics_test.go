@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	c := NewCounter("test_sold_total", "Credits earned.", "item")
+	c.Add(10, "Wheat")
+	c.Inc(`Sw"ord`)
+	c.Add(-5, "Wheat")
+
+	h := NewHistogram("test_duration_seconds", "Duration.", []float64{0.1, 1})
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(5)
+
+	NewGaugeFunc("test_loaded", "Loaded entities.", "kind", func() map[string]float64 {
+		return map[string]float64{"city": 3, "grid": 1}
+	})
+
+	var buf bytes.Buffer
+	Write(&buf)
+	res := buf.String()
+
+	expected := []string{
+		"# TYPE test_sold_total counter\n",
+		"test_sold_total{item=\"Sw\\\"ord\"} 1\n",
+		"test_sold_total{item=\"Wheat\"} 10\n",
+		"# TYPE test_duration_seconds histogram\n",
+		"test_duration_seconds_bucket{le=\"0.1\"} 1\n",
+		"test_duration_seconds_bucket{le=\"1\"} 2\n",
+		"test_duration_seconds_bucket{le=\"+Inf\"} 3\n",
+		"test_duration_seconds_sum 5.55\n",
+		"test_duration_seconds_count 3\n",
+		"test_loaded{kind=\"city\"} 3\n",
+		"test_loaded{kind=\"grid\"} 1\n",
+	}
+	for _, v := range expected {
+		if !strings.Contains(res, v) {
+			t.Errorf("Expected %q in:\n%s", v, res)
+		}
+	}
+
+	if strings.Index(res, "test_duration_seconds") > strings.Index(res, "test_sold_total") {
+		t.Errorf("Expected metrics to be sorted by name")
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	NewCounter("test_twice_total", "Twice.")
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected registering a name twice to panic")
+		}
+	}()
+	NewCounter("test_twice_total", "Twice.")
+}
@@ -0,0 +1,160 @@
+//Package ratelimit token buckets, by route group and key (IP, user ...).
+//A limit is written "burst/period", eg "5/1m": bucket holds 5 tokens and refills all of them over a minute.
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"upsilon_cities_go/lib/misc/logger"
+)
+
+//Limit of a bucket.
+type Limit struct {
+	Burst  int     // tokens a full bucket holds.
+	PerSec float64 // tokens refilled every second.
+}
+
+//ParseLimit parse "burst/period" limit, period being a go duration. "0" or "" means unlimited, reported as nil.
+func ParseLimit(s string) (*Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" {
+		return nil, nil
+	}
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid limit %s, expected burst/period", s)
+	}
+	burst, err := strconv.Atoi(parts[0])
+	if err != nil || burst <= 0 {
+		return nil, fmt.Errorf("invalid burst in limit %s", s)
+	}
+	period, err := time.ParseDuration(parts[1])
+	if err != nil || period <= 0 {
+		return nil, fmt.Errorf("invalid period in limit %s", s)
+	}
+	return &Limit{Burst: burst, PerSec: float64(burst) / period.Seconds()}, nil
+}
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+}
+
+//Limiter buckets of a route group, by key.
+type Limiter struct {
+	limit   Limit
+	lock    sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+//New limiter.
+func New(limit Limit) *Limiter {
+	return &Limiter{limit: limit, buckets: make(map[string]*bucket), swept: time.Now()}
+}
+
+//refill bucket up to now.
+func (l *Limiter) refill(b *bucket, now time.Time) {
+	b.tokens += now.Sub(b.at).Seconds() * l.limit.PerSec
+	if b.tokens > float64(l.limit.Burst) {
+		b.tokens = float64(l.limit.Burst)
+	}
+	b.at = now
+}
+
+//Allow take a token from key's bucket. When bucket is empty, tells how long to wait for next token.
+func (l *Limiter) Allow(key string, now time.Time) (bool, time.Duration) {
+	ok, _, wait := l.AllowAll([]string{key}, now)
+	return ok, wait
+}
+
+//AllowAll take a token from each key's bucket, only when none of them is empty.
+//Otherwise no token is taken, first empty bucket's key is returned with how long to wait for its next token.
+func (l *Limiter) AllowAll(keys []string, now time.Time) (bool, string, time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.sweep(now)
+
+	buckets := make([]*bucket, 0, len(keys))
+	for _, key := range keys {
+		b, found := l.buckets[key]
+		if !found {
+			b = &bucket{tokens: float64(l.limit.Burst), at: now}
+			l.buckets[key] = b
+		}
+		l.refill(b, now)
+
+		if b.tokens < 1 {
+			return false, key, time.Duration((1 - b.tokens) / l.limit.PerSec * float64(time.Second))
+		}
+		buckets = append(buckets, b)
+	}
+
+	for _, b := range buckets {
+		b.tokens--
+	}
+	return true, "", 0
+}
+
+//sweep forget full buckets once in a while, they behave just like new ones.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < time.Minute {
+		return
+	}
+	l.swept = now
+	for k, b := range l.buckets {
+		l.refill(b, now)
+		if b.tokens >= float64(l.limit.Burst) {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+//Defaults limits by route group, used unless configured otherwise.
+var Defaults = map[string]string{
+	"login":    "10/1m",
+	"register": "30/1m",
+	"recovery": "5/10m",
+	"api":      "300/1m",
+	"web":      "600/1m",
+}
+
+var groups = make(map[string]*Limiter)
+var groupsLock sync.RWMutex
+
+//Configure limiters by route group on top of Defaults. Invalid limits are reported and default one is kept.
+func Configure(limits map[string]string) {
+	merged := make(map[string]string)
+	for group, s := range Defaults {
+		merged[group] = s
+	}
+	for group, s := range limits {
+		if _, err := ParseLimit(s); err != nil {
+			logger.Errorf("RateLimit", "Ignoring limit of %s: %s", group, err)
+			continue
+		}
+		merged[group] = s
+	}
+
+	res := make(map[string]*Limiter)
+	for group, s := range merged {
+		if limit, _ := ParseLimit(s); limit != nil {
+			res[group] = New(*limit)
+		}
+	}
+
+	groupsLock.Lock()
+	groups = res
+	groupsLock.Unlock()
+}
+
+//For limiter of route group, nil when group isn't limited.
+func For(group string) *Limiter {
+	groupsLock.RLock()
+	defer groupsLock.RUnlock()
+	return groups[group]
+}
@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	l, err := ParseLimit("5/1m")
+	if err != nil || l.Burst != 5 || l.PerSec != 5.0/60 {
+		t.Errorf("Expected 5 tokens refilled over a minute, got %+v %v", l, err)
+	}
+	if l, err := ParseLimit("0"); l != nil || err != nil {
+		t.Errorf("Expected 0 to be unlimited, got %+v %v", l, err)
+	}
+	for _, s := range []string{"5", "x/1m", "5/x", "-1/1m", "5/0s"} {
+		if _, err := ParseLimit(s); err == nil {
+			t.Errorf("Expected %s to be refused", s)
+		}
+	}
+}
+
+func TestAllow(t *testing.T) {
+	l := New(Limit{Burst: 2, PerSec: 1})
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("a", now); !ok {
+			t.Errorf("Expected burst to be allowed")
+		}
+	}
+	ok, retry := l.Allow("a", now)
+	if ok || retry != time.Second {
+		t.Errorf("Expected empty bucket to wait a second, got %v %s", ok, retry)
+	}
+	if ok, _ := l.Allow("b", now); !ok {
+		t.Errorf("Expected keys to have their own bucket")
+	}
+	if ok, _ := l.Allow("a", now.Add(time.Second)); !ok {
+		t.Errorf("Expected bucket to be refilled")
+	}
+
+	l.Allow("a", now.Add(2*time.Minute))
+	if len(l.buckets) != 1 {
+		t.Errorf("Expected full buckets to be swept, %d left", len(l.buckets))
+	}
+}
+
+func TestAllowAllTakesNothingWhenRefused(t *testing.T) {
+	l := New(Limit{Burst: 1, PerSec: 1})
+	now := time.Now()
+
+	l.Allow("ip", now)
+	ok, key, _ := l.AllowAll([]string{"login", "ip"}, now)
+	if ok || key != "ip" {
+		t.Errorf("Expected request to be refused on ip, got %v %s", ok, key)
+		return
+	}
+
+	if ok, _ := l.Allow("login", now); !ok {
+		t.Errorf("Expected refused request not to use login token")
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	Configure(map[string]string{"login": "5/1m", "web": "0", "api": "bad"})
+	if l := For("login"); l == nil || l.limit.Burst != 5 {
+		t.Errorf("Expected login to be limited as configured")
+	}
+	if l := For("api"); l == nil || l.limit.Burst != 300 {
+		t.Errorf("Expected invalid limit to fall back on default")
+	}
+	if For("web") != nil || For("unknown") != nil {
+		t.Errorf("Expected disabled and unknown groups to be unlimited")
+	}
+}
@@ -0,0 +1,53 @@
+//Package shutdown let anyone ask for the server to stop gracefully.
+//Web server stops accepting requests, then main persists in memory state and exits with requested code.
+package shutdown
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"upsilon_cities_go/lib/misc/logger"
+)
+
+//Exit codes expected by install/upsilon.service: anything but ExitOk gets the service restarted.
+const (
+	ExitOk           = 0
+	ExitFailure      = 1
+	ExitReloadDb     = 5001
+	ExitReloadServer = 5002
+)
+
+//Request tells why server should stop and how.
+type Request struct {
+	Code    int
+	Reason  string
+	Persist bool   // in memory state gets saved before exiting.
+	Before  func() // executed once state got handled, right before exiting.
+}
+
+var requests = make(chan Request, 1)
+
+//Ask for server to stop. Only first request is honored, later ones are ignored.
+func Ask(req Request) {
+	select {
+	case requests <- req:
+		logger.Infof("Shutdown", "Requested with code %d: %s", req.Code, req.Reason)
+	default:
+		logger.Infof("Shutdown", "Already requested, ignoring %s", req.Reason)
+	}
+}
+
+//Requests provide shutdown requests, including ones triggered by SIGINT and SIGTERM.
+func Requests() <-chan Request {
+	return requests
+}
+
+//Notify turns SIGINT and SIGTERM into shutdown requests persisting state.
+func Notify() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		s := <-sig
+		Ask(Request{Code: ExitOk, Reason: s.String(), Persist: true})
+	}()
+}
@@ -0,0 +1,20 @@
+package shutdown
+
+import "testing"
+
+func TestAskOnlyFirst(t *testing.T) {
+	Ask(Request{Code: ExitReloadServer, Reason: "first"})
+	Ask(Request{Code: ExitOk, Reason: "second"})
+
+	req := <-Requests()
+	if req.Code != ExitReloadServer || req.Reason != "first" {
+		t.Errorf("Expected first request to be honored, got %d %s", req.Code, req.Reason)
+		return
+	}
+
+	select {
+	case req = <-Requests():
+		t.Errorf("Expected second request to be ignored, got %s", req.Reason)
+	default:
+	}
+}
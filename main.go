@@ -2,7 +2,7 @@ package main
 
 import (
 	"flag"
-	"log"
+	"fmt"
 	"math/rand"
 	"os"
 	"time"
@@ -11,7 +11,9 @@ import (
 	"upsilon_cities_go/lib/cities/city/producer_generator"
 	"upsilon_cities_go/lib/cities/city/resource_generator"
 	"upsilon_cities_go/lib/cities/city_manager"
+	"upsilon_cities_go/lib/cities/corporation_ai"
 	"upsilon_cities_go/lib/cities/corporation_manager"
+	city_evolution "upsilon_cities_go/lib/cities/evolution/city"
 	"upsilon_cities_go/lib/cities/map/grid_manager"
 	"upsilon_cities_go/lib/cities/map/map_generator/region"
 	"upsilon_cities_go/lib/cities/tools"
@@ -19,6 +21,10 @@ import (
 	"upsilon_cities_go/lib/misc/config/gameplay"
 	"upsilon_cities_go/lib/misc/config/system"
 	"upsilon_cities_go/lib/misc/generator"
+	"upsilon_cities_go/lib/misc/logger"
+	"upsilon_cities_go/lib/misc/mailer"
+	"upsilon_cities_go/lib/misc/ratelimit"
+	"upsilon_cities_go/lib/misc/shutdown"
 	"upsilon_cities_go/web"
 	"upsilon_cities_go/web/templates"
 	"upsilon_cities_go/web/webtools"
@@ -28,17 +34,26 @@ func main() {
 	rand.Seed(time.Now().Unix())
 
 	shouldLogInFile := flag.Bool("log", false, "moves logs to logs.txt file.")
+	replayCaravan := flag.Int("replay", 0, "rebuilds caravan state from its recorded events, compares it with stored state and exits.")
 	flag.Parse()
 	if *shouldLogInFile {
 		f, err := os.OpenFile("logs.txt", os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
 		if err != nil {
-			log.Fatalf("error opening file: %v", err)
+			logger.Fatalf("Main", "error opening file: %v", err)
 		}
-		log.SetOutput(f)
+		logger.SetOutput(f)
 	}
-	log.SetFlags(log.Ldate | log.Ltime | log.Lmicroseconds | log.Lshortfile)
+	logger.CaptureStd()
 
 	system.LoadConf()
+	logger.Configure(system.Get("log_format", "text"), system.Get("log_level", "info"), system.GetStringMap("log_levels"))
+	ratelimit.Configure(system.GetStringMap("rate_limits"))
+	if err := mailer.Configure(system.Get("mail_transport", "stdout"), system.Get("mail_from", "upsilon@localhost"), system.GetStringMap("mail_settings")); err != nil {
+		logger.Fatalf("Main", "Unable to configure mailer: %s", err)
+	}
+	if system.Get("http_base_url", "") == "" {
+		logger.Warnf("Main", "http_base_url isn't set: no mail with links (verification, password reset) can be sent")
+	}
 	gameplay.LoadConf()
 
 	tools.InitCycle()
@@ -56,15 +71,60 @@ func main() {
 
 	resource_generator.Load()
 	caravan.Init()
+	city_evolution.CSInit()
+	corporation_ai.Init()
 	handler := db.New()
 	db.CheckVersion(handler)
 	handler.Close()
 
+	if *replayCaravan != 0 {
+		replay(*replayCaravan)
+		return
+	}
+
 	region.Load()
 
 	router := web.RouterSetup()
 	webtools.SetRouter(router)
 	templates.LoadTemplates()
-	web.ListenAndServe(router)
 
+	shutdown.Notify()
+	req := web.ListenAndServe(router)
+
+	if failed := grid_manager.Shutdown(req.Persist); failed > 0 {
+		logger.Errorf("Main", "%d entities couldn't be persisted", failed)
+	}
+	if req.Before != nil {
+		req.Before()
+	}
+	logger.Infof("Main", "Exiting with code %d", req.Code)
+	os.Exit(req.Code)
+}
+
+// replay rebuild caravan state from its events and tell whether it matches stored state.
+func replay(crvID int) {
+	dbh := db.New()
+	defer dbh.Close()
+
+	crv, err := caravan.ByID(dbh, crvID)
+	if err != nil {
+		logger.Fatalf("Replay", "Unable to load caravan %d: %s", crvID, err)
+	}
+
+	events, err := caravan.EventsByCaravanID(dbh, crvID)
+	if err != nil {
+		logger.Fatalf("Replay", "Unable to load caravan %d events: %s", crvID, err)
+	}
+
+	for _, v := range events {
+		fmt.Printf("%s %s loaded %v unloaded %v credits %d\n", v.Cycle.Format(time.RFC3339), v.String(), v.Loaded, v.Unloaded, v.Credits)
+	}
+
+	res := caravan.Replay(events)
+	fmt.Printf("Replayed %d events: state %s, credits %d, goods %v\n", res.Events, caravan.StateToString[res.State], res.Credits, res.Goods)
+	if ok, mismatch := res.Matches(crv); !ok {
+		fmt.Printf("Replayed state doesn't match stored caravan: %s\n", mismatch)
+		os.Exit(1)
+	}
+	fmt.Printf("Replayed state matches stored caravan.\n")
 }
@@ -1,27 +1,31 @@
 package admin_controller
 
 import (
-	"encoding/json"
 	"net/http"
-	"os"
 	"upsilon_cities_go/lib/cities/user"
 	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/shutdown"
+	"upsilon_cities_go/web/dto"
 	"upsilon_cities_go/web/templates"
 	"upsilon_cities_go/web/webtools"
 )
 
 //Index GET /admin/users ... Must be logged as admin to get here ;)
 func Index(w http.ResponseWriter, req *http.Request) {
-	if !webtools.IsAdmin(req) {
-		webtools.Redirect(w, req, "/")
+	if !webtools.CheckAdmin(w, req) {
 		return
 	}
 	dbh := db.New()
 	defer dbh.Close()
-	users := user.All(dbh)
-	if webtools.IsAPI(req) {
-		webtools.GenerateAPIOk(w)
-		json.NewEncoder(w).Encode(users)
+	users, err := user.All(dbh)
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusInternalServerError, "unable to list users", "/")
+		return
+	}
+	if webtools.IsAPIv1(req) {
+		webtools.GenerateAPIData(w, dto.NewUsers(users))
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPIData(w, users)
 	} else {
 		templates.RenderTemplate(w, req, "admin/index", users)
 	}
@@ -29,46 +33,62 @@ func Index(w http.ResponseWriter, req *http.Request) {
 
 //AdminTools GET /admin/users ... Must be logged as admin to get here ;)
 func AdminTools(w http.ResponseWriter, req *http.Request) {
-	if !webtools.IsAdmin(req) {
-		webtools.Redirect(w, req, "/")
+	if !webtools.CheckAdmin(w, req) {
 		return
 	}
 
 	if webtools.IsAPI(req) {
-		webtools.GenerateAPIOk(w)
+		webtools.GenerateAPIOkAndSend(w)
 	} else {
 		templates.RenderTemplate(w, req, "admin/tools", "")
 	}
 }
 
-//ReloadDb GET /admin/users ... Must be logged as admin to get here ;)
+//ReloadDb DELETE /admin/tools/rdb ... Must be logged as admin to get here ;)
+//Server stops gracefully, database gets flushed once every grid stopped, in memory state is dropped.
 func ReloadDb(w http.ResponseWriter, req *http.Request) {
 	if !webtools.CheckAdmin(w, req) {
 		return
 	}
 
-	dbh := db.New()
-	defer dbh.Close()
-
-	db.FlushDatabase(dbh)
+	shutdown.Ask(shutdown.Request{
+		Code:   shutdown.ExitReloadDb,
+		Reason: "database reload requested by admin",
+		Before: func() {
+			dbh := db.New()
+			defer dbh.Close()
 
-	os.Exit(5001)
+			db.FlushDatabase(dbh)
+		},
+	})
 
+	replyShutdown(w, req)
 }
 
-//ReloadServer GET /admin/users ... Must be logged as admin to get here ;)
+//ReloadServer DELETE /admin/tools/rsrv ... Must be logged as admin to get here ;)
+//Server stops gracefully, in memory state gets persisted before restart.
 func ReloadServer(w http.ResponseWriter, req *http.Request) {
 	if !webtools.CheckAdmin(w, req) {
 		return
 	}
 
-	os.Exit(5002)
+	shutdown.Ask(shutdown.Request{Code: shutdown.ExitReloadServer, Reason: "server reload requested by admin", Persist: true})
+
+	replyShutdown(w, req)
+}
+
+//replyShutdown running request completes before server stops.
+func replyShutdown(w http.ResponseWriter, req *http.Request) {
+	if webtools.IsAPI(req) {
+		webtools.GenerateAPIOkAndSend(w)
+	} else {
+		webtools.Redirect(w, req, "/admin/tools")
+	}
 }
 
 //AdminShow GET /admin/users/:user_id ... Must be logged as admin to get here ;)
 func AdminShow(w http.ResponseWriter, req *http.Request) {
-	if !webtools.IsAdmin(req) {
-		webtools.Redirect(w, req, "/")
+	if !webtools.CheckAdmin(w, req) {
 		return
 	}
 
@@ -84,13 +104,14 @@ func AdminShow(w http.ResponseWriter, req *http.Request) {
 	user, err := user.ByID(dbh, id)
 
 	if err != nil {
-		webtools.Fail(w, req, "fail to find requested user", "/admin/users/")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "fail to find requested user", "/admin/users/")
 		return
 	}
 
-	if webtools.IsAPI(req) {
-		webtools.GenerateAPIOk(w)
-		json.NewEncoder(w).Encode(user)
+	if webtools.IsAPIv1(req) {
+		webtools.GenerateAPIData(w, dto.NewUser(user))
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPIData(w, user)
 	} else {
 		templates.RenderTemplate(w, req, "user/show", user)
 	}
@@ -115,7 +136,7 @@ func AdminReset(w http.ResponseWriter, req *http.Request) {
 	usr, err := user.ByID(dbh, id)
 
 	if err != nil {
-		webtools.Fail(w, req, "unable to find user", "/")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unable to find user", "/")
 		return
 	}
 
@@ -150,11 +171,13 @@ func Lock(w http.ResponseWriter, req *http.Request) {
 	usr, err := user.ByID(dbh, id)
 
 	if err != nil {
-		webtools.Fail(w, req, "unable to find user", "/")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unable to find user", "/")
 		return
 	}
 
 	usr.Enabled = state == 1
+	// admin decision prevails over a pending email verification.
+	usr.NeedEmailVerification = false
 	usr.Update(dbh)
 
 	if webtools.IsAPI(req) {
@@ -184,7 +207,7 @@ func AdminDestroy(w http.ResponseWriter, req *http.Request) {
 	usr, err := user.ByID(dbh, id)
 
 	if err != nil {
-		webtools.Fail(w, req, "unable to find user", "/")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unable to find user", "/")
 		return
 	}
 
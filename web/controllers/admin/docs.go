@@ -0,0 +1,20 @@
+package admin_controller
+
+import (
+	"upsilon_cities_go/web/dto"
+	"upsilon_cities_go/web/openapi"
+)
+
+//Docs API routes of administration, admin only.
+func Docs() []openapi.Doc {
+	return []openapi.Doc{
+		{Method: "GET", Path: "/admin", Summary: "List users.", Data: []dto.User{}},
+		{Method: "GET", Path: "/admin/users", Summary: "List users.", Data: []dto.User{}},
+		{Method: "GET", Path: "/admin/users/{user_id}", Summary: "User details.", Data: dto.User{}},
+		{Method: "DELETE", Path: "/admin/tools/rdb", Summary: "Stop server gracefully, flush database and restart, in memory state is dropped."},
+		{Method: "DELETE", Path: "/admin/tools/rsrv", Summary: "Stop server gracefully, persisting in memory state, and restart."},
+		{Method: "POST", Path: "/admin/users/{user_id}/reset", Summary: "Require user to change password."},
+		{Method: "DELETE", Path: "/admin/users/{user_id}", Summary: "Destroy a user."},
+		{Method: "POST", Path: "/admin/users/{user_id}/state/{user_state}", Summary: "Enable (1) or lock (0) a user."},
+	}
+}
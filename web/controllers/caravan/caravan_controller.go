@@ -2,18 +2,20 @@ package caravan_controller
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
+	"sort"
 	"upsilon_cities_go/lib/cities/caravan"
 	"upsilon_cities_go/lib/cities/caravan_manager"
 	"upsilon_cities_go/lib/cities/city"
 	"upsilon_cities_go/lib/cities/city/producer"
 	"upsilon_cities_go/lib/cities/city_manager"
-	"upsilon_cities_go/lib/cities/corporation"
 	"upsilon_cities_go/lib/cities/corporation_manager"
+	"upsilon_cities_go/lib/cities/map/grid_manager"
 	"upsilon_cities_go/lib/cities/storage"
 	libtools "upsilon_cities_go/lib/cities/tools"
 	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/logger"
+	"upsilon_cities_go/web/dto"
 	"upsilon_cities_go/web/templates"
 	"upsilon_cities_go/web/webtools"
 )
@@ -27,7 +29,7 @@ func Index(w http.ResponseWriter, req *http.Request) {
 	cid, err := webtools.CurrentCorpID(req)
 
 	if err != nil {
-		webtools.Fail(w, req, "corporation doesn't exist ... maybe it has been kicked from the map", "/map")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "corporation doesn't exist ... maybe it has been kicked from the map", "/map")
 		return
 	}
 	crv, _ := caravan_manager.GetCaravanHandlerByCorpID(cid)
@@ -39,9 +41,16 @@ func Index(w http.ResponseWriter, req *http.Request) {
 		data = append(data, v.Get())
 	}
 
-	if webtools.IsAPI(req) {
-		webtools.GenerateAPIOk(w)
-		json.NewEncoder(w).Encode(data)
+	if webtools.IsAPIv1(req) {
+		res := make([]dto.Caravan, 0, len(crv))
+		for _, v := range crv {
+			v.Call(func(caravan *caravan.Caravan) {
+				res = append(res, dto.NewCaravan(caravan))
+			})
+		}
+		webtools.GenerateAPIData(w, res)
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPIData(w, data)
 	} else {
 		templates.RenderTemplate(w, req, "caravan/index", data)
 	}
@@ -50,6 +59,8 @@ func Index(w http.ResponseWriter, req *http.Request) {
 type candidateCity struct {
 	TargetCityID   int
 	TargetCityName string
+	Distance       int // in nodes, by road.
+	TravelCycles   int
 }
 
 type candidateImport struct {
@@ -63,6 +74,8 @@ type candidateImport struct {
 type candidateCityExt struct {
 	TargetCityID   int
 	TargetCityName string
+	Distance       int
+	TravelCycles   int
 	Imports        []candidateImport
 }
 
@@ -117,7 +130,13 @@ func New(w http.ResponseWriter, req *http.Request) {
 	cb := make(chan newData)
 	defer close(cb)
 
-	neighbours := cm.Get().NeighboursID
+	// any city reachable by road is a candidate.
+	reachable, err := grid_manager.ReachableCities(cityID)
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusInternalServerError, "unable to seek cities reachable from origin", "")
+		return
+	}
+	speed := caravan.New().TravelingSpeed
 
 	cm.Cast(func(city *city.City) {
 		var data newData
@@ -200,7 +219,8 @@ func New(w http.ResponseWriter, req *http.Request) {
 		// call ensure we wait for end of this function before continuing on.
 		// as we alter "data" that live in this thread (and not in city thread)
 
-		if !libtools.InList(cty.Get().ID, neighbours) {
+		distance, isReachable := reachable[cty.Get().ID]
+		if !isReachable {
 			continue
 		}
 
@@ -249,6 +269,8 @@ func New(w http.ResponseWriter, req *http.Request) {
 					var ccity candidateCity
 					ccity.TargetCityID = city.ID
 					ccity.TargetCityName = city.Name
+					ccity.Distance = distance
+					ccity.TravelCycles = distance * speed
 
 					cd.Cities = append(cd.Cities, ccity)
 
@@ -261,6 +283,8 @@ func New(w http.ResponseWriter, req *http.Request) {
 			var cce candidateCityExt
 			cce.TargetCityID = city.ID
 			cce.TargetCityName = city.Name
+			cce.Distance = distance
+			cce.TravelCycles = distance * speed
 
 			for k, v := range city.ProductFactories {
 				for kk, vv := range v.Products {
@@ -294,8 +318,10 @@ func New(w http.ResponseWriter, req *http.Request) {
 
 	for k, v := range data.AvailableProducts {
 		v.Sellable = len(v.Cities) != 0
+		sort.Slice(v.Cities, func(i, j int) bool { return v.Cities[i].Distance < v.Cities[j].Distance })
 		data.AvailableProducts[k] = v
 	}
+	sort.Slice(data.Cities, func(i, j int) bool { return data.Cities[i].Distance < data.Cities[j].Distance })
 
 	prods, _ := json.Marshal(data.AvailableProducts)
 	cities, _ := json.Marshal(data.Cities)
@@ -303,9 +329,10 @@ func New(w http.ResponseWriter, req *http.Request) {
 	data.JSONAvailableProducts = string(prods)
 	data.JSONCities = string(cities)
 
-	if webtools.IsAPI(req) {
-		webtools.GenerateAPIOk(w)
-		json.NewEncoder(w).Encode(data)
+	if webtools.IsAPIv1(req) {
+		webtools.GenerateAPIData(w, candidatesDTO(data))
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPIData(w, data)
 	} else {
 		templates.RenderTemplate(w, req, "caravan/new", data)
 	}
@@ -331,6 +358,8 @@ type createJSON struct {
 	OriginComp          int
 	TargetComp          int
 	Delay               int
+	Penalty             int
+	OriginInsured       bool
 }
 
 //Create POST /caravan details of caravan. Expect only JS requests on this one ;)
@@ -360,26 +389,34 @@ func Create(w http.ResponseWriter, req *http.Request) {
 	target, err := city_manager.GetCityHandler(crv.CityTargetID)
 
 	if err != nil {
-		webtools.Fail(w, req, "targeted city doesn't exist", "")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "targeted city doesn't exist", "")
 		return
 	}
 
 	if target.Get().CorporationID == 0 {
-		webtools.Fail(w, req, "targeted city doesn't have a corporation", "")
+		webtools.FailWithStatus(w, req, http.StatusConflict, "targeted city doesn't have a corporation", "")
 		return
 	}
 
 	origin, err := city_manager.GetCityHandler(crv.CityOriginID)
 
 	if err != nil {
-		webtools.Fail(w, req, "origin city doesn't exist", "")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "origin city doesn't exist", "")
 		return
 	}
 
 	if origin.Get().CorporationID == 0 {
-		webtools.Fail(w, req, "origin city doesn't have a corporation", "")
+		webtools.FailWithStatus(w, req, http.StatusConflict, "origin city doesn't have a corporation", "")
+		return
+	}
+
+	reachable, err := grid_manager.ReachableCities(crv.CityOriginID)
+	distance, isReachable := reachable[crv.CityTargetID]
+	if err != nil || !isReachable {
+		webtools.FailWithStatus(w, req, http.StatusConflict, "targeted city can't be reached by road", "")
 		return
 	}
+	crv.TravelingDistance = distance
 
 	crv.CorpTargetID = target.Get().CorporationID
 	crv.ExchangeRateLHS = t.OriginExRate
@@ -407,14 +444,14 @@ func Create(w http.ResponseWriter, req *http.Request) {
 	prod := <-cb
 
 	if prod == nil {
-		webtools.Fail(w, req, "unable to find requested export producer", "")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unable to find requested export producer", "")
 		return
 	}
 
 	// seek product in producer ;)
 	product, found := prod.Products[t.ExportedProduct]
 	if !found {
-		webtools.Fail(w, req, "unable to find requested exported product", "")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unable to find requested exported product", "")
 		return
 	}
 
@@ -439,14 +476,14 @@ func Create(w http.ResponseWriter, req *http.Request) {
 	prod = <-cb
 
 	if prod == nil {
-		webtools.Fail(w, req, "unable to find requested imported producer", "")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unable to find requested imported producer", "")
 		return
 	}
 
 	// seek product in producer ;)
 	product, found = prod.Products[t.ImportedProduct]
 	if !found {
-		webtools.Fail(w, req, "unable to find requested imported product", "")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unable to find requested imported product", "")
 		return
 	}
 
@@ -455,52 +492,30 @@ func Create(w http.ResponseWriter, req *http.Request) {
 	crv.Imported.Quantity = libtools.IntRange{Min: t.ImportedMinQuantity, Max: t.ImportedMaxQuantity}
 	crv.Imported.Quality = libtools.IntRange{Min: t.ImportedMinQuality, Max: t.ImportedMaxQuality}
 	crv.LoadingDelay = t.Delay
-	crv.MapID = origin.Get().MapID
+	crv.Penalty = libtools.Max(t.Penalty, 0)
+	crv.OriginInsured = t.OriginInsured
 
 	dbh := db.New()
 	defer dbh.Close()
 
-	err = crv.Insert(dbh)
-
-	crv.Reload(dbh) // ensure it get all infos.
-	// should load it.
-
-	caravan_manager.GenerateHandler(crv)
-
+	crm, err := submitProposal(dbh, crv)
+	if err == errInsert {
+		webtools.FailWithStatus(w, req, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
 	if err != nil {
-		log.Printf("CrvCtrl: Failed to insert caravan %+v, %s", crv, err)
-		webtools.Fail(w, req, "failed to insert caravan in database", "")
+		webtools.FailWithStatus(w, req, http.StatusConflict, err.Error(), "")
 		return
 	}
 
-	origin.Call(func(city *city.City) {
-		city.Reload(dbh)
-	})
-	target.Call(func(city *city.City) {
-		city.Reload(dbh)
-	})
-
-	corp, _ := webtools.CurrentCorp(req)
-	corp.Call(func(corp *corporation.Corporation) {
-		corp.Reload(dbh)
-	})
-
-	targetcorp, _ := corporation_manager.GetCorporationHandler(crv.CorpTargetID)
-	targetcorp.Call(func(corp *corporation.Corporation) {
-		dbh := db.New()
-		defer dbh.Close()
-		corp.Reload(dbh)
-		if corp.OwnerID == 0 {
-
-			err := crv.Accept(dbh, corp.ID)
-			if err != nil {
-				log.Printf("CrvCtrl: Failed to auto accept by %d, caravan %+v", corp.ID, crv)
-			}
-		}
-	})
-
-	if webtools.IsAPI(req) {
-		webtools.GenerateAPIOkAndSend(w)
+	if webtools.IsAPIv1(req) {
+		var data dto.Caravan
+		crm.Call(func(caravan *caravan.Caravan) {
+			data = dto.NewCaravan(caravan)
+		})
+		webtools.GenerateAPICreated(w, data)
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPICreated(w, crm.Get())
 	} else {
 		webtools.Redirect(w, req, "")
 	}
@@ -534,13 +549,18 @@ func Show(w http.ResponseWriter, req *http.Request) {
 	}
 
 	if crv.Get().CorpOriginID != corpID && crv.Get().CorpTargetID != corpID {
-		webtools.Fail(w, req, "can't fetch caravan informations when corporation isn't linked to caravan", "")
+		webtools.FailWithStatus(w, req, http.StatusForbidden, "can't fetch caravan informations when corporation isn't linked to caravan", "")
 		return
 	}
 
-	if webtools.IsAPI(req) {
-		webtools.GenerateAPIOk(w)
-		json.NewEncoder(w).Encode(crv.Get())
+	if webtools.IsAPIv1(req) {
+		var data dto.Caravan
+		crv.Call(func(caravan *caravan.Caravan) {
+			data = dto.NewCaravan(caravan)
+		})
+		webtools.GenerateAPIData(w, data)
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPIData(w, crv.Get())
 	} else {
 		templates.RenderTemplate(w, req, "caravan/show", crv.Get())
 	}
@@ -574,7 +594,7 @@ func Accept(w http.ResponseWriter, req *http.Request) {
 	}
 
 	if crv.Get().CorpOriginID != corpID && crv.Get().CorpTargetID != corpID {
-		webtools.Fail(w, req, "can't fetch caravan informations when corporation isn't linked to caravan", "")
+		webtools.FailWithStatus(w, req, http.StatusForbidden, "can't fetch caravan informations when corporation isn't linked to caravan", "")
 		return
 	}
 
@@ -627,7 +647,7 @@ func Reject(w http.ResponseWriter, req *http.Request) {
 	}
 
 	if crv.Get().CorpOriginID != corpID && crv.Get().CorpTargetID != corpID {
-		webtools.Fail(w, req, "can't fetch caravan informations when corporation isn't linked to caravan", "")
+		webtools.FailWithStatus(w, req, http.StatusForbidden, "can't fetch caravan informations when corporation isn't linked to caravan", "")
 		return
 	}
 
@@ -680,7 +700,7 @@ func Abort(w http.ResponseWriter, req *http.Request) {
 	}
 
 	if crv.Get().CorpOriginID != corpID && crv.Get().CorpTargetID != corpID {
-		webtools.Fail(w, req, "can't fetch caravan informations when corporation isn't linked to caravan", "")
+		webtools.FailWithStatus(w, req, http.StatusForbidden, "can't fetch caravan informations when corporation isn't linked to caravan", "")
 		return
 	}
 
@@ -691,7 +711,9 @@ func Abort(w http.ResponseWriter, req *http.Request) {
 		dbh := db.New()
 		defer dbh.Close()
 		err := caravan.Abort(dbh, corpID)
-		log.Printf("CrvCtrl: Aborting: %s %+v", caravan.StringState(corpID), caravan)
+		logger.Infof("CrvCtrl", "Aborting: %s %+v", caravan.StringState(corpID), caravan)
+		// caravan may have stopped right away.
+		caravan.Settle(dbh)
 		cb <- err
 	})
 
@@ -726,6 +748,7 @@ type counterData struct {
 	OriginComp   int
 	TargetComp   int
 	Delay        int
+	Penalty      int
 }
 
 //GetCounter GET /caravan/:crv_id/counter propose counter proposition
@@ -756,7 +779,7 @@ func GetCounter(w http.ResponseWriter, req *http.Request) {
 	}
 
 	if crv.Get().CorpOriginID != corpID && crv.Get().CorpTargetID != corpID {
-		webtools.Fail(w, req, "can't fetch caravan informations when corporation isn't linked to caravan", "")
+		webtools.FailWithStatus(w, req, http.StatusForbidden, "can't fetch caravan informations when corporation isn't linked to caravan", "")
 		return
 	}
 
@@ -786,6 +809,7 @@ func GetCounter(w http.ResponseWriter, req *http.Request) {
 		data.OriginComp = caravan.ExportCompensation
 		data.TargetComp = caravan.ImportCompensation
 		data.Delay = caravan.LoadingDelay
+		data.Penalty = caravan.Penalty
 
 		cb <- data
 
@@ -793,8 +817,7 @@ func GetCounter(w http.ResponseWriter, req *http.Request) {
 
 	data := <-cb
 	if webtools.IsAPI(req) {
-		webtools.GenerateAPIOk(w)
-		json.NewEncoder(w).Encode(data)
+		webtools.GenerateAPIData(w, data)
 	} else {
 		templates.RenderTemplate(w, req, "caravan/proposition", data)
 	}
@@ -807,6 +830,8 @@ type counterReplyJSON struct {
 	OriginComp   int
 	TargetComp   int
 	Delay        int
+	Penalty      int
+	Insured      bool
 }
 
 //PostCounter POST /caravan/:crv_id/counter propose counter proposition
@@ -841,7 +866,7 @@ func PostCounter(w http.ResponseWriter, req *http.Request) {
 	}
 
 	if crv.Get().CorpOriginID != corpID && crv.Get().CorpTargetID != corpID {
-		webtools.Fail(w, req, "can't fetch caravan informations when corporation isn't linked to caravan", "")
+		webtools.FailWithStatus(w, req, http.StatusForbidden, "can't fetch caravan informations when corporation isn't linked to caravan", "")
 		return
 	}
 
@@ -862,6 +887,8 @@ func PostCounter(w http.ResponseWriter, req *http.Request) {
 		caravan.ExportCompensation = t.OriginComp
 		caravan.ImportCompensation = t.TargetComp
 		caravan.LoadingDelay = t.Delay
+		caravan.Penalty = libtools.Max(t.Penalty, 0)
+		caravan.TargetInsured = t.Insured
 
 		dbh := db.New()
 		defer dbh.Close()
@@ -911,7 +938,7 @@ func Drop(w http.ResponseWriter, req *http.Request) {
 	}
 
 	if crv.Get().CorpOriginID != corpID && crv.Get().CorpTargetID != corpID {
-		webtools.Fail(w, req, "can't fetch caravan informations when corporation isn't linked to caravan", "")
+		webtools.FailWithStatus(w, req, http.StatusForbidden, "can't fetch caravan informations when corporation isn't linked to caravan", "")
 		return
 	}
 
@@ -922,7 +949,7 @@ func Drop(w http.ResponseWriter, req *http.Request) {
 		dbh := db.New()
 		defer dbh.Close()
 		err := caravan.CorpDrop(dbh, corpID)
-		log.Printf("CrvCtrl: Dropping: %s %+v", caravan.StringState(corpID), caravan)
+		logger.Infof("CrvCtrl", "Dropping: %s %+v", caravan.StringState(corpID), caravan)
 
 		// it's already been removed from db by caravan.
 		if caravan.OriginDropped && caravan.TargetDropped {
@@ -943,3 +970,58 @@ func Drop(w http.ResponseWriter, req *http.Request) {
 		webtools.Redirect(w, req, "")
 	}
 }
+
+type historyInfo struct {
+	Events   []caravan.Event
+	Replayed caravan.Replayed
+	Matches  bool
+	Mismatch string
+}
+
+//History GET /api/caravan/:crv_id/history every recorded event of caravan along its replayed state.
+func History(w http.ResponseWriter, req *http.Request) {
+	if !webtools.CheckLogged(w, req) {
+		return
+	}
+	if !webtools.CheckAPI(w, req) {
+		return
+	}
+
+	crvID, err := webtools.GetInt(req, "crv_id")
+	if err != nil {
+		webtools.Fail(w, req, "invalid caravan id provided.", "")
+		return
+	}
+
+	crm, err := caravan_manager.GetCaravanHandler(crvID)
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unknown caravan", "")
+		return
+	}
+
+	corpID, _ := webtools.CurrentCorpID(req)
+	crv := crm.Get()
+	if crv.CorpOriginID != corpID && crv.CorpTargetID != corpID {
+		webtools.FailWithStatus(w, req, http.StatusForbidden, "can't fetch caravan informations when corporation isn't linked to caravan", "")
+		return
+	}
+
+	dbh := db.New()
+	defer dbh.Close()
+
+	var data historyInfo
+	data.Events, err = caravan.EventsByCaravanID(dbh, crvID)
+	if err != nil {
+		logger.Infof("CrvCtrl", "%s", err)
+		webtools.FailWithStatus(w, req, http.StatusInternalServerError, "unable to fetch caravan history", "")
+		return
+	}
+	data.Replayed = caravan.Replay(data.Events)
+	data.Matches, data.Mismatch = data.Replayed.Matches(&crv)
+
+	if webtools.IsAPIv1(req) {
+		webtools.GenerateAPIData(w, dto.NewCaravanHistory(data.Events, data.Replayed, data.Matches, data.Mismatch))
+	} else {
+		webtools.GenerateAPIData(w, data)
+	}
+}
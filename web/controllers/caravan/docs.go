@@ -0,0 +1,29 @@
+package caravan_controller
+
+import (
+	"net/http"
+	"upsilon_cities_go/web/dto"
+	"upsilon_cities_go/web/openapi"
+)
+
+//Docs API routes of caravans.
+func Docs() []openapi.Doc {
+	return []openapi.Doc{
+		{Method: "GET", Path: "/caravan", Summary: "Caravans of current corporation.", Data: []dto.Caravan{}},
+		{Method: "POST", Path: "/caravan", Summary: "Propose a caravan.", Body: createJSON{}, Data: dto.Caravan{}, Status: http.StatusCreated},
+		{Method: "GET", Path: "/caravan/new/{city_id}", Summary: "Candidates for a caravan from city.", Data: dto.CaravanCandidates{}},
+		{Method: "GET", Path: "/caravan/templates", Summary: "Contract templates of current corporation.", Data: []dto.Template{}},
+		{Method: "POST", Path: "/caravan/templates/{template_id}/propose", Summary: "Propose a caravan from a template."},
+		{Method: "POST", Path: "/caravan/templates/{template_id}/drop", Summary: "Drop a template."},
+		{Method: "GET", Path: "/caravan/{crv_id}", Summary: "Caravan details.", Data: dto.Caravan{}},
+		{Method: "GET", Path: "/caravan/{crv_id}/history", Summary: "Events of caravan.", Data: dto.CaravanHistory{}},
+		{Method: "POST", Path: "/caravan/{crv_id}/accept", Summary: "Accept a proposal."},
+		{Method: "POST", Path: "/caravan/{crv_id}/reject", Summary: "Reject a proposal."},
+		{Method: "POST", Path: "/caravan/{crv_id}/abort", Summary: "Abort a running contract."},
+		{Method: "POST", Path: "/caravan/{crv_id}/counter", Summary: "Counter a proposal.", Body: counterReplyJSON{}},
+		{Method: "POST", Path: "/caravan/{crv_id}/drop", Summary: "Drop a terminated caravan."},
+		{Method: "POST", Path: "/caravan/{crv_id}/renew", Summary: "Renew a terminated contract."},
+		{Method: "POST", Path: "/caravan/{crv_id}/auto_renew", Summary: "Toggle automatic renewal."},
+		{Method: "POST", Path: "/caravan/{crv_id}/template", Summary: "Save terms as a template.", Body: templateJSON{}, Data: dto.Template{}, Status: http.StatusCreated},
+	}
+}
@@ -0,0 +1,46 @@
+package caravan_controller
+
+import (
+	"upsilon_cities_go/web/dto"
+)
+
+//candidatesDTO caravan candidates as replied by /api/v1.
+func candidatesDTO(data newData) dto.CaravanCandidates {
+	res := dto.CaravanCandidates{
+		OriginCity: dto.Ref{ID: data.OriginCityID, Name: data.OriginCityName},
+		Exports:    make([]dto.CandidateExport, 0, len(data.AvailableProducts)),
+		Cities:     make([]dto.CandidateCity, 0, len(data.Cities)),
+	}
+
+	for _, v := range data.AvailableProducts {
+		exp := dto.CandidateExport{
+			CandidateProduct:   dto.CandidateProduct{ProducerID: v.ProducerID, ProductID: v.ProductID, ItemName: v.ItemName, ItemTypes: append([]string{}, v.ItemType...)},
+			Production:         v.Production,
+			ProductionQuality:  dto.NewRange(v.ProductionQuality),
+			ProductionDuration: v.ProductionDuration,
+			CityIDs:            make([]int, 0, len(v.Cities)),
+			AlreadyExchanged:   v.AlreadyExchanged,
+			RecentlyProduced:   v.HasRecentlyProduced,
+			CurrentStock:       v.CurrentStock,
+		}
+		for _, c := range v.Cities {
+			exp.CityIDs = append(exp.CityIDs, c.TargetCityID)
+		}
+		res.Exports = append(res.Exports, exp)
+	}
+
+	for _, v := range data.Cities {
+		cty := dto.CandidateCity{
+			CityID:       v.TargetCityID,
+			CityName:     v.TargetCityName,
+			Distance:     v.Distance,
+			TravelCycles: v.TravelCycles,
+			Imports:      make([]dto.CandidateProduct, 0, len(v.Imports)),
+		}
+		for _, i := range v.Imports {
+			cty.Imports = append(cty.Imports, dto.CandidateProduct{ProducerID: i.ProducerID, ProductID: i.ProductID, ItemName: i.ItemName, ItemTypes: append([]string{}, i.ItemType...)})
+		}
+		res.Cities = append(res.Cities, cty)
+	}
+	return res
+}
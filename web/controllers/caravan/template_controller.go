@@ -0,0 +1,329 @@
+package caravan_controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"upsilon_cities_go/lib/cities/caravan"
+	"upsilon_cities_go/lib/cities/caravan_manager"
+	"upsilon_cities_go/lib/cities/city_manager"
+	"upsilon_cities_go/lib/cities/corporation_manager"
+	"upsilon_cities_go/lib/cities/map/grid_manager"
+	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/logger"
+	"upsilon_cities_go/web/dto"
+	"upsilon_cities_go/web/templates"
+	"upsilon_cities_go/web/webtools"
+)
+
+type templatesInfo struct {
+	CorpID    int
+	Templates []caravan.Template
+}
+
+type templateJSON struct {
+	Name string
+}
+
+// errInsert proposal failed to be stored, unlike other errors it's not caused by proposal itself.
+var errInsert = errors.New("failed to insert caravan in database")
+
+// submitProposal register a new proposal and let target corporation answer it if it's able to.
+func submitProposal(dbh *db.Handler, crv *caravan.Caravan) (*caravan_manager.Handler, error) {
+	origin, err := city_manager.GetCityHandler(crv.CityOriginID)
+	if err != nil || origin.Get().CorporationID != crv.CorpOriginID {
+		return nil, errors.New("origin city isn't owned by corporation anymore")
+	}
+
+	target, err := city_manager.GetCityHandler(crv.CityTargetID)
+	if err != nil || target.Get().CorporationID == 0 {
+		return nil, errors.New("targeted city doesn't have a corporation")
+	}
+	crv.CorpTargetID = target.Get().CorporationID
+	crv.MapID = origin.Get().MapID
+
+	reachable, err := grid_manager.ReachableCities(crv.CityOriginID)
+	distance, isReachable := reachable[crv.CityTargetID]
+	if err != nil || !isReachable {
+		return nil, errors.New("targeted city can't be reached by road")
+	}
+	crv.TravelingDistance = distance
+
+	crm, err := caravan_manager.Register(dbh, crv)
+	if err != nil {
+		logger.Errorf("CrvCtrl", "Failed to insert caravan %+v, %s", crv, err)
+		return nil, errInsert
+	}
+
+	targetCity := target.Get()
+	targetcorp, err := corporation_manager.GetCorporationHandler(crv.CorpTargetID)
+	if err != nil {
+		return crm, nil
+	}
+
+	tcorp := targetcorp.Get()
+	crm.Call(func(crv *caravan.Caravan) {
+		if tcorp.OwnerID == 0 {
+			if err := crv.Accept(dbh, tcorp.ID); err != nil {
+				logger.Errorf("CrvCtrl", "Failed to auto accept by %d, caravan %+v", tcorp.ID, crv)
+			}
+			return
+		}
+		crv.ApplyProposalRules(dbh, &tcorp, &targetCity)
+	})
+	return crm, nil
+}
+
+//Templates GET /caravan/templates list contract templates of current corporation.
+func Templates(w http.ResponseWriter, req *http.Request) {
+	if !webtools.CheckLogged(w, req) {
+		return
+	}
+
+	corpID, err := webtools.CurrentCorpID(req)
+	if err != nil {
+		webtools.Fail(w, req, "can't fetch templates without a corporation", "")
+		return
+	}
+
+	dbh := db.New()
+	defer dbh.Close()
+
+	var data templatesInfo
+	data.CorpID = corpID
+	data.Templates, err = caravan.TemplatesByCorpID(dbh, corpID)
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusInternalServerError, "unable to fetch templates", "")
+		return
+	}
+
+	if webtools.IsAPIv1(req) {
+		webtools.GenerateAPIData(w, dto.NewTemplates(data.Templates))
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPIData(w, data)
+	} else {
+		templates.RenderTemplate(w, req, "caravan/templates", data)
+	}
+}
+
+//SaveTemplate POST /caravan/:crv_id/template save caravan terms as a template. May provide a json {Name}.
+func SaveTemplate(w http.ResponseWriter, req *http.Request) {
+	if !webtools.CheckLogged(w, req) {
+		return
+	}
+
+	crvID, err := webtools.GetInt(req, "crv_id")
+	if err != nil {
+		webtools.Fail(w, req, "invalid caravan id provided.", "")
+		return
+	}
+
+	crm, err := caravan_manager.GetCaravanHandler(crvID)
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unknown caravan", "")
+		return
+	}
+
+	corpID, _ := webtools.CurrentCorpID(req)
+	crv := crm.Get()
+	if crv.CorpOriginID != corpID {
+		webtools.FailWithStatus(w, req, http.StatusForbidden, "only caravan origin may save its terms", "")
+		return
+	}
+
+	var t templateJSON
+	if webtools.IsAPI(req) {
+		json.NewDecoder(req.Body).Decode(&t)
+	}
+
+	dbh := db.New()
+	defer dbh.Close()
+
+	tmpl := caravan.NewTemplate(&crv, corpID, t.Name)
+	err = tmpl.Insert(dbh)
+	if err != nil {
+		logger.Infof("CrvCtrl", "%s", err)
+		webtools.FailWithStatus(w, req, http.StatusInternalServerError, "failed to save template", "")
+		return
+	}
+
+	if webtools.IsAPIv1(req) {
+		webtools.GenerateAPICreated(w, dto.NewTemplate(tmpl))
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPICreated(w, tmpl)
+	} else {
+		webtools.Redirect(w, req, "")
+	}
+}
+
+//ProposeTemplate POST /caravan/templates/:template_id/propose propose a new caravan based on template.
+func ProposeTemplate(w http.ResponseWriter, req *http.Request) {
+	if !webtools.CheckLogged(w, req) {
+		return
+	}
+
+	templateID, err := webtools.GetInt(req, "template_id")
+	if err != nil {
+		webtools.Fail(w, req, "invalid template id provided.", "")
+		return
+	}
+
+	corpID, _ := webtools.CurrentCorpID(req)
+	corp, err := corporation_manager.GetCorporationHandler(corpID)
+	if err != nil {
+		webtools.Fail(w, req, "can't propose caravan without a corporation", "")
+		return
+	}
+
+	dbh := db.New()
+	defer dbh.Close()
+
+	tmpl, err := caravan.TemplateByID(dbh, templateID, corpID)
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unknown template", "")
+		return
+	}
+
+	_, err = submitProposal(dbh, tmpl.Proposal(corp.Get().MapID))
+	if err != nil {
+		webtools.Fail(w, req, err.Error(), "")
+		return
+	}
+
+	if webtools.IsAPI(req) {
+		webtools.GenerateAPIOkAndSend(w)
+	} else {
+		webtools.Redirect(w, req, "")
+	}
+}
+
+//DropTemplate POST /caravan/templates/:template_id/drop remove a template.
+func DropTemplate(w http.ResponseWriter, req *http.Request) {
+	if !webtools.CheckLogged(w, req) {
+		return
+	}
+
+	templateID, err := webtools.GetInt(req, "template_id")
+	if err != nil {
+		webtools.Fail(w, req, "invalid template id provided.", "")
+		return
+	}
+
+	corpID, _ := webtools.CurrentCorpID(req)
+
+	dbh := db.New()
+	defer dbh.Close()
+
+	err = caravan.DropTemplate(dbh, templateID, corpID)
+	if err != nil {
+		logger.Infof("CrvCtrl", "%s", err)
+		webtools.FailWithStatus(w, req, http.StatusInternalServerError, "failed to drop template", "")
+		return
+	}
+
+	if webtools.IsAPI(req) {
+		webtools.GenerateAPIOkAndSend(w)
+	} else {
+		webtools.Redirect(w, req, "")
+	}
+}
+
+//Renew POST /caravan/:crv_id/renew propose again a terminated contract with identical terms.
+func Renew(w http.ResponseWriter, req *http.Request) {
+	if !webtools.CheckLogged(w, req) {
+		return
+	}
+
+	crvID, err := webtools.GetInt(req, "crv_id")
+	if err != nil {
+		webtools.Fail(w, req, "invalid caravan id provided.", "")
+		return
+	}
+
+	crm, err := caravan_manager.GetCaravanHandler(crvID)
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unknown caravan", "")
+		return
+	}
+
+	corpID, _ := webtools.CurrentCorpID(req)
+	if crm.Get().CorpOriginID != corpID {
+		webtools.FailWithStatus(w, req, http.StatusForbidden, "only caravan origin may renew the contract", "")
+		return
+	}
+
+	// renewal is reserved first so that concurrent requests and auto renewal can't renew it twice.
+	var renewal *caravan.Caravan
+	crm.Call(func(caravan *caravan.Caravan) {
+		renewal, err = caravan.StartRenewal()
+	})
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusConflict, err.Error(), "")
+		return
+	}
+
+	dbh := db.New()
+	defer dbh.Close()
+
+	_, err = submitProposal(dbh, renewal)
+	crm.Call(func(caravan *caravan.Caravan) {
+		if err != nil {
+			caravan.EndRenewal(0)
+			return
+		}
+		caravan.EndRenewal(renewal.ID)
+		caravan.Update(dbh)
+	})
+	if err != nil {
+		webtools.Fail(w, req, err.Error(), "")
+		return
+	}
+
+	if webtools.IsAPI(req) {
+		webtools.GenerateAPIOkAndSend(w)
+	} else {
+		webtools.Redirect(w, req, "")
+	}
+}
+
+//AutoRenew POST /caravan/:crv_id/auto_renew toggle corporation opt-in for automatic renewal.
+func AutoRenew(w http.ResponseWriter, req *http.Request) {
+	if !webtools.CheckLogged(w, req) {
+		return
+	}
+
+	crvID, err := webtools.GetInt(req, "crv_id")
+	if err != nil {
+		webtools.Fail(w, req, "invalid caravan id provided.", "")
+		return
+	}
+
+	crm, err := caravan_manager.GetCaravanHandler(crvID)
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unknown caravan", "")
+		return
+	}
+
+	corpID, _ := webtools.CurrentCorpID(req)
+
+	dbh := db.New()
+	defer dbh.Close()
+
+	crm.Call(func(caravan *caravan.Caravan) {
+		_, err = caravan.ToggleAutoRenew(corpID)
+		if err == nil {
+			err = caravan.Update(dbh)
+		}
+	})
+
+	if err != nil {
+		webtools.Fail(w, req, err.Error(), "")
+		return
+	}
+
+	if webtools.IsAPI(req) {
+		webtools.GenerateAPIOkAndSend(w)
+	} else {
+		webtools.Redirect(w, req, "")
+	}
+}
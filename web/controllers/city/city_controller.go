@@ -2,9 +2,7 @@
 package city_controller
 
 import (
-	"encoding/json"
 	"fmt"
-	"log"
 	"math"
 	"net/http"
 	"sort"
@@ -15,6 +13,7 @@ import (
 	"upsilon_cities_go/lib/cities/city"
 	"upsilon_cities_go/lib/cities/city_manager"
 	"upsilon_cities_go/lib/cities/corporation"
+	"upsilon_cities_go/lib/cities/corporation_manager"
 	grid_evolution "upsilon_cities_go/lib/cities/evolution/grid"
 	"upsilon_cities_go/lib/cities/item"
 	"upsilon_cities_go/lib/cities/map/grid"
@@ -23,6 +22,8 @@ import (
 	lib_tools "upsilon_cities_go/lib/cities/tools"
 	"upsilon_cities_go/lib/db"
 	"upsilon_cities_go/lib/misc/config/gameplay"
+	"upsilon_cities_go/lib/misc/logger"
+	"upsilon_cities_go/web/dto"
 	"upsilon_cities_go/web/templates"
 	"upsilon_cities_go/web/webtools"
 )
@@ -98,6 +99,13 @@ type simpleCity struct {
 	Ressources      []simpleProducer
 	Factories       []simpleProducer
 	Caravans        []simpleCaravan
+	Claims          []city.Claim
+	ContestEnd      string
+	CanClaim        bool
+	ClaimCost       int
+	ClaimFame       int
+	FameHistory     []city.FameEntry
+	FameChart       string // svg polyline points of fame history.
 }
 
 type upgrade struct {
@@ -106,6 +114,30 @@ type upgrade struct {
 	Result  bool
 }
 
+// fameChart points of a polyline drawing fame history in a width x height box.
+func fameChart(history []city.FameEntry, width int, height int) string {
+	if len(history) < 2 {
+		return ""
+	}
+
+	low, high := history[0].Fame, history[0].Fame
+	for _, v := range history {
+		low = lib_tools.Min(low, v.Fame)
+		high = lib_tools.Max(high, v.Fame)
+	}
+	if high == low {
+		high = low + 1
+	}
+
+	points := make([]string, 0, len(history))
+	for idx, v := range history {
+		x := idx * width / (len(history) - 1)
+		y := height - (v.Fame-low)*height/(high-low)
+		points = append(points, fmt.Sprintf("%d,%d", x, y))
+	}
+	return strings.Join(points, " ")
+}
+
 func prepareSingleCity(corpID int, cm *city_manager.Handler) (res simpleCity) {
 	callback := make(chan simpleCity)
 	defer close(callback)
@@ -127,8 +159,15 @@ func prepareSingleCity(corpID int, cm *city_manager.Handler) (res simpleCity) {
 		rs.CorporationName = cty.CorporationName
 		rs.Filled = cty.CorporationID == corpID
 		rs.Fame = cty.Fame[corpID]
+		rs.Claims = cty.Claims
+		if !cty.ContestEnd.IsZero() {
+			rs.ContestEnd = cty.ContestEnd.Format(time.RFC3339)
+		}
+		rs.CanClaim = cty.CanClaim(corpID) == nil
+		rs.ClaimCost = city.ClaimCost()
+		rs.ClaimFame = city.ClaimFame()
 
-		log.Printf("City: Preping city for display targeted corp %d city fame %v found fame %d", corpID, cty.Fame, rs.Fame)
+		logger.Infof("City", "Preping city for display targeted corp %d city fame %v found fame %d", corpID, cty.Fame, rs.Fame)
 
 		keylist := []int{}
 
@@ -295,6 +334,17 @@ func prepareSingleCity(corpID int, cm *city_manager.Handler) (res simpleCity) {
 
 	res = <-callback
 
+	if corpID != 0 {
+		dbh := db.New()
+		defer dbh.Close()
+		history, err := city.FameHistory(dbh, res.ID, corpID, gameplay.GetInt("fame_history_length", 50))
+		if err != nil {
+			logger.Infof("CityCtrl", "%s", err)
+		}
+		res.FameHistory = history
+		res.FameChart = fameChart(history, 300, 80)
+	}
+
 	cb := make(chan simpleNeighbourg)
 	defer close(cb)
 
@@ -368,7 +418,7 @@ func Index(w http.ResponseWriter, req *http.Request) {
 	gm, err := grid_manager.GetGridHandler(id)
 
 	if err != nil {
-		webtools.Fail(w, req, "Unknown map id", fmt.Sprintf("/map"))
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "Unknown map id", fmt.Sprintf("/map"))
 		return
 	}
 
@@ -383,12 +433,13 @@ func Index(w http.ResponseWriter, req *http.Request) {
 		callback <- res
 	})
 
-	if webtools.IsAPI(req) {
-		webtools.GenerateAPIOk(w)
-		json.NewEncoder(w).Encode(prepareCities(<-callback))
+	if webtools.IsAPIv1(req) {
+		webtools.GenerateAPIData(w, citiesDTO(prepareCities(<-callback)))
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPIData(w, prepareCities(<-callback))
 	} else {
 
-		webtools.GenerateAPIError(w, "Accessible only through API")
+		webtools.GenerateAPIErrorWithStatus(w, http.StatusNotFound, "Accessible only through API")
 	}
 }
 
@@ -402,7 +453,7 @@ func Show(w http.ResponseWriter, req *http.Request) {
 
 	cm, err := city_manager.GetCityHandler(cityID)
 	if err != nil {
-		webtools.Fail(w, req, "Unknown city id", "")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "Unknown city id", "")
 		return
 	}
 
@@ -425,10 +476,11 @@ func Show(w http.ResponseWriter, req *http.Request) {
 
 	corpid, _ := webtools.CurrentCorpID(req)
 
-	log.Printf("CityCtrl: About to display city: %d as corp %d", cityID, corpid)
-	if webtools.IsAPI(req) {
-		webtools.GenerateAPIOk(w)
-		json.NewEncoder(w).Encode(prepareSingleCity(corpid, cm))
+	logger.Debugf("CityCtrl", "About to display city: %d as corp %d", cityID, corpid)
+	if webtools.IsAPIv1(req) {
+		webtools.GenerateAPIData(w, cityDTO(prepareSingleCity(corpid, cm)))
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPIData(w, prepareSingleCity(corpid, cm))
 	} else {
 		templates.RenderTemplate(w, req, "city/show", prepareSingleCity(corpid, cm))
 	}
@@ -446,11 +498,15 @@ func IDShow(w http.ResponseWriter, req *http.Request) {
 	gm, _ := grid_manager.GetGridHandler(gridId)
 	thisgrid := gm.Get()
 	city := thisgrid.GetCityByLocation(node.NP(cityX, cityY))
+	if city == nil {
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "No city at location", "")
+		return
+	}
 	cityID := city.ID
 
 	cm, err := city_manager.GetCityHandler(cityID)
 	if err != nil {
-		webtools.Fail(w, req, "Unknown city id", "")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "Unknown city id", "")
 		return
 	}
 
@@ -471,8 +527,11 @@ func IDShow(w http.ResponseWriter, req *http.Request) {
 
 	corpid, _ := webtools.CurrentCorpID(req)
 
-	log.Printf("CityCtrl: About to display city: %d as corp %d", cityID, corpid)
-	if webtools.IsAPI(req) {
+	logger.Debugf("CityCtrl", "About to display city: %d as corp %d", cityID, corpid)
+	if webtools.IsAPIv1(req) {
+		webtools.GenerateAPIData(w, cityDTO(prepareSingleCity(corpid, cm)))
+	} else if webtools.IsAPI(req) {
+		// legacy API replies with an HTML fragment.
 		templates.RenderTemplate(w, req, "city/show", prepareSingleCity(corpid, cm))
 	}
 }
@@ -486,13 +545,14 @@ func ProducerUpgrade(w http.ResponseWriter, req *http.Request) {
 
 	cm, err := city_manager.GetCityHandler(cityID)
 	if err != nil {
-		webtools.Fail(w, req, "Unknown city id", "")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "Unknown city id", "")
 		return
 	}
 
-	if webtools.IsAPI(req) {
-		webtools.GenerateAPIOk(w)
-		json.NewEncoder(w).Encode(upgradeSingleProducer(cm, producerID, action, product))
+	if webtools.IsAPIv1(req) {
+		webtools.GenerateAPIData(w, upgradeDTO(upgradeSingleProducer(cm, producerID, action, product)))
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPIData(w, upgradeSingleProducer(cm, producerID, action, product))
 	}
 }
 
@@ -534,6 +594,7 @@ func upgradeSingleProducer(cm *city_manager.Handler, prodID int, actionID int, p
 type itemOpRes struct {
 	Item       item.Item
 	Producable bool
+	Value      float64
 	Success    bool
 }
 
@@ -545,7 +606,7 @@ func Give(w http.ResponseWriter, req *http.Request) {
 
 	corpid, err := webtools.CurrentCorpID(req)
 	if err != nil {
-		webtools.Fail(w, req, "unable to find corporation ... can't proceed", "/map")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unable to find corporation ... can't proceed", "/map")
 		return
 	}
 
@@ -553,7 +614,7 @@ func Give(w http.ResponseWriter, req *http.Request) {
 
 	cm, err := city_manager.GetCityHandler(cityID)
 	if err != nil {
-		webtools.Fail(w, req, "Unknown city id", "")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "Unknown city id", "")
 		return
 	}
 
@@ -564,7 +625,7 @@ func Give(w http.ResponseWriter, req *http.Request) {
 	}
 
 	if !cm.Get().Storage.Has(int64(itm)) {
-		webtools.Fail(w, req, "requested item isn't in store", "")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "requested item isn't in store", "")
 		return
 	}
 
@@ -597,15 +658,108 @@ func Give(w http.ResponseWriter, req *http.Request) {
 
 	if opres.Success {
 
-		log.Printf("CityCtrl: About to display city: %d as corp %d", cityID, corpid)
-		if webtools.IsAPI(req) {
-			webtools.GenerateAPIOk(w)
-			json.NewEncoder(w).Encode(opres.Item)
+		logger.Debugf("CityCtrl", "About to display city: %d as corp %d", cityID, corpid)
+		if webtools.IsAPIv1(req) {
+			webtools.GenerateAPIData(w, dto.NewItem(opres.Item))
+		} else if webtools.IsAPI(req) {
+			webtools.GenerateAPIData(w, opres.Item)
 		} else {
 			templates.RenderTemplate(w, req, "city/item", opres.Item)
 		}
 	} else {
-		webtools.Fail(w, req, "fail to perform operation", "/map")
+		webtools.FailWithStatus(w, req, http.StatusInternalServerError, "fail to perform operation", "/map")
+	}
+}
+
+type claimRes struct {
+	CityID     int
+	Contested  bool
+	Claimed    bool
+	ContestEnd string
+}
+
+//Claim POST /city/:city_id/claim claim an uncorporated city for current corporation.
+func Claim(w http.ResponseWriter, req *http.Request) {
+	if !webtools.CheckLogged(w, req) {
+		return
+	}
+
+	corpid, err := webtools.CurrentCorpID(req)
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unable to find corporation ... can't proceed", "/map")
+		return
+	}
+
+	cityID, err := webtools.GetInt(req, "city_id")
+
+	cm, err := city_manager.GetCityHandler(cityID)
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "Unknown city id", "")
+		return
+	}
+
+	corpm, err := corporation_manager.GetCorporationHandler(corpid)
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unable to find corporation ... can't proceed", "/map")
+		return
+	}
+
+	cty := cm.Get()
+	err = cty.CanClaim(corpid)
+	if err != nil {
+		webtools.Fail(w, req, err.Error(), "")
+		return
+	}
+
+	dbh := db.New()
+	defer dbh.Close()
+
+	// claim cost is locked before hand, refunded when claim fails.
+	cost := city.ClaimCost()
+	var claim city.Claim
+	corpm.Call(func(corp *corporation.Corporation) {
+		if corp.Credits < cost {
+			err = fmt.Errorf("not enough credits, claim costs %d", cost)
+			return
+		}
+		corp.Credits -= cost
+		corp.Update(dbh)
+		claim = city.Claim{CorporationID: corp.ID, CorporationName: corp.Name, Paid: cost}
+	})
+
+	if err != nil {
+		webtools.Fail(w, req, err.Error(), "")
+		return
+	}
+
+	res := claimRes{CityID: cityID}
+	cm.Call(func(cty *city.City) {
+		res.Contested, err = cty.FileClaim(claim, time.Now().UTC())
+		if err != nil {
+			return
+		}
+		if !res.Contested {
+			res.Claimed = cty.ResolveClaims(dbh, time.Now().UTC())
+		} else {
+			res.ContestEnd = cty.ContestEnd.Format(time.RFC3339)
+			cty.Update(dbh)
+		}
+	})
+
+	if err != nil {
+		corpm.Call(func(corp *corporation.Corporation) {
+			corp.Credits += cost
+			corp.Update(dbh)
+		})
+		webtools.Fail(w, req, err.Error(), "")
+		return
+	}
+
+	logger.Infof("CityCtrl", "Corporation %d claimed city %d (contested: %v)", corpid, cityID, res.Contested)
+	if webtools.IsAPIv1(req) {
+		webtools.GenerateAPIData(w, claimDTO(res))
+	} else {
+		webtools.GenerateAPIData(w, res)
 	}
 }
 
@@ -617,7 +771,7 @@ func Drop(w http.ResponseWriter, req *http.Request) {
 
 	corpid, err := webtools.CurrentCorpID(req)
 	if err != nil {
-		webtools.Fail(w, req, "unable to find corporation ... can't proceed", "/map")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unable to find corporation ... can't proceed", "/map")
 		return
 	}
 
@@ -625,7 +779,7 @@ func Drop(w http.ResponseWriter, req *http.Request) {
 
 	cm, err := city_manager.GetCityHandler(cityID)
 	if err != nil {
-		webtools.Fail(w, req, "Unknown city id", "")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "Unknown city id", "")
 		return
 	}
 
@@ -636,7 +790,7 @@ func Drop(w http.ResponseWriter, req *http.Request) {
 	}
 
 	if !cm.Get().Storage.Has(int64(itm)) {
-		webtools.Fail(w, req, "requested item isn't in store", "")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "requested item isn't in store", "")
 		return
 	}
 
@@ -661,15 +815,16 @@ func Drop(w http.ResponseWriter, req *http.Request) {
 
 	if opres.Success {
 
-		log.Printf("CityCtrl: About to display city: %d as corp %d", cityID, corpid)
-		if webtools.IsAPI(req) {
-			webtools.GenerateAPIOk(w)
-			json.NewEncoder(w).Encode(opres.Item)
+		logger.Debugf("CityCtrl", "About to display city: %d as corp %d", cityID, corpid)
+		if webtools.IsAPIv1(req) {
+			webtools.GenerateAPIData(w, dto.NewItem(opres.Item))
+		} else if webtools.IsAPI(req) {
+			webtools.GenerateAPIData(w, opres.Item)
 		} else {
 			templates.RenderTemplate(w, req, "city/item", opres.Item)
 		}
 	} else {
-		webtools.Fail(w, req, "fail to perform operation", "/map")
+		webtools.FailWithStatus(w, req, http.StatusInternalServerError, "fail to perform operation", "/map")
 	}
 }
 
@@ -681,7 +836,7 @@ func Sell(w http.ResponseWriter, req *http.Request) {
 
 	corpid, err := webtools.CurrentCorpID(req)
 	if err != nil {
-		webtools.Fail(w, req, "unable to find corporation ... can't proceed", "/map")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unable to find corporation ... can't proceed", "/map")
 		return
 	}
 
@@ -689,7 +844,7 @@ func Sell(w http.ResponseWriter, req *http.Request) {
 
 	cm, err := city_manager.GetCityHandler(cityID)
 	if err != nil {
-		webtools.Fail(w, req, "Unknown city id", "")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "Unknown city id", "")
 		return
 	}
 
@@ -700,7 +855,7 @@ func Sell(w http.ResponseWriter, req *http.Request) {
 	}
 
 	if !cm.Get().Storage.Has(int64(itm)) {
-		webtools.Fail(w, req, "requested item isn't in store", "")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "requested item isn't in store", "")
 		return
 	}
 
@@ -718,6 +873,7 @@ func Sell(w http.ResponseWriter, req *http.Request) {
 		r.Item = city.Storage.Content[int64(itm)]
 		r.Success = true
 		r.Producable = city.CanProduce(r.Item)
+		r.Value = city.SaleValue(r.Item, r.Item.Quantity)
 		city.Storage.Remove(int64(itm), 0) // remove all
 		cb <- r
 	})
@@ -729,21 +885,18 @@ func Sell(w http.ResponseWriter, req *http.Request) {
 		corpm, _ := webtools.CurrentCorp(req)
 
 		corpm.Call(func(corp *corporation.Corporation) {
-			if opres.Producable {
-				corp.Credits += int(math.Floor(float64(opres.Item.Price()*opres.Item.Quantity) * gameplay.GetFloat("producable_item_price", 0.5)))
-			} else {
-				corp.Credits += int(math.Floor(float64(opres.Item.Price()*opres.Item.Quantity) * gameplay.GetFloat("unproducable_item_price", 1)))
-			}
+			corp.Sell(opres.Value)
 		})
 
-		log.Printf("CityCtrl: About to display city: %d as corp %d", cityID, corpid)
-		if webtools.IsAPI(req) {
-			webtools.GenerateAPIOk(w)
-			json.NewEncoder(w).Encode(opres.Item)
+		logger.Debugf("CityCtrl", "About to display city: %d as corp %d", cityID, corpid)
+		if webtools.IsAPIv1(req) {
+			webtools.GenerateAPIData(w, dto.NewItem(opres.Item))
+		} else if webtools.IsAPI(req) {
+			webtools.GenerateAPIData(w, opres.Item)
 		} else {
 			templates.RenderTemplate(w, req, "city/item", opres.Item)
 		}
 	} else {
-		webtools.Fail(w, req, "fail to perform operation", "/map")
+		webtools.FailWithStatus(w, req, http.StatusInternalServerError, "fail to perform operation", "/map")
 	}
 }
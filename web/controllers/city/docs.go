@@ -0,0 +1,21 @@
+package city_controller
+
+import (
+	"upsilon_cities_go/web/dto"
+	"upsilon_cities_go/web/openapi"
+)
+
+//Docs API routes of cities.
+func Docs() []openapi.Doc {
+	return []openapi.Doc{
+		{Method: "GET", Path: "/map/{map_id}/cities", Summary: "Cities of map.", Data: []dto.CitySummary{}},
+		{Method: "GET", Path: "/map/{map_id}/city/X/{x_loc}/Y/{y_loc}", Summary: "City at location.", Data: dto.City{}},
+		{Method: "GET", Path: "/map/{map_id}/corp/{corp_id}/city/X/{x_loc}/Y/{y_loc}", Summary: "City at location as seen by a corporation.", Data: dto.City{}},
+		{Method: "GET", Path: "/city/{city_id}", Summary: "City details.", Data: dto.City{}},
+		{Method: "POST", Path: "/city/{city_id}/give/{item}", Summary: "Give an item to city, admin only.", Data: dto.Item{}},
+		{Method: "POST", Path: "/city/{city_id}/drop/{item}", Summary: "Drop an item from city storage.", Data: dto.Item{}},
+		{Method: "POST", Path: "/city/{city_id}/sell/{item}", Summary: "Sell an item from city storage.", Data: dto.Item{}},
+		{Method: "POST", Path: "/city/{city_id}/claim", Summary: "Claim an uncorporated city.", Data: dto.ClaimResult{}},
+		{Method: "POST", Path: "/city/{city_id}/producer/{producer_id}/{action}/{product}", Summary: "Upgrade a producer.", Data: dto.UpgradeResult{}},
+	}
+}
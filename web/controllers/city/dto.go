@@ -0,0 +1,123 @@
+package city_controller
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"upsilon_cities_go/web/dto"
+)
+
+//parseTime display times are RFC3339, unset when empty.
+func parseTime(s string) *time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+func neighboursDTO(neighbours []simpleNeighbourg) []dto.CityRef {
+	res := make([]dto.CityRef, 0, len(neighbours))
+	for _, v := range neighbours {
+		res = append(res, dto.CityRef{ID: v.ID, Name: v.Name, Location: dto.NewPoint(v.Location)})
+	}
+	return res
+}
+
+func producersDTO(producers []simpleProducer) []dto.Producer {
+	res := make([]dto.Producer, 0, len(producers))
+	for _, v := range producers {
+		prod := dto.Producer{
+			ID:            v.ProducerID,
+			Name:          v.ProducerName,
+			Active:        v.Active,
+			EndTime:       parseTime(v.EndTime),
+			Requirements:  make([]string, 0),
+			CanBigUpgrade: v.BigUpgrade,
+			Products:      make([]dto.Product, 0, len(v.Products)),
+		}
+		for _, rq := range strings.Split(v.Requirements, "\n") {
+			if rq != "" {
+				prod.Requirements = append(prod.Requirements, rq)
+			}
+		}
+		for _, p := range v.Products {
+			prod.CanUpgrade = prod.CanUpgrade || p.Upgrade
+			prod.Products = append(prod.Products, dto.Product{
+				ID:               p.ID,
+				Name:             p.ProductName,
+				Types:            append([]string{}, p.ProductType...),
+				Quality:          dto.NewRange(p.Quality),
+				Quantity:         dto.NewRange(p.Quantity),
+				QualityUpgrades:  p.UpQlt,
+				QuantityUpgrades: p.UpQty,
+			})
+		}
+		res = append(res, prod)
+	}
+	return res
+}
+
+//cityDTO city as replied by /api/v1.
+func cityDTO(cty simpleCity) dto.City {
+	res := dto.City{
+		ID:              cty.ID,
+		Name:            cty.Name,
+		Location:        dto.NewPoint(cty.Location),
+		CorporationID:   cty.CorpoID,
+		CorporationName: cty.CorporationName,
+		Owned:           cty.Filled,
+		Fame:            cty.Fame,
+		Neighbours:      neighboursDTO(cty.Neighbours),
+		Resources:       producersDTO(cty.Ressources),
+		Factories:       producersDTO(cty.Factories),
+		Caravans:        make([]dto.CityCaravan, 0, len(cty.Caravans)),
+		Claims:          dto.NewClaims(cty.Claims),
+		ContestEnd:      parseTime(cty.ContestEnd),
+		CanClaim:        cty.CanClaim,
+		ClaimCost:       cty.ClaimCost,
+		ClaimFame:       cty.ClaimFame,
+		FameHistory:     dto.NewFameHistory(cty.FameHistory),
+	}
+
+	if cty.Filled {
+		res.Storage = &dto.Storage{Count: cty.Storage.Count, Capacity: cty.Storage.Capacity, Items: make([]dto.Item, 0)}
+		names := make([]string, 0, len(cty.Storage.Item))
+		for k := range cty.Storage.Item {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, v := range names {
+			res.Storage.Items = append(res.Storage.Items, dto.NewItems(cty.Storage.Item[v].Items)...)
+		}
+	}
+
+	for _, v := range cty.Caravans {
+		res.Caravans = append(res.Caravans, dto.CityCaravan{
+			ID:         v.ID,
+			Outgoing:   v.To,
+			Exported:   v.ExportedItem,
+			Imported:   v.ImportedItem,
+			RemoteCity: v.CityName,
+			Active:     v.Active,
+		})
+	}
+	return res
+}
+
+//citiesDTO cities as listed by /api/v1.
+func citiesDTO(cities []simpleCity) []dto.CitySummary {
+	res := make([]dto.CitySummary, 0, len(cities))
+	for _, v := range cities {
+		res = append(res, dto.CitySummary{ID: v.ID, Name: v.Name, Location: dto.NewPoint(v.Location), Neighbours: neighboursDTO(v.Neighbours)})
+	}
+	return res
+}
+
+func claimDTO(res claimRes) dto.ClaimResult {
+	return dto.ClaimResult{CityID: res.CityID, Claimed: res.Claimed, Contested: res.Contested, ContestEnd: parseTime(res.ContestEnd)}
+}
+
+func upgradeDTO(res upgrade) dto.UpgradeResult {
+	return dto.UpgradeResult{CityID: res.CityID, Upgraded: res.Result}
+}
@@ -1,14 +1,14 @@
 package corporation_controller
 
 import (
-	"encoding/json"
-	"log"
 	"net/http"
+	"sort"
 	"time"
 	"upsilon_cities_go/lib/cities/caravan"
 	"upsilon_cities_go/lib/cities/caravan_manager"
 	"upsilon_cities_go/lib/cities/corporation"
 	"upsilon_cities_go/lib/cities/corporation_manager"
+	"upsilon_cities_go/lib/misc/logger"
 	"upsilon_cities_go/web/templates"
 	"upsilon_cities_go/web/webtools"
 )
@@ -19,6 +19,9 @@ type corpInfo struct {
 	ID   int
 	Name string
 
+	Reputation int
+	Tier       string
+
 	IsOwner  bool
 	Extended corpExtended
 }
@@ -38,15 +41,22 @@ type caravanMeta struct {
 	IsRequiringAction bool
 	IsActive          bool
 	CanCounter        bool
+	CanRenew          bool
+	CanSaveTemplate   bool
+	AutoRenew         bool
 
 	StringState string
 
 	NextUpdate    time.Time
 	NextUpdateStr string
+
+	Committed int
+	Priority  bool // proposal from a corporation with priority perk.
 }
 
 type corpExtended struct {
 	Credits           int
+	Committed         int // credits locked in caravan escrow.
 	ActiveCaravans    int
 	AvailableCaravans int
 
@@ -54,6 +64,31 @@ type corpExtended struct {
 	Cities   []int
 
 	Caravans []caravanMeta
+
+	Warehouse warehouseInfo
+	Rules     []ruleMeta
+	RoadWorks int
+
+	PriceBonus     int // percent added to sales.
+	NextTier       string
+	NextReputation int
+}
+
+// proposalPriority tell whether the other party of a caravan has proposal priority.
+// Must not be called for own corporation, as it is being accessed.
+func proposalPriority(corpID int, crv *caravan.Caravan) bool {
+	other := crv.CorpOriginID
+	if other == corpID {
+		other = crv.CorpTargetID
+	}
+	if other == corpID {
+		return false
+	}
+	cm, err := corporation_manager.GetCorporationHandler(other)
+	if err != nil {
+		return false
+	}
+	return cm.Get().Tier().ProposalPriority
 }
 
 //Show /corporation/:corp_id shows details of corporation
@@ -61,7 +96,7 @@ type corpExtended struct {
 func Show(w http.ResponseWriter, req *http.Request) {
 
 	if !webtools.IsLogged(req) {
-		webtools.Fail(w, req, "must be logged to access this content.", "")
+		webtools.FailWithStatus(w, req, http.StatusUnauthorized, "must be logged to access this content.", "")
 		return
 	}
 
@@ -70,9 +105,9 @@ func Show(w http.ResponseWriter, req *http.Request) {
 
 	corp, err := corporation_manager.GetCorporationHandler(reqCorp)
 	if err != nil {
-		log.Printf("Web: Failed access to %s due to %s", req.URL.String(), "Corporation doesn't exist or has been kicked out of the region")
+		logger.Errorf("Web", "Failed access to %s due to %s", req.URL.String(), "Corporation doesn't exist or has been kicked out of the region")
 		if webtools.IsAPI(req) {
-			webtools.GenerateAPIError(w, "Corporation doesn't exist or has been kicked out of the region")
+			webtools.GenerateAPIErrorWithStatus(w, http.StatusNotFound, "Corporation doesn't exist or has been kicked out of the region")
 		} else {
 			webtools.GetSession(req).AddFlash("Corporation doesn't exist or has been kicked out of the region", "error")
 			http.Error(w, "Corporation doesn't exist or has been kicked out of the region", 500)
@@ -88,6 +123,8 @@ func Show(w http.ResponseWriter, req *http.Request) {
 
 		data.ID = corp.ID
 		data.Name = corp.Name
+		data.Reputation = corp.Reputation
+		data.Tier = corp.Tier().Name
 
 		if corpid == corp.ID {
 			data.IsOwner = true
@@ -95,7 +132,7 @@ func Show(w http.ResponseWriter, req *http.Request) {
 
 			storedCrv := make(map[int]bool)
 
-			log.Printf("CorpCtrl: Has %d caravans in stock", len(corp.CaravanID))
+			logger.Infof("CorpCtrl", "Has %d caravans in stock", len(corp.CaravanID))
 			for _, v := range corp.CaravanID {
 				if storedCrv[v] {
 					// already in.
@@ -107,7 +144,7 @@ func Show(w http.ResponseWriter, req *http.Request) {
 				defer close(ccb)
 				cm, err := caravan_manager.GetCaravanHandler(v)
 				if err != nil {
-					webtools.Fail(w, req, "unable to find caravans information for corporation", "")
+					webtools.FailWithStatus(w, req, http.StatusNotFound, "unable to find caravans information for corporation", "")
 
 					cb <- data
 					return
@@ -128,13 +165,21 @@ func Show(w http.ResponseWriter, req *http.Request) {
 					meta.IsWaiting = crv.IsWaiting()
 					meta.IsRequiringAction = (crv.State == caravan.CRVProposal && corpid == crv.CorpTargetID) || (crv.State == caravan.CRVCounterProposal && corpid == crv.CorpOriginID)
 					meta.CanCounter = meta.IsRequiringAction && crv.CorpTargetID == corpid
+					meta.CanRenew = crv.CanRenew() && crv.CorpOriginID == corpid
+					meta.CanSaveTemplate = crv.CorpOriginID == corpid
+					meta.AutoRenew = (crv.CorpOriginID == corpid && crv.OriginAutoRenew) || (crv.CorpTargetID == corpid && crv.TargetAutoRenew)
 					meta.NextUpdate = crv.NextChange
 					meta.NextUpdateStr = crv.NextChange.Format(time.RFC3339)
+					meta.Committed = crv.Committed(corpid)
+					if meta.IsRequiringAction {
+						meta.Priority = proposalPriority(corp.ID, crv)
+					}
 
 					ccb <- meta
 				})
 
 				mt := <-ccb
+				data.Extended.Committed += mt.Committed
 
 				if mt.IsDisplayed {
 					data.Extended.Caravans = append(data.Extended.Caravans, mt)
@@ -153,16 +198,30 @@ func Show(w http.ResponseWriter, req *http.Request) {
 			data.Extended.ActiveCaravans = i
 			data.Extended.IsViable = corp.IsViable()
 			data.Extended.Cities = corp.CitiesID
+			data.Extended.Warehouse = prepareWarehouse(corp)
+			data.Extended.Rules = prepareRules(corp).Rules
+			data.Extended.RoadWorks = len(corp.RoadWorks)
+			data.Extended.PriceBonus = int(corp.Tier().PriceBonus * 100)
+			if next, found := corp.NextTier(); found {
+				data.Extended.NextTier = next.Name
+				data.Extended.NextReputation = next.Reputation
+			}
+
+			// proposals of renowned corporations come first.
+			sort.SliceStable(data.Extended.Caravans, func(i, j int) bool {
+				return data.Extended.Caravans[i].Priority && !data.Extended.Caravans[j].Priority
+			})
 		}
 
 		cb <- data
 	})
 
 	data := <-cb
-	log.Printf("CorpCtrl: About to display corporation: %d as owner? %v", corpid, corpid == reqCorp)
-	if webtools.IsAPI(req) {
-		webtools.GenerateAPIOk(w)
-		json.NewEncoder(w).Encode(data)
+	logger.Debugf("CorpCtrl", "About to display corporation: %d as owner? %v", corpid, corpid == reqCorp)
+	if webtools.IsAPIv1(req) {
+		webtools.GenerateAPIData(w, corporationDTO(data))
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPIData(w, data)
 	} else {
 		templates.RenderTemplate(w, req, "corporation/show", data)
 	}
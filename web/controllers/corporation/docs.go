@@ -0,0 +1,24 @@
+package corporation_controller
+
+import (
+	"net/http"
+	"upsilon_cities_go/lib/cities/corporation"
+	"upsilon_cities_go/web/dto"
+	"upsilon_cities_go/web/openapi"
+)
+
+//Docs API routes of corporations.
+func Docs() []openapi.Doc {
+	return []openapi.Doc{
+		{Method: "GET", Path: "/corporation/{corp_id}", Summary: "Corporation details, extended for its owner.", Data: dto.Corporation{}},
+		{Method: "GET", Path: "/corporation/{corp_id}/warehouse", Summary: "Warehouse content and transfers.", Data: dto.Warehouse{}},
+		{Method: "POST", Path: "/corporation/{corp_id}/warehouse/upgrade", Summary: "Upgrade warehouse.", Data: dto.Warehouse{}},
+		{Method: "POST", Path: "/corporation/{corp_id}/warehouse/{item}/retrieve/{city_id}", Summary: "Ship an item from warehouse to a city.", Data: dto.Transfer{}},
+		{Method: "POST", Path: "/city/{city_id}/store/{item}", Summary: "Ship an item from a city to warehouse.", Data: dto.Transfer{}},
+		{Method: "GET", Path: "/corporation/{corp_id}/rules", Summary: "Standing rules answering proposals.", Data: []dto.Rule{}},
+		{Method: "POST", Path: "/corporation/{corp_id}/rules", Summary: "Add a standing rule.", Body: corporation.ProposalRule{}, Data: dto.Rule{}, Status: http.StatusCreated},
+		{Method: "POST", Path: "/corporation/{corp_id}/rules/{rule_id}/drop", Summary: "Drop a standing rule."},
+		{Method: "GET", Path: "/corporation/{corp_id}/roads", Summary: "Roads that may be built or upgraded.", Data: dto.Roads{}},
+		{Method: "POST", Path: "/corporation/{corp_id}/roads", Summary: "Quote or commission a road, replies 201 once commissioned.", Body: roadJSON{}, Data: dto.RoadWork{}, Status: http.StatusCreated},
+	}
+}
@@ -0,0 +1,94 @@
+package corporation_controller
+
+import (
+	"upsilon_cities_go/web/dto"
+)
+
+//corporationDTO corporation as replied by /api/v1, details are only provided to owner.
+func corporationDTO(data corpInfo) dto.Corporation {
+	res := dto.Corporation{ID: data.ID, Name: data.Name, Reputation: data.Reputation, Tier: data.Tier, Owned: data.IsOwner}
+	if !data.IsOwner {
+		return res
+	}
+
+	ext := data.Extended
+	res.Details = &dto.CorporationDetails{
+		Credits:        ext.Credits,
+		Committed:      ext.Committed,
+		ActiveCaravans: ext.ActiveCaravans,
+		Viable:         ext.IsViable,
+		CityIDs:        append([]int{}, ext.Cities...),
+		Caravans:       make([]dto.CaravanStatus, 0, len(ext.Caravans)),
+		RoadWorks:      ext.RoadWorks,
+		PriceBonus:     ext.PriceBonus,
+		NextTier:       ext.NextTier,
+		NextReputation: ext.NextReputation,
+	}
+	for _, v := range ext.Caravans {
+		res.Details.Caravans = append(res.Details.Caravans, dto.CaravanStatus{
+			ID:             v.ID,
+			OriginCity:     dto.Ref{ID: v.OriginCityID, Name: v.OriginCityName},
+			TargetCity:     dto.Ref{ID: v.TargetCityID, Name: v.TargetCityName},
+			Status:         v.StringState,
+			Active:         v.IsActive,
+			Moving:         v.IsMoving,
+			Waiting:        v.IsWaiting,
+			RequiresAction: v.IsRequiringAction,
+			CanCounter:     v.CanCounter,
+			CanRenew:       v.CanRenew,
+			AutoRenew:      v.AutoRenew,
+			Priority:       v.Priority,
+			Committed:      v.Committed,
+			NextUpdate:     v.NextUpdate,
+		})
+	}
+	return res
+}
+
+//warehouseDTO warehouse as replied by /api/v1.
+func warehouseDTO(data warehouseInfo) dto.Warehouse {
+	res := dto.Warehouse{
+		CorporationID: data.CorpID,
+		Count:         data.Count,
+		Capacity:      data.Capacity,
+		UpgradeCost:   data.UpgradeCost,
+		Items:         dto.NewItems(data.Items),
+		Transfers:     make([]dto.Transfer, 0, len(data.Transfers)),
+		Cities:        make([]dto.Ref, 0, len(data.Cities)),
+	}
+	for _, v := range data.Transfers {
+		res.Transfers = append(res.Transfers, dto.Transfer{
+			ID:          v.ID,
+			City:        dto.Ref{ID: v.CityID, Name: v.CityName},
+			ToWarehouse: v.ToWarehouse,
+			Items:       dto.NewItems(v.Items),
+			StartTime:   v.StartTime,
+			EndTime:     v.EndTime,
+		})
+	}
+	for _, v := range data.Cities {
+		res.Cities = append(res.Cities, dto.Ref{ID: v.ID, Name: v.Name})
+	}
+	return res
+}
+
+//rulesDTO standing rules as replied by /api/v1.
+func rulesDTO(data rulesInfo) []dto.Rule {
+	res := make([]dto.Rule, 0, len(data.Rules))
+	for _, v := range data.Rules {
+		res = append(res, dto.NewRule(v.ProposalRule))
+	}
+	return res
+}
+
+//roadsDTO road works and links as replied by /api/v1.
+func roadsDTO(data roadsInfo) dto.Roads {
+	res := dto.Roads{CorporationID: data.CorpID, Links: make([]dto.RoadLink, 0, len(data.Links)), Works: make([]dto.RoadWork, 0, len(data.Works))}
+	for _, v := range data.Links {
+		res.Links = append(res.Links, dto.RoadLink{FromCity: dto.Ref{ID: v.FromCityID, Name: v.FromCityName}, ToCity: dto.Ref{ID: v.ToCityID, Name: v.ToCityName}, HasRoad: v.HasRoad})
+	}
+	for _, v := range data.Works {
+		res.Works = append(res.Works, dto.NewRoadWork(v.RoadWork))
+	}
+	return res
+}
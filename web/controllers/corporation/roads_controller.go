@@ -0,0 +1,230 @@
+package corporation_controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+	"upsilon_cities_go/lib/cities/caravan_manager"
+	"upsilon_cities_go/lib/cities/city_manager"
+	"upsilon_cities_go/lib/cities/corporation"
+	"upsilon_cities_go/lib/cities/map/grid"
+	"upsilon_cities_go/lib/cities/map/grid_manager"
+	"upsilon_cities_go/lib/cities/map/map_generator/road_generator"
+	"upsilon_cities_go/lib/cities/node"
+	"upsilon_cities_go/lib/cities/tools"
+	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/logger"
+	"upsilon_cities_go/web/dto"
+	"upsilon_cities_go/web/templates"
+	"upsilon_cities_go/web/webtools"
+)
+
+type roadLink struct {
+	FromCityID   int
+	FromCityName string
+	ToCityID     int
+	ToCityName   string
+	HasRoad      bool
+}
+
+type roadWorkMeta struct {
+	corporation.RoadWork
+	Description string
+	EndTimeStr  string
+}
+
+type roadsInfo struct {
+	CorpID int
+	Links  []roadLink
+	Works  []roadWorkMeta
+}
+
+type roadJSON struct {
+	FromCityID int
+	ToCityID   int
+	Upgrade    bool
+	Quote      bool // only tell how much it would cost.
+}
+
+// tradeLinks cities linked by a caravan of the corporation, starting from an owned city.
+func tradeLinks(corp *corporation.Corporation) (res []roadLink) {
+	known := make(map[[2]int]bool)
+	for _, v := range corp.CaravanID {
+		cm, err := caravan_manager.GetCaravanHandler(v)
+		if err != nil {
+			continue
+		}
+		crv := cm.Get()
+
+		from, to := crv.CityOriginID, crv.CityTargetID
+		if !tools.InList(from, corp.CitiesID) {
+			from, to = to, from
+		}
+		if !tools.InList(from, corp.CitiesID) || known[[2]int{from, to}] {
+			continue
+		}
+		known[[2]int{from, to}] = true
+
+		fcm, err := city_manager.GetCityHandler(from)
+		if err != nil {
+			continue
+		}
+		tcm, err := city_manager.GetCityHandler(to)
+		if err != nil {
+			continue
+		}
+
+		var link roadLink
+		fcty := fcm.Get()
+		link.FromCityID = from
+		link.FromCityName = fcty.Name
+		link.ToCityID = to
+		link.ToCityName = tcm.Get().Name
+		for _, r := range fcty.Roads {
+			if r.ToCityID == to {
+				link.HasRoad = true
+			}
+		}
+		res = append(res, link)
+	}
+	return
+}
+
+func prepareRoads(corp *corporation.Corporation) (res roadsInfo) {
+	res.CorpID = corp.ID
+	res.Links = tradeLinks(corp)
+	res.Works = make([]roadWorkMeta, 0, len(corp.RoadWorks))
+	for _, v := range corp.RoadWorks {
+		res.Works = append(res.Works, roadWorkMeta{RoadWork: v, Description: v.String(), EndTimeStr: v.EndTime.Format(time.RFC3339)})
+	}
+	return
+}
+
+//Roads GET /corporation/:corp_id/roads road works of corporation and cities it may connect.
+func Roads(w http.ResponseWriter, req *http.Request) {
+	if !webtools.CheckLogged(w, req) {
+		return
+	}
+
+	corpm, ok := ownedCorp(w, req)
+	if !ok {
+		return
+	}
+
+	// caravans and cities are accessed, so avoid holding corporation.
+	corp := corpm.Get()
+	data := prepareRoads(&corp)
+
+	if webtools.IsAPIv1(req) {
+		webtools.GenerateAPIData(w, roadsDTO(data))
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPIData(w, data)
+	} else {
+		templates.RenderTemplate(w, req, "corporation/roads", data)
+	}
+}
+
+//CommissionRoad POST /corporation/:corp_id/roads build or upgrade a road to a trade partner. Expects a json {FromCityID, ToCityID, Upgrade, Quote}
+func CommissionRoad(w http.ResponseWriter, req *http.Request) {
+	if !webtools.CheckLogged(w, req) {
+		return
+	}
+	if !webtools.CheckAPI(w, req) {
+		return
+	}
+
+	corpm, ok := ownedCorp(w, req)
+	if !ok {
+		return
+	}
+
+	var rj roadJSON
+	err := json.NewDecoder(req.Body).Decode(&rj)
+	if err != nil {
+		webtools.Fail(w, req, "unable to parse provided json", "")
+		return
+	}
+
+	corp := corpm.Get()
+	linked := false
+	for _, v := range tradeLinks(&corp) {
+		if v.FromCityID == rj.FromCityID && v.ToCityID == rj.ToCityID {
+			linked = true
+		}
+	}
+	if !linked {
+		webtools.FailWithStatus(w, req, http.StatusForbidden, "roads may only be built from an owned city to a trade partner", "")
+		return
+	}
+
+	from, err := city_manager.GetCityHandler(rj.FromCityID)
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unknown city", "")
+		return
+	}
+	to, err := city_manager.GetCityHandler(rj.ToCityID)
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unknown city", "")
+		return
+	}
+
+	gm, err := grid_manager.GetGridHandler(corp.MapID)
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unable to find map", "")
+		return
+	}
+
+	fcty := from.Get()
+	var road node.Path
+	for _, v := range fcty.Roads {
+		if rj.Upgrade && v.ToCityID == rj.ToCityID {
+			road = v.Road
+		}
+	}
+
+	var work corporation.RoadWork
+	gm.Call(func(gd *grid.Grid) {
+		rg := road_generator.Create()
+		if road == nil {
+			road, err = rg.Plan(gd, fcty.Location, to.Get().Location)
+			if err != nil {
+				return
+			}
+		}
+		cost, nodes := rg.Cost(gd, road, rj.Upgrade)
+		work = corporation.NewRoadWork(rj.FromCityID, rj.ToCityID, rj.Upgrade, road, cost, nodes)
+	})
+
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusConflict, "no road may be built between these cities", "")
+		return
+	}
+
+	if !rj.Quote {
+		dbh := db.New()
+		defer dbh.Close()
+
+		corpm.Call(func(corp *corporation.Corporation) {
+			work, err = corp.CommissionRoad(work, time.Now().UTC())
+			if err == nil {
+				corp.Update(dbh)
+			}
+		})
+
+		if err != nil {
+			webtools.Fail(w, req, err.Error(), "")
+			return
+		}
+		logger.Infof("CorpCtrl", "Corporation %d commissioned %s", corpm.ID(), work.String())
+	}
+
+	var data interface{} = roadWorkMeta{RoadWork: work, Description: work.String(), EndTimeStr: work.EndTime.Format(time.RFC3339)}
+	if webtools.IsAPIv1(req) {
+		data = dto.NewRoadWork(work)
+	}
+	if rj.Quote {
+		webtools.GenerateAPIData(w, data)
+	} else {
+		webtools.GenerateAPICreated(w, data)
+	}
+}
@@ -0,0 +1,156 @@
+package corporation_controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"upsilon_cities_go/lib/cities/corporation"
+	"upsilon_cities_go/lib/cities/corporation_manager"
+	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/logger"
+	"upsilon_cities_go/web/dto"
+	"upsilon_cities_go/web/templates"
+	"upsilon_cities_go/web/webtools"
+)
+
+type ruleMeta struct {
+	corporation.ProposalRule
+	Description string
+}
+
+type rulesInfo struct {
+	CorpID int
+	Rules  []ruleMeta
+}
+
+func prepareRules(corp *corporation.Corporation) (res rulesInfo) {
+	res.CorpID = corp.ID
+	res.Rules = make([]ruleMeta, 0, len(corp.ProposalRules))
+	for _, v := range corp.ProposalRules {
+		res.Rules = append(res.Rules, ruleMeta{ProposalRule: v, Description: v.String()})
+	}
+	return
+}
+
+// ownedCorp ensure requested corporation is the one of current user.
+func ownedCorp(w http.ResponseWriter, req *http.Request) (*corporation_manager.Handler, bool) {
+	reqCorp, _ := webtools.GetInt(req, "corp_id")
+	corpm, err := webtools.CurrentCorp(req)
+	if err != nil || corpm.ID() != reqCorp {
+		webtools.FailWithStatus(w, req, http.StatusForbidden, "only accessible to corporation owner", "")
+		return nil, false
+	}
+	return corpm, true
+}
+
+//Rules GET /corporation/:corp_id/rules standing rules answering incoming proposals.
+func Rules(w http.ResponseWriter, req *http.Request) {
+	if !webtools.CheckLogged(w, req) {
+		return
+	}
+
+	corpm, ok := ownedCorp(w, req)
+	if !ok {
+		return
+	}
+
+	cb := make(chan rulesInfo)
+	defer close(cb)
+
+	corpm.Cast(func(corp *corporation.Corporation) {
+		cb <- prepareRules(corp)
+	})
+
+	data := <-cb
+
+	if webtools.IsAPIv1(req) {
+		webtools.GenerateAPIData(w, rulesDTO(data))
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPIData(w, data)
+	} else {
+		templates.RenderTemplate(w, req, "corporation/rules", data)
+	}
+}
+
+//AddRule POST /corporation/:corp_id/rules add a standing rule. Expects a json ProposalRule.
+func AddRule(w http.ResponseWriter, req *http.Request) {
+	if !webtools.CheckLogged(w, req) {
+		return
+	}
+	if !webtools.CheckAPI(w, req) {
+		return
+	}
+
+	corpm, ok := ownedCorp(w, req)
+	if !ok {
+		return
+	}
+
+	var rule corporation.ProposalRule
+	err := json.NewDecoder(req.Body).Decode(&rule)
+	if err != nil {
+		webtools.Fail(w, req, "unable to parse provided json", "")
+		return
+	}
+
+	dbh := db.New()
+	defer dbh.Close()
+
+	corpm.Call(func(corp *corporation.Corporation) {
+		rule, err = corp.AddRule(rule)
+		if err == nil {
+			corp.Update(dbh)
+		}
+	})
+
+	if err != nil {
+		webtools.Fail(w, req, err.Error(), "")
+		return
+	}
+
+	logger.Infof("CorpCtrl", "Corporation %d added %s", corpm.ID(), rule.String())
+	if webtools.IsAPIv1(req) {
+		webtools.GenerateAPICreated(w, dto.NewRule(rule))
+	} else {
+		webtools.GenerateAPICreated(w, ruleMeta{ProposalRule: rule, Description: rule.String()})
+	}
+}
+
+//DropRule POST /corporation/:corp_id/rules/:rule_id/drop remove a standing rule.
+func DropRule(w http.ResponseWriter, req *http.Request) {
+	if !webtools.CheckLogged(w, req) {
+		return
+	}
+
+	corpm, ok := ownedCorp(w, req)
+	if !ok {
+		return
+	}
+
+	ruleID, err := webtools.GetInt(req, "rule_id")
+	if err != nil {
+		webtools.Fail(w, req, "unable to parse rule id", "")
+		return
+	}
+
+	dbh := db.New()
+	defer dbh.Close()
+
+	corpm.Call(func(corp *corporation.Corporation) {
+		err = corp.DropRule(ruleID)
+		if err == nil {
+			corp.Update(dbh)
+		}
+	})
+
+	if err != nil {
+		webtools.Fail(w, req, err.Error(), "")
+		return
+	}
+
+	if webtools.IsAPI(req) {
+		webtools.GenerateAPIOkAndSend(w)
+	} else {
+		webtools.Redirect(w, req, fmt.Sprintf("/corporation/%d", corpm.ID()))
+	}
+}
@@ -0,0 +1,418 @@
+package corporation_controller
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+	"upsilon_cities_go/lib/cities/city"
+	"upsilon_cities_go/lib/cities/city_manager"
+	"upsilon_cities_go/lib/cities/corporation"
+	"upsilon_cities_go/lib/cities/corporation_manager"
+	"upsilon_cities_go/lib/cities/item"
+	"upsilon_cities_go/lib/cities/map/grid"
+	"upsilon_cities_go/lib/cities/map/grid_manager"
+	"upsilon_cities_go/lib/cities/tools"
+	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/logger"
+	"upsilon_cities_go/web/dto"
+	"upsilon_cities_go/web/templates"
+	"upsilon_cities_go/web/webtools"
+)
+
+type transferMeta struct {
+	ID          int
+	CityID      int
+	CityName    string
+	ToWarehouse bool
+	Items       []item.Item
+	StartTime   time.Time
+	EndTime     time.Time
+	EndTimeStr  string
+}
+
+type warehouseCity struct {
+	ID   int
+	Name string
+}
+
+type warehouseInfo struct {
+	CorpID      int
+	Count       int
+	Capacity    int
+	UpgradeCost int
+	Items       []item.Item
+	Transfers   []transferMeta
+	Cities      []warehouseCity
+}
+
+type transferRes struct {
+	Success  bool
+	Message  string
+	Transfer corporation.Transfer
+}
+
+func prepareWarehouse(corp *corporation.Corporation) (res warehouseInfo) {
+	res.CorpID = corp.ID
+	res.Count = corp.Warehouse.Count()
+	res.Capacity = corp.Warehouse.Capacity
+	res.UpgradeCost = corp.WarehouseUpgradeCost()
+	res.Items = make([]item.Item, 0, len(corp.Warehouse.Content))
+	for _, v := range corp.Warehouse.Content {
+		res.Items = append(res.Items, v)
+	}
+	sort.Slice(res.Items, func(i, j int) bool { return res.Items[i].ID < res.Items[j].ID })
+
+	res.Transfers = make([]transferMeta, 0, len(corp.Transfers))
+	for _, v := range corp.Transfers {
+		var meta transferMeta
+		meta.ID = v.ID
+		meta.CityID = v.CityID
+		meta.CityName = v.CityName
+		meta.ToWarehouse = v.ToWarehouse
+		meta.Items = v.Items
+		meta.StartTime = v.StartTime
+		meta.EndTime = v.EndTime
+		meta.EndTimeStr = v.EndTime.Format(time.RFC3339)
+		res.Transfers = append(res.Transfers, meta)
+	}
+
+	res.Cities = make([]warehouseCity, 0, len(corp.CitiesID))
+	for _, v := range corp.CitiesID {
+		cm, err := city_manager.GetCityHandler(v)
+		if err != nil {
+			continue
+		}
+		res.Cities = append(res.Cities, warehouseCity{ID: v, Name: cm.Get().Name})
+	}
+	return
+}
+
+// warehouseDistance seek road distance between city and corporation warehouse, and relocate warehouse if it lost its city.
+func warehouseDistance(corpm *corporation_manager.Handler, cm *city_manager.Handler) (int, error) {
+	cty := cm.Get()
+	warehouseCityID := cty.ID
+
+	corpm.Call(func(corp *corporation.Corporation) {
+		if corp.WarehouseCityID == 0 || !tools.InList(corp.WarehouseCityID, corp.CitiesID) {
+			corp.WarehouseCityID = cty.ID
+		}
+		warehouseCityID = corp.WarehouseCityID
+	})
+
+	if warehouseCityID == cty.ID {
+		return 0, nil
+	}
+
+	whm, err := city_manager.GetCityHandler(warehouseCityID)
+	if err != nil {
+		return 0, fmt.Errorf("unable to find warehouse city")
+	}
+
+	gm, err := grid_manager.GetGridHandler(cty.MapID)
+	if err != nil {
+		return 0, fmt.Errorf("unable to find map")
+	}
+
+	distance := 0
+	gm.Call(func(gd *grid.Grid) {
+		distance, err = gd.RoadDistanceBetween(cty.Location, whm.Get().Location)
+	})
+
+	if err != nil {
+		return 0, fmt.Errorf("no road between %s and warehouse", cty.Name)
+	}
+	return distance, nil
+}
+
+func ownedCity(w http.ResponseWriter, req *http.Request) (*corporation_manager.Handler, *city_manager.Handler, bool) {
+	corpm, err := webtools.CurrentCorp(req)
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unable to find corporation ... can't proceed", "/map")
+		return nil, nil, false
+	}
+
+	cityID, err := webtools.GetInt(req, "city_id")
+	if err != nil {
+		webtools.Fail(w, req, "unable to parse city id", "")
+		return nil, nil, false
+	}
+
+	cm, err := city_manager.GetCityHandler(cityID)
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "Unknown city id", "")
+		return nil, nil, false
+	}
+
+	if cm.Get().CorporationID != corpm.ID() {
+		webtools.FailWithStatus(w, req, http.StatusForbidden, "city isn't owned by corporation", "")
+		return nil, nil, false
+	}
+	return corpm, cm, true
+}
+
+//Warehouse GET /corporation/:corp_id/warehouse details of corporation warehouse.
+func Warehouse(w http.ResponseWriter, req *http.Request) {
+	if !webtools.CheckLogged(w, req) {
+		return
+	}
+
+	reqCorp, _ := webtools.GetInt(req, "corp_id")
+	corpid, _ := webtools.CurrentCorpID(req)
+
+	if reqCorp != corpid {
+		webtools.FailWithStatus(w, req, http.StatusForbidden, "warehouse is only accessible to its owner", "")
+		return
+	}
+
+	corpm, err := corporation_manager.GetCorporationHandler(corpid)
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "Corporation doesn't exist or has been kicked out of the region", "")
+		return
+	}
+
+	cb := make(chan warehouseInfo)
+	defer close(cb)
+
+	corpm.Cast(func(corp *corporation.Corporation) {
+		cb <- prepareWarehouse(corp)
+	})
+
+	data := <-cb
+
+	if webtools.IsAPIv1(req) {
+		webtools.GenerateAPIData(w, warehouseDTO(data))
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPIData(w, data)
+	} else {
+		templates.RenderTemplate(w, req, "corporation/warehouse", data)
+	}
+}
+
+//Store POST /city/:city_id/store/:item sends item from city to corporation warehouse.
+func Store(w http.ResponseWriter, req *http.Request) {
+	if !webtools.CheckLogged(w, req) {
+		return
+	}
+
+	corpm, cm, ok := ownedCity(w, req)
+	if !ok {
+		return
+	}
+
+	itm, err := webtools.GetInt(req, "item")
+	if err != nil {
+		webtools.Fail(w, req, "unable to parse requested item", "")
+		return
+	}
+
+	stored, found := cm.Get().Storage.Get(int64(itm))
+	if !found {
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "requested item isn't in store", "")
+		return
+	}
+
+	distance, err := warehouseDistance(corpm, cm)
+	if err != nil {
+		webtools.Fail(w, req, err.Error(), "")
+		return
+	}
+
+	// reserve space in warehouse first.
+	var reservation int64
+	corpm.Call(func(corp *corporation.Corporation) {
+		reservation, err = corp.Warehouse.Reserve(stored.Quantity)
+	})
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusConflict, "not enough space left in warehouse", "")
+		return
+	}
+
+	cb := make(chan transferRes)
+	defer close(cb)
+
+	cm.Cast(func(city *city.City) {
+		var r transferRes
+		it, found := city.Storage.Get(int64(itm))
+		if !found || it.Quantity < stored.Quantity {
+			r.Message = "requested item isn't in store"
+			cb <- r
+			return
+		}
+		city.Storage.Remove(int64(itm), stored.Quantity)
+		it.Quantity = stored.Quantity
+		stored = it
+		r.Success = true
+		cb <- r
+	})
+
+	opres := <-cb
+
+	if !opres.Success {
+		corpm.Call(func(corp *corporation.Corporation) {
+			corp.Warehouse.GiveBack(reservation)
+		})
+		webtools.Fail(w, req, opres.Message, "")
+		return
+	}
+
+	dbh := db.New()
+	defer dbh.Close()
+
+	corpm.Call(func(corp *corporation.Corporation) {
+		opres.Transfer, err = corp.StartTransfer(cm.Get().ID, cm.Get().Name, true, []item.Item{stored}, reservation, distance, tools.RoundNow())
+		if err != nil {
+			corp.Warehouse.GiveBack(reservation)
+			return
+		}
+		corp.Update(dbh)
+	})
+
+	if err != nil {
+		// transfer didn't happen, give item back to city.
+		cm.Call(func(city *city.City) {
+			city.Storage.Add(stored)
+		})
+		webtools.Fail(w, req, err.Error(), "")
+		return
+	}
+
+	cm.Call(func(city *city.City) {
+		city.Update(dbh)
+	})
+
+	logger.Infof("CorpCtrl", "%s", opres.Transfer.String())
+	if webtools.IsAPIv1(req) {
+		webtools.GenerateAPIData(w, dto.NewTransfer(opres.Transfer))
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPIData(w, opres.Transfer)
+	} else {
+		templates.RenderTemplate(w, req, "city/item", stored)
+	}
+}
+
+//Retrieve POST /corporation/:corp_id/warehouse/:item/retrieve/:city_id sends item from warehouse to city.
+func Retrieve(w http.ResponseWriter, req *http.Request) {
+	if !webtools.CheckLogged(w, req) {
+		return
+	}
+
+	corpm, cm, ok := ownedCity(w, req)
+	if !ok {
+		return
+	}
+
+	if reqCorp, _ := webtools.GetInt(req, "corp_id"); reqCorp != corpm.ID() {
+		webtools.FailWithStatus(w, req, http.StatusForbidden, "warehouse is only accessible to its owner", "")
+		return
+	}
+
+	itm, err := webtools.GetInt(req, "item")
+	if err != nil {
+		webtools.Fail(w, req, "unable to parse requested item", "")
+		return
+	}
+
+	stored, found := corpm.Get().Warehouse.Get(int64(itm))
+	if !found {
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "requested item isn't in warehouse", "")
+		return
+	}
+
+	distance, err := warehouseDistance(corpm, cm)
+	if err != nil {
+		webtools.Fail(w, req, err.Error(), "")
+		return
+	}
+
+	// reserve space in city first.
+	var reservation int64
+	cm.Call(func(city *city.City) {
+		reservation, err = city.Storage.Reserve(stored.Quantity)
+	})
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusConflict, "not enough space left in city", "")
+		return
+	}
+
+	dbh := db.New()
+	defer dbh.Close()
+
+	var opres transferRes
+	corpm.Call(func(corp *corporation.Corporation) {
+		it, found := corp.Warehouse.Get(int64(itm))
+		if !found || it.Quantity < stored.Quantity {
+			opres.Message = "requested item isn't in warehouse"
+			return
+		}
+		it.Quantity = stored.Quantity
+		opres.Transfer, err = corp.StartTransfer(cm.Get().ID, cm.Get().Name, false, []item.Item{it}, reservation, distance, tools.RoundNow())
+		if err != nil {
+			opres.Message = err.Error()
+			return
+		}
+		corp.Warehouse.Remove(int64(itm), stored.Quantity)
+		opres.Success = true
+		corp.Update(dbh)
+	})
+
+	cm.Call(func(city *city.City) {
+		if !opres.Success {
+			city.Storage.GiveBack(reservation)
+		}
+		city.Update(dbh)
+	})
+
+	if !opres.Success {
+		webtools.Fail(w, req, opres.Message, "")
+		return
+	}
+
+	logger.Infof("CorpCtrl", "%s", opres.Transfer.String())
+	if webtools.IsAPIv1(req) {
+		webtools.GenerateAPIData(w, dto.NewTransfer(opres.Transfer))
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPIData(w, opres.Transfer)
+	} else {
+		webtools.Redirect(w, req, fmt.Sprintf("/corporation/%d/warehouse", corpm.ID()))
+	}
+}
+
+//UpgradeWarehouse POST /corporation/:corp_id/warehouse/upgrade buy more warehouse capacity.
+func UpgradeWarehouse(w http.ResponseWriter, req *http.Request) {
+	if !webtools.CheckLogged(w, req) {
+		return
+	}
+
+	reqCorp, _ := webtools.GetInt(req, "corp_id")
+	corpm, err := webtools.CurrentCorp(req)
+	if err != nil || corpm.ID() != reqCorp {
+		webtools.FailWithStatus(w, req, http.StatusForbidden, "warehouse is only accessible to its owner", "")
+		return
+	}
+
+	dbh := db.New()
+	defer dbh.Close()
+
+	var data warehouseInfo
+	corpm.Call(func(corp *corporation.Corporation) {
+		err = corp.UpgradeWarehouse()
+		if err == nil {
+			corp.Update(dbh)
+		}
+		data = prepareWarehouse(corp)
+	})
+
+	if err != nil {
+		webtools.Fail(w, req, err.Error(), "")
+		return
+	}
+
+	if webtools.IsAPIv1(req) {
+		webtools.GenerateAPIData(w, warehouseDTO(data))
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPIData(w, data)
+	} else {
+		webtools.Redirect(w, req, fmt.Sprintf("/corporation/%d/warehouse", corpm.ID()))
+	}
+}
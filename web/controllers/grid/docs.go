@@ -0,0 +1,22 @@
+package grid_controller
+
+import (
+	"net/http"
+	"upsilon_cities_go/web/dto"
+	"upsilon_cities_go/web/openapi"
+)
+
+//Docs API routes of maps.
+func Docs() []openapi.Doc {
+	return []openapi.Doc{
+		{Method: "GET", Path: "/map", Summary: "List maps.", Data: []dto.MapSummary{}},
+		{Method: "POST", Path: "/map", Summary: "Generate a new map.", Form: []string{"regionTypeName"}, Data: dto.Map{}, Status: http.StatusCreated},
+		{Method: "GET", Path: "/map/{map_id}", Summary: "Map with corporation of current user.", Data: dto.MapInfo{}},
+		{Method: "GET", Path: "/map/{map_id}/corp/{corp_id}", Summary: "Map as seen by a corporation, admin only.", Data: dto.MapInfo{}},
+		{Method: "DELETE", Path: "/map/{map_id}", Summary: "Drop map."},
+		{Method: "GET", Path: "/map/{map_id}/select_corporation", Summary: "Corporations still available on map.", Data: []dto.Ref{}},
+		{Method: "POST", Path: "/map/{map_id}/select_corporation", Summary: "Take ownership of a corporation.", Form: []string{"corporation"}},
+		{Method: "GET", Path: "/map/{map_id}/standings", Summary: "Final standings of a closed map.", Data: dto.Standings{}},
+		{Method: "GET", Path: "/map/{map_id}/leaderboard", Summary: "Current leaderboard, ?sort= and ?snapshot= are optional.", Data: dto.Leaderboard{}},
+	}
+}
@@ -1,21 +1,24 @@
 package grid_controller
 
 import (
-	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"strconv"
+	"time"
 	"upsilon_cities_go/lib/cities/caravan_manager"
 	"upsilon_cities_go/lib/cities/city"
 	"upsilon_cities_go/lib/cities/corporation"
 	"upsilon_cities_go/lib/cities/corporation_manager"
+	grid_evolution "upsilon_cities_go/lib/cities/evolution/grid"
 	"upsilon_cities_go/lib/cities/map/grid"
 	"upsilon_cities_go/lib/cities/map/grid_manager"
 	"upsilon_cities_go/lib/cities/map/map_generator/region"
 	"upsilon_cities_go/lib/cities/node"
+	"upsilon_cities_go/lib/cities/tools"
 	"upsilon_cities_go/lib/cities/user"
 	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/logger"
+	"upsilon_cities_go/web/dto"
 	"upsilon_cities_go/web/templates"
 	"upsilon_cities_go/web/webtools"
 )
@@ -24,7 +27,7 @@ import (
 func Index(w http.ResponseWriter, req *http.Request) {
 
 	if !webtools.IsLogged(req) {
-		webtools.Fail(w, req, "must be logged in", "/")
+		webtools.FailWithStatus(w, req, http.StatusUnauthorized, "must be logged in", "/")
 		return
 	}
 
@@ -34,20 +37,22 @@ func Index(w http.ResponseWriter, req *http.Request) {
 
 	grids, err := grid.AllShortened(dbh)
 	if err != nil {
-		webtools.Fail(w, req, "Failed to get all maps ...", "/")
+		webtools.FailWithStatus(w, req, http.StatusInternalServerError, "Failed to get all maps ...", "/")
 		return
 	}
 
 	var dataList []indexGrid
 	for _, localgrid := range grids {
 		isUserOnMap, _ := user.IsUserOnMap(dbh, uid, localgrid.ID)
-		if isUserOnMap {
+		if localgrid.Ended {
+			dataList = append(dataList, indexGrid{Name: localgrid.Name, RegionType: localgrid.RegionType, ID: localgrid.ID, Ended: true})
+		} else if isUserOnMap {
 
 			corp, err := corporation.ByMapIDByUserID(dbh, localgrid.ID, uid)
 			// grid should be loaded first ... some stuff should be kept updated ;)
 			if err != nil {
 				// failed to find corporation.
-				webtools.Fail(w, req, "An Error as occured.", "/map")
+				webtools.FailWithStatus(w, req, http.StatusInternalServerError, "An Error as occured.", "/map")
 				return
 			}
 			var data indexGrid
@@ -74,9 +79,18 @@ func Index(w http.ResponseWriter, req *http.Request) {
 		}
 	}
 
-	if webtools.IsAPI(req) {
-		webtools.GenerateAPIOk(w)
-		json.NewEncoder(w).Encode(grids)
+	if webtools.IsAPIv1(req) {
+		data := make([]dto.MapSummary, 0, len(grids))
+		for idx, v := range grids {
+			summary := dto.NewMapSummary(*v)
+			if corp := dataList[idx].UserCorp; corp.ID != 0 {
+				summary.Corporation = &dto.PlayerCorporation{ID: corp.ID, Name: corp.Name, Credits: corp.Credits, CaravansWaiting: corp.CrvWaiting}
+			}
+			data = append(data, summary)
+		}
+		webtools.GenerateAPIData(w, data)
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPIData(w, grids)
 	} else {
 		webtools.GetSession(req).Values["current_corp_id"] = 0
 		templates.RenderTemplate(w, req, "map/index", dataList)
@@ -88,7 +102,7 @@ func Index(w http.ResponseWriter, req *http.Request) {
 func AdminIndex(w http.ResponseWriter, req *http.Request) {
 
 	if !webtools.IsLogged(req) {
-		webtools.Fail(w, req, "must be logged in", "/")
+		webtools.FailWithStatus(w, req, http.StatusUnauthorized, "must be logged in", "/")
 		return
 	}
 
@@ -97,7 +111,7 @@ func AdminIndex(w http.ResponseWriter, req *http.Request) {
 
 	grids, err := grid.AllShortened(dbh)
 	if err != nil {
-		webtools.Fail(w, req, "Failed to get all maps ...", "/")
+		webtools.FailWithStatus(w, req, http.StatusInternalServerError, "Failed to get all maps ...", "/")
 		return
 	}
 
@@ -107,7 +121,7 @@ func AdminIndex(w http.ResponseWriter, req *http.Request) {
 		// grid should be loaded first ... some stuff should be kept updated ;)
 		if err != nil {
 			// failed to find corporation.
-			webtools.Fail(w, req, "An Error as occured.", "/map")
+			webtools.FailWithStatus(w, req, http.StatusInternalServerError, "An Error as occured.", "/map")
 			return
 		}
 		var data adminIndexGrid
@@ -185,6 +199,7 @@ type indexGrid struct {
 	Name       string
 	RegionType string
 	ID         int
+	Ended      bool
 }
 
 type adminIndexGrid struct {
@@ -198,7 +213,7 @@ type adminIndexGrid struct {
 func Show(w http.ResponseWriter, req *http.Request) {
 
 	if !webtools.IsLogged(req) {
-		webtools.Fail(w, req, "must be logged in", "/")
+		webtools.FailWithStatus(w, req, http.StatusUnauthorized, "must be logged in", "/")
 		return
 	}
 
@@ -213,13 +228,21 @@ func Show(w http.ResponseWriter, req *http.Request) {
 	dbh := db.New()
 	defer dbh.Close()
 
+	if grid.IsEndedByID(dbh, mapID) {
+		if webtools.IsAPI(req) {
+			webtools.GenerateAPIErrorWithStatus(w, http.StatusConflict, fmt.Sprintf("Region is closed, see /api/map/%d/standings", mapID))
+		} else {
+			webtools.Redirect(w, req, fmt.Sprintf("/map/%d/standings", mapID))
+		}
+		return
+	}
+
 	if err != nil {
 		uid, err := webtools.CurrentUserID(req)
 		corp, err = corporation.ByMapIDByUserID(dbh, mapID, uid)
 		if err != nil {
 			if webtools.IsAPI(req) {
-				webtools.GenerateAPIOk(w)
-				json.NewEncoder(w).Encode(fmt.Sprintf("Need to select a corporation, call /api/map/%d/select_corporation", mapID))
+				webtools.GenerateAPIErrorWithStatus(w, http.StatusConflict, fmt.Sprintf("Need to select a corporation, call /api/map/%d/select_corporation", mapID))
 			} else {
 				webtools.Redirect(w, req, fmt.Sprintf("/map/%d/select_corporation", mapID))
 			}
@@ -228,13 +251,13 @@ func Show(w http.ResponseWriter, req *http.Request) {
 	} else {
 
 		if !webtools.IsAdmin(req) {
-			webtools.Fail(w, req, "must be Admin", "/")
+			webtools.FailWithStatus(w, req, http.StatusForbidden, "must be Admin", "/")
 			return
 		}
 
 		corp, err = corporation.ByID(dbh, corpID)
 		if err != nil {
-			webtools.Fail(w, req, "can't find select corporation", "admin/map")
+			webtools.FailWithStatus(w, req, http.StatusNotFound, "can't find select corporation", "admin/map")
 			return
 		}
 	}
@@ -284,7 +307,18 @@ func GetMapInfo(w http.ResponseWriter, req *http.Request) {
 	grd, err := grid_manager.GetGridHandler(mapID)
 	if err != nil {
 		// failed to find requested map.
-		webtools.Fail(w, req, "Unknown map id", "/map")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "Unknown map id", "/map")
+		return
+	}
+
+	if webtools.IsAPIv1(req) {
+		crvs, _ := caravan_manager.GetCaravaRequiringAction(corp.ID)
+		var data dto.MapInfo
+		grd.Call(func(grid *grid.Grid) {
+			data.Map = dto.NewMap(grid)
+		})
+		data.Corporation = dto.PlayerCorporation{ID: corp.ID, Name: corp.Name, Credits: corp.Credits, CaravansWaiting: len(crvs)}
+		webtools.GenerateAPIData(w, data)
 		return
 	}
 
@@ -307,8 +341,7 @@ func GetMapInfo(w http.ResponseWriter, req *http.Request) {
 	var data gameInfo
 	data = <-callback
 
-	webtools.GenerateAPIOk(w)
-	json.NewEncoder(w).Encode(data)
+	webtools.GenerateAPIData(w, data)
 
 }
 
@@ -320,7 +353,7 @@ type shortCorporation struct {
 //ShowSelectableCorporation GET /map/:map_id/select_corporation allow one use to select a claimable corporation.
 func ShowSelectableCorporation(w http.ResponseWriter, req *http.Request) {
 	if !webtools.IsLogged(req) {
-		webtools.Fail(w, req, "must be logged in", "/")
+		webtools.FailWithStatus(w, req, http.StatusUnauthorized, "must be logged in", "/")
 		return
 	}
 
@@ -334,6 +367,12 @@ func ShowSelectableCorporation(w http.ResponseWriter, req *http.Request) {
 	uid, err := webtools.CurrentUserID(req)
 	dbh := db.New()
 	defer dbh.Close()
+
+	if grid.IsEndedByID(dbh, id) {
+		webtools.FailWithStatus(w, req, http.StatusConflict, "Region is closed.", "/map")
+		return
+	}
+
 	_, err = corporation.ByMapIDByUserID(dbh, id, uid)
 
 	if err == nil {
@@ -351,13 +390,13 @@ func ShowSelectableCorporation(w http.ResponseWriter, req *http.Request) {
 
 	if err != nil {
 		// failed to convert id to int ...
-		webtools.Fail(w, req, "Unable to find claimable corporations.", "/map")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "Unable to find claimable corporations.", "/map")
 		return
 	}
 
 	if len(corps) == 0 {
 		// failed to convert id to int ...
-		webtools.Fail(w, req, "No corporations left to claim.", "/map")
+		webtools.FailWithStatus(w, req, http.StatusConflict, "No corporations left to claim.", "/map")
 		return
 	}
 
@@ -378,9 +417,14 @@ func ShowSelectableCorporation(w http.ResponseWriter, req *http.Request) {
 	res.Data = data
 	res.MapID = id
 
-	if webtools.IsAPI(req) {
-		webtools.GenerateAPIOk(w)
-		json.NewEncoder(w).Encode(res)
+	if webtools.IsAPIv1(req) {
+		refs := make([]dto.Ref, 0, len(data))
+		for _, v := range data {
+			refs = append(refs, dto.Ref{ID: v.ID, Name: v.Name})
+		}
+		webtools.GenerateAPIData(w, refs)
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPIData(w, res)
 	} else {
 		templates.RenderTemplate(w, req, "map/select_corp", res)
 	}
@@ -390,7 +434,7 @@ func ShowSelectableCorporation(w http.ResponseWriter, req *http.Request) {
 func SelectCorporation(w http.ResponseWriter, req *http.Request) {
 
 	if !webtools.IsLogged(req) {
-		webtools.Fail(w, req, "must be logged in", "/")
+		webtools.FailWithStatus(w, req, http.StatusUnauthorized, "must be logged in", "/")
 		return
 	}
 	id, err := webtools.GetInt(req, "map_id")
@@ -403,6 +447,12 @@ func SelectCorporation(w http.ResponseWriter, req *http.Request) {
 	uid, err := webtools.CurrentUserID(req)
 	dbh := db.New()
 	defer dbh.Close()
+
+	if grid.IsEndedByID(dbh, id) {
+		webtools.FailWithStatus(w, req, http.StatusConflict, "Region is closed.", "/map")
+		return
+	}
+
 	_, err = corporation.ByMapIDByUserID(dbh, id, uid)
 
 	if err == nil {
@@ -421,7 +471,7 @@ func SelectCorporation(w http.ResponseWriter, req *http.Request) {
 	corpID, err := strconv.Atoi(f.Get("corporation"))
 	if err != nil {
 		// failed to convert id to int ...
-		webtools.Fail(w, req, "Unable to find read corporation", "/map")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "Unable to find read corporation", "/map")
 		return
 	}
 
@@ -430,7 +480,7 @@ func SelectCorporation(w http.ResponseWriter, req *http.Request) {
 	if err != nil {
 
 		// failed to convert id to int ...
-		webtools.Fail(w, req, "Unable to find requested corporation", "/map")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "Unable to find requested corporation", "/map")
 		return
 	}
 
@@ -447,7 +497,7 @@ func SelectCorporation(w http.ResponseWriter, req *http.Request) {
 	err = <-cb
 	if err != nil {
 		// failed to convert id to int ...
-		webtools.Fail(w, req, "Unable to claim corporation", "/map")
+		webtools.FailWithStatus(w, req, http.StatusConflict, "Unable to claim corporation", "/map")
 		return
 	}
 
@@ -462,7 +512,7 @@ func SelectCorporation(w http.ResponseWriter, req *http.Request) {
 func Create(w http.ResponseWriter, req *http.Request) {
 
 	if !webtools.IsAdmin(req) {
-		webtools.Fail(w, req, "must be admin", "/")
+		webtools.FailWithStatus(w, req, http.StatusForbidden, "must be admin", "/")
 		return
 	}
 
@@ -470,29 +520,52 @@ func Create(w http.ResponseWriter, req *http.Request) {
 	f := req.Form
 	regionTypeName := f.Get("regionTypeName")
 
+	// victory conditions of the map, configured ones unless provided.
+	victory := grid.VictoryConditions(grid_evolution.LoadVictoryConditions())
+	invalid := make(map[string]string)
+	for key, value := range map[string]*int{"victory_credits": &victory.Credits, "victory_cities": &victory.Cities, "victory_cycles": &victory.Cycles} {
+		if f.Get(key) == "" {
+			continue
+		}
+		nb, err := strconv.Atoi(f.Get(key))
+		if err != nil || nb < 0 {
+			invalid[key] = "must be a positive number, 0 disables it"
+			continue
+		}
+		*value = nb
+	}
+	if len(invalid) > 0 {
+		webtools.FailFields(w, req, "invalid parameter provided", invalid, "/map")
+		return
+	}
+
 	var grd *grid.Grid
 	handler := db.New()
 	defer handler.Close()
 	reg, err := region.Generate(regionTypeName)
 	if err != nil {
-		webtools.Fail(w, req, fmt.Sprintf("Unable to create an %s map", regionTypeName), "/")
+		webtools.FailWithStatus(w, req, http.StatusInternalServerError, fmt.Sprintf("Unable to create an %s map", regionTypeName), "/")
 		return
 	}
 
 	grd, err = reg.Generate(handler, regionTypeName)
 	if err != nil {
-		webtools.Fail(w, req, fmt.Sprintf("Unable to generate an %s map", regionTypeName), "/")
+		webtools.FailWithStatus(w, req, http.StatusInternalServerError, fmt.Sprintf("Unable to generate an %s map", regionTypeName), "/")
 		return
 	}
 
+	grd.Victory = victory
 	grid.Store(handler, grd)
-	log.Printf("GC: Store map: \n%s", grd.String())
+	logger.Debugf("GC", "Store map: \n%s", grd.String())
 
+	// converted before grid gets handed to its actor.
+	created := dto.NewMap(grd)
 	grid_manager.GenerateGridHandler(grd)
 
-	if webtools.IsAPI(req) {
-		webtools.GenerateAPIOk(w)
-		json.NewEncoder(w).Encode(grd)
+	if webtools.IsAPIv1(req) {
+		webtools.GenerateAPICreated(w, created)
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPICreated(w, grd)
 	} else {
 		webtools.Redirect(w, req, fmt.Sprintf("/map/%d", grd.ID))
 	}
@@ -502,7 +575,7 @@ func Create(w http.ResponseWriter, req *http.Request) {
 func Destroy(w http.ResponseWriter, req *http.Request) {
 
 	if !webtools.IsAdmin(req) {
-		webtools.Fail(w, req, "must be admin", "/")
+		webtools.FailWithStatus(w, req, http.StatusForbidden, "must be admin", "/")
 		return
 	}
 
@@ -516,15 +589,177 @@ func Destroy(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	log.Printf("GridCtrl: About to delete map %d", id)
+	logger.Debugf("GridCtrl", "About to delete map %d", id)
 
 	grid_manager.DropGridHandler(id)
 	grid.DropByID(handler, id)
 
 	if webtools.IsAPI(req) {
-		webtools.GenerateAPIOk(w)
-		json.NewEncoder(w).Encode("success")
+		webtools.GenerateAPIOkAndSend(w)
 	} else {
 		webtools.Redirect(w, req, "/map")
 	}
 }
+
+type standingsInfo struct {
+	MapID     int
+	Name      string
+	Ended     bool
+	EndReason string
+	EndedAt   string
+	Standings []grid.Standing
+}
+
+//Standings GET /map/:map_id/standings archived standings of a closed region.
+func Standings(w http.ResponseWriter, req *http.Request) {
+	if !webtools.IsLogged(req) {
+		webtools.FailWithStatus(w, req, http.StatusUnauthorized, "must be logged in", "/")
+		return
+	}
+
+	mapID, err := webtools.GetInt(req, "map_id")
+	if err != nil {
+		webtools.Fail(w, req, "Invalid map id format", "/map")
+		return
+	}
+
+	gm, err := grid_manager.GetGridHandler(mapID)
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "Unknown map id", "/map")
+		return
+	}
+
+	var endedAt time.Time
+	callback := make(chan standingsInfo)
+	defer close(callback)
+	gm.Cast(func(grd *grid.Grid) {
+		var data standingsInfo
+		data.MapID = grd.ID
+		data.Name = grd.Name
+		data.Ended = grd.IsEnded()
+		data.EndReason = grd.EndReason
+		if data.Ended {
+			endedAt = grd.EndedAt
+			data.EndedAt = grd.EndedAt.Format(time.RFC3339)
+		}
+		callback <- data
+	})
+	data := <-callback
+
+	if !data.Ended {
+		webtools.FailWithStatus(w, req, http.StatusConflict, "Region is still running.", fmt.Sprintf("/map/%d", mapID))
+		return
+	}
+
+	dbh := db.New()
+	defer dbh.Close()
+	data.Standings, err = grid.StandingsByMapID(dbh, mapID)
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "Unable to find standings.", "/map")
+		return
+	}
+
+	if webtools.IsAPIv1(req) {
+		webtools.GenerateAPIData(w, dto.NewStandings(data.MapID, data.Name, data.EndReason, endedAt, data.Standings))
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPIData(w, data)
+	} else {
+		templates.RenderTemplate(w, req, "map/standings", data)
+	}
+}
+
+type leaderboardInfo struct {
+	MapID     int
+	Name      string
+	SortBy    string
+	TakenAt   string
+	Criteria  []string
+	Entries   []rankedEntry
+	Snapshots []leaderboardSnapshot
+}
+
+type rankedEntry struct {
+	grid.LeaderboardEntry
+	Rank int
+}
+
+type leaderboardSnapshot struct {
+	ID      int
+	TakenAt string
+}
+
+//Leaderboard GET /map/:map_id/leaderboard ranking of corporations of the map.
+// Accepts ?sort=credits|cities|fame|caravans|goods and ?snapshot=:id to display an archived leaderboard.
+func Leaderboard(w http.ResponseWriter, req *http.Request) {
+	if !webtools.IsLogged(req) {
+		webtools.FailWithStatus(w, req, http.StatusUnauthorized, "must be logged in", "/")
+		return
+	}
+
+	mapID, err := webtools.GetInt(req, "map_id")
+	if err != nil {
+		webtools.Fail(w, req, "Invalid map id format", "/map")
+		return
+	}
+
+	gm, err := grid_manager.GetGridHandler(mapID)
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "Unknown map id", "/map")
+		return
+	}
+
+	var data leaderboardInfo
+	data.MapID = mapID
+	data.SortBy = req.URL.Query().Get("sort")
+	if data.SortBy == "" {
+		data.SortBy = grid.LBCredits
+	}
+	data.Criteria = []string{grid.LBCredits, grid.LBCities, grid.LBFame, grid.LBCaravans, grid.LBGoods}
+
+	dbh := db.New()
+	defer dbh.Close()
+
+	var lb grid.Leaderboard
+	gm.Call(func(grd *grid.Grid) {
+		data.Name = grd.Name
+		if len(grd.Leaderboard.Entries) == 0 && !grd.IsEnded() {
+			// not computed since map got loaded.
+			grd.Leaderboard = grid_evolution.ComputeLeaderboard(grd, grid_evolution.Cities(grd), tools.RoundNow())
+		}
+		lb = grd.Leaderboard
+	})
+
+	snapshots, err := grid.LeaderboardsByMapID(dbh, mapID)
+	if err != nil {
+		logger.Infof("GridCtrl", "%s", err)
+	}
+	for _, v := range snapshots {
+		data.Snapshots = append(data.Snapshots, leaderboardSnapshot{ID: v.ID, TakenAt: v.TakenAt.Format(time.RFC3339)})
+	}
+
+	if snapshot := req.URL.Query().Get("snapshot"); snapshot != "" || len(lb.Entries) == 0 {
+		id, _ := strconv.Atoi(snapshot)
+		if id == 0 && len(snapshots) > 0 {
+			// closed maps show their last snapshot.
+			id = snapshots[0].ID
+		}
+		lb, err = grid.LeaderboardByID(dbh, id)
+		if err != nil || lb.MapID != mapID {
+			webtools.FailWithStatus(w, req, http.StatusNotFound, "Unknown leaderboard snapshot", fmt.Sprintf("/map/%d", mapID))
+			return
+		}
+	}
+
+	data.TakenAt = lb.TakenAt.Format(time.RFC3339)
+	for idx, v := range lb.SortBy(data.SortBy) {
+		data.Entries = append(data.Entries, rankedEntry{LeaderboardEntry: v, Rank: idx + 1})
+	}
+
+	if webtools.IsAPIv1(req) {
+		webtools.GenerateAPIData(w, dto.NewLeaderboard(mapID, data.Name, lb, data.SortBy, data.Criteria, snapshots))
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPIData(w, data)
+	} else {
+		templates.RenderTemplate(w, req, "map/leaderboard", data)
+	}
+}
@@ -0,0 +1,110 @@
+package health_controller
+
+import (
+	"net/http"
+	"time"
+	"upsilon_cities_go/lib/cities/caravan_manager"
+	"upsilon_cities_go/lib/cities/city/producer_generator"
+	"upsilon_cities_go/lib/cities/city/resource_generator"
+	"upsilon_cities_go/lib/cities/city_manager"
+	"upsilon_cities_go/lib/cities/corporation_manager"
+	"upsilon_cities_go/lib/cities/map/grid_manager"
+	"upsilon_cities_go/lib/cities/map/map_generator/region"
+	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/generator"
+	"upsilon_cities_go/web/webtools"
+)
+
+//pingTimeout how long an actor may take to pick up a ping before being deemed stuck.
+const pingTimeout = 2 * time.Second
+
+//Check outcome of a single check.
+type Check struct {
+	Status string `json:"status"` // "ok" or "error"
+	Detail string `json:"detail,omitempty"`
+	Count  int    `json:"count,omitempty"`
+}
+
+func ok(count int) Check {
+	return Check{Status: "ok", Count: count}
+}
+
+func failed(detail string) Check {
+	return Check{Status: "error", Detail: detail}
+}
+
+//reply with 200 when every check passed, 503 otherwise.
+func reply(w http.ResponseWriter, checks map[string]Check) {
+	for _, v := range checks {
+		if v.Status != "ok" {
+			webtools.SendAPI(w, http.StatusServiceUnavailable, webtools.APIResponse{Status: "error", Code: webtools.StatusCode(http.StatusServiceUnavailable), Message: "some checks failed", Data: checks})
+			return
+		}
+	}
+	webtools.SendAPI(w, http.StatusOK, webtools.APIResponse{Status: "ok", Data: checks})
+}
+
+func pinged(responsive bool) Check {
+	if responsive {
+		return ok(0)
+	}
+	return failed("manager didn't reply in time")
+}
+
+//Healthz GET /healthz process is alive and actor loops still pick up work.
+func Healthz(w http.ResponseWriter, req *http.Request) {
+	checks := make(map[string]Check)
+
+	stalled, responsive := grid_manager.Ping(pingTimeout)
+	switch {
+	case !responsive:
+		checks["grid_manager"] = failed("manager didn't reply in time")
+	case stalled > 0:
+		checks["grid_manager"] = Check{Status: "error", Detail: "some maps didn't reply in time", Count: stalled}
+	default:
+		checks["grid_manager"] = ok(0)
+	}
+	checks["city_manager"] = pinged(city_manager.Ping(pingTimeout))
+	checks["caravan_manager"] = pinged(caravan_manager.Ping(pingTimeout))
+	checks["corporation_manager"] = pinged(corporation_manager.Ping(pingTimeout))
+
+	reply(w, checks)
+}
+
+//loaded check data files got loaded.
+func loaded(count int) Check {
+	if count == 0 {
+		return failed("nothing loaded")
+	}
+	return ok(count)
+}
+
+//Readyz GET /readyz database is reachable and up to date, data files are loaded.
+func Readyz(w http.ResponseWriter, req *http.Request) {
+	checks := make(map[string]Check)
+
+	dbh, err := db.Open()
+	if err != nil {
+		checks["database"] = failed(err.Error())
+		checks["migrations"] = failed("database unreachable")
+	} else {
+		checks["database"] = ok(0)
+		pending, err := db.PendingMigrations(dbh)
+		switch {
+		case err != nil:
+			checks["migrations"] = failed(err.Error())
+		case len(pending) > 0:
+			checks["migrations"] = Check{Status: "error", Detail: "migrations not applied", Count: len(pending)}
+		default:
+			checks["migrations"] = ok(0)
+		}
+		dbh.Close()
+	}
+
+	checks["producers"] = loaded(producer_generator.Loaded())
+	checks["resources"] = loaded(resource_generator.Loaded())
+	checks["regions"] = loaded(region.Loaded())
+	checks["names"] = loaded(generator.Loaded())
+
+	reply(w, checks)
+}
@@ -0,0 +1,34 @@
+package user_controller
+
+import (
+	"net/http"
+	"upsilon_cities_go/web/dto"
+	"upsilon_cities_go/web/openapi"
+)
+
+//Docs API routes of users.
+func Docs() []openapi.Doc {
+	return []openapi.Doc{
+		{Method: "GET", Path: "/user", Summary: "Current user.", Data: dto.User{}},
+		{Method: "POST", Path: "/user", Summary: "Create an account.", Form: []string{"login", "email", "password"}, Data: dto.User{}, Status: http.StatusCreated},
+		{Method: "DELETE", Path: "/user", Summary: "Destroy account of current user."},
+		{Method: "GET", Path: "/user/new", Summary: "Check an account may be created."},
+		{Method: "GET", Path: "/user/checkavailable/login/{login}/mail/{mail}", Summary: "Check login and mail are available."},
+		{Method: "GET", Path: "/user/login", Summary: "Check user may log in."},
+		{Method: "POST", Path: "/user/login", Summary: "Log in, session is kept in a cookie.", Form: []string{"login", "password"}},
+		{Method: "GET", Path: "/user/logout", Summary: "Log out."},
+		{Method: "POST", Path: "/user/logout", Summary: "Log out."},
+		{Method: "GET", Path: "/user/logs", Summary: "Last messages of current user.", Data: []dto.UserLog{}},
+		{Method: "GET", Path: "/user/reset_password", Summary: "Check password may be reset."},
+		{Method: "POST", Path: "/user/reset_password", Summary: "Change password.", Form: []string{"password"}},
+		{Method: "GET", Path: "/user/forgot_password", Summary: "Check a forgotten password may be reset."},
+		{Method: "POST", Path: "/user/forgot_password", Summary: "Mail a single use link to reset password, whether an account matches isn't told.", Form: []string{"email"}},
+		{Method: "GET", Path: "/user/recover/{token}", Summary: "Check password may be reset with mailed token."},
+		{Method: "POST", Path: "/user/recover/{token}", Summary: "Set a new password with mailed token.", Form: []string{"password"}},
+		{Method: "GET", Path: "/user/verify/{token}", Summary: "Verify email with mailed token and activate account."},
+		{Method: "GET", Path: "/user/tokens", Summary: "Personal API tokens, session only.", Data: []dto.Token{}},
+		{Method: "POST", Path: "/user/tokens", Summary: "Create a personal API token, scope is read or full. Secret is only sent once.", Form: []string{"name", "scope"}, Data: dto.CreatedToken{}, Status: http.StatusCreated},
+		{Method: "DELETE", Path: "/user/tokens/{token_id}", Summary: "Revoke a personal API token."},
+		{Method: "POST", Path: "/user/tokens/{token_id}/revoke", Summary: "Revoke a personal API token."},
+	}
+}
@@ -0,0 +1,210 @@
+package user_controller
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+	"upsilon_cities_go/lib/cities/user"
+	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/mailer"
+	"upsilon_cities_go/web/templates"
+	"upsilon_cities_go/web/webtools"
+)
+
+// sendMailToken issue a mail token of purpose for user and mail its link. Previous tokens of same purpose are dropped.
+func sendMailToken(dbh *db.Handler, usr *user.User, purpose string) error {
+	ttl := user.VerifyTokenTTL()
+	subject := "Verify your email"
+	path := "/user/verify/"
+	intro := "Welcome to Upsilon Cities! Follow this link to verify your email and activate your account:"
+	if purpose == user.MailTokenReset {
+		ttl = user.ResetTokenTTL()
+		subject = "Reset your password"
+		path = "/user/recover/"
+		intro = "Someone, hopefully you, asked to reset your Upsilon Cities password. Follow this link to choose a new one:"
+	}
+
+	tok, plain, err := user.NewMailToken(usr.ID, purpose, ttl)
+	if err != nil {
+		return err
+	}
+	link, err := webtools.AbsoluteURL(path + plain)
+	if err != nil {
+		return err
+	}
+
+	if err := user.DropMailTokens(dbh, usr.ID, purpose); err != nil {
+		return err
+	}
+	if err := tok.Insert(dbh); err != nil {
+		return err
+	}
+
+	return mailer.Send(mailer.Mail{
+		To:      usr.Email,
+		Subject: subject,
+		Body: fmt.Sprintf("Hello %s,\n\n%s\n\n%s\n\nLink is valid until %s and may be used once. If you didn't ask for it, just ignore this mail.\n",
+			usr.Login, intro, link, tok.ExpiresAt.Format(time.RFC1123)),
+	})
+}
+
+//ShowForgotPassword GET /user/forgot_password ask for email of account to recover.
+func ShowForgotPassword(w http.ResponseWriter, req *http.Request) {
+	if webtools.IsLogged(req) {
+		webtools.FailWithStatus(w, req, http.StatusForbidden, "must not be logged in", "/")
+		return
+	}
+	if webtools.IsAPI(req) {
+		webtools.GenerateAPIOkAndSend(w)
+	} else {
+		templates.RenderTemplate(w, req, "user/forgot_password", "")
+	}
+}
+
+//ForgotPassword POST /user/forgot_password mail a link to reset password.
+//Reply doesn't tell whether an account matches, so that it can't be used to find out accounts.
+func ForgotPassword(w http.ResponseWriter, req *http.Request) {
+	if webtools.IsLogged(req) {
+		webtools.FailWithStatus(w, req, http.StatusForbidden, "must not be logged in", "/")
+		return
+	}
+
+	req.ParseForm()
+	mail := req.Form.Get("email")
+	if !user.CheckMail(mail) {
+		webtools.FailFields(w, req, "invalid parameter provided", map[string]string{"email": "must be a valid mail address"}, "/user/forgot_password")
+		return
+	}
+
+	dbh := db.New()
+	defer dbh.Close()
+
+	usr, err := user.ByEmail(dbh, mail)
+	if err == nil {
+		if err := sendMailToken(dbh, usr, user.MailTokenReset); err != nil {
+			webtools.Log(req, "User").Errorf("Failed to send reset link to user %d: %s", usr.ID, err)
+		}
+	} else {
+		webtools.Log(req, "User").Infof("Asked to reset password of unknown email")
+	}
+
+	if webtools.IsAPI(req) {
+		webtools.GenerateAPIOkAndSend(w)
+	} else {
+		webtools.GetSession(req).AddFlash("If an account matches this email, a link to reset its password has been sent.", "info")
+		webtools.Redirect(w, req, "/user/login")
+	}
+}
+
+type recoverData struct {
+	Token string
+}
+
+//ShowRecover GET /user/recover/:token ask for a new password. Token is only used once password is submitted.
+func ShowRecover(w http.ResponseWriter, req *http.Request) {
+	token, _ := webtools.GetString(req, "token")
+	if webtools.IsAPI(req) {
+		webtools.GenerateAPIOkAndSend(w)
+	} else {
+		templates.RenderTemplate(w, req, "user/recover", recoverData{Token: token})
+	}
+}
+
+//Recover POST /user/recover/:token set a new password with a reset token.
+func Recover(w http.ResponseWriter, req *http.Request) {
+	token, _ := webtools.GetString(req, "token")
+
+	req.ParseForm()
+	password := req.Form.Get("password")
+	if !user.CheckPassword(password) {
+		webtools.FailFields(w, req, "invalid parameter provided", map[string]string{"password": "must be at least 8 characters long"}, "/user/recover/"+token)
+		return
+	}
+
+	dbh := db.New()
+	defer dbh.Close()
+
+	tok, err := user.ConsumeMailToken(dbh, token, user.MailTokenReset, time.Now().UTC())
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusBadRequest, "invalid or expired link, ask for a new one", "/user/forgot_password")
+		return
+	}
+
+	usr, err := user.ByID(dbh, tok.UserID)
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unable to find user", "/")
+		return
+	}
+
+	usr.Password, err = user.HashPassword(usr.Login + password)
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusInternalServerError, "unable to hash user password ", "/")
+		return
+	}
+	if err := usr.UpdatePassword(dbh); err != nil {
+		webtools.FailWithStatus(w, req, http.StatusInternalServerError, "unable to update user password ", "/")
+		return
+	}
+
+	// owner of the mail box is back, lockout is pointless now.
+	usr.LoginSucceeded()
+	if err := usr.UpdateLockout(dbh); err != nil {
+		webtools.Log(req, "User").Errorf("%s", err)
+	}
+	if err := user.DropMailTokens(dbh, usr.ID, user.MailTokenReset); err != nil {
+		webtools.Log(req, "User").Errorf("%s", err)
+	}
+	// account may have been compromised: whoever got in must get out.
+	if err := user.DropSessions(dbh, usr.ID); err != nil {
+		webtools.Log(req, "User").Errorf("%s", err)
+	}
+	if err := user.RevokeTokens(dbh, usr.ID); err != nil {
+		webtools.Log(req, "User").Errorf("%s", err)
+	}
+	webtools.Log(req, "User").Infof("User %d reset password by mail", usr.ID)
+
+	if webtools.IsAPI(req) {
+		webtools.GenerateAPIOkAndSend(w)
+	} else {
+		webtools.GetSession(req).AddFlash("Password successfully reset, you may now log in.", "info")
+		webtools.Redirect(w, req, "/user/login")
+	}
+}
+
+//Verify GET /user/verify/:token verify email of a new user and enable account.
+func Verify(w http.ResponseWriter, req *http.Request) {
+	token, _ := webtools.GetString(req, "token")
+
+	dbh := db.New()
+	defer dbh.Close()
+
+	tok, err := user.ConsumeMailToken(dbh, token, user.MailTokenVerify, time.Now().UTC())
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusBadRequest, "invalid or expired link, log in to get a new one", "/user/login")
+		return
+	}
+
+	usr, err := user.ByID(dbh, tok.UserID)
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unable to find user", "/")
+		return
+	}
+
+	usr.EmailVerified()
+	if err := usr.Update(dbh); err != nil {
+		webtools.FailWithStatus(w, req, http.StatusInternalServerError, "unable to update user", "/")
+		return
+	}
+	webtools.Log(req, "User").Infof("User %d verified email", usr.ID)
+
+	msg := "Email verified, you may now log in."
+	if !usr.Enabled {
+		msg = "Email verified, an admin still has to enable your account."
+	}
+	if webtools.IsAPI(req) {
+		webtools.GenerateAPIOkAndSend(w)
+	} else {
+		webtools.GetSession(req).AddFlash(msg, "info")
+		webtools.Redirect(w, req, "/user/login")
+	}
+}
@@ -1,12 +1,14 @@
 package user_controller
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 	"upsilon_cities_go/lib/cities/user"
 	"upsilon_cities_go/lib/cities/user_log"
 	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/web/dto"
 	"upsilon_cities_go/web/templates"
 	"upsilon_cities_go/web/webtools"
 )
@@ -21,13 +23,14 @@ func Show(w http.ResponseWriter, req *http.Request) {
 	user, err := webtools.CurrentUser(req)
 
 	if err != nil {
-		webtools.Fail(w, req, "unable to find current user ...", "/")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unable to find current user ...", "/")
 		return
 	}
 
-	if webtools.IsAPI(req) {
-		webtools.GenerateAPIOk(w)
-		json.NewEncoder(w).Encode(user)
+	if webtools.IsAPIv1(req) {
+		webtools.GenerateAPIData(w, dto.NewUser(user))
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPIData(w, user)
 	} else {
 		templates.RenderTemplate(w, req, "user/show", user)
 	}
@@ -37,7 +40,7 @@ func Show(w http.ResponseWriter, req *http.Request) {
 func New(w http.ResponseWriter, req *http.Request) {
 
 	if webtools.IsLogged(req) {
-		webtools.Fail(w, req, "must not be logged in", "/")
+		webtools.FailWithStatus(w, req, http.StatusForbidden, "must not be logged in", "/")
 		return
 	}
 
@@ -51,7 +54,7 @@ func New(w http.ResponseWriter, req *http.Request) {
 //Create POST /user ... Create new account
 func Create(w http.ResponseWriter, req *http.Request) {
 	if webtools.IsLogged(req) {
-		webtools.Fail(w, req, "must not be logged in", "/")
+		webtools.FailWithStatus(w, req, http.StatusForbidden, "must not be logged in", "/")
 		return
 	}
 
@@ -65,8 +68,18 @@ func Create(w http.ResponseWriter, req *http.Request) {
 	login := f.Get("login")
 	htmlpwd := f.Get("password")
 
-	if !user.CheckLogin(login) || !user.CheckPassword(htmlpwd) || !user.CheckMail(mail) {
-		webtools.Fail(w, req, "invalid parameter provided", "/user/new")
+	fields := make(map[string]string)
+	if !user.CheckLogin(login) {
+		fields["login"] = "must start with a letter and be at least 4 characters long"
+	}
+	if !user.CheckPassword(htmlpwd) {
+		fields["password"] = "must be at least 8 characters long"
+	}
+	if !user.CheckMail(mail) {
+		fields["email"] = "must be a valid mail address"
+	}
+	if len(fields) > 0 {
+		webtools.FailFields(w, req, "invalid parameter provided", fields, "/user/new")
 		return
 	}
 
@@ -77,16 +90,37 @@ func Create(w http.ResponseWriter, req *http.Request) {
 	usr.Password = pwd
 
 	if err != nil {
-		webtools.Fail(w, req, "fail to hash password for database storage.", "/")
+		webtools.FailWithStatus(w, req, http.StatusInternalServerError, "fail to hash password for database storage.", "/")
 		return
 	}
 
 	dbh := db.New()
 	defer dbh.Close()
 
+	if user.VerificationRequired() {
+		usr.RequireEmailVerification()
+	}
+
 	err = usr.Insert(dbh)
 	if err != nil {
-		webtools.Fail(w, req, "failed to insert user in database", "/user/new")
+		webtools.FailWithStatus(w, req, http.StatusInternalServerError, "failed to insert user in database", "/user/new")
+		return
+	}
+
+	if usr.NeedEmailVerification {
+		// user gets logged in once email is verified.
+		if err := sendMailToken(dbh, usr, user.MailTokenVerify); err != nil {
+			webtools.Log(req, "User").Errorf("Failed to send verification link to user %d: %s", usr.ID, err)
+		}
+
+		if webtools.IsAPIv1(req) {
+			webtools.GenerateAPICreated(w, dto.NewUser(usr))
+		} else if webtools.IsAPI(req) {
+			webtools.GenerateAPICreated(w, usr)
+		} else {
+			webtools.GetSession(req).AddFlash("User successfully created, follow the link mailed to you to activate it.", "info")
+			webtools.Redirect(w, req, "/user/login")
+		}
 		return
 	}
 
@@ -94,8 +128,10 @@ func Create(w http.ResponseWriter, req *http.Request) {
 	webtools.GetSession(req).Values["is_admin"] = usr.Admin
 	webtools.GetSession(req).Values["is_enabled"] = usr.Enabled
 
-	if webtools.IsAPI(req) {
-		webtools.GenerateAPIOkAndSend(w)
+	if webtools.IsAPIv1(req) {
+		webtools.GenerateAPICreated(w, dto.NewUser(usr))
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPICreated(w, usr)
 	} else {
 		webtools.GetSession(req).AddFlash("User successfully created.", "info")
 		webtools.Redirect(w, req, "/map")
@@ -106,7 +142,7 @@ func Create(w http.ResponseWriter, req *http.Request) {
 func ShowLogin(w http.ResponseWriter, req *http.Request) {
 
 	if webtools.IsLogged(req) {
-		webtools.Fail(w, req, "must not be logged in", "/")
+		webtools.FailWithStatus(w, req, http.StatusForbidden, "must not be logged in", "/")
 		return
 	}
 	if webtools.IsAPI(req) {
@@ -120,6 +156,7 @@ func ShowLogin(w http.ResponseWriter, req *http.Request) {
 func CheckAvailable(w http.ResponseWriter, req *http.Request) {
 
 	unavailable := ""
+	fields := make(map[string]string)
 	mail, _ := webtools.GetString(req, "mail")
 	login, _ := webtools.GetString(req, "login")
 
@@ -128,20 +165,22 @@ func CheckAvailable(w http.ResponseWriter, req *http.Request) {
 	loginavb, err := user.CheckLoginAvailability(dbh, login)
 	if !loginavb {
 		unavailable += "The login is unavailable."
+		fields["login"] = "unavailable"
 	}
 
 	mailavb, err := user.CheckMailAvailability(dbh, mail)
 	if !mailavb {
 		unavailable += "The mail is unavailable"
+		fields["email"] = "unavailable"
 	}
 
 	if err != nil {
-		webtools.Fail(w, req, fmt.Sprintf("A problem happen : %s. Try Later.", err), "/")
+		webtools.FailWithStatus(w, req, http.StatusInternalServerError, fmt.Sprintf("A problem happen : %s. Try Later.", err), "/")
 		return
 	}
 
 	if unavailable != "" {
-		webtools.Fail(w, req, unavailable, "/")
+		webtools.FailFields(w, req, unavailable, fields, "/")
 		return
 	} else if webtools.IsAPI(req) {
 		webtools.GenerateAPIOkAndSend(w)
@@ -152,7 +191,7 @@ func CheckAvailable(w http.ResponseWriter, req *http.Request) {
 func Login(w http.ResponseWriter, req *http.Request) {
 
 	if webtools.IsLogged(req) {
-		webtools.Fail(w, req, "must not be logged in", "/")
+		webtools.FailWithStatus(w, req, http.StatusForbidden, "must not be logged in", "/")
 		return
 	}
 
@@ -171,11 +210,37 @@ func Login(w http.ResponseWriter, req *http.Request) {
 	usr, err := user.ByLogin(dbh, login)
 
 	if err != nil {
-		webtools.Fail(w, req, "unable to log user in", "/")
+		webtools.FailWithStatus(w, req, http.StatusUnauthorized, "unable to log user in", "/")
+		return
+	}
+
+	now := time.Now().UTC()
+	if usr.IsLocked(now) {
+		// don't even check password, bcrypt is costly.
+		w.Header().Set("Retry-After", strconv.Itoa(int(usr.LockedUntil.Sub(now).Seconds())+1))
+		webtools.FailWithStatus(w, req, http.StatusTooManyRequests, "too many failed logins, account is locked for now", "/")
 		return
 	}
 
 	if user.CheckPasswordHash(login+password, usr.Password) {
+		if usr.FailedLogins > 0 {
+			usr.LoginSucceeded()
+			if err := usr.UpdateLockout(dbh); err != nil {
+				webtools.Log(req, "User").Errorf("%s", err)
+			}
+		}
+
+		if !usr.Enabled {
+			if usr.NeedEmailVerification {
+				if err := sendMailToken(dbh, usr, user.MailTokenVerify); err != nil {
+					webtools.Log(req, "User").Errorf("Failed to send verification link to user %d: %s", usr.ID, err)
+				}
+				webtools.FailWithStatus(w, req, http.StatusForbidden, "email must be verified first, a new link has been mailed to you", "/user/login")
+			} else {
+				webtools.FailWithStatus(w, req, http.StatusForbidden, "account is disabled", "/")
+			}
+			return
+		}
 
 		webtools.GetSession(req).Values["current_user_id"] = usr.ID
 		webtools.GetSession(req).Values["is_admin"] = usr.Admin
@@ -201,7 +266,14 @@ func Login(w http.ResponseWriter, req *http.Request) {
 		}
 		return
 	}
-	webtools.Fail(w, req, "unable to log user in", "/")
+
+	if err := usr.RecordLoginFailure(dbh, now); err != nil {
+		webtools.Log(req, "User").Errorf("%s", err)
+	}
+	if usr.IsLocked(now) {
+		webtools.Log(req, "User").Warnf("Locked user %d until %s after %d failed logins", usr.ID, usr.LockedUntil, usr.FailedLogins)
+	}
+	webtools.FailWithStatus(w, req, http.StatusUnauthorized, "unable to log user in", "/")
 	return
 }
 
@@ -250,19 +322,19 @@ func ResetPassword(w http.ResponseWriter, req *http.Request) {
 	usr, err := webtools.CurrentUser(req)
 
 	if err != nil {
-		webtools.Fail(w, req, "unable to find user", "/")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unable to find user", "/")
 		return
 	}
 
 	usr.Password, err = user.HashPassword(usr.Login + f.Get("password"))
 	if err != nil {
-		webtools.Fail(w, req, "unable to hash user password ", "/")
+		webtools.FailWithStatus(w, req, http.StatusInternalServerError, "unable to hash user password ", "/")
 		return
 	}
 
 	err = usr.UpdatePassword(dbh)
 	if err != nil {
-		webtools.Fail(w, req, "unable to update user password ", "/")
+		webtools.FailWithStatus(w, req, http.StatusInternalServerError, "unable to update user password ", "/")
 		return
 	}
 
@@ -283,7 +355,7 @@ func Destroy(w http.ResponseWriter, req *http.Request) {
 	usr, err := webtools.CurrentUser(req)
 
 	if err != nil {
-		webtools.Fail(w, req, "unable to find user", "/")
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unable to find user", "/")
 		return
 	}
 
@@ -312,12 +384,133 @@ func Logs(w http.ResponseWriter, req *http.Request) {
 	dbh := db.New()
 	defer dbh.Close()
 
-	logs := user_log.LastMessages(dbh, uid)
+	logs, err := user_log.LastMessages(dbh, uid)
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusInternalServerError, "unable to fetch logs", "/")
+		return
+	}
 
-	if webtools.IsAPI(req) {
-		webtools.GenerateAPIOk(w)
-		json.NewEncoder(w).Encode(logs)
+	if webtools.IsAPIv1(req) {
+		webtools.GenerateAPIData(w, dto.NewUserLogs(logs))
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPIData(w, logs)
 	} else {
 		templates.RenderTemplate(w, req, "user/logs", logs)
 	}
 }
+
+type tokensData struct {
+	Tokens  []*user.Token
+	Created *user.Token
+	Secret  string // plain token, displayed once upon creation.
+}
+
+type createdToken struct {
+	Token  *user.Token
+	Secret string
+}
+
+// checkSessionLogged token management is only available to users logged with a session.
+func checkSessionLogged(w http.ResponseWriter, req *http.Request) bool {
+	if !webtools.CheckLogged(w, req) {
+		return false
+	}
+	if webtools.APIToken(req) != nil {
+		webtools.FailWithStatus(w, req, http.StatusForbidden, "API tokens can't be managed with an API token", "/user")
+		return false
+	}
+	return true
+}
+
+func renderTokens(w http.ResponseWriter, req *http.Request, data tokensData) {
+	uid, _ := webtools.CurrentUserID(req)
+
+	dbh := db.New()
+	defer dbh.Close()
+
+	tokens, err := user.TokensByUserID(dbh, uid)
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusInternalServerError, "unable to fetch API tokens", "/user")
+		return
+	}
+	data.Tokens = tokens
+
+	if webtools.IsAPIv1(req) {
+		webtools.GenerateAPIData(w, dto.NewTokens(data.Tokens))
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPIData(w, data.Tokens)
+	} else {
+		templates.RenderTemplate(w, req, "user/tokens", data)
+	}
+}
+
+//Tokens GET /user/tokens list personal API tokens
+func Tokens(w http.ResponseWriter, req *http.Request) {
+	if !checkSessionLogged(w, req) {
+		return
+	}
+	renderTokens(w, req, tokensData{})
+}
+
+//CreateToken POST /user/tokens create a personal API token, expects name and scope (read or full)
+func CreateToken(w http.ResponseWriter, req *http.Request) {
+	if !checkSessionLogged(w, req) {
+		return
+	}
+
+	req.ParseForm()
+	f := req.Form
+
+	uid, _ := webtools.CurrentUserID(req)
+	tok, secret, err := user.NewToken(uid, f.Get("name"), f.Get("scope"))
+	if err != nil {
+		webtools.Fail(w, req, fmt.Sprintf("unable to create API token: %s", err), "/user/tokens")
+		return
+	}
+
+	dbh := db.New()
+	defer dbh.Close()
+
+	if err := tok.Insert(dbh); err != nil {
+		webtools.FailWithStatus(w, req, http.StatusInternalServerError, "unable to store API token", "/user/tokens")
+		return
+	}
+
+	if webtools.IsAPIv1(req) {
+		webtools.GenerateAPICreated(w, dto.CreatedToken{Token: dto.NewToken(tok), Secret: secret})
+	} else if webtools.IsAPI(req) {
+		webtools.GenerateAPICreated(w, createdToken{Token: tok, Secret: secret})
+	} else {
+		renderTokens(w, req, tokensData{Created: tok, Secret: secret})
+	}
+}
+
+//RevokeToken POST /user/tokens/:token_id/revoke revoke a personal API token
+func RevokeToken(w http.ResponseWriter, req *http.Request) {
+	if !checkSessionLogged(w, req) {
+		return
+	}
+
+	id, err := webtools.GetInt(req, "token_id")
+	if err != nil {
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unknown token", "/user/tokens")
+		return
+	}
+
+	uid, _ := webtools.CurrentUserID(req)
+
+	dbh := db.New()
+	defer dbh.Close()
+
+	if err := user.RevokeToken(dbh, uid, id); err != nil {
+		webtools.FailWithStatus(w, req, http.StatusNotFound, "unable to revoke API token", "/user/tokens")
+		return
+	}
+
+	if webtools.IsAPI(req) {
+		webtools.GenerateAPIOkAndSend(w)
+	} else {
+		webtools.GetSession(req).AddFlash("API token revoked.", "info")
+		webtools.Redirect(w, req, "/user/tokens")
+	}
+}
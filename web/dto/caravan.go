@@ -0,0 +1,284 @@
+package dto
+
+import (
+	"sort"
+	"time"
+	"upsilon_cities_go/lib/cities/caravan"
+	"upsilon_cities_go/lib/cities/item"
+	"upsilon_cities_go/lib/cities/storage"
+)
+
+//stateNames stable names of caravan states, display names may change.
+var stateNames = map[int]string{
+	caravan.CRVProposal:          "proposal",
+	caravan.CRVCounterProposal:   "counter_proposal",
+	caravan.CRVRefused:           "refused",
+	caravan.CRVWaitingOriginLoad: "waiting_origin_load",
+	caravan.CRVTravelingToTarget: "traveling_to_target",
+	caravan.CRVWaitingTargetLoad: "waiting_target_load",
+	caravan.CRVTravelingToOrigin: "traveling_to_origin",
+	caravan.CRVAborted:           "aborted",
+	caravan.CRVTerminated:        "terminated",
+}
+
+//CaravanState stable name of a caravan state, "unknown" for unexpected values.
+func CaravanState(state int) string {
+	if res, found := stateNames[state]; found {
+		return res
+	}
+	return "unknown"
+}
+
+//Party one end of a caravan contract.
+type Party struct {
+	CorporationID   int    `json:"corporation_id"`
+	CorporationName string `json:"corporation_name"`
+	CityID          int    `json:"city_id"`
+	CityName        string `json:"city_name"`
+	Dropped         bool   `json:"dropped"` // no longer displays caravan.
+	AutoRenew       bool   `json:"auto_renew"`
+	Insured         bool   `json:"insured"`
+	Escrow          int    `json:"escrow"` // penalty escrowed on accept.
+}
+
+//Goods what a party sends.
+type Goods struct {
+	ItemName     string   `json:"item_name"`
+	ItemTypes    []string `json:"item_types"`
+	Quality      Range    `json:"quality"`
+	Quantity     Range    `json:"quantity"`
+	Compensation int      `json:"compensation"` // credits sent along to buy goods.
+}
+
+//ExchangeRate goods sent by origin for goods sent by target.
+type ExchangeRate struct {
+	Origin int `json:"origin"`
+	Target int `json:"target"`
+}
+
+//Caravan contract between two cities.
+type Caravan struct {
+	ID                int          `json:"id"`
+	MapID             int          `json:"map_id"`
+	State             string       `json:"state"` // see CaravanState.
+	Origin            Party        `json:"origin"`
+	Target            Party        `json:"target"`
+	Exported          Goods        `json:"exported"`
+	Imported          Goods        `json:"imported"`
+	SendQuantity      int          `json:"send_quantity"`
+	ExchangeRate      ExchangeRate `json:"exchange_rate"`
+	LoadingDelay      int          `json:"loading_delay"`      // in cycles.
+	TravelingDistance int          `json:"traveling_distance"` // in nodes.
+	TravelingSpeed    int          `json:"traveling_speed"`    // in cycles.
+	Credits           int          `json:"credits"`
+	Cargo             []Item       `json:"cargo"`
+	Location          Point        `json:"location"`
+	Aborted           bool         `json:"aborted"`
+	Penalty           int          `json:"penalty"`
+	BreachedBy        int          `json:"breached_by,omitempty"` // corporation that broke the contract.
+	Settled           bool         `json:"settled"`
+	RenewedID         int          `json:"renewed_id,omitempty"` // caravan created on renewal.
+	LastChange        time.Time    `json:"last_change"`
+	NextChange        time.Time    `json:"next_change"`
+	EndOfTerm         time.Time    `json:"end_of_term"`
+}
+
+//Event recorded change of a caravan.
+type Event struct {
+	ID        int       `json:"id"`
+	FromState string    `json:"from_state"`
+	ToState   string    `json:"to_state"`
+	Cycle     time.Time `json:"cycle"`
+	Loaded    []Item    `json:"loaded"`
+	Unloaded  []Item    `json:"unloaded"`
+	Credits   int       `json:"credits"` // negative when taken out.
+	Reason    string    `json:"reason"`
+}
+
+//Replayed state of a caravan rebuilt from its events.
+type Replayed struct {
+	State   string         `json:"state"`
+	Credits int            `json:"credits"`
+	Goods   map[string]int `json:"goods"` // quantity carried by item name.
+	Events  int            `json:"events"`
+}
+
+//CaravanHistory events of a caravan and whether replaying them matches its state.
+type CaravanHistory struct {
+	Events   []Event  `json:"events"`
+	Replayed Replayed `json:"replayed"`
+	Matches  bool     `json:"matches"`
+	Mismatch string   `json:"mismatch,omitempty"`
+}
+
+//Template saved contract terms.
+type Template struct {
+	ID            int          `json:"id"`
+	Name          string       `json:"name"`
+	OriginCityID  int          `json:"origin_city_id"`
+	TargetCityID  int          `json:"target_city_id"`
+	Exported      Goods        `json:"exported"`
+	Imported      Goods        `json:"imported"`
+	ExchangeRate  ExchangeRate `json:"exchange_rate"`
+	LoadingDelay  int          `json:"loading_delay"`
+	Penalty       int          `json:"penalty"`
+	OriginInsured bool         `json:"origin_insured"`
+	TargetInsured bool         `json:"target_insured"`
+}
+
+//CandidateProduct product a city may export or import.
+type CandidateProduct struct {
+	ProducerID int      `json:"producer_id"`
+	ProductID  int      `json:"product_id"`
+	ItemName   string   `json:"item_name"`
+	ItemTypes  []string `json:"item_types"`
+}
+
+//CandidateCity city a caravan may reach.
+type CandidateCity struct {
+	CityID       int                `json:"city_id"`
+	CityName     string             `json:"city_name"`
+	Distance     int                `json:"distance"` // in nodes, by road.
+	TravelCycles int                `json:"travel_cycles"`
+	Imports      []CandidateProduct `json:"imports"`
+}
+
+//CandidateExport product of origin city with cities willing to get it.
+type CandidateExport struct {
+	CandidateProduct
+	Production         int   `json:"production"`
+	ProductionQuality  Range `json:"production_quality"`
+	ProductionDuration int   `json:"production_duration"`
+	CityIDs            []int `json:"city_ids"`
+	AlreadyExchanged   bool  `json:"already_exchanged"`
+	RecentlyProduced   bool  `json:"recently_produced"`
+	CurrentStock       int   `json:"current_stock"`
+}
+
+//CaravanCandidates what a caravan from a city may exchange.
+type CaravanCandidates struct {
+	OriginCity Ref               `json:"origin_city"`
+	Exports    []CandidateExport `json:"exports"`
+	Cities     []CandidateCity   `json:"cities"`
+}
+
+func newGoods(obj caravan.Object, compensation int) Goods {
+	return Goods{ItemName: obj.ItemName, ItemTypes: strings(obj.ItemType), Quality: NewRange(obj.Quality), Quantity: NewRange(obj.Quantity), Compensation: compensation}
+}
+
+//NewStorageItems content of store sorted by id, never nil.
+func NewStorageItems(store *storage.Storage) []Item {
+	if store == nil {
+		return []Item{}
+	}
+	itms := make([]item.Item, 0, len(store.Content))
+	for _, v := range store.Content {
+		itms = append(itms, v)
+	}
+	sort.Slice(itms, func(i, j int) bool { return itms[i].ID < itms[j].ID })
+	return NewItems(itms)
+}
+
+//NewCaravan from a caravan, must be called within caravan actor.
+func NewCaravan(crv *caravan.Caravan) Caravan {
+	return Caravan{
+		ID:    crv.ID,
+		MapID: crv.MapID,
+		State: CaravanState(crv.State),
+		Origin: Party{
+			CorporationID:   crv.CorpOriginID,
+			CorporationName: crv.CorpOriginName,
+			CityID:          crv.CityOriginID,
+			CityName:        crv.CityOriginName,
+			Dropped:         crv.OriginDropped,
+			AutoRenew:       crv.OriginAutoRenew,
+			Insured:         crv.OriginInsured,
+			Escrow:          crv.OriginEscrow,
+		},
+		Target: Party{
+			CorporationID:   crv.CorpTargetID,
+			CorporationName: crv.CorpTargetName,
+			CityID:          crv.CityTargetID,
+			CityName:        crv.CityTargetName,
+			Dropped:         crv.TargetDropped,
+			AutoRenew:       crv.TargetAutoRenew,
+			Insured:         crv.TargetInsured,
+			Escrow:          crv.TargetEscrow,
+		},
+		Exported:          newGoods(crv.Exported, crv.ExportCompensation),
+		Imported:          newGoods(crv.Imported, crv.ImportCompensation),
+		SendQuantity:      crv.SendQty,
+		ExchangeRate:      ExchangeRate{Origin: crv.ExchangeRateLHS, Target: crv.ExchangeRateRHS},
+		LoadingDelay:      crv.LoadingDelay,
+		TravelingDistance: crv.TravelingDistance,
+		TravelingSpeed:    crv.TravelingSpeed,
+		Credits:           crv.Credits,
+		Cargo:             NewStorageItems(crv.Store),
+		Location:          NewPoint(crv.Location),
+		Aborted:           crv.Aborted,
+		Penalty:           crv.Penalty,
+		BreachedBy:        crv.BreachedBy,
+		Settled:           crv.Settled,
+		RenewedID:         crv.RenewedID,
+		LastChange:        crv.LastChange,
+		NextChange:        crv.NextChange,
+		EndOfTerm:         crv.EndOfTerm,
+	}
+}
+
+//NewEvent from a caravan event.
+func NewEvent(evt caravan.Event) Event {
+	return Event{
+		ID:        evt.ID,
+		FromState: CaravanState(evt.FromState),
+		ToState:   CaravanState(evt.ToState),
+		Cycle:     evt.Cycle,
+		Loaded:    NewItems(evt.Loaded),
+		Unloaded:  NewItems(evt.Unloaded),
+		Credits:   evt.Credits,
+		Reason:    evt.Reason,
+	}
+}
+
+//NewCaravanHistory from events, their replay and whether it matches caravan.
+func NewCaravanHistory(events []caravan.Event, replayed caravan.Replayed, matches bool, mismatch string) CaravanHistory {
+	res := CaravanHistory{
+		Events:   make([]Event, 0, len(events)),
+		Replayed: Replayed{State: CaravanState(replayed.State), Credits: replayed.Credits, Goods: replayed.Goods, Events: replayed.Events},
+		Matches:  matches,
+		Mismatch: mismatch,
+	}
+	if res.Replayed.Goods == nil {
+		res.Replayed.Goods = make(map[string]int)
+	}
+	for _, v := range events {
+		res.Events = append(res.Events, NewEvent(v))
+	}
+	return res
+}
+
+//NewTemplate from a contract template.
+func NewTemplate(tmpl caravan.Template) Template {
+	return Template{
+		ID:            tmpl.ID,
+		Name:          tmpl.Name,
+		OriginCityID:  tmpl.CityOriginID,
+		TargetCityID:  tmpl.CityTargetID,
+		Exported:      newGoods(tmpl.Terms.Exported, tmpl.Terms.ExportCompensation),
+		Imported:      newGoods(tmpl.Terms.Imported, tmpl.Terms.ImportCompensation),
+		ExchangeRate:  ExchangeRate{Origin: tmpl.Terms.ExchangeRateLHS, Target: tmpl.Terms.ExchangeRateRHS},
+		LoadingDelay:  tmpl.Terms.LoadingDelay,
+		Penalty:       tmpl.Terms.Penalty,
+		OriginInsured: tmpl.Terms.OriginInsured,
+		TargetInsured: tmpl.Terms.TargetInsured,
+	}
+}
+
+//NewTemplates from contract templates, never nil.
+func NewTemplates(tmpls []caravan.Template) []Template {
+	res := make([]Template, 0, len(tmpls))
+	for _, v := range tmpls {
+		res = append(res, NewTemplate(v))
+	}
+	return res
+}
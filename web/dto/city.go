@@ -0,0 +1,136 @@
+package dto
+
+import (
+	"time"
+	"upsilon_cities_go/lib/cities/city"
+)
+
+//CityRef identifies a city and its location.
+type CityRef struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Location Point  `json:"location"`
+}
+
+//CitySummary city as listed on a map.
+type CitySummary struct {
+	ID         int       `json:"id"`
+	Name       string    `json:"name"`
+	Location   Point     `json:"location"`
+	Neighbours []CityRef `json:"neighbours"`
+}
+
+//Storage content of a storage.
+type Storage struct {
+	Count    int    `json:"count"`
+	Capacity int    `json:"capacity"`
+	Items    []Item `json:"items"`
+}
+
+//Product what a producer makes.
+type Product struct {
+	ID               int      `json:"id"`
+	Name             string   `json:"name"`
+	Types            []string `json:"types"`
+	Quality          Range    `json:"quality"`
+	Quantity         Range    `json:"quantity"`
+	QualityUpgrades  int      `json:"quality_upgrades"`
+	QuantityUpgrades int      `json:"quantity_upgrades"`
+}
+
+//Producer ressource producer or factory of a city.
+type Producer struct {
+	ID            int        `json:"id"`
+	Name          string     `json:"name"`
+	Active        bool       `json:"active"`
+	EndTime       *time.Time `json:"end_time,omitempty"` // end of running production.
+	Requirements  []string   `json:"requirements"`
+	CanUpgrade    bool       `json:"can_upgrade"`
+	CanBigUpgrade bool       `json:"can_big_upgrade"`
+	Products      []Product  `json:"products"`
+}
+
+//CityCaravan caravan as seen from one of its cities.
+type CityCaravan struct {
+	ID         int    `json:"id"`
+	Outgoing   bool   `json:"outgoing"` // proposed by this city.
+	Exported   string `json:"exported"` // goods leaving this city.
+	Imported   string `json:"imported"` // goods coming to this city.
+	RemoteCity string `json:"remote_city"`
+	Active     bool   `json:"active"`
+}
+
+//Claim pending claim on an uncorporated city.
+type Claim struct {
+	CorporationID   int       `json:"corporation_id"`
+	CorporationName string    `json:"corporation_name"`
+	Paid            int       `json:"paid"` // credits refunded if claim fails.
+	FiledAt         time.Time `json:"filed_at"`
+}
+
+//FameEntry change of fame of a corporation in a city.
+type FameEntry struct {
+	Delta  int       `json:"delta"`
+	Fame   int       `json:"fame"` // once change got applied.
+	Reason string    `json:"reason"`
+	Cycle  time.Time `json:"cycle"`
+}
+
+//City city as seen by current corporation. Storage and caravans are only provided to owner.
+type City struct {
+	ID              int           `json:"id"`
+	Name            string        `json:"name"`
+	Location        Point         `json:"location"`
+	CorporationID   int           `json:"corporation_id"` // 0 when uncorporated.
+	CorporationName string        `json:"corporation_name"`
+	Owned           bool          `json:"owned"` // by current corporation.
+	Fame            int           `json:"fame"`  // of current corporation.
+	Neighbours      []CityRef     `json:"neighbours"`
+	Storage         *Storage      `json:"storage,omitempty"`
+	Resources       []Producer    `json:"resources"`
+	Factories       []Producer    `json:"factories"`
+	Caravans        []CityCaravan `json:"caravans"`
+	Claims          []Claim       `json:"claims"`
+	ContestEnd      *time.Time    `json:"contest_end,omitempty"` // claims get resolved then.
+	CanClaim        bool          `json:"can_claim"`
+	ClaimCost       int           `json:"claim_cost"`
+	ClaimFame       int           `json:"claim_fame"`
+	FameHistory     []FameEntry   `json:"fame_history"`
+}
+
+//ClaimResult outcome of a claim.
+type ClaimResult struct {
+	CityID     int        `json:"city_id"`
+	Claimed    bool       `json:"claimed"`
+	Contested  bool       `json:"contested"`
+	ContestEnd *time.Time `json:"contest_end,omitempty"`
+}
+
+//UpgradeResult outcome of a producer upgrade.
+type UpgradeResult struct {
+	CityID   int  `json:"city_id"`
+	Upgraded bool `json:"upgraded"`
+}
+
+//NewClaim from a city claim.
+func NewClaim(cl city.Claim) Claim {
+	return Claim{CorporationID: cl.CorporationID, CorporationName: cl.CorporationName, Paid: cl.Paid, FiledAt: cl.FiledAt}
+}
+
+//NewClaims from city claims, never nil.
+func NewClaims(cls []city.Claim) []Claim {
+	res := make([]Claim, 0, len(cls))
+	for _, v := range cls {
+		res = append(res, NewClaim(v))
+	}
+	return res
+}
+
+//NewFameHistory from fame entries, never nil.
+func NewFameHistory(entries []city.FameEntry) []FameEntry {
+	res := make([]FameEntry, 0, len(entries))
+	for _, v := range entries {
+		res = append(res, FameEntry{Delta: v.Delta, Fame: v.Fame, Reason: v.Reason, Cycle: v.Cycle})
+	}
+	return res
+}
@@ -0,0 +1,166 @@
+package dto
+
+import (
+	"time"
+	"upsilon_cities_go/lib/cities/corporation"
+)
+
+//CaravanStatus caravan as listed by one of its corporations.
+type CaravanStatus struct {
+	ID             int       `json:"id"`
+	OriginCity     Ref       `json:"origin_city"`
+	TargetCity     Ref       `json:"target_city"`
+	Status         string    `json:"status"` // human readable, depends on corporation side.
+	Active         bool      `json:"active"`
+	Moving         bool      `json:"moving"`
+	Waiting        bool      `json:"waiting"`
+	RequiresAction bool      `json:"requires_action"`
+	CanCounter     bool      `json:"can_counter"`
+	CanRenew       bool      `json:"can_renew"`
+	AutoRenew      bool      `json:"auto_renew"`
+	Priority       bool      `json:"priority"`  // proposal from a corporation with priority perk.
+	Committed      int       `json:"committed"` // credits locked in escrow.
+	NextUpdate     time.Time `json:"next_update"`
+}
+
+//CorporationDetails only provided to corporation owner.
+type CorporationDetails struct {
+	Credits        int             `json:"credits"`
+	Committed      int             `json:"committed"` // credits locked in caravan escrow.
+	ActiveCaravans int             `json:"active_caravans"`
+	Viable         bool            `json:"viable"`
+	CityIDs        []int           `json:"city_ids"`
+	Caravans       []CaravanStatus `json:"caravans"`
+	RoadWorks      int             `json:"road_works"`
+	PriceBonus     int             `json:"price_bonus"` // percent added to sales.
+	NextTier       string          `json:"next_tier,omitempty"`
+	NextReputation int             `json:"next_reputation,omitempty"`
+}
+
+//Corporation as seen by current corporation.
+type Corporation struct {
+	ID         int                 `json:"id"`
+	Name       string              `json:"name"`
+	Reputation int                 `json:"reputation"`
+	Tier       string              `json:"tier"`
+	Owned      bool                `json:"owned"` // by current user.
+	Details    *CorporationDetails `json:"details,omitempty"`
+}
+
+//Transfer goods moving between a city and corporation warehouse.
+type Transfer struct {
+	ID          int       `json:"id"`
+	City        Ref       `json:"city"`
+	ToWarehouse bool      `json:"to_warehouse"` // false when goods are sent back to city.
+	Items       []Item    `json:"items"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+}
+
+//Warehouse corporation storage.
+type Warehouse struct {
+	CorporationID int        `json:"corporation_id"`
+	Count         int        `json:"count"`
+	Capacity      int        `json:"capacity"`
+	UpgradeCost   int        `json:"upgrade_cost"`
+	Items         []Item     `json:"items"`
+	Transfers     []Transfer `json:"transfers"`
+	Cities        []Ref      `json:"cities"` // owned cities goods may be retrieved to.
+}
+
+//Rule standing rule answering incoming proposals.
+type Rule struct {
+	ID                int     `json:"id"`
+	Accept            bool    `json:"accept"`              // false means reject.
+	FromCorporationID int     `json:"from_corporation_id"` // 0 matches any corporation.
+	ItemName          string  `json:"item_name"`           // empty matches any item.
+	MinRate           float64 `json:"min_rate"`            // goods received per good given, 0 ignores.
+	MinQuality        int     `json:"min_quality"`         // 0 ignores.
+	BelowQuality      int     `json:"below_quality"`       // 0 ignores.
+	NotProducible     bool    `json:"not_producible"`      // received item mustn't be producible by targeted city.
+	Description       string  `json:"description"`
+}
+
+//RoadWork road being built or upgraded by a corporation.
+type RoadWork struct {
+	ID          int            `json:"id"`
+	FromCityID  int            `json:"from_city_id"`
+	ToCityID    int            `json:"to_city_id"`
+	Upgrade     bool           `json:"upgrade"`
+	Path        []Point        `json:"path"`
+	Credits     int            `json:"credits"`
+	Items       map[string]int `json:"items"` // quantity consumed by item type.
+	StartTime   time.Time      `json:"start_time"`
+	EndTime     time.Time      `json:"end_time"`
+	Description string         `json:"description"`
+}
+
+//RoadLink trade partners a road may connect.
+type RoadLink struct {
+	FromCity Ref  `json:"from_city"`
+	ToCity   Ref  `json:"to_city"`
+	HasRoad  bool `json:"has_road"`
+}
+
+//Roads road works of a corporation and links it may build.
+type Roads struct {
+	CorporationID int        `json:"corporation_id"`
+	Links         []RoadLink `json:"links"`
+	Works         []RoadWork `json:"works"`
+}
+
+//NewTransfer from a warehouse transfer.
+func NewTransfer(tr corporation.Transfer) Transfer {
+	return Transfer{
+		ID:          tr.ID,
+		City:        Ref{ID: tr.CityID, Name: tr.CityName},
+		ToWarehouse: tr.ToWarehouse,
+		Items:       NewItems(tr.Items),
+		StartTime:   tr.StartTime,
+		EndTime:     tr.EndTime,
+	}
+}
+
+//NewRule from a proposal rule.
+func NewRule(rule corporation.ProposalRule) Rule {
+	return Rule{
+		ID:                rule.ID,
+		Accept:            rule.Accept,
+		FromCorporationID: rule.FromCorpID,
+		ItemName:          rule.ItemName,
+		MinRate:           rule.MinRate,
+		MinQuality:        rule.MinQuality,
+		BelowQuality:      rule.BelowQuality,
+		NotProducible:     rule.NotProducible,
+		Description:       rule.String(),
+	}
+}
+
+//NewRules from proposal rules, never nil.
+func NewRules(rules []corporation.ProposalRule) []Rule {
+	res := make([]Rule, 0, len(rules))
+	for _, v := range rules {
+		res = append(res, NewRule(v))
+	}
+	return res
+}
+
+//NewRoadWork from a road work.
+func NewRoadWork(work corporation.RoadWork) RoadWork {
+	res := RoadWork{
+		ID:          work.ID,
+		FromCityID:  work.FromCityID,
+		ToCityID:    work.ToCityID,
+		Upgrade:     work.Upgrade,
+		Path:        NewPoints(work.Road),
+		Credits:     work.Credits,
+		Items:       work.Items,
+		StartTime:   work.StartTime,
+		EndTime:     work.EndTime,
+		Description: work.String(),
+	}
+	if res.Items == nil {
+		res.Items = make(map[string]int)
+	}
+	return res
+}
@@ -0,0 +1,104 @@
+//Package dto holds types replied by /api/v1. They are the API contract: fields may be added, never renamed nor removed.
+//Internal structures are converted here, so refactoring them doesn't leak to clients.
+package dto
+
+import (
+	"time"
+	"upsilon_cities_go/lib/cities/item"
+	"upsilon_cities_go/lib/cities/node"
+	"upsilon_cities_go/lib/cities/tools"
+)
+
+//Ref identifies a named entity.
+type Ref struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+//Point location on map.
+type Point struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+//Range inclusive bounds.
+type Range struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+//Item stack of goods.
+type Item struct {
+	ID         int64    `json:"id"`
+	Name       string   `json:"name"`
+	Types      []string `json:"types"`
+	Quality    int      `json:"quality"`
+	Quantity   int      `json:"quantity"`
+	BasePrice  int      `json:"base_price"` // price at quality 100.
+	Perishable bool     `json:"perishable"` // loses quality every cycle.
+}
+
+//NewPoint from a node location.
+func NewPoint(pt node.Point) Point {
+	return Point{X: pt.X, Y: pt.Y}
+}
+
+//NewPoints from node locations, never nil.
+func NewPoints(pts []node.Point) []Point {
+	res := make([]Point, 0, len(pts))
+	for _, v := range pts {
+		res = append(res, NewPoint(v))
+	}
+	return res
+}
+
+//NewRange from an int range.
+func NewRange(rg tools.IntRange) Range {
+	return Range{Min: rg.Min, Max: rg.Max}
+}
+
+//NewItem from an item.
+func NewItem(itm item.Item) Item {
+	return Item{
+		ID:         itm.ID,
+		Name:       itm.Name,
+		Types:      strings(itm.Type),
+		Quality:    itm.Quality,
+		Quantity:   itm.Quantity,
+		BasePrice:  itm.BasePrice,
+		Perishable: itm.Decay.PerCycle > 0,
+	}
+}
+
+//NewItems from items, never nil.
+func NewItems(itms []item.Item) []Item {
+	res := make([]Item, 0, len(itms))
+	for _, v := range itms {
+		res = append(res, NewItem(v))
+	}
+	return res
+}
+
+//strings ensure lists get encoded as [] rather than null.
+func strings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+//ints ensure lists get encoded as [] rather than null.
+func ints(s []int) []int {
+	if s == nil {
+		return []int{}
+	}
+	return s
+}
+
+//optTime unset times are omitted.
+func optTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,227 @@
+package dto
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+	"upsilon_cities_go/lib/cities/caravan"
+	"upsilon_cities_go/lib/cities/city"
+	"upsilon_cities_go/lib/cities/corporation"
+	"upsilon_cities_go/lib/cities/item"
+	"upsilon_cities_go/lib/cities/map/grid"
+	"upsilon_cities_go/lib/cities/node"
+	"upsilon_cities_go/lib/cities/nodetype"
+	"upsilon_cities_go/lib/cities/storage"
+	"upsilon_cities_go/lib/cities/tools"
+	"upsilon_cities_go/lib/cities/user"
+	"upsilon_cities_go/lib/cities/user_log"
+)
+
+//go test ./web/dto -update rewrites contracts, only do so on purpose.
+var update = flag.Bool("update", false, "rewrite API contracts in testdata")
+
+var at = time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+func fixtureItem(id int64) item.Item {
+	return item.Item{ID: id, Name: "Wheat", Type: []string{"Food", "Cereal"}, Quality: 40, Quantity: 12, BasePrice: 3, Decay: item.Decay{PerCycle: 1, MinQuality: 5}}
+}
+
+func fixtureObject() caravan.Object {
+	return caravan.Object{ItemName: "Wheat", ItemType: []string{"Food"}, Quality: tools.IntRange{Min: 10, Max: 50}, Quantity: tools.IntRange{Min: 5, Max: 20}}
+}
+
+func fixtureGrid() *grid.Grid {
+	grd := new(grid.Grid)
+	grd.ID = 3
+	grd.Name = "Upsilon"
+	grd.RegionType = "Elvenwood"
+	grd.Size = 2
+	grd.CreatedAt = at
+	grd.LastUpdate = at
+	grd.EndedAt = at
+	grd.WinnerID = 4
+	grd.EndReason = "domination"
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 2; x++ {
+			grd.Nodes = append(grd.Nodes, node.Node{ID: y*2 + x, Location: node.NP(x, y), Ground: nodetype.Plain, Landscape: nodetype.Forest})
+		}
+	}
+	grd.Nodes[1].IsStructure = true
+	grd.Nodes[2].IsRoad = true
+	grd.Nodes[2].RoadLevel = 2
+	cty := &city.City{ID: 7, Location: node.NP(1, 0)}
+	grd.Cities = map[int]*city.City{cty.ID: cty}
+	grd.LocationToCity = map[int]*city.City{1: cty}
+	return grd
+}
+
+func fixtureCaravan() *caravan.Caravan {
+	crv := new(caravan.Caravan)
+	crv.ID = 9
+	crv.MapID = 3
+	crv.CorpOriginID, crv.CorpOriginName, crv.CityOriginID, crv.CityOriginName = 4, "Guild", 7, "Alpha"
+	crv.CorpTargetID, crv.CorpTargetName, crv.CityTargetID, crv.CityTargetName = 5, "League", 8, "Beta"
+	crv.OriginAutoRenew, crv.OriginInsured, crv.OriginEscrow = true, true, 50
+	crv.TargetDropped = true
+	crv.Exported = fixtureObject()
+	crv.Imported = fixtureObject()
+	crv.ExportCompensation, crv.ImportCompensation = 10, 20
+	crv.SendQty = 8
+	crv.State = caravan.CRVTravelingToTarget
+	crv.ExchangeRateLHS, crv.ExchangeRateRHS = 2, 3
+	crv.LoadingDelay, crv.TravelingDistance, crv.TravelingSpeed = 5, 12, 10
+	crv.Credits = 30
+	crv.Store = storage.New()
+	crv.Store.Content[2] = fixtureItem(2)
+	crv.Store.Content[1] = fixtureItem(1)
+	crv.Location = node.NP(1, 1)
+	crv.Penalty, crv.BreachedBy, crv.Settled, crv.RenewedID = 50, 5, true, 10
+	crv.LastChange, crv.NextChange, crv.EndOfTerm = at, at, at
+	return crv
+}
+
+func fixtureCity() City {
+	end := at
+	return City{
+		ID:              7,
+		Name:            "Alpha",
+		Location:        Point{X: 1, Y: 0},
+		CorporationID:   4,
+		CorporationName: "Guild",
+		Owned:           true,
+		Fame:            120,
+		Neighbours:      []CityRef{{ID: 8, Name: "Beta", Location: Point{X: 0, Y: 1}}},
+		Storage:         &Storage{Count: 12, Capacity: 100, Items: NewItems([]item.Item{fixtureItem(1)})},
+		Resources: []Producer{{
+			ID: 1, Name: "Farm", Active: true, EndTime: &end, Requirements: []string{"Plain"}, CanUpgrade: true, CanBigUpgrade: true,
+			Products: []Product{{ID: 1, Name: "Wheat", Types: []string{"Food"}, Quality: Range{Min: 10, Max: 50}, Quantity: Range{Min: 5, Max: 20}, QualityUpgrades: 1, QuantityUpgrades: 2}},
+		}},
+		Factories:   []Producer{},
+		Caravans:    []CityCaravan{{ID: 9, Outgoing: true, Exported: "Wheat", Imported: "Iron", RemoteCity: "Beta", Active: true}},
+		Claims:      NewClaims([]city.Claim{{CorporationID: 5, CorporationName: "League", Paid: 100, FiledAt: at}}),
+		ContestEnd:  &end,
+		CanClaim:    true,
+		ClaimCost:   100,
+		ClaimFame:   50,
+		FameHistory: NewFameHistory([]city.FameEntry{{ID: 1, CityID: 7, CorporationID: 4, Delta: 5, Fame: 120, Reason: "caravan", Cycle: at}}),
+	}
+}
+
+//fixtures every DTO fully populated, by contract name.
+func fixtures() map[string]interface{} {
+	grd := fixtureGrid()
+	crv := fixtureCaravan()
+	lb := grid.Leaderboard{ID: 2, MapID: 3, TakenAt: at, Entries: []grid.LeaderboardEntry{
+		{CorporationID: 4, CorporationName: "Guild", Credits: 900, Cities: 2, Fame: 300, CaravansCompleted: 4, GoodsProduced: 80},
+		{CorporationID: 5, CorporationName: "League", Credits: 100, Eliminated: true},
+	}}
+	events := []caravan.Event{{ID: 1, CaravanID: 9, FromState: caravan.CRVWaitingOriginLoad, ToState: caravan.CRVTravelingToTarget, Cycle: at, Loaded: []item.Item{fixtureItem(1)}, Credits: 10, Reason: "loaded"}}
+	tmpl := caravan.NewTemplate(crv, 4, "wheat run")
+	tmpl.ID = 6
+	usr := &user.User{ID: 1, Login: "mayor", Email: "mayor@upsilon.test", Password: "secret", LastLogin: at, Enabled: true}
+	tok := &user.Token{ID: 2, UserID: 1, Name: "bot", Scope: user.TokenRead, Hint: "upc_ab12", Hash: "hash", CreatedAt: at, LastUsed: at}
+
+	return map[string]interface{}{
+		"map_summary": func() MapSummary {
+			res := NewMapSummary(grid.ShortGrid{ID: 3, Name: "Upsilon", RegionType: "Elvenwood", LastUpdate: at, Ended: true})
+			res.Corporation = &PlayerCorporation{ID: 4, Name: "Guild", Credits: 900, CaravansWaiting: 1}
+			return res
+		}(),
+		"map_info":        MapInfo{Map: NewMap(grd), Corporation: PlayerCorporation{ID: 4, Name: "Guild", Credits: 900, CaravansWaiting: 1}},
+		"standings":       NewStandings(3, "Upsilon", "domination", at, []grid.Standing{{ID: 1, MapID: 3, Rank: 1, CorporationID: 4, CorporationName: "Guild", UserID: 1, Credits: 900, Cities: 2, Reputation: 300, Winner: true, ArchivedAt: at}}),
+		"leaderboard":     NewLeaderboard(3, "Upsilon", lb, grid.LBCredits, []string{grid.LBCredits, grid.LBCities}, []grid.Leaderboard{lb}),
+		"city_summary":    CitySummary{ID: 7, Name: "Alpha", Location: Point{X: 1, Y: 0}, Neighbours: []CityRef{{ID: 8, Name: "Beta", Location: Point{X: 0, Y: 1}}}},
+		"city":            fixtureCity(),
+		"claim_result":    ClaimResult{CityID: 7, Claimed: false, Contested: true, ContestEnd: &at},
+		"upgrade":         UpgradeResult{CityID: 7, Upgraded: true},
+		"caravan":         NewCaravan(crv),
+		"caravan_history": NewCaravanHistory(events, caravan.Replayed{State: caravan.CRVTravelingToTarget, Credits: 10, Goods: map[string]int{"Wheat": 12}, Events: 1}, false, "credits differ"),
+		"template":        NewTemplate(tmpl),
+		"caravan_candidates": CaravanCandidates{
+			OriginCity: Ref{ID: 7, Name: "Alpha"},
+			Exports: []CandidateExport{{
+				CandidateProduct: CandidateProduct{ProducerID: 1, ProductID: 1, ItemName: "Wheat", ItemTypes: []string{"Food"}},
+				Production:       10, ProductionQuality: Range{Min: 10, Max: 50}, ProductionDuration: 6, CityIDs: []int{8}, AlreadyExchanged: true, RecentlyProduced: true, CurrentStock: 12,
+			}},
+			Cities: []CandidateCity{{CityID: 8, CityName: "Beta", Distance: 12, TravelCycles: 2, Imports: []CandidateProduct{{ProducerID: 2, ProductID: 3, ItemName: "Iron", ItemTypes: []string{"Ore"}}}}},
+		},
+		"corporation": Corporation{ID: 4, Name: "Guild", Reputation: 300, Tier: "Renowned", Owned: true, Details: &CorporationDetails{
+			Credits: 900, Committed: 50, ActiveCaravans: 1, Viable: true, CityIDs: []int{7}, RoadWorks: 1, PriceBonus: 5, NextTier: "Legendary", NextReputation: 1000,
+			Caravans: []CaravanStatus{{ID: 9, OriginCity: Ref{ID: 7, Name: "Alpha"}, TargetCity: Ref{ID: 8, Name: "Beta"}, Status: "Traveling", Active: true, Moving: true, Waiting: false, RequiresAction: true, CanCounter: true, CanRenew: true, AutoRenew: true, Priority: true, Committed: 50, NextUpdate: at}},
+		}},
+		"warehouse": Warehouse{CorporationID: 4, Count: 12, Capacity: 50, UpgradeCost: 200, Items: NewItems([]item.Item{fixtureItem(1)}),
+			Transfers: []Transfer{NewTransfer(corporation.Transfer{ID: 1, CityID: 7, CityName: "Alpha", ToWarehouse: true, Items: []item.Item{fixtureItem(1)}, Reservation: 3, StartTime: at, EndTime: at})},
+			Cities:    []Ref{{ID: 7, Name: "Alpha"}}},
+		"rule": NewRule(corporation.ProposalRule{ID: 1, Accept: true, FromCorpID: 5, ItemName: "Wheat", MinRate: 1.5, MinQuality: 20, BelowQuality: 80, NotProducible: true}),
+		"roads": Roads{CorporationID: 4,
+			Links: []RoadLink{{FromCity: Ref{ID: 7, Name: "Alpha"}, ToCity: Ref{ID: 8, Name: "Beta"}, HasRoad: true}},
+			Works: []RoadWork{NewRoadWork(corporation.RoadWork{ID: 1, FromCityID: 7, ToCityID: 8, Upgrade: true, Road: node.Path{node.NP(1, 0), node.NP(0, 1)}, Credits: 300, Items: map[string]int{"Stone": 10}, StartTime: at, EndTime: at})}},
+		"user":          NewUser(usr),
+		"created_token": CreatedToken{Token: NewToken(tok), Secret: "upc_ab12secret"},
+		"user_log":      NewUserLogs([]user_log.UserLog{{ID: 1, UserID: 1, Message: "Welcome", Gravity: 1, Inserted: at, Acknowledged: true}}),
+	}
+}
+
+//TestContracts fails whenever a DTO encoding differs from its recorded contract.
+func TestContracts(t *testing.T) {
+	for name, v := range fixtures() {
+		got, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			t.Errorf("%s: failed to encode: %s", name, err)
+			return
+		}
+		got = append(got, '\n')
+
+		path := filepath.Join("testdata", name+".json")
+		if *update {
+			if err := ioutil.WriteFile(path, got, 0644); err != nil {
+				t.Errorf("%s: failed to update contract: %s", name, err)
+			}
+			continue
+		}
+
+		expected, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Errorf("%s: missing contract %s, run with -update to record it", name, path)
+			return
+		}
+		if !bytes.Equal(got, expected) {
+			t.Errorf("%s: encoding no longer matches %s, API v1 fields may only be added.\ngot:\n%s", name, path, got)
+			return
+		}
+	}
+}
+
+//TestNeverNull lists of empty values are encoded as [] so clients may iterate without checks.
+func TestNeverNull(t *testing.T) {
+	empty := map[string]interface{}{
+		"caravan":  NewCaravan(new(caravan.Caravan)),
+		"map":      NewMap(&grid.Grid{}),
+		"history":  NewCaravanHistory(nil, caravan.Replayed{}, true, ""),
+		"item":     NewItem(item.Item{}),
+		"template": NewTemplate(caravan.Template{}),
+		"roadwork": NewRoadWork(corporation.RoadWork{}),
+	}
+	for name, v := range empty {
+		got, _ := json.Marshal(v)
+		if bytes.Contains(got, []byte("null")) {
+			t.Errorf("%s: expected no null in %s", name, got)
+			return
+		}
+	}
+}
+
+func TestCaravanState(t *testing.T) {
+	if CaravanState(caravan.CRVCounterProposal) != "counter_proposal" {
+		t.Errorf("Expected stable state name, got %s", CaravanState(caravan.CRVCounterProposal))
+		return
+	}
+	if CaravanState(42) != "unknown" {
+		t.Errorf("Expected unknown state to be reported as such")
+		return
+	}
+}
@@ -0,0 +1,213 @@
+package dto
+
+import (
+	"time"
+	"upsilon_cities_go/lib/cities/map/grid"
+	"upsilon_cities_go/lib/cities/node"
+)
+
+//PlayerCorporation corporation played by current user on a map.
+type PlayerCorporation struct {
+	ID              int    `json:"id"`
+	Name            string `json:"name"`
+	Credits         int    `json:"credits"`
+	CaravansWaiting int    `json:"caravans_waiting"` // caravans requiring an action from player.
+}
+
+//MapSummary map as listed.
+type MapSummary struct {
+	ID          int                `json:"id"`
+	Name        string             `json:"name"`
+	RegionType  string             `json:"region_type"`
+	LastUpdate  time.Time          `json:"last_update"`
+	Ended       bool               `json:"ended"`
+	Corporation *PlayerCorporation `json:"corporation,omitempty"` // set when current user plays on map.
+}
+
+//Tile a node of a map.
+type Tile struct {
+	Location    Point  `json:"location"`
+	Ground      string `json:"ground"`
+	Landscape   string `json:"landscape"`
+	IsRoad      bool   `json:"is_road"`
+	RoadLevel   int    `json:"road_level"`
+	IsStructure bool   `json:"is_structure"`
+	CityID      int    `json:"city_id,omitempty"` // set when a city stands on tile.
+}
+
+//Map a map with its tiles.
+type Map struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	RegionType string     `json:"region_type"`
+	Size       int        `json:"size"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUpdate time.Time  `json:"last_update"`
+	EndedAt    *time.Time `json:"ended_at,omitempty"`
+	WinnerID   int        `json:"winner_id,omitempty"` // corporation, unset when map ended without winner.
+	EndReason  string     `json:"end_reason,omitempty"`
+	Tiles      [][]Tile   `json:"tiles"` // by row, tiles[y][x].
+}
+
+//MapInfo map as seen by a corporation.
+type MapInfo struct {
+	Map         Map               `json:"map"`
+	Corporation PlayerCorporation `json:"corporation"`
+}
+
+//Standing final rank of a corporation on a closed map.
+type Standing struct {
+	Rank            int    `json:"rank"`
+	CorporationID   int    `json:"corporation_id"`
+	CorporationName string `json:"corporation_name"`
+	Credits         int    `json:"credits"`
+	Cities          int    `json:"cities"`
+	Reputation      int    `json:"reputation"`
+	Eliminated      bool   `json:"eliminated"`
+	Winner          bool   `json:"winner"`
+}
+
+//Standings of a closed map.
+type Standings struct {
+	MapID     int        `json:"map_id"`
+	Name      string     `json:"name"`
+	EndReason string     `json:"end_reason"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	Standings []Standing `json:"standings"`
+}
+
+//LeaderboardEntry current rank of a corporation.
+type LeaderboardEntry struct {
+	Rank              int    `json:"rank"`
+	CorporationID     int    `json:"corporation_id"`
+	CorporationName   string `json:"corporation_name"`
+	Credits           int    `json:"credits"`
+	Cities            int    `json:"cities"`
+	Fame              int    `json:"fame"` // summed over all cities.
+	CaravansCompleted int    `json:"caravans_completed"`
+	GoodsProduced     int    `json:"goods_produced"`
+	Eliminated        bool   `json:"eliminated"`
+}
+
+//Snapshot archived leaderboard.
+type Snapshot struct {
+	ID      int       `json:"id"`
+	TakenAt time.Time `json:"taken_at"`
+}
+
+//Leaderboard ranking of corporations of a map.
+type Leaderboard struct {
+	MapID     int                `json:"map_id"`
+	Name      string             `json:"name"`
+	SortBy    string             `json:"sort_by"`
+	Criteria  []string           `json:"criteria"` // accepted values of sort_by.
+	TakenAt   *time.Time         `json:"taken_at,omitempty"`
+	Entries   []LeaderboardEntry `json:"entries"`
+	Snapshots []Snapshot         `json:"snapshots"`
+}
+
+//NewMapSummary from a listed map.
+func NewMapSummary(grd grid.ShortGrid) MapSummary {
+	return MapSummary{ID: grd.ID, Name: grd.Name, RegionType: grd.RegionType, LastUpdate: grd.LastUpdate, Ended: grd.Ended}
+}
+
+//NewTile from a node, cityID is 0 when no city stands on it.
+func NewTile(nd node.Node, cityID int) Tile {
+	return Tile{
+		Location:    NewPoint(nd.Location),
+		Ground:      nd.Ground.String(),
+		Landscape:   nd.Landscape.String(),
+		IsRoad:      nd.IsRoad,
+		RoadLevel:   nd.RoadLevel,
+		IsStructure: nd.IsStructure,
+		CityID:      cityID,
+	}
+}
+
+//NewMap from a grid, must be called within grid actor.
+func NewMap(grd *grid.Grid) Map {
+	res := Map{
+		ID:         grd.ID,
+		Name:       grd.Name,
+		RegionType: grd.RegionType,
+		Size:       grd.Size,
+		CreatedAt:  grd.CreatedAt,
+		LastUpdate: grd.LastUpdate,
+		EndedAt:    optTime(grd.EndedAt),
+		WinnerID:   grd.WinnerID,
+		EndReason:  grd.EndReason,
+		Tiles:      make([][]Tile, 0, grd.Size),
+	}
+
+	row := make([]Tile, 0, grd.Size)
+	for _, nd := range grd.Nodes {
+		cityID := 0
+		if cty := grd.GetCityByLocation(nd.Location); cty != nil {
+			cityID = cty.ID
+		}
+		row = append(row, NewTile(nd, cityID))
+		if len(row) == grd.Size {
+			res.Tiles = append(res.Tiles, row)
+			row = make([]Tile, 0, grd.Size)
+		}
+	}
+	return res
+}
+
+//NewStanding from an archived standing.
+func NewStanding(st grid.Standing) Standing {
+	return Standing{
+		Rank:            st.Rank,
+		CorporationID:   st.CorporationID,
+		CorporationName: st.CorporationName,
+		Credits:         st.Credits,
+		Cities:          st.Cities,
+		Reputation:      st.Reputation,
+		Eliminated:      st.Eliminated,
+		Winner:          st.Winner,
+	}
+}
+
+//NewStandings of a closed grid.
+func NewStandings(mapID int, name string, endReason string, endedAt time.Time, standings []grid.Standing) Standings {
+	res := Standings{MapID: mapID, Name: name, EndReason: endReason, EndedAt: optTime(endedAt), Standings: make([]Standing, 0, len(standings))}
+	for _, v := range standings {
+		res.Standings = append(res.Standings, NewStanding(v))
+	}
+	return res
+}
+
+//NewLeaderboardEntry from an entry ranked at rank.
+func NewLeaderboardEntry(rank int, entry grid.LeaderboardEntry) LeaderboardEntry {
+	return LeaderboardEntry{
+		Rank:              rank,
+		CorporationID:     entry.CorporationID,
+		CorporationName:   entry.CorporationName,
+		Credits:           entry.Credits,
+		Cities:            entry.Cities,
+		Fame:              entry.Fame,
+		CaravansCompleted: entry.CaravansCompleted,
+		GoodsProduced:     entry.GoodsProduced,
+		Eliminated:        entry.Eliminated,
+	}
+}
+
+//NewLeaderboard from leaderboard of map sorted by sortBy, along with archived snapshots.
+func NewLeaderboard(mapID int, name string, lb grid.Leaderboard, sortBy string, criteria []string, snapshots []grid.Leaderboard) Leaderboard {
+	res := Leaderboard{
+		MapID:     mapID,
+		Name:      name,
+		SortBy:    sortBy,
+		Criteria:  strings(criteria),
+		TakenAt:   optTime(lb.TakenAt),
+		Entries:   make([]LeaderboardEntry, 0, len(lb.Entries)),
+		Snapshots: make([]Snapshot, 0, len(snapshots)),
+	}
+	for idx, v := range lb.SortBy(sortBy) {
+		res.Entries = append(res.Entries, NewLeaderboardEntry(idx+1, v))
+	}
+	for _, v := range snapshots {
+		res.Snapshots = append(res.Snapshots, Snapshot{ID: v.ID, TakenAt: v.TakenAt})
+	}
+	return res
+}
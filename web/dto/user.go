@@ -0,0 +1,88 @@
+package dto
+
+import (
+	"time"
+	"upsilon_cities_go/lib/cities/user"
+	"upsilon_cities_go/lib/cities/user_log"
+)
+
+//User account, password is never sent.
+type User struct {
+	ID              int        `json:"id"`
+	Login           string     `json:"login"`
+	Email           string     `json:"email"`
+	LastLogin       *time.Time `json:"last_login,omitempty"`
+	NeedNewPassword bool       `json:"need_new_password"`
+	Enabled         bool       `json:"enabled"`
+	Admin           bool       `json:"admin"`
+}
+
+//Token personal API token, secret is only sent once upon creation.
+type Token struct {
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	Scope     string     `json:"scope"` // read or full.
+	Hint      string     `json:"hint"`  // first characters of the token.
+	CreatedAt time.Time  `json:"created_at"`
+	LastUsed  *time.Time `json:"last_used,omitempty"`
+}
+
+//CreatedToken token along its secret.
+type CreatedToken struct {
+	Token  Token  `json:"token"`
+	Secret string `json:"secret"`
+}
+
+//UserLog message sent to a user.
+type UserLog struct {
+	ID           int       `json:"id"`
+	Message      string    `json:"message"`
+	Gravity      int       `json:"gravity"`
+	Inserted     time.Time `json:"inserted"`
+	Acknowledged bool      `json:"acknowledged"`
+}
+
+//NewUser from a user.
+func NewUser(usr *user.User) User {
+	return User{
+		ID:              usr.ID,
+		Login:           usr.Login,
+		Email:           usr.Email,
+		LastLogin:       optTime(usr.LastLogin),
+		NeedNewPassword: usr.NeedNewPassword,
+		Enabled:         usr.Enabled,
+		Admin:           usr.Admin,
+	}
+}
+
+//NewUsers from users, never nil.
+func NewUsers(usrs []*user.User) []User {
+	res := make([]User, 0, len(usrs))
+	for _, v := range usrs {
+		res = append(res, NewUser(v))
+	}
+	return res
+}
+
+//NewToken from a token.
+func NewToken(tok *user.Token) Token {
+	return Token{ID: tok.ID, Name: tok.Name, Scope: tok.Scope, Hint: tok.Hint, CreatedAt: tok.CreatedAt, LastUsed: optTime(tok.LastUsed)}
+}
+
+//NewTokens from tokens, never nil.
+func NewTokens(toks []*user.Token) []Token {
+	res := make([]Token, 0, len(toks))
+	for _, v := range toks {
+		res = append(res, NewToken(v))
+	}
+	return res
+}
+
+//NewUserLogs from user logs, never nil.
+func NewUserLogs(logs []user_log.UserLog) []UserLog {
+	res := make([]UserLog, 0, len(logs))
+	for _, v := range logs {
+		res = append(res, UserLog{ID: v.ID, Message: v.Message, Gravity: v.Gravity, Inserted: v.Inserted, Acknowledged: v.Acknowledged})
+	}
+	return res
+}
@@ -0,0 +1,234 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+//Doc documents an API route, values provided as Body and Data are reflected into schemas.
+type Doc struct {
+	Method  string
+	Path    string // as registered, API prefix excluded. ex: /map/{map_id}
+	Summary string
+	Form    []string    // form encoded fields expected.
+	Body    interface{} // JSON body expected.
+	Data    interface{} // data of a successful reply.
+	Status  int         // status of a successful reply, 200 when unset.
+}
+
+//Document subset of OpenAPI 3 document.
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Security   []map[string][]string            `json:"security"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+
+	prefix string                  // routes documented are under prefix.
+	types  map[string]reflect.Type // named types by component name.
+}
+
+//Info about API.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+//Operation a method on a path.
+type Operation struct {
+	Tags        []string            `json:"tags,omitempty"`
+	Summary     string              `json:"summary,omitempty"`
+	OperationID string              `json:"operationId"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+//Parameter of an operation.
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+//RequestBody of an operation.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+//MediaType content of a body.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+//Response of an operation.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+//SecurityScheme how clients authenticate.
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+	In     string `json:"in,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+//Components shared by operations.
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+var pathParam = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// paramSchema path parameters are identifiers and coordinates, except items and actions.
+func paramSchema(name string) *Schema {
+	if strings.HasSuffix(name, "_id") || strings.HasSuffix(name, "_loc") || name == "user_state" {
+		return &Schema{Type: "integer"}
+	}
+	return &Schema{Type: "string"}
+}
+
+//operationID derived from method and path without prefix: GET /map/{map_id} => get_map_map_id
+func operationID(method string, path string) string {
+	res := strings.ToLower(method)
+	for _, v := range strings.Split(path, "/") {
+		v = strings.Trim(v, "{}")
+		if v != "" {
+			res += "_" + v
+		}
+	}
+	return res
+}
+
+//New empty document of routes under prefix.
+func New(prefix string, title string, version string) *Document {
+	doc := new(Document)
+	doc.prefix = prefix
+	doc.OpenAPI = "3.0.3"
+	doc.Info = Info{Title: title, Version: version, Description: "Every reply is wrapped in an envelope: {status, data} on success, {status, code, message, fields} on error."}
+	doc.Paths = make(map[string]map[string]*Operation)
+	doc.Components.Schemas = make(map[string]*Schema)
+	doc.types = make(map[string]reflect.Type)
+	doc.Components.SecuritySchemes = map[string]SecurityScheme{
+		"bearerAuth": {Type: "http", Scheme: "bearer"},
+		"cookieAuth": {Type: "apiKey", In: "cookie", Name: "session-key"},
+	}
+	doc.Security = []map[string][]string{{"bearerAuth": {}}, {"cookieAuth": {}}}
+
+	doc.Components.Schemas["Error"] = &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"status":  {Type: "string", Enum: []string{"error"}},
+			"code":    {Type: "string", Enum: []string{"bad_request", "unauthorized", "forbidden", "not_found", "conflict", "invalid", "too_many_requests", "internal"}},
+			"message": {Type: "string"},
+			"fields":  {Type: "object", AdditionalProperties: &Schema{Type: "string"}, Description: "errors by request field"},
+		},
+		Required: []string{"status", "code", "message"},
+	}
+	return doc
+}
+
+//Add operation described by doc.
+func (doc *Document) Add(d Doc) {
+	path := doc.prefix + d.Path
+	op := new(Operation)
+	op.Summary = d.Summary
+	op.OperationID = operationID(d.Method, d.Path)
+	if tag := strings.Split(strings.TrimPrefix(d.Path, "/"), "/")[0]; tag != "" {
+		op.Tags = []string{tag}
+	}
+
+	for _, v := range pathParam.FindAllStringSubmatch(path, -1) {
+		op.Parameters = append(op.Parameters, Parameter{Name: v[1], In: "path", Required: true, Schema: paramSchema(v[1])})
+	}
+	// mux patterns are irrelevant to clients.
+	path = pathParam.ReplaceAllString(path, "{$1}")
+
+	if len(d.Form) > 0 {
+		form := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		for _, v := range d.Form {
+			form.Properties[v] = &Schema{Type: "string"}
+		}
+		op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{"application/x-www-form-urlencoded": {Schema: form}}}
+	}
+	if d.Body != nil {
+		op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{"application/json": {Schema: doc.SchemaOf(d.Body)}}}
+	}
+
+	status := d.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	envelope := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"status": {Type: "string", Enum: []string{"ok"}},
+		},
+		Required: []string{"status"},
+	}
+	if d.Data != nil {
+		envelope.Properties["data"] = doc.SchemaOf(d.Data)
+	}
+	errorReply := Response{Description: "error", Content: map[string]MediaType{"application/json": {Schema: &Schema{Ref: "#/components/schemas/Error"}}}}
+	op.Responses = map[string]Response{
+		fmt.Sprint(status): {Description: http.StatusText(status), Content: map[string]MediaType{"application/json": {Schema: envelope}}},
+		"default":          errorReply,
+	}
+
+	if _, found := doc.Paths[path]; !found {
+		doc.Paths[path] = make(map[string]*Operation)
+	}
+	doc.Paths[path][strings.ToLower(d.Method)] = op
+}
+
+//Generate document every route of router under prefix. Routes without doc are listed with a generic reply.
+func Generate(r *mux.Router, prefix string, title string, version string, docs ...[]Doc) *Document {
+	known := make(map[string]Doc)
+	for _, v := range docs {
+		for _, d := range v {
+			known[d.Method+" "+prefix+d.Path] = d
+		}
+	}
+
+	doc := New(prefix, title, version)
+	routes := make([]Doc, 0)
+	r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if route.GetHandler() == nil {
+			return nil
+		}
+		path, err := route.GetPathTemplate()
+		if err != nil || !strings.HasPrefix(path, prefix+"/") {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, m := range methods {
+			d, found := known[m+" "+path]
+			if !found {
+				d = Doc{Method: m, Path: strings.TrimPrefix(path, prefix)}
+			}
+			routes = append(routes, d)
+		}
+		return nil
+	})
+
+	sort.SliceStable(routes, func(i, j int) bool { return routes[i].Path < routes[j].Path })
+	for _, v := range routes {
+		doc.Add(v)
+	}
+	return doc
+}
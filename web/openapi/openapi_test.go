@@ -0,0 +1,90 @@
+package openapi
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type sampleNode struct {
+	ID       int
+	Name     string `json:"name"`
+	Hidden   string `json:"-"`
+	At       time.Time
+	Children []*sampleNode
+	ByID     map[int]int
+	secret   int
+}
+
+func noop(w http.ResponseWriter, req *http.Request) {}
+
+func TestGenerate(t *testing.T) {
+	r := mux.NewRouter()
+	api := r.PathPrefix("/api").Subrouter()
+	api.HandleFunc("/node/{node_id}", noop).Methods("GET")
+	api.HandleFunc("/node/{node_id}/{action}", noop).Methods("POST")
+	r.HandleFunc("/node", noop).Methods("GET")
+
+	doc := Generate(r, "/api", "test", "1", []Doc{{Method: "GET", Path: "/node/{node_id}", Summary: "A node.", Data: sampleNode{}}})
+
+	if len(doc.Paths) != 2 {
+		t.Errorf("Expected only /api routes to be documented, got %d paths", len(doc.Paths))
+		return
+	}
+
+	op := doc.Paths["/api/node/{node_id}"]["get"]
+	if op == nil || op.Summary != "A node." || op.Tags[0] != "node" {
+		t.Errorf("Expected documented operation, got %+v", op)
+		return
+	}
+	if len(op.Parameters) != 1 || op.Parameters[0].Schema.Type != "integer" {
+		t.Errorf("Expected node_id integer path parameter, got %+v", op.Parameters)
+		return
+	}
+
+	undocumented := doc.Paths["/api/node/{node_id}/{action}"]["post"]
+	if undocumented == nil || undocumented.Parameters[1].Schema.Type != "string" {
+		t.Errorf("Expected undocumented route to be listed with its parameters")
+		return
+	}
+
+	schema := doc.Components.Schemas["openapi.sampleNode"]
+	if schema == nil {
+		t.Errorf("Expected sampleNode to be registered as component")
+		return
+	}
+	for _, v := range []string{"Hidden", "secret", "Name"} {
+		if _, found := schema.Properties[v]; found {
+			t.Errorf("Field %s shouldn't be documented", v)
+			return
+		}
+	}
+	if schema.Properties["name"] == nil || schema.Properties["At"].Format != "date-time" {
+		t.Errorf("Expected json names and time format to be honored, got %+v", schema.Properties)
+		return
+	}
+	if schema.Properties["Children"].Items.Ref != "#/components/schemas/openapi.sampleNode" {
+		t.Errorf("Expected recursive type to refer to itself")
+		return
+	}
+}
+
+func TestGeneratePrefix(t *testing.T) {
+	r := mux.NewRouter()
+	r.PathPrefix("/api/v1").Subrouter().HandleFunc("/node/{node_id}", noop).Methods("GET")
+	r.PathPrefix("/api").Subrouter().HandleFunc("/node/{node_id}", noop).Methods("GET")
+
+	doc := Generate(r, "/api/v1", "test", "1", []Doc{{Method: "GET", Path: "/node/{node_id}", Summary: "A node."}})
+
+	if len(doc.Paths) != 1 {
+		t.Errorf("Expected only /api/v1 routes to be documented, got %d paths", len(doc.Paths))
+		return
+	}
+	op := doc.Paths["/api/v1/node/{node_id}"]["get"]
+	if op == nil || op.Summary != "A node." || op.OperationID != "get_node_node_id" {
+		t.Errorf("Expected documented operation without prefix in its id, got %+v", op)
+		return
+	}
+}
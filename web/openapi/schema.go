@@ -0,0 +1,142 @@
+package openapi
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+)
+
+//Schema subset of OpenAPI 3 schema object.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// schemaName component name of a named type, prefixed by its package.
+// Packages sharing a name (lib and controllers) are told apart by their parent directory.
+func (doc *Document) schemaName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	name := path.Base(pkg) + "." + t.Name()
+	if known, found := doc.types[name]; found && known != t {
+		name = path.Base(path.Dir(pkg)) + "." + name
+	}
+	doc.types[name] = t
+	return name
+}
+
+// jsonField name of field once encoded, and whether it's encoded at all.
+func jsonField(f reflect.StructField) (string, bool) {
+	if f.PkgPath != "" && !f.Anonymous {
+		return "", false
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name := strings.Split(tag, ",")[0]
+	if name == "" {
+		name = f.Name
+	}
+	return name, true
+}
+
+//SchemaOf reflect value type into a schema, named structs are registered as components.
+func (doc *Document) SchemaOf(v interface{}) *Schema {
+	if v == nil {
+		return &Schema{}
+	}
+	return doc.schemaOf(reflect.TypeOf(v))
+}
+
+func (doc *Document) schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Implements(marshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: doc.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: doc.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return doc.structSchema(t)
+		}
+		name := doc.schemaName(t)
+		if _, found := doc.Components.Schemas[name]; !found {
+			// register before walking fields, recursive types refer to themselves.
+			doc.Components.Schemas[name] = &Schema{Type: "object"}
+			doc.Components.Schemas[name] = doc.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	// interfaces, channels, functions: anything goes.
+	return &Schema{}
+}
+
+func (doc *Document) structSchema(t reflect.Type) *Schema {
+	res := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, encoded := jsonField(f)
+		if !encoded {
+			continue
+		}
+
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && f.Tag.Get("json") == "" && ft.Kind() == reflect.Struct && ft != timeType {
+			// embedded struct fields are promoted.
+			for k, v := range doc.structSchema(ft).Properties {
+				if _, found := res.Properties[k]; !found {
+					res.Properties[k] = v
+				}
+			}
+			continue
+		}
+		switch ft.Kind() {
+		case reflect.Chan, reflect.Func:
+			continue
+		}
+		res.Properties[name] = doc.schemaOf(f.Type)
+	}
+	return res
+}
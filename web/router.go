@@ -1,10 +1,15 @@
 package web
 
 import (
+	gocontext "context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 	"upsilon_cities_go/lib/cities/caravan"
 	"upsilon_cities_go/lib/cities/caravan_manager"
@@ -15,13 +20,19 @@ import (
 	"upsilon_cities_go/lib/cities/map/grid_manager"
 	"upsilon_cities_go/lib/db"
 	"upsilon_cities_go/lib/misc/config/system"
+	"upsilon_cities_go/lib/misc/logger"
+	"upsilon_cities_go/lib/misc/metrics"
+	"upsilon_cities_go/lib/misc/ratelimit"
+	"upsilon_cities_go/lib/misc/shutdown"
 	controllers "upsilon_cities_go/web/controllers"
 	admin_controller "upsilon_cities_go/web/controllers/admin"
 	crv_controller "upsilon_cities_go/web/controllers/caravan"
 	city_controller "upsilon_cities_go/web/controllers/city"
 	corp_controller "upsilon_cities_go/web/controllers/corporation"
 	grid_controller "upsilon_cities_go/web/controllers/grid"
+	health_controller "upsilon_cities_go/web/controllers/health"
 	user_controller "upsilon_cities_go/web/controllers/user"
+	"upsilon_cities_go/web/openapi"
 	"upsilon_cities_go/web/webtools"
 
 	"github.com/antonlindstrom/pgstore"
@@ -41,7 +52,7 @@ func RouterSetup() *mux.Router {
 	store, err = pgstore.NewPGStoreFromPool(dbh.Raw(), []byte(system.Get("http_session_secret_key", "12345678912345678912345678912345")))
 	if err != nil {
 		// failed to find a store in there ...
-		log.Fatalf("Session: Failed to initialize session for web request ... %s", err)
+		logger.Fatalf("Session", "Failed to initialize session for web request ... %s", err)
 	}
 
 	// Run a background goroutine to clean up expired sessions from the database.
@@ -50,6 +61,13 @@ func RouterSetup() *mux.Router {
 	// ensure session knows how to keep some complex data types.
 	initConverters()
 
+	// scraped by Prometheus, no session required.
+	r.HandleFunc("/metrics", metrics.Handler).Methods("GET")
+
+	// probed by supervisors and load balancers, no session required either.
+	r.HandleFunc("/healthz", health_controller.Healthz).Methods("GET")
+	r.HandleFunc("/readyz", health_controller.Readyz).Methods("GET")
+
 	sessionned := r.PathPrefix("").Subrouter()
 
 	sessionned.HandleFunc("", controllers.Home).Methods("GET")
@@ -64,6 +82,8 @@ func RouterSetup() *mux.Router {
 	maps.HandleFunc("/corp/{corp_id}", grid_controller.Show).Methods("GET")
 	maps.HandleFunc("/select_corporation", grid_controller.ShowSelectableCorporation).Methods("GET")
 	maps.HandleFunc("/select_corporation", grid_controller.SelectCorporation).Methods("POST")
+	maps.HandleFunc("/standings", grid_controller.Standings).Methods("GET")
+	maps.HandleFunc("/leaderboard", grid_controller.Leaderboard).Methods("GET")
 	maps.HandleFunc("/cities", city_controller.Index).Methods("GET")
 
 	// ensure map get generated ...
@@ -74,6 +94,8 @@ func RouterSetup() *mux.Router {
 	city.HandleFunc("/give/{item}", city_controller.Give).Methods("POST")
 	city.HandleFunc("/drop/{item}", city_controller.Drop).Methods("POST")
 	city.HandleFunc("/sell/{item}", city_controller.Sell).Methods("POST")
+	city.HandleFunc("/claim", city_controller.Claim).Methods("POST")
+	city.HandleFunc("/store/{item}", corp_controller.Store).Methods("POST")
 	city.HandleFunc("/producer/{producer_id}/{action}", city_controller.ProducerUpgrade).Methods("POST")
 
 	// ensure map get generated ...
@@ -81,6 +103,14 @@ func RouterSetup() *mux.Router {
 
 	corporation := sessionned.PathPrefix("/corporation/{corp_id}").Subrouter()
 	corporation.HandleFunc("", corp_controller.Show).Methods("GET")
+	corporation.HandleFunc("/warehouse", corp_controller.Warehouse).Methods("GET")
+	corporation.HandleFunc("/warehouse/upgrade", corp_controller.UpgradeWarehouse).Methods("POST")
+	corporation.HandleFunc("/warehouse/{item}/retrieve/{city_id}", corp_controller.Retrieve).Methods("POST")
+	corporation.HandleFunc("/rules", corp_controller.Rules).Methods("GET")
+	corporation.HandleFunc("/rules", corp_controller.AddRule).Methods("POST")
+	corporation.HandleFunc("/rules/{rule_id}/drop", corp_controller.DropRule).Methods("POST")
+	corporation.HandleFunc("/roads", corp_controller.Roads).Methods("GET")
+	corporation.HandleFunc("/roads", corp_controller.CommissionRoad).Methods("POST")
 
 	// ensure map get generated ...
 	corporation.Use(mapMw)
@@ -89,6 +119,9 @@ func RouterSetup() *mux.Router {
 	// caravan related stuff
 	caravan.HandleFunc("", crv_controller.Index).Methods("GET")
 	caravan.HandleFunc("/new/{city_id}", crv_controller.New).Methods("GET")
+	caravan.HandleFunc("/templates", crv_controller.Templates).Methods("GET")
+	caravan.HandleFunc("/templates/{template_id}/propose", crv_controller.ProposeTemplate).Methods("POST")
+	caravan.HandleFunc("/templates/{template_id}/drop", crv_controller.DropTemplate).Methods("POST")
 	caravan.HandleFunc("/{crv_id}", crv_controller.Show).Methods("GET")
 	caravan.HandleFunc("/{crv_id}/accept", crv_controller.Accept).Methods("POST")
 	caravan.HandleFunc("/{crv_id}/reject", crv_controller.Reject).Methods("POST")
@@ -96,6 +129,9 @@ func RouterSetup() *mux.Router {
 	caravan.HandleFunc("/{crv_id}/counter", crv_controller.GetCounter).Methods("POST")
 	caravan.HandleFunc("/{crv_id}/counter", crv_controller.PostCounter).Methods("POST")
 	caravan.HandleFunc("/{crv_id}/drop", crv_controller.Drop).Methods("POST")
+	caravan.HandleFunc("/{crv_id}/renew", crv_controller.Renew).Methods("POST")
+	caravan.HandleFunc("/{crv_id}/auto_renew", crv_controller.AutoRenew).Methods("POST")
+	caravan.HandleFunc("/{crv_id}/template", crv_controller.SaveTemplate).Methods("POST")
 
 	// ensure map get generated ...
 	caravan.Use(mapMw)
@@ -104,16 +140,24 @@ func RouterSetup() *mux.Router {
 	usr := sessionned.PathPrefix("/user").Subrouter()
 	usr.HandleFunc("", user_controller.Show).Methods("GET")
 	usr.HandleFunc("/new", user_controller.New).Methods("GET")
-	usr.HandleFunc("/checkavailable/login/{login}/mail/{mail}", user_controller.CheckAvailable).Methods("GET")
-	usr.HandleFunc("", user_controller.Create).Methods("POST")
+	usr.HandleFunc("/checkavailable/login/{login}/mail/{mail}", limit("register", user_controller.CheckAvailable)).Methods("GET")
+	usr.HandleFunc("", limit("register", user_controller.Create)).Methods("POST")
 	usr.HandleFunc("/login", user_controller.ShowLogin).Methods("GET")
-	usr.HandleFunc("/login", user_controller.Login).Methods("POST")
+	usr.HandleFunc("/login", limit("login", user_controller.Login)).Methods("POST")
 	usr.HandleFunc("/logs", user_controller.Logs).Methods("GET")
 	usr.HandleFunc("/logout", user_controller.Logout).Methods("GET")
 	usr.HandleFunc("/logout", user_controller.Logout).Methods("POST")
 	usr.HandleFunc("/reset_password", user_controller.ShowResetPassword).Methods("GET")
 	usr.HandleFunc("/reset_password", user_controller.ResetPassword).Methods("POST")
+	usr.HandleFunc("/forgot_password", user_controller.ShowForgotPassword).Methods("GET")
+	usr.HandleFunc("/forgot_password", limit("recovery", user_controller.ForgotPassword)).Methods("POST")
+	usr.HandleFunc("/recover/{token}", user_controller.ShowRecover).Methods("GET")
+	usr.HandleFunc("/recover/{token}", limit("recovery", user_controller.Recover)).Methods("POST")
+	usr.HandleFunc("/verify/{token}", limit("register", user_controller.Verify)).Methods("GET")
 	usr.HandleFunc("", user_controller.Destroy).Methods("DELETE")
+	usr.HandleFunc("/tokens", user_controller.Tokens).Methods("GET")
+	usr.HandleFunc("/tokens", user_controller.CreateToken).Methods("POST")
+	usr.HandleFunc("/tokens/{token_id}/revoke", user_controller.RevokeToken).Methods("POST")
 
 	// Interface Admin
 	admin := sessionned.PathPrefix("/admin").Subrouter()
@@ -128,17 +172,53 @@ func RouterSetup() *mux.Router {
 	admin.HandleFunc("/users/{user_id}", admin_controller.AdminDestroy).Methods("DELETE")
 	admin.HandleFunc("/users/{user_id}/state/{user_state}", admin_controller.Lock).Methods("POST")
 
-	// JSON Access ...
+	// JSON Access ... versioned API replies with DTOs, legacy /api is kept as an alias during deprecation.
+	// v1 goes first so legacy doesn't catch its routes.
+	doc := openAPI(r)
+	v1 := sessionned.PathPrefix("/api/v1").Subrouter()
+	apiRoutes(v1, doc)
+
 	jsonAPI := sessionned.PathPrefix("/api").Subrouter()
-	jsonAPI.HandleFunc("/map", grid_controller.Index).Methods("GET")
-	jsonAPI.HandleFunc("/map", grid_controller.Create).Methods("POST")
+	apiRoutes(jsonAPI, doc)
+	jsonAPI.Use(deprecatedMw)
+
+	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir(system.MakePath(system.Get("web_static_files", "web/static"))))))
+
+	r.HandleFunc("/favicon.ico", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, filepath.FromSlash(fmt.Sprintf("%s/img/favicon.ico", system.MakePath(system.Get("web_static_files", "web/static")))))
+	})
+
+	r.NotFoundHandler = http.HandlerFunc(notFound)
+	r.MethodNotAllowedHandler = http.HandlerFunc(methodNotAllowed)
+
+	r.Use(logResultMw)
+	r.Use(loggingMw)
+	r.Use(securityHeadersMw)
+	// parent middlewares run first: token must be known before CSRF check.
+	sessionned.Use(sessionMw)
+	sessionned.Use(tokenMw)
+	sessionned.Use(webLimitMw)
+	sessionned.Use(csrfMw)
+	v1.Use(limitMw("api"))
+	jsonAPI.Use(limitMw("api"))
 
-	maps = jsonAPI.PathPrefix("/map/{map_id}").Subrouter()
+	return r
+}
+
+// apiRoutes register JSON routes on api.
+func apiRoutes(api *mux.Router, doc http.HandlerFunc) {
+	api.HandleFunc("/openapi.json", doc).Methods("GET")
+	api.HandleFunc("/map", grid_controller.Index).Methods("GET")
+	api.HandleFunc("/map", grid_controller.Create).Methods("POST")
+
+	maps := api.PathPrefix("/map/{map_id}").Subrouter()
 	maps.HandleFunc("", grid_controller.GetMapInfo).Methods("GET")
 	maps.HandleFunc("/corp/{corp_id}", grid_controller.GetMapInfo).Methods("GET")
 	maps.HandleFunc("", grid_controller.Destroy).Methods("DELETE")
 	maps.HandleFunc("/select_corporation", grid_controller.ShowSelectableCorporation).Methods("GET")
 	maps.HandleFunc("/select_corporation", grid_controller.SelectCorporation).Methods("POST")
+	maps.HandleFunc("/standings", grid_controller.Standings).Methods("GET")
+	maps.HandleFunc("/leaderboard", grid_controller.Leaderboard).Methods("GET")
 	maps.HandleFunc("/cities", city_controller.Index).Methods("GET")
 	maps.HandleFunc("/corp/{corp_id}/city/X/{x_loc}/Y/{y_loc}", city_controller.IDShow).Methods("GET")
 	maps.HandleFunc("/city/X/{x_loc}/Y/{y_loc}", city_controller.IDShow).Methods("GET")
@@ -146,41 +226,65 @@ func RouterSetup() *mux.Router {
 	// ensure map get generated ...
 	maps.Use(mapMw)
 
-	city = jsonAPI.PathPrefix("/city/{city_id}").Subrouter()
+	city := api.PathPrefix("/city/{city_id}").Subrouter()
 	city.HandleFunc("", city_controller.Show).Methods("GET")
 	city.HandleFunc("/give/{item}", city_controller.Give).Methods("POST")
 	city.HandleFunc("/drop/{item}", city_controller.Drop).Methods("POST")
 	city.HandleFunc("/sell/{item}", city_controller.Sell).Methods("POST")
+	city.HandleFunc("/claim", city_controller.Claim).Methods("POST")
+	city.HandleFunc("/store/{item}", corp_controller.Store).Methods("POST")
 	city.HandleFunc("/producer/{producer_id}/{action}/{product}", city_controller.ProducerUpgrade).Methods("POST")
 
 	// ensure map get generated ...
 	city.Use(mapMw)
 
-	usr = jsonAPI.PathPrefix("/user").Subrouter()
+	usr := api.PathPrefix("/user").Subrouter()
 	usr.HandleFunc("", user_controller.Show).Methods("GET")
 	usr.HandleFunc("/new", user_controller.New).Methods("GET")
-	usr.HandleFunc("/checkavailable/login/{login}/mail/{mail}", user_controller.CheckAvailable).Methods("GET")
-	usr.HandleFunc("", user_controller.Create).Methods("POST")
+	usr.HandleFunc("/checkavailable/login/{login}/mail/{mail}", limit("register", user_controller.CheckAvailable)).Methods("GET")
+	usr.HandleFunc("", limit("register", user_controller.Create)).Methods("POST")
 	usr.HandleFunc("/login", user_controller.ShowLogin).Methods("GET")
 	usr.HandleFunc("/logs", user_controller.Logs).Methods("GET")
-	usr.HandleFunc("/login", user_controller.Login).Methods("POST")
+	usr.HandleFunc("/login", limit("login", user_controller.Login)).Methods("POST")
 	usr.HandleFunc("/logout", user_controller.Logout).Methods("GET")
 	usr.HandleFunc("/logout", user_controller.Logout).Methods("POST")
 	usr.HandleFunc("/reset_password", user_controller.ShowResetPassword).Methods("GET")
 	usr.HandleFunc("/reset_password", user_controller.ResetPassword).Methods("POST")
+	usr.HandleFunc("/forgot_password", user_controller.ShowForgotPassword).Methods("GET")
+	usr.HandleFunc("/forgot_password", limit("recovery", user_controller.ForgotPassword)).Methods("POST")
+	usr.HandleFunc("/recover/{token}", user_controller.ShowRecover).Methods("GET")
+	usr.HandleFunc("/recover/{token}", limit("recovery", user_controller.Recover)).Methods("POST")
+	usr.HandleFunc("/verify/{token}", limit("register", user_controller.Verify)).Methods("GET")
 	usr.HandleFunc("", user_controller.Destroy).Methods("DELETE")
+	usr.HandleFunc("/tokens", user_controller.Tokens).Methods("GET")
+	usr.HandleFunc("/tokens", user_controller.CreateToken).Methods("POST")
+	usr.HandleFunc("/tokens/{token_id}", user_controller.RevokeToken).Methods("DELETE")
+	usr.HandleFunc("/tokens/{token_id}/revoke", user_controller.RevokeToken).Methods("POST")
 
-	corporation = jsonAPI.PathPrefix("/corporation/{corp_id}").Subrouter()
+	corporation := api.PathPrefix("/corporation/{corp_id}").Subrouter()
+	corporation.HandleFunc("", corp_controller.Show).Methods("GET")
 	corporation.HandleFunc("/", corp_controller.Show).Methods("GET")
+	corporation.HandleFunc("/warehouse", corp_controller.Warehouse).Methods("GET")
+	corporation.HandleFunc("/warehouse/upgrade", corp_controller.UpgradeWarehouse).Methods("POST")
+	corporation.HandleFunc("/warehouse/{item}/retrieve/{city_id}", corp_controller.Retrieve).Methods("POST")
+	corporation.HandleFunc("/rules", corp_controller.Rules).Methods("GET")
+	corporation.HandleFunc("/rules", corp_controller.AddRule).Methods("POST")
+	corporation.HandleFunc("/rules/{rule_id}/drop", corp_controller.DropRule).Methods("POST")
+	corporation.HandleFunc("/roads", corp_controller.Roads).Methods("GET")
+	corporation.HandleFunc("/roads", corp_controller.CommissionRoad).Methods("POST")
 
 	// ensure map get generated ...
 	corporation.Use(mapMw)
 
-	caravan = jsonAPI.PathPrefix("/caravan").Subrouter()
+	caravan := api.PathPrefix("/caravan").Subrouter()
 	// caravan related stuff
 	caravan.HandleFunc("", crv_controller.Index).Methods("GET")
 	caravan.HandleFunc("/new/{city_id}", crv_controller.New).Methods("GET")
+	caravan.HandleFunc("/templates", crv_controller.Templates).Methods("GET")
+	caravan.HandleFunc("/templates/{template_id}/propose", crv_controller.ProposeTemplate).Methods("POST")
+	caravan.HandleFunc("/templates/{template_id}/drop", crv_controller.DropTemplate).Methods("POST")
 	caravan.HandleFunc("", crv_controller.Create).Methods("POST")
+	caravan.HandleFunc("/{crv_id}/history", crv_controller.History).Methods("GET")
 	caravan.HandleFunc("/{crv_id}", crv_controller.Show).Methods("GET")
 	caravan.HandleFunc("/{crv_id}/accept", crv_controller.Accept).Methods("POST")
 	caravan.HandleFunc("/{crv_id}/reject", crv_controller.Reject).Methods("POST")
@@ -188,30 +292,31 @@ func RouterSetup() *mux.Router {
 	caravan.HandleFunc("/{crv_id}/counter", crv_controller.GetCounter).Methods("POST")
 	caravan.HandleFunc("/{crv_id}/counter", crv_controller.PostCounter).Methods("POST")
 	caravan.HandleFunc("/{crv_id}/drop", crv_controller.Drop).Methods("POST")
+	caravan.HandleFunc("/{crv_id}/renew", crv_controller.Renew).Methods("POST")
+	caravan.HandleFunc("/{crv_id}/auto_renew", crv_controller.AutoRenew).Methods("POST")
+	caravan.HandleFunc("/{crv_id}/template", crv_controller.SaveTemplate).Methods("POST")
 
 	// ensure map get generated ...
 	caravan.Use(mapMw)
 
-	admin = jsonAPI.PathPrefix("/admin").Subrouter()
+	admin := api.PathPrefix("/admin").Subrouter()
 	admin.HandleFunc("", admin_controller.Index).Methods("GET")
 	admin.HandleFunc("/tools/rdb", admin_controller.ReloadDb).Methods("DELETE")
 	admin.HandleFunc("/tools/rsrv", admin_controller.ReloadServer).Methods("DELETE")
-	admin.HandleFunc("/users", admin_controller.AdminShow).Methods("GET")
+	admin.HandleFunc("/users", admin_controller.Index).Methods("GET")
+	admin.HandleFunc("/users/{user_id}", admin_controller.AdminShow).Methods("GET")
 	admin.HandleFunc("/users/{user_id}/reset", admin_controller.AdminReset).Methods("POST")
 	admin.HandleFunc("/users/{user_id}", admin_controller.AdminDestroy).Methods("DELETE")
 	admin.HandleFunc("/users/{user_id}/state/{user_state}", admin_controller.Lock).Methods("POST")
+}
 
-	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir(system.MakePath(system.Get("web_static_files", "web/static"))))))
-
-	r.HandleFunc("/favicon.ico", func(w http.ResponseWriter, r *http.Request) {
-		http.ServeFile(w, r, filepath.FromSlash(fmt.Sprintf("%s/img/favicon.ico", system.MakePath(system.Get("web_static_files", "web/static")))))
+// deprecatedMw flag legacy API replies and point to their versioned successor.
+func deprecatedMw(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", strings.Replace(r.URL.Path, "/api/", "/api/v1/", 1)))
+		next.ServeHTTP(w, r)
 	})
-
-	r.Use(logResultMw)
-	r.Use(loggingMw)
-	sessionned.Use(sessionMw)
-
-	return r
 }
 
 // initialize "gob" that handle struct serialization for session.
@@ -273,6 +378,58 @@ func mapMw(next http.Handler) http.Handler {
 	})
 }
 
+// notFound API replies with an error envelope as well.
+func notFound(w http.ResponseWriter, req *http.Request) {
+	if webtools.IsAPI(req) {
+		webtools.GenerateAPIErrorWithStatus(w, http.StatusNotFound, "unknown route, see /api/v1/openapi.json")
+		return
+	}
+	http.NotFound(w, req)
+}
+
+// methodNotAllowed API replies with an error envelope as well.
+func methodNotAllowed(w http.ResponseWriter, req *http.Request) {
+	if webtools.IsAPI(req) {
+		webtools.GenerateAPIErrorWithStatus(w, http.StatusMethodNotAllowed, "method not allowed, see /api/v1/openapi.json")
+		return
+	}
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
+// openAPI serve OpenAPI document of /api/v1 routes, generated once every route got registered.
+func openAPI(r *mux.Router) http.HandlerFunc {
+	var once sync.Once
+	var doc *openapi.Document
+	return func(w http.ResponseWriter, req *http.Request) {
+		once.Do(func() {
+			doc = openapi.Generate(r, "/api/v1", "Upsilon Cities API", "1.0",
+				grid_controller.Docs(),
+				city_controller.Docs(),
+				crv_controller.Docs(),
+				corp_controller.Docs(),
+				user_controller.Docs(),
+				admin_controller.Docs())
+		})
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(doc)
+	}
+}
+
+// isAPIRoute tell whether request targets JSON API, on which personal tokens are accepted.
+func isAPIRoute(r *http.Request) bool {
+	return r.URL.Path == "/api" || strings.HasPrefix(r.URL.Path, "/api/")
+}
+
+// tokenMw authenticate API requests bearing a personal token. Tokens are ignored outside of API.
+func tokenMw(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isAPIRoute(r) && !webtools.AuthenticateToken(w, r) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 //sessionMw start a session
 func sessionMw(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -281,7 +438,12 @@ func sessionMw(next http.Handler) http.Handler {
 		session, err := store.Get(r, "session-key")
 
 		if err != nil {
-			log.Fatalf(err.Error())
+			// usually a cookie that can't be decoded anymore, a fresh session is provided then.
+			webtools.Log(r, "Session").Warnf("Failed to load session: %s", err)
+			if session == nil {
+				http.Error(w, "Failed to load session", http.StatusInternalServerError)
+				return
+			}
 		}
 
 		context.Set(r, "session", session)
@@ -290,29 +452,176 @@ func sessionMw(next http.Handler) http.Handler {
 
 		// Must save session before replying.
 		// session := GetSession(req)
-		// log.Printf("saving session: content %v", session.Values)
+		// logger.Infof("Web", "saving session: content %v", session.Values)
 		// if err := session.Save(req, w); err != nil {
-		//	log.Printf("Error saving session: content %v", session.Values)
+		//	logger.Errorf("Web", "Error saving session: content %v", session.Values)
 		//
-		//	log.Fatalf("Error saving session: %v", err)
+		//	logger.Fatalf("Web", "Error saving session: %v", err)
 		//}
 	})
 }
 
+// csrfMw refuse state changing requests relying on session cookie that don't carry session's CSRF token.
+// API requests authenticated by a valid token aren't sent by browsers on their own, so they don't need one.
+func csrfMw(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if webtools.IsSafeMethod(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if isAPIRoute(r) && webtools.APIToken(r) != nil {
+			// authenticated by token, no cookie involved.
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !webtools.CheckCSRF(r) {
+			webtools.Log(r, "Web").Warnf("Refused %s %s: invalid or missing CSRF token", r.Method, r.URL)
+			webtools.FailWithStatus(w, r, http.StatusForbidden, "invalid or missing CSRF token, reload page and try again", "")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+var rateLimited = metrics.NewCounter("upsilon_http_rate_limited_total", "Requests refused for exceeding rate limits, by route group.", "group")
+
+// limited tell whether request exceeds limits of route group, by client IP, by user when known and by extra keys.
+// Refused requests get replied to.
+func limited(w http.ResponseWriter, r *http.Request, group string, keys ...string) bool {
+	l := ratelimit.For(group)
+	if l == nil {
+		return false
+	}
+
+	keys = append(keys, "ip:"+webtools.ClientIP(r))
+	if uid, err := webtools.CurrentUserID(r); err == nil {
+		keys = append(keys, fmt.Sprintf("user:%d", uid))
+	}
+
+	// a refused request doesn't use any token, not even from buckets that had some left.
+	ok, k, retry := l.AllowAll(keys, time.Now())
+	if ok {
+		return false
+	}
+	rateLimited.Inc(group)
+	webtools.Log(r, "Web").Warnf("Rate limited %s %s (group=%s key=%s)", r.Method, r.URL, group, k)
+	w.Header().Set("Retry-After", fmt.Sprint(int(retry.Seconds())+1))
+	if webtools.IsAPI(r) {
+		webtools.GenerateAPIErrorWithStatus(w, http.StatusTooManyRequests, "too many requests, retry later")
+	} else {
+		// no redirection, it would be limited as well.
+		http.Error(w, "Too many requests, retry later", http.StatusTooManyRequests)
+	}
+	return true
+}
+
+// limitMw throttle requests of route group, see rate_limits.
+func limitMw(group string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if limited(w, r, group) {
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// webLimitMw throttle browser requests, /api ones are only accounted for in api group.
+func webLimitMw(next http.Handler) http.Handler {
+	limited := limitMw("web")(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isAPIRoute(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		limited.ServeHTTP(w, r)
+	})
+}
+
+// limit throttle a single route, submitted login or email is accounted for as well so that an account can't be hammered from many IPs.
+func limit(group string, fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var keys []string
+		if login := r.PostFormValue("login"); login != "" {
+			keys = append(keys, "login:"+login)
+		}
+		if mail := r.PostFormValue("email"); mail != "" {
+			keys = append(keys, "email:"+mail)
+		}
+		if limited(w, r, group, keys...) {
+			return
+		}
+		fn(w, r)
+	}
+}
+
+// defaultContentSecurityPolicy allow assets the layout pulls from CDNs, inline scripts of templates and forbid framing.
+const defaultContentSecurityPolicy = "default-src 'self'; " +
+	"script-src 'self' 'unsafe-inline' https://code.jquery.com https://cdnjs.cloudflare.com https://stackpath.bootstrapcdn.com https://cdn.jsdelivr.net; " +
+	"style-src 'self' 'unsafe-inline' https://use.fontawesome.com https://stackpath.bootstrapcdn.com; " +
+	"font-src 'self' https://use.fontawesome.com; " +
+	"img-src 'self' data: blob:; " +
+	"connect-src 'self'; " +
+	"frame-ancestors 'none'; form-action 'self'; base-uri 'self'"
+
+// securityHeadersMw set standard security headers, HSTS only when served over TLS.
+func securityHeadersMw(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("Content-Security-Policy", system.Get("http_content_security_policy", defaultContentSecurityPolicy))
+		h.Set("X-Frame-Options", "DENY")
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("Referrer-Policy", "same-origin")
+		if r.TLS != nil || (system.GetBool("http_behind_tls_proxy", false) && r.Header.Get("X-Forwarded-Proto") == "https") {
+			h.Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // loggingMw tell what route has been called.
 func loggingMw(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("Web: Received request: %s %s", r.Method, r.URL)
+		webtools.Log(r, "Web").Debugf("Received request: %s %s", r.Method, r.URL)
 		next.ServeHTTP(w, r)
 	})
 }
 
-// loggingMw tell what route has been called.
+var requestDuration = metrics.NewHistogram("upsilon_http_request_duration_seconds", "Duration of HTTP requests, by method, route and status code.", metrics.DefaultBuckets, "method", "route", "code")
+
+// routeOf path template of matched route, so that ids don't explode metrics cardinality.
+func routeOf(req *http.Request) string {
+	if route := mux.CurrentRoute(req); route != nil {
+		if tmpl, err := route.GetPathTemplate(); err == nil {
+			return tmpl
+		}
+	}
+	return "unmatched"
+}
+
+// newRequestID random identifier of a request.
+func newRequestID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// logResultMw identify, log and measure result of requests.
 func logResultMw(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		defer context.Clear(req)
+
+		id := req.Header.Get("X-Request-Id")
+		if id == "" || len(id) > 64 {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-Id", id)
+		webtools.SetRequestID(req, id)
+
 		m := httpsnoop.CaptureMetrics(next, w, req)
-		log.Printf(
-			"Web: %s %s (code=%d dt=%s written=%d)",
+		requestDuration.Observe(m.Duration.Seconds(), req.Method, routeOf(req), fmt.Sprint(m.Code))
+		webtools.Log(req, "Web").Infof("%s %s (code=%d dt=%s written=%d)",
 			req.Method,
 			req.URL,
 			m.Code,
@@ -322,9 +631,10 @@ func logResultMw(next http.Handler) http.Handler {
 	})
 }
 
-// ListenAndServe start listing http server
-func ListenAndServe(router *mux.Router) {
-	log.Printf("Web: Preping ")
+// ListenAndServe start listing http server, until a shutdown gets requested.
+// Server then stops accepting requests and waits for running ones to complete before returning the request.
+func ListenAndServe(router *mux.Router) shutdown.Request {
+	logger.Infof("Web", "Preping")
 
 	s := &http.Server{
 		Addr:           fmt.Sprintf("%s:%s", system.Get("http_address", "127.0.0.1"), system.Get("http_port", "80")),
@@ -334,6 +644,26 @@ func ListenAndServe(router *mux.Router) {
 		MaxHeaderBytes: 1 << 20,
 	}
 
-	log.Printf("Web: Started server on %s and listening ... ", fmt.Sprintf("%s:%s", system.Get("http_address", ""), system.Get("http_port", "80")))
-	log.Fatal(s.ListenAndServe())
+	failed := make(chan error, 1)
+	go func() {
+		failed <- s.ListenAndServe()
+	}()
+
+	logger.Infof("Web", "Started server on %s and listening ...", fmt.Sprintf("%s:%s", system.Get("http_address", ""), system.Get("http_port", "80")))
+
+	var res shutdown.Request
+	select {
+	case err := <-failed:
+		logger.Infof("Web", "Server failed: %s", err)
+		return shutdown.Request{Code: shutdown.ExitFailure, Reason: err.Error(), Persist: true}
+	case res = <-shutdown.Requests():
+	}
+
+	logger.Infof("Web", "Shutting down: %s", res.Reason)
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), 30*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		logger.Infof("Web", "Some requests didn't complete: %s", err)
+	}
+	return res
 }
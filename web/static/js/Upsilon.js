@@ -26,10 +26,10 @@ const mapInfo = (function () {
     url: "/api/" + window.location.pathname,
     type: 'GET',
       'success': function (result) {
-          mapTmp = result;
+          mapTmp = result.data;
       }, 
       error: function(result) {        
-        alert("(Phaser3) Failed to get city data... " + result["error"]);
+        alert("(Phaser3) Failed to get city data... " + (result.responseJSON || {})["message"]);
       }
   });
   return mapTmp;
@@ -187,7 +187,7 @@ function create() {
               $('#city_click').html(result);
           }, 
           error: function(result) {            
-            alert("Failed to get city data... " + result["error"]);
+            alert("Failed to get city data... " + (result.responseJSON || {})["message"]);
           }
       }); 
 
@@ -1,3 +1,7 @@
+// every script issued request carries session's CSRF token, see layout.
+$.ajaxSetup({
+    headers: { 'X-CSRF-Token': $('meta[name="csrf-token"]').attr('content') }
+});
 
 reloadCorp = function() {
     console.log("Calling on: " + '/corporation/' + $("#nav-corp").data("corp-id"))
@@ -8,7 +12,7 @@ reloadCorp = function() {
             
         }, 
         error: function(result) {
-            alert(result["error"]);
+            alert((result.responseJSON || {})["message"]);
             window.location.replace("/map");
         }
     });
@@ -23,7 +27,7 @@ fetchRecentsUserLogs = function() {
             
         }, 
         error: function(result) {
-            alert(result["error"]);
+            alert((result.responseJSON || {})["message"]);
             window.location.replace("/map");
         }
     });
@@ -43,7 +47,7 @@ $(document).ready( function() {
                 $('#NewUsrForm').submit()                  
             }, 
             error: function(result) {            
-                alert("Le compte n'a pas pu être créé pour les raisons suivantes : " + result.responseJSON["error"]);
+                alert("Le compte n'a pas pu être créé pour les raisons suivantes : " + result.responseJSON["message"]);
             }
         }); 
         }
@@ -66,7 +70,7 @@ $(document).ready( function() {
                 location.reload();
             }, error: function(result) {
                 // Do something with the result
-                alert("Failed to drop map... " + result["error"]);
+                alert("Failed to drop map... " + (result.responseJSON || {})["message"]);
                 location.reload();
             }
         });
@@ -190,18 +194,18 @@ $(document).ready( function() {
             type: 'POST',
             success: function(result) {
                 $.ajax({
-                    url: '/city/' + result.CityID,
+                    url: '/city/' + result.data.CityID,
                     type: 'GET',
                     success: function(result) {
                         $('#city_click').html(result)                 
                     }, 
                     error: function(result) {                        
-                        alert("Failed to get city data... " + result["error"]);
+                        alert("Failed to get city data... " + (result.responseJSON || {})["message"]);
                     }
                 });              
             }, 
             error: function(result) {                
-                alert("Failed to update city data... " + result["error"]);
+                alert("Failed to update city data... " + (result.responseJSON || {})["message"]);
             }
         });       
     });
@@ -212,20 +216,48 @@ $(document).ready( function() {
             type: 'POST',
             success: function(result) {
                 $.ajax({
-                    url: '/city/' + result.CityID,
+                    url: '/city/' + result.data.CityID,
                     type: 'GET',
                     success: function(result) {
                         $('#city_click').html(result)                                       
                     }, 
                     error: function(result) {                        
-                        alert("Failed to get city data... " + result["error"]);
+                        alert("Failed to get city data... " + (result.responseJSON || {})["message"]);
                     }
                 });                
             }, 
             error: function(result) {                
-                alert("Failed to update city data... " + result["error"]);
+                alert("Failed to update city data... " + (result.responseJSON || {})["message"]);
             }
         });       
     });
 
+    $('#city_click').on('click','span.claim_city[data-city]', function() {
+        if (!confirm("Claim this city for " + $(this).data('cost') + " $$ ?")) {
+            return
+        }
+        $.ajax({
+            url: '/api/city/' + $(this).data('city') + '/claim',
+            type: 'POST',
+            success: function(result) {
+                if (result.data.Contested) {
+                    alert("Claim is contested, it will be resolved by " + result.data.ContestEnd);
+                }
+                $.ajax({
+                    url: '/city/' + result.data.CityID,
+                    type: 'GET',
+                    success: function(result) {
+                        $('#city_click').html(result)
+                    },
+                    error: function(result) {
+                        alert("Failed to get city data... " + (result.responseJSON || {})["message"]);
+                    }
+                });
+            },
+            error: function(result) {
+                alert("Failed to claim city... " + (result.responseJSON || {})["message"]);
+            }
+        });
+    });
+
 });
\ No newline at end of file
@@ -2,6 +2,7 @@ package functions
 
 import (
 	"errors"
+	"fmt"
 	"html/template"
 	"net/http"
 	"upsilon_cities_go/lib/cities/user"
@@ -29,6 +30,8 @@ func PreLoadFunctions(t *template.Template) {
 	fns["InfoAlerts"] = func() string { return "" }
 	fns["WarningAlerts"] = func() string { return "" }
 	fns["UserLogs"] = func() []user_log.UserLog { return make([]user_log.UserLog, 0) }
+	fns["CSRFToken"] = func() string { return "" }
+	fns["CSRFField"] = func() template.HTML { return "" }
 
 	t = t.Funcs(fns)
 }
@@ -50,6 +53,8 @@ func LoadFunctions(w http.ResponseWriter, req *http.Request, t *template.Templat
 	fns["InfoAlerts"] = InfoAlerts(w, req)
 	fns["WarningAlerts"] = WarningAlerts(w, req)
 	fns["UserLogs"] = UserLogs(w, req)
+	fns["CSRFToken"] = CSRFToken(w, req)
+	fns["CSRFField"] = CSRFField(w, req)
 
 	t = t.Funcs(fns)
 }
@@ -137,3 +142,17 @@ func UserLogs(w http.ResponseWriter, req *http.Request) func() []user_log.UserLo
 		return res
 	}
 }
+
+//CSRFToken Function generator, token is meant for scripts, see layout's csrf-token meta.
+func CSRFToken(w http.ResponseWriter, req *http.Request) func() string {
+	return func() string {
+		return webtools.CSRFToken(req)
+	}
+}
+
+//CSRFField Function generator, hidden input every POST form must hold.
+func CSRFField(w http.ResponseWriter, req *http.Request) func() template.HTML {
+	return func() template.HTML {
+		return template.HTML(fmt.Sprintf(`<input type="hidden" name="%s" value="%s">`, webtools.CSRFField, template.HTMLEscapeString(webtools.CSRFToken(req))))
+	}
+}
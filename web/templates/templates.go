@@ -4,7 +4,6 @@ package templates
 
 import (
 	"html/template"
-	"log"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -12,6 +11,7 @@ import (
 	"strings"
 	"time"
 	"upsilon_cities_go/lib/misc/config/system"
+	"upsilon_cities_go/lib/misc/logger"
 	"upsilon_cities_go/web/templates/functions"
 	"upsilon_cities_go/web/webtools"
 
@@ -75,7 +75,7 @@ func LoadTemplates() {
 
 	err = filepath.Walk(system.MakePath(templateConfig.TemplateLayoutPath), func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			log.Fatalf("Templates: prevent panic by handling failure accessing a path %q: %v\n", templateConfig.TemplateLayoutPath, err)
+			logger.Fatalf("Templates", "prevent panic by handling failure accessing a path %q: %v", templateConfig.TemplateLayoutPath, err)
 			return err
 		}
 		if strings.HasSuffix(info.Name(), ".tmpl") {
@@ -94,7 +94,7 @@ func LoadTemplates() {
 
 			layouts[layoutbase][layoutname] = tmpl
 
-			log.Printf("Templates: Added Layout of file : %s as %s", path, layoutbase)
+			logger.Debugf("Templates", "Added Layout of file : %s as %s", path, layoutbase)
 
 		}
 
@@ -102,12 +102,12 @@ func LoadTemplates() {
 	})
 
 	if err != nil {
-		log.Fatalf("Templates: Failed to load layout templates: %s\n", err)
+		logger.Fatalf("Templates", "Failed to load layout templates: %s", err)
 	}
 
 	err = filepath.Walk(system.MakePath(templateConfig.TemplateSharedPath), func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			log.Fatalf("Templates: prevent panic by handling failure accessing a path %q: %v\n", system.MakePath(templateConfig.TemplateSharedPath), err)
+			logger.Fatalf("Templates", "prevent panic by handling failure accessing a path %q: %v", system.MakePath(templateConfig.TemplateSharedPath), err)
 			return err
 		}
 
@@ -117,19 +117,19 @@ func LoadTemplates() {
 			sharedinfo.path = path
 			sharedinfo.lastUpdate = time.Now().UTC()
 			sharedCheck[path] = sharedinfo
-			log.Printf("Templates: Added shared of file : %s ", path)
+			logger.Debugf("Templates", "Added shared of file : %s", path)
 		}
 
 		return nil
 	})
 
 	if err != nil {
-		log.Fatalf("Templates: Failed to load shared templates: %s\n", err)
+		logger.Fatalf("Templates", "Failed to load shared templates: %s", err)
 	}
 
 	err = filepath.Walk(system.MakePath(templateConfig.TemplateIncludePath), func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			log.Fatalf("Templates: prevent panic by handling failure accessing a path %q: %v\n", system.MakePath(templateConfig.TemplateIncludePath), err)
+			logger.Fatalf("Templates", "prevent panic by handling failure accessing a path %q: %v", system.MakePath(templateConfig.TemplateIncludePath), err)
 			return err
 		}
 		if strings.HasSuffix(info.Name(), ".tmpl") {
@@ -142,7 +142,7 @@ func LoadTemplates() {
 			tmpl.baseTmpl, err = mainTemplate.Clone()
 			functions.PreLoadFunctions(tmpl.baseTmpl)
 			if err != nil {
-				log.Fatalf("Templates: Failed to clone mainTemplate: %s\n", err)
+				logger.Fatalf("Templates", "Failed to clone mainTemplate: %s", err)
 			}
 
 			files := append(append(paths(layouts[""]), append(paths(layouts[templatebase]), path)...), shared...)
@@ -153,21 +153,21 @@ func LoadTemplates() {
 
 			templates[templatefullname] = tmpl
 
-			log.Printf("Templates: Loaded template : %s as %s using layout %s\n", path, templatefullname, templatebase)
+			logger.Infof("Templates", "Loaded template : %s as %s using layout %s", path, templatefullname, templatebase)
 		}
 
 		return nil
 	})
 
 	if err != nil {
-		log.Fatalf("Templates: Failed to load templates: %s", err)
+		logger.Fatalf("Templates", "Failed to load templates: %s", err)
 
 	}
 
-	log.Printf("Templates: Loading successful Available: %d: %v", len(templates), reflect.ValueOf(templates).MapKeys())
+	logger.Infof("Templates", "Loading successful Available: %d: %v", len(templates), reflect.ValueOf(templates).MapKeys())
 
 	bufpool = bpool.NewBufferPool(64)
-	log.Println("Templates: buffer allocation successful")
+	logger.Debugf("Templates", "buffer allocation successful")
 }
 
 // if shared has been updated need to reload all templates ...
@@ -177,7 +177,7 @@ func checkShared() {
 	altered := false
 	err := filepath.Walk(system.MakePath(templateConfig.TemplateSharedPath), func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			log.Fatalf("Templates: prevent panic by handling failure accessing a path %q: %v\n", system.MakePath(templateConfig.TemplateLayoutPath), err)
+			logger.Fatalf("Templates", "prevent panic by handling failure accessing a path %q: %v", system.MakePath(templateConfig.TemplateLayoutPath), err)
 			return err
 		}
 
@@ -189,11 +189,11 @@ func checkShared() {
 			tmpShared = append(tmpShared, path)
 			sharedinfo, found := sharedCheck[path]
 			if !found {
-				log.Printf("Templates: Added shared of file : %s ", path)
+				logger.Debugf("Templates", "Added shared of file : %s", path)
 				altered = true
 			} else {
 				if info.ModTime().After(sharedinfo.lastUpdate) {
-					log.Printf("Templates: Shared file has been altered : %s ", path)
+					logger.Infof("Templates", "Shared file has been altered : %s", path)
 					altered = true
 				}
 			}
@@ -203,7 +203,7 @@ func checkShared() {
 	})
 
 	if err != nil {
-		log.Fatalf("Templates: Failed to load shared templates: %s\n", err)
+		logger.Fatalf("Templates", "Failed to load shared templates: %s", err)
 	}
 
 	if altered {
@@ -212,7 +212,7 @@ func checkShared() {
 		mainTemplate := template.New("main")
 		mainTemplate, _ = mainTemplate.Parse(mainTmpl)
 
-		log.Printf("Templates: Rebuilding templates as shared have evolved...")
+		logger.Infof("Templates", "Rebuilding templates as shared have evolved...")
 		for k, v := range templates {
 			files := append(append(paths(layouts[""]), append(paths(layouts[v.base]), v.path)...), shared...)
 			v.baseTmpl, err = mainTemplate.Clone()
@@ -231,7 +231,7 @@ func checkLayouts() {
 	altered := false
 	err := filepath.Walk(system.MakePath(templateConfig.TemplateLayoutPath), func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			log.Fatalf("Templates: prevent panic by handling failure accessing a path %q: %v\n", system.MakePath(templateConfig.TemplateLayoutPath), err)
+			logger.Fatalf("Templates", "prevent panic by handling failure accessing a path %q: %v", system.MakePath(templateConfig.TemplateLayoutPath), err)
 			return err
 		}
 
@@ -251,16 +251,16 @@ func checkLayouts() {
 			// iterate on all layouts ...
 			_, found := layouts[layoutbase]
 			if !found {
-				log.Printf("Templates: Added Layout of file : %s ", layoutfullname)
+				logger.Debugf("Templates", "Added Layout of file : %s", layoutfullname)
 				altered = true
 			} else {
 				locallayout, found := layouts[layoutbase][layoutname]
 				if !found {
-					log.Printf("Templates: Added Layout of file : %s ", layoutfullname)
+					logger.Debugf("Templates", "Added Layout of file : %s", layoutfullname)
 					altered = true
 				} else {
 					if shif.lastUpdate.After(locallayout.lastUpdate) {
-						log.Printf("Templates: Layout file has been altered : %s ", layoutfullname)
+						logger.Infof("Templates", "Layout file has been altered : %s", layoutfullname)
 						altered = true
 					}
 				}
@@ -271,7 +271,7 @@ func checkLayouts() {
 	})
 
 	if err != nil {
-		log.Fatalf("Templates: Failed to load shared templates: %s\n", err)
+		logger.Fatalf("Templates", "Failed to load shared templates: %s", err)
 	}
 
 	if altered {
@@ -279,7 +279,7 @@ func checkLayouts() {
 		mainTemplate := template.New("main")
 		mainTemplate, _ = mainTemplate.Parse(mainTmpl)
 
-		log.Printf("Templates: Rebuilding templates as shared have evolved...")
+		logger.Infof("Templates", "Rebuilding templates as shared have evolved...")
 		for k, v := range templates {
 			files := append(append(paths(layouts[""]), append(paths(layouts[v.base]), v.path)...), shared...)
 			v.baseTmpl, err = mainTemplate.Clone()
@@ -299,7 +299,7 @@ func RenderTemplateFn(w http.ResponseWriter, req *http.Request, name string, dat
 	tmpl, ok := templates[filepath.FromSlash(name)]
 	if !ok {
 		http.Error(w, "Failed to render page", http.StatusInternalServerError)
-		log.Fatalf("Templates: The template %s does not exist. Can't render. Available: %d: %v", name, len(templates), reflect.ValueOf(templates).MapKeys())
+		webtools.Log(req, "Templates").Errorf("The template %s does not exist. Can't render. Available: %d: %v", name, len(templates), reflect.ValueOf(templates).MapKeys())
 		return
 	}
 
@@ -312,14 +312,14 @@ func RenderTemplateFn(w http.ResponseWriter, req *http.Request, name string, dat
 		file, err := os.Open(tmpl.path)
 		if err != nil {
 			http.Error(w, "Failed to render page - page missing", http.StatusInternalServerError)
-			log.Fatalf("Templates: The template %s does not exist. Can't render.", name)
+			webtools.Log(req, "Templates").Errorf("The template %s does not exist. Can't render.", name)
 			return
 		}
 
 		info, _ := file.Stat()
 
 		if info.ModTime().After(tmpl.lastUpdate) {
-			log.Printf("Templates: An update is available for template: %s - %s", name, tmpl.path)
+			logger.Infof("Templates", "An update is available for template: %s - %s", name, tmpl.path)
 			mainTemplate := template.New("main")
 			mainTemplate, _ = mainTemplate.Parse(mainTmpl)
 			tmpl.baseTmpl, err = mainTemplate.Clone()
@@ -334,23 +334,32 @@ func RenderTemplateFn(w http.ResponseWriter, req *http.Request, name string, dat
 	buf := bufpool.Get()
 	defer bufpool.Put(buf)
 
-	functions.LoadFunctions(w, req, tmpl.tmpl, fns)
+	// functions are request bound: bind them on a clone, never on the shared template.
+	t, err := tmpl.tmpl.Clone()
+	if err != nil {
+		http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		webtools.Log(req, "Templates").Errorf("Error while cloning template %s : %s", tmpl.path, err)
+		return
+	}
+
+	functions.LoadFunctions(w, req, t, fns)
 
-	err := tmpl.tmpl.Execute(buf, data)
+	err = t.Execute(buf, data)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		log.Printf("Templates: Error while rendering template %s : %s", tmpl.path, err)
+		http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		webtools.Log(req, "Templates").Errorf("Error while rendering template %s : %s", tmpl.path, err)
+		return
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 
 	session := webtools.GetSession(req)
 
-	log.Printf("saving session: content %v", session.Values)
+	logger.Debugf("Templates", "saving session: content %v", session.Values)
 	if err := session.Save(req, w); err != nil {
-		log.Printf("Error saving session: content %v", session.Values)
-
-		log.Fatalf("Error saving session: %v", err)
+		http.Error(w, "Failed to save session", http.StatusInternalServerError)
+		webtools.Log(req, "Templates").Errorf("Error saving session: %v", err)
+		return
 	}
 
 	buf.WriteTo(w)
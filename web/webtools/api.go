@@ -0,0 +1,99 @@
+package webtools
+
+import (
+	"encoding/json"
+	"net/http"
+	"upsilon_cities_go/lib/misc/logger"
+)
+
+//Error codes of API replies, clients should rely on them rather than on messages.
+const (
+	CodeBadRequest   string = "bad_request"
+	CodeUnauthorized string = "unauthorized"
+	CodeForbidden    string = "forbidden"
+	CodeNotFound     string = "not_found"
+	CodeConflict     string = "conflict"
+	CodeInvalid      string = "invalid"
+	CodeTooMany      string = "too_many_requests"
+	CodeInternal     string = "internal"
+)
+
+//APIResponse envelope of every reply under /api.
+type APIResponse struct {
+	Status  string            `json:"status"`            // "ok" or "error"
+	Data    interface{}       `json:"data,omitempty"`    // payload of successful replies.
+	Code    string            `json:"code,omitempty"`    // error code, see Code* constants.
+	Message string            `json:"message,omitempty"` // human readable error.
+	Fields  map[string]string `json:"fields,omitempty"`  // errors by request field.
+}
+
+//StatusCode error code matching http status.
+func StatusCode(status int) string {
+	switch status {
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusUnprocessableEntity:
+		return CodeInvalid
+	case http.StatusTooManyRequests:
+		return CodeTooMany
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
+
+//SendAPI write envelope with provided http status.
+func SendAPI(w http.ResponseWriter, status int, res APIResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		logger.Errorf("Web", "Failed to encode API reply: %s", err)
+	}
+}
+
+//GenerateAPIData reply with status ok and data.
+func GenerateAPIData(w http.ResponseWriter, data interface{}) {
+	SendAPI(w, http.StatusOK, APIResponse{Status: "ok", Data: data})
+}
+
+//GenerateAPICreated reply with status created and data of created resource.
+func GenerateAPICreated(w http.ResponseWriter, data interface{}) {
+	SendAPI(w, http.StatusCreated, APIResponse{Status: "ok", Data: data})
+}
+
+//GenerateAPIFieldErrors reply with invalid status and errors by request field.
+func GenerateAPIFieldErrors(w http.ResponseWriter, message string, fields map[string]string) {
+	SendAPI(w, http.StatusUnprocessableEntity, APIResponse{Status: "error", Code: CodeInvalid, Message: message, Fields: fields})
+}
+
+//FailWithStatus fails current request with provided http status in API, redirect with Web.
+func FailWithStatus(w http.ResponseWriter, req *http.Request, status int, err string, backRoute string) {
+	logger.Errorf("Web", "Failed access to %s due to %s", req.URL.String(), err)
+	if IsAPI(req) {
+		GenerateAPIErrorWithStatus(w, status, err)
+	} else {
+		GetSession(req).AddFlash(err, "error")
+		Redirect(w, req, backRoute)
+	}
+}
+
+//FailFields fails current request due to invalid fields. Web gets a flash by field.
+func FailFields(w http.ResponseWriter, req *http.Request, err string, fields map[string]string, backRoute string) {
+	logger.Errorf("Web", "Failed access to %s due to %s %v", req.URL.String(), err, fields)
+	if IsAPI(req) {
+		GenerateAPIFieldErrors(w, err, fields)
+	} else {
+		GetSession(req).AddFlash(err, "error")
+		for k, v := range fields {
+			GetSession(req).AddFlash(k+": "+v, "error")
+		}
+		Redirect(w, req, backRoute)
+	}
+}
@@ -0,0 +1,51 @@
+package webtools
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+)
+
+//CSRFHeader header expected to carry CSRF token on requests issued by scripts.
+const CSRFHeader = "X-CSRF-Token"
+
+//CSRFField form field expected to carry CSRF token on form submissions.
+const CSRFField = "csrf_token"
+
+//CSRFToken token of current session, generated on first use. Session gets saved when page is rendered.
+func CSRFToken(req *http.Request) string {
+	session := GetSession(req)
+	if tok, ok := session.Values["csrf_token"].(string); ok && tok != "" {
+		return tok
+	}
+
+	buf := make([]byte, 32)
+	rand.Read(buf)
+	tok := hex.EncodeToString(buf)
+	session.Values["csrf_token"] = tok
+	return tok
+}
+
+//IsSafeMethod tell whether request method isn't expected to alter state.
+func IsSafeMethod(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+//CheckCSRF tell whether request carries CSRF token of its session, either in header or form.
+func CheckCSRF(req *http.Request) bool {
+	expected, ok := GetSession(req).Values["csrf_token"].(string)
+	if !ok || expected == "" {
+		return false
+	}
+
+	provided := req.Header.Get(CSRFHeader)
+	if provided == "" {
+		provided = req.PostFormValue(CSRFField)
+	}
+	return subtle.ConstantTimeCompare([]byte(provided), []byte(expected)) == 1
+}
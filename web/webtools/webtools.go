@@ -1,17 +1,19 @@
 package webtools
 
 import (
-	"encoding/json"
 	"errors"
-	"log"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 	"upsilon_cities_go/lib/cities/corporation_manager"
 	"upsilon_cities_go/lib/cities/tools"
 	"upsilon_cities_go/lib/cities/user"
 	"upsilon_cities_go/lib/cities/user_log"
 	"upsilon_cities_go/lib/db"
+	"upsilon_cities_go/lib/misc/config/system"
+	"upsilon_cities_go/lib/misc/logger"
 
 	"github.com/gorilla/context"
 	"github.com/gorilla/mux"
@@ -40,32 +42,136 @@ func CloseSession(r *http.Request) {
 	GetSession(r).Options.MaxAge = -1
 }
 
+//SetRequestID identifies request in logs.
+func SetRequestID(r *http.Request, id string) {
+	context.Set(r, "request_id", id)
+}
+
+//Log logger of subsystem for request, bearing request id and current user id when known.
+func Log(r *http.Request, subsystem string) *logger.Logger {
+	fields := logger.Fields{}
+	if id, ok := context.Get(r, "request_id").(string); ok {
+		fields["request_id"] = id
+	}
+	if tok := APIToken(r); tok != nil {
+		fields["user_id"] = tok.UserID
+	} else if session, ok := context.Get(r, "session").(*sessions.Session); ok {
+		if uid, ok := session.Values["current_user_id"].(int); ok {
+			fields["user_id"] = uid
+		}
+	}
+	return logger.For(subsystem).WithFields(fields)
+}
+
+//ClientIP address of client. Behind a proxy, the one it appended to X-Forwarded-For.
+func ClientIP(req *http.Request) string {
+	if system.GetBool("http_behind_tls_proxy", false) {
+		if fwd := req.Header.Get("X-Forwarded-For"); fwd != "" {
+			hops := strings.Split(fwd, ",")
+			return strings.TrimSpace(hops[len(hops)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+//AbsoluteURL url of path on this server, for links sent outside (mails ...).
+//Built from http_base_url only: request's Host header can't be trusted.
+func AbsoluteURL(path string) (string, error) {
+	base := system.Get("http_base_url", "")
+	if base == "" {
+		return "", errors.New("http_base_url isn't set, can't build absolute url")
+	}
+	return strings.TrimRight(base, "/") + path, nil
+}
+
 // IsAPI Tell whether request requires API reply or not.
 func IsAPI(req *http.Request) bool {
 	return strings.Contains(req.URL.String(), "/api/")
 }
 
+//IsAPIv1 Tell whether request targets versioned API, which replies with DTOs.
+func IsAPIv1(req *http.Request) bool {
+	return strings.Contains(req.URL.String(), "/api/v1/")
+}
+
 // IsMap Tell whether request open a map.
 func IsMap(req *http.Request) bool {
 	return strings.Contains(req.URL.String(), "/map/")
 }
 
+//BearerToken token provided in Authorization header, if any.
+func BearerToken(req *http.Request) (string, bool) {
+	auth := req.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	return token, token != ""
+}
+
+//APIToken token used to authenticate request, nil when request relies on session.
+func APIToken(req *http.Request) *user.Token {
+	tok, found := context.GetOk(req, "api_token")
+	if !found {
+		return nil
+	}
+	return tok.(*user.Token)
+}
+
+//AuthenticateToken validate bearer token of an API request, its scope and record its use.
+//Returns false and fails request when token is refused. Requests without token are left to session.
+func AuthenticateToken(w http.ResponseWriter, req *http.Request) bool {
+	plain, found := BearerToken(req)
+	if !found {
+		return true
+	}
+
+	dbh := db.New()
+	defer dbh.Close()
+
+	tok, err := user.Authenticate(dbh, plain)
+	if err != nil {
+		logger.Infof("Web", "Refused token access to %s: %s", req.URL.String(), err)
+		GenerateAPIErrorWithStatus(w, http.StatusUnauthorized, "invalid API token")
+		return false
+	}
+	if !tok.Allows(req.Method) {
+		logger.Infof("Web", "Refused %s token %d access to %s %s", tok.Scope, tok.ID, req.Method, req.URL.String())
+		GenerateAPIErrorWithStatus(w, http.StatusForbidden, "API token is read only")
+		return false
+	}
+
+	if err := tok.Touch(dbh, time.Now().UTC()); err != nil {
+		logger.Infof("Web", "%s", err)
+	}
+	context.Set(req, "api_token", tok)
+	return true
+}
+
 //CurrentUser fetch current user.
 func CurrentUser(req *http.Request) (*user.User, error) {
-	if IsLogged(req) {
-		dbh := db.New()
-		defer dbh.Close()
-		us, err := user.ByID(dbh, GetSession(req).Values["current_user_id"].(int))
-		if err != nil {
-			return nil, err
-		}
-		return us, nil
+	uid, err := CurrentUserID(req)
+	if err != nil {
+		return nil, err
 	}
-	return nil, errors.New("no user logged in")
+	dbh := db.New()
+	defer dbh.Close()
+	us, err := user.ByID(dbh, uid)
+	if err != nil {
+		return nil, err
+	}
+	return us, nil
 }
 
-//CurrentUserID fetch current user.
+//CurrentUserID fetch current user, either from API token or session.
 func CurrentUserID(req *http.Request) (int, error) {
+	if tok := APIToken(req); tok != nil {
+		return tok.UserID, nil
+	}
 	if IsLogged(req) {
 		return GetSession(req).Values["current_user_id"].(int), nil
 	}
@@ -80,23 +186,39 @@ func UserLogs(req *http.Request) ([]user_log.UserLog, error) {
 	dbh := db.New()
 	defer dbh.Close()
 	uid, _ := CurrentUserID(req)
-	return user_log.Since(dbh, uid, tools.AboutNow(-300)), nil
+	return user_log.Since(dbh, uid, tools.AboutNow(-300))
 }
 
-//IsLogged tell whether user is logged or not.
+//IsLogged tell whether user is logged or not, either through session or API token.
 func IsLogged(req *http.Request) bool {
+	if APIToken(req) != nil {
+		return true
+	}
 	_, found := GetSession(req).Values["current_user_id"]
 	return found
 }
 
-//IsAdmin tell whether user is logged or not.
+//IsAdmin tell whether user is logged as an admin. Session holds admin flag of every user, not only admins.
 func IsAdmin(req *http.Request) bool {
-	_, found := GetSession(req).Values["is_admin"]
-	return found
+	admin, _ := GetSession(req).Values["is_admin"].(bool)
+	return admin
 }
 
 //CurrentCorpID tell whether user is logged or not.
+//API token users pick their corporation with X-Corporation-ID header, it must belong to them.
 func CurrentCorpID(req *http.Request) (int, error) {
+	if tok := APIToken(req); tok != nil {
+		corpID, err := strconv.Atoi(req.Header.Get("X-Corporation-ID"))
+		if err != nil {
+			return 0, errors.New("not found")
+		}
+		cm, err := corporation_manager.GetCorporationHandler(corpID)
+		if err != nil || cm.Get().OwnerID != tok.UserID {
+			return 0, errors.New("not found")
+		}
+		return corpID, nil
+	}
+
 	corp, found := GetSession(req).Values["current_corp_id"]
 	if !found {
 		return 0, errors.New("not found")
@@ -133,7 +255,7 @@ func GetInt(req *http.Request, key string) (int, error) {
 	vars := mux.Vars(req)
 	value, err := strconv.Atoi(vars[key])
 	if err != nil {
-		log.Printf("Web: requested key: %s , not found in: %s", key, req.URL)
+		logger.Infof("Web", "requested key: %s , not found in: %s", key, req.URL)
 		return 0, errors.New("Invalid key requested")
 	}
 	return value, nil
@@ -156,27 +278,21 @@ func GetString(req *http.Request, key string) (value string, found bool) {
 	return
 }
 
-//Fail fails current request with API and redirect with Web; should set session error ;) but can't right now ...
+//Fail fails current request as a bad request with API and redirect with Web.
 func Fail(w http.ResponseWriter, req *http.Request, err string, backRoute string) {
-	log.Printf("Web: Failed access to %s due to %s", req.URL.String(), err)
-	if IsAPI(req) {
-		GenerateAPIError(w, err)
-	} else {
-		GetSession(req).AddFlash(err, "error")
-		Redirect(w, req, backRoute)
-	}
+	FailWithStatus(w, req, http.StatusBadRequest, err, backRoute)
 }
 
 //Redirect user to targeted page. If route is empty, will redirect to referer. (calling webpage)
 func Redirect(w http.ResponseWriter, req *http.Request, route string) {
-	log.Printf("Web: Redirecting to %s", route)
+	logger.Infof("Web", "Redirecting to %s", route)
 
 	session := GetSession(req)
-	log.Printf("saving session: content %v", session.Values)
+	logger.Debugf("Web", "saving session: content %v", session.Values)
 	if err := session.Save(req, w); err != nil {
-		log.Printf("Error saving session: content %v", session.Values)
-
-		log.Fatalf("Error saving session: %v", err)
+		http.Error(w, "Failed to save session", http.StatusInternalServerError)
+		Log(req, "Web").Errorf("Error saving session: %v", err)
+		return
 	}
 
 	if route == "" {
@@ -227,7 +343,7 @@ func HasValue(req *http.Request, key string) bool {
 //CheckLogged if not logged return false and fails request
 func CheckLogged(w http.ResponseWriter, req *http.Request) bool {
 	if !IsLogged(req) {
-		Fail(w, req, "must be logged to access this content.", "")
+		FailWithStatus(w, req, http.StatusUnauthorized, "must be logged to access this content.", "")
 		return false
 	}
 	return true
@@ -236,7 +352,7 @@ func CheckLogged(w http.ResponseWriter, req *http.Request) bool {
 //CheckAPI if not logged return false and fails request
 func CheckAPI(w http.ResponseWriter, req *http.Request) bool {
 	if !IsAPI(req) {
-		Fail(w, req, "content is only accessible in API", "")
+		FailWithStatus(w, req, http.StatusNotFound, "content is only accessible in API", "")
 		return false
 	}
 	return true
@@ -245,7 +361,7 @@ func CheckAPI(w http.ResponseWriter, req *http.Request) bool {
 //CheckWeb if not logged return false and fails request
 func CheckWeb(w http.ResponseWriter, req *http.Request) bool {
 	if IsAPI(req) {
-		Fail(w, req, "content is only accessible in WEB", "")
+		FailWithStatus(w, req, http.StatusNotFound, "content is only accessible in WEB", "")
 		return false
 	}
 	return true
@@ -254,7 +370,7 @@ func CheckWeb(w http.ResponseWriter, req *http.Request) bool {
 //CheckAdmin if not logged return false and fails request
 func CheckAdmin(w http.ResponseWriter, req *http.Request) bool {
 	if !IsAdmin(req) {
-		Fail(w, req, "content is for admin eyes only", "")
+		FailWithStatus(w, req, http.StatusForbidden, "content is for admin eyes only", "")
 		return false
 	}
 	return true
@@ -262,28 +378,15 @@ func CheckAdmin(w http.ResponseWriter, req *http.Request) bool {
 
 // GenerateAPIError generate a simple JSON reply with error message provided.
 func GenerateAPIError(w http.ResponseWriter, message string) {
-	var repm = make(map[string]string)
-	repm["status"] = "error"
-	repm["error"] = message
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusBadRequest)
-	json.NewEncoder(w).Encode(repm)
+	GenerateAPIErrorWithStatus(w, http.StatusBadRequest, message)
+}
+
+// GenerateAPIErrorWithStatus generate a simple JSON reply with error message and http status provided.
+func GenerateAPIErrorWithStatus(w http.ResponseWriter, status int, message string) {
+	SendAPI(w, status, APIResponse{Status: "error", Code: StatusCode(status), Message: message})
 }
 
 // GenerateAPIOkAndSend generate a simple JSON reply with status: ok.
 func GenerateAPIOkAndSend(w http.ResponseWriter) {
-	var repm = make(map[string]string)
-	repm["status"] = "ok"
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(200)
-	json.NewEncoder(w).Encode(repm)
-}
-
-// GenerateAPIOk generate a simple JSON reply with status: ok.
-func GenerateAPIOk(w http.ResponseWriter) map[string]string {
-	var repm = make(map[string]string)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(200)
-	repm["status"] = "ok"
-	return repm
+	SendAPI(w, http.StatusOK, APIResponse{Status: "ok"})
 }